@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// abort interrupts the run with the given cause, like a SIGINT: workers stop taking new work,
+// requests in flight are aborted and the phase saves its checkpoint
+func (te *TestExecutor) abort(cause error) {
+	if te.cancel == nil || te.interrupted() {
+		return
+	}
+	printFailure("\nAborting run: %v\n", cause)
+	te.cancel(cause)
+}
+
+// abortCause returns why the run aborted itself, nil if it was not aborted or was interrupted by a
+// signal
+func (te *TestExecutor) abortCause() error {
+	cause := context.Cause(te.ctx)
+	if cause == nil || errors.Is(cause, context.Canceled) {
+		return nil
+	}
+	return cause
+}
+
+// errorBudget aborts the run when the share of failed requests, those without a response or with
+// an error status, exceeds the configured percentage in an interval with enough requests
+type errorBudget struct {
+	percent     float64
+	minRequests int
+	interval    time.Duration
+	stats       *TestStats
+	abort       func(error)
+	stop        chan struct{}
+	done        chan struct{}
+
+	// total and failed are the request counts at the previous evaluation
+	total  int
+	failed int
+}
+
+// StartErrorBudget starts watching the error rate of the run in the background, if an abort
+// threshold is configured
+func (te *TestExecutor) StartErrorBudget() error {
+	cfg := te.config.Execution
+	if cfg.AbortErrorPercent <= 0 {
+		return nil
+	}
+	if err := validateErrorBudget(cfg); err != nil {
+		return err
+	}
+
+	budget := &errorBudget{
+		percent:     cfg.AbortErrorPercent,
+		minRequests: cfg.AbortMinRequests,
+		interval:    time.Duration(cfg.AbortInterval) * time.Second,
+		stats:       te.stats,
+		abort:       te.abort,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	budget.total, budget.failed = te.stats.requestCounts()
+
+	te.errorBudget = budget
+	go budget.run()
+
+	return nil
+}
+
+// validateErrorBudget checks the abort interval, if an abort threshold is configured
+func validateErrorBudget(cfg ExecutionConfig) error {
+	if cfg.AbortErrorPercent > 0 && cfg.AbortInterval < 1 {
+		return fmt.Errorf("abort interval must be positive, got %d", cfg.AbortInterval)
+	}
+	return nil
+}
+
+// StopErrorBudget stops watching the error rate
+func (te *TestExecutor) StopErrorBudget() {
+	if te.errorBudget == nil {
+		return
+	}
+
+	close(te.errorBudget.stop)
+	<-te.errorBudget.done
+	te.errorBudget = nil
+}
+
+// run evaluates the error rate every interval until stopped
+func (eb *errorBudget) run() {
+	defer close(eb.done)
+
+	ticker := time.NewTicker(eb.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			eb.evaluate()
+		case <-eb.stop:
+			return
+		}
+	}
+}
+
+// evaluate aborts the run if too many of the requests completed since the previous evaluation
+// failed. An interval with fewer requests than the minimum is added to the next one.
+func (eb *errorBudget) evaluate() {
+	total, failed := eb.stats.requestCounts()
+	requests, failures := total-eb.total, failed-eb.failed
+	if requests == 0 || requests < eb.minRequests {
+		return
+	}
+	eb.total, eb.failed = total, failed
+
+	rate := float64(failures) * 100 / float64(requests)
+	if rate > eb.percent {
+		eb.abort(fmt.Errorf("%.1f%% of the last %d requests failed, above the abort threshold of %g%%", rate, requests, eb.percent))
+	}
+}
+
+// requestCounts returns the number of requests completed so far and how many of them failed
+func (ts *TestStats) requestCounts() (total, failed int) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	for _, op := range ts.Operations {
+		total += len(op.Latencies)
+		failed += op.Failed
+	}
+	return total, failed
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// accessLogTimeFormat is the %t timestamp format of the Apache access log
+const accessLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// accessLog writes every request in the Apache combined log format, so tools built for server
+// access logs such as goaccess or an ELK pipeline can analyze the traffic as the client saw it
+type accessLog struct {
+	file    *os.File
+	writer  *bufio.Writer
+	latency bool // append the request duration in microseconds to each line
+	mutex   sync.Mutex
+}
+
+// newAccessLog opens the access log at path for appending, as a web server does across restarts
+func newAccessLog(path string, latency bool) (*accessLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %v", err)
+	}
+
+	return &accessLog{file: file, writer: bufio.NewWriter(file), latency: latency}, nil
+}
+
+// Record writes a line for a request sent at start that completed after duration with the given
+// status code, 0 when it got no response, and response body size
+func (l *accessLog) Record(req *http.Request, start time.Time, duration time.Duration, statusCode int, size int64) {
+	user, _, ok := req.BasicAuth()
+	if !ok || user == "" {
+		user = "-"
+	}
+
+	bytes := "-"
+	if size > 0 {
+		bytes = fmt.Sprint(size)
+	}
+
+	agent := req.UserAgent()
+	if agent == "" {
+		agent = "go-perf"
+	}
+
+	line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s %q %q",
+		req.URL.Hostname(), user, start.Format(accessLogTimeFormat), req.Method, req.URL.RequestURI(), req.Proto,
+		statusCode, bytes, orDash(req.Referer()), agent)
+	if l.latency {
+		line += fmt.Sprintf(" %d", duration.Microseconds())
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.writer.WriteString(line + "\n")
+}
+
+// Close flushes and closes the access log
+func (l *accessLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.writer.Flush(); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to write access log: %v", err)
+	}
+	return l.file.Close()
+}
+
+// orDash returns s, or "-" for an empty field as the access log format has it
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
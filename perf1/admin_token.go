@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Auth modes for admin requests
+const (
+	// AuthModeBasic sends the tenant admin credentials with basic authentication
+	AuthModeBasic = "basic"
+	// AuthModeClientCredentials obtains access tokens with the client credentials grant
+	AuthModeClientCredentials = "client_credentials"
+	// AuthModePassword obtains access tokens for the tenant admin with the password grant
+	AuthModePassword = "password"
+)
+
+// adminTokenExpirySkew is how long before its expiry a cached token is replaced, so requests in
+// flight do not carry a token that expires on the way
+const adminTokenExpirySkew = 30 * time.Second
+
+// adminTokenCache obtains access tokens for admin requests from the token endpoint of each tenant
+// and caches them until shortly before they expire. It is shared by all workers so each tenant's
+// token is requested once rather than once per worker.
+type adminTokenCache struct {
+	grant  string
+	scope  string
+	tokens map[int]*cachedAdminToken
+	mutex  sync.Mutex
+}
+
+// cachedAdminToken is the current token of one tenant. Its mutex is held while the token is
+// renewed, so workers of the same tenant wait for one renewal instead of each starting their own.
+type cachedAdminToken struct {
+	accessToken  string
+	refreshToken string
+	expiresAt    time.Time
+	mutex        sync.Mutex
+}
+
+// newAdminTokenCache creates a token cache for the configured auth mode, or returns nil when admin
+// requests use basic authentication
+func newAdminTokenCache(config *ServerConfig) (*adminTokenCache, error) {
+	switch config.AuthMode {
+	case "", AuthModeBasic:
+		return nil, nil
+	case AuthModeClientCredentials, AuthModePassword:
+		return &adminTokenCache{
+			grant:  config.AuthMode,
+			scope:  config.AuthScope,
+			tokens: make(map[int]*cachedAdminToken),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown auth mode %q, expected %s, %s or %s", config.AuthMode, AuthModeBasic, AuthModeClientCredentials, AuthModePassword)
+}
+
+// Token returns a valid access token for a tenant, renewing it when it is missing or about to
+// expire. A refresh token is used for renewal when the server issued one, falling back to a new
+// grant if the refresh is refused.
+func (c *adminTokenCache) Token(ctx context.Context, client *HTTPClient, tenantIndex int) (string, error) {
+	c.mutex.Lock()
+	cached, ok := c.tokens[tenantIndex]
+	if !ok {
+		cached = &cachedAdminToken{}
+		c.tokens[tenantIndex] = cached
+	}
+	c.mutex.Unlock()
+
+	cached.mutex.Lock()
+	defer cached.mutex.Unlock()
+
+	if cached.accessToken != "" && (cached.expiresAt.IsZero() || time.Now().Before(cached.expiresAt)) {
+		return cached.accessToken, nil
+	}
+
+	var tokens *TokenResponse
+	var err error
+	if cached.refreshToken != "" {
+		form := url.Values{}
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", cached.refreshToken)
+		tokens, err = client.requestToken(ctx, tenantIndex, form)
+	}
+	if tokens == nil {
+		tokens, err = client.requestToken(ctx, tenantIndex, c.grantForm(client.config, tenantIndex))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	cached.accessToken = tokens.AccessToken
+	cached.refreshToken = tokens.RefreshToken
+	cached.expiresAt = time.Time{}
+	if tokens.ExpiresIn > 0 {
+		cached.expiresAt = time.Now().Add(time.Duration(tokens.ExpiresIn)*time.Second - adminTokenExpirySkew)
+	}
+
+	return cached.accessToken, nil
+}
+
+// Invalidate drops a tenant's token after the server rejected it, unless another worker has
+// already replaced it
+func (c *adminTokenCache) Invalidate(tenantIndex int, token string) {
+	c.mutex.Lock()
+	cached, ok := c.tokens[tenantIndex]
+	c.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	cached.mutex.Lock()
+	defer cached.mutex.Unlock()
+
+	if cached.accessToken == token {
+		cached.accessToken = ""
+	}
+}
+
+// grantForm builds the token request for a tenant's admin token
+func (c *adminTokenCache) grantForm(config *Config, tenantIndex int) url.Values {
+	form := url.Values{}
+	form.Set("grant_type", c.grant)
+	if c.grant == AuthModePassword {
+		form.Set("username", config.GetTenantUsername(tenantIndex))
+		form.Set("password", config.Server.Password)
+	}
+	if c.scope != "" {
+		form.Set("scope", c.scope)
+	}
+	return form
+}
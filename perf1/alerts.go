@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// alertRule fires when the given latency percentile of the requests completed in every evaluation
+// interval stays above the threshold for the given duration
+type alertRule struct {
+	spec       string
+	percentile float64
+	threshold  time.Duration
+	duration   time.Duration
+
+	// breachedAt is when the rule started breaching, zero while it is not
+	breachedAt time.Time
+	firing     bool
+}
+
+// alertRulePattern matches rules such as p95>1s/60s
+var alertRulePattern = regexp.MustCompile(`^p([0-9]+(?:\.[0-9]+)?)>([^/]+)/(.+)$`)
+
+// parseAlertRule parses a rule of the form p<percentile>><threshold>/<duration>, e.g. p95>1s/60s
+func parseAlertRule(spec string) (*alertRule, error) {
+	match := alertRulePattern.FindStringSubmatch(spec)
+	if match == nil {
+		return nil, fmt.Errorf("invalid alert rule %q, expected e.g. p95>1s/60s", spec)
+	}
+
+	p, err := strconv.ParseFloat(match[1], 64)
+	if err != nil || p <= 0 || p > 100 {
+		return nil, fmt.Errorf("invalid percentile in alert rule %q", spec)
+	}
+
+	threshold, err := time.ParseDuration(match[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid threshold in alert rule %q: %v", spec, err)
+	}
+
+	duration, err := time.ParseDuration(match[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration in alert rule %q: %v", spec, err)
+	}
+
+	return &alertRule{spec: spec, percentile: p, threshold: threshold, duration: duration}, nil
+}
+
+// parseAlertRules checks the alert interval and parses the configured rules
+func parseAlertRules(cfg AlertsConfig) ([]*alertRule, error) {
+	if len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+	if cfg.Interval < 1 {
+		return nil, fmt.Errorf("alert interval must be positive, got %d", cfg.Interval)
+	}
+
+	rules := make([]*alertRule, 0, len(cfg.Rules))
+	for _, spec := range cfg.Rules {
+		rule, err := parseAlertRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Alert is the notification posted to the alert webhook when a rule starts or stops firing
+type Alert struct {
+	Text      string    `json:"text"` // for chat webhooks that only show a message
+	Rule      string    `json:"rule"`
+	Status    string    `json:"status"` // firing or resolved
+	Phase     string    `json:"phase"`
+	Value     string    `json:"value"`
+	Threshold string    `json:"threshold"`
+	Since     time.Time `json:"since"`
+}
+
+// alertMonitor evaluates the alert rules against the request latencies of the run
+type alertMonitor struct {
+	rules    []*alertRule
+	webhook  string
+	interval time.Duration
+	stats    *TestStats
+	client   *http.Client
+	stop     chan struct{}
+	done     chan struct{}
+
+	// abort interrupts the run when a rule fires, nil to only report it
+	abort func(error)
+}
+
+// StartAlerts starts evaluating the configured alert rules in the background
+func (te *TestExecutor) StartAlerts() error {
+	cfg := te.config.Alerts
+	if len(cfg.Rules) == 0 {
+		return nil
+	}
+
+	rules, err := parseAlertRules(cfg)
+	if err != nil {
+		return err
+	}
+
+	monitor := &alertMonitor{
+		rules:    rules,
+		webhook:  cfg.Webhook,
+		interval: time.Duration(cfg.Interval) * time.Second,
+		stats:    te.stats,
+		client:   &http.Client{Transport: offlineGuard(te.config, http.DefaultTransport), Timeout: 10 * time.Second},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if cfg.Abort {
+		monitor.abort = te.abort
+	}
+
+	te.stats.StartAlertWindow()
+	te.alerts = monitor
+	go monitor.run()
+
+	return nil
+}
+
+// StopAlerts stops evaluating the alert rules
+func (te *TestExecutor) StopAlerts() {
+	if te.alerts == nil {
+		return
+	}
+
+	close(te.alerts.stop)
+	<-te.alerts.done
+	te.alerts = nil
+}
+
+// run evaluates the rules every interval until stopped
+func (am *alertMonitor) run() {
+	defer close(am.done)
+
+	ticker := time.NewTicker(am.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			am.evaluate(time.Now())
+		case <-am.stop:
+			return
+		}
+	}
+}
+
+// evaluate checks every rule against the requests completed since the previous evaluation. An
+// interval without completed requests breaches every rule while requests are in flight, since the
+// server is not answering at all, and leaves the rules unchanged while the client is idle.
+func (am *alertMonitor) evaluate(now time.Time) {
+	latencies, inFlight := am.stats.TakeAlertWindow()
+	if len(latencies) == 0 && inFlight == 0 {
+		return
+	}
+	stalled := len(latencies) == 0
+
+	sortDurations(latencies)
+
+	var notifications sync.WaitGroup
+	for _, rule := range am.rules {
+		// No request answered in the interval, so the percentile is as high as it gets
+		value := "unbounded"
+		if !stalled {
+			latency := percentile(latencies, rule.percentile)
+			if latency <= rule.threshold {
+				if rule.firing {
+					printSuccess("\n[ALERT RESOLVED] %s: p%g is %v\n", rule.spec, rule.percentile, latency.Round(time.Millisecond))
+					am.notify(&notifications, rule, "resolved", latency.Round(time.Millisecond).String(), now)
+				}
+				rule.breachedAt = time.Time{}
+				rule.firing = false
+				continue
+			}
+			value = latency.Round(time.Millisecond).String()
+		}
+
+		if rule.breachedAt.IsZero() {
+			rule.breachedAt = now
+		}
+
+		if !rule.firing && now.Sub(rule.breachedAt) >= rule.duration {
+			rule.firing = true
+			printWarning("\n[ALERT] %s: p%g is %s, above %v since %s\n", rule.spec, rule.percentile,
+				value, rule.threshold, rule.breachedAt.Format("15:04:05"))
+			am.notify(&notifications, rule, "firing", value, now)
+			if am.abort != nil {
+				am.abort(fmt.Errorf("alert %s fired with p%g at %s", rule.spec, rule.percentile, value))
+			}
+		}
+	}
+
+	notifications.Wait()
+}
+
+// notify posts an alert to the webhook, if one is configured
+func (am *alertMonitor) notify(wg *sync.WaitGroup, rule *alertRule, status, value string, now time.Time) {
+	if am.webhook == "" {
+		return
+	}
+
+	since := rule.breachedAt
+	if status == "resolved" {
+		since = now
+	}
+
+	alert := Alert{
+		Text:      fmt.Sprintf("go-perf alert %s: %s (p%g is %s)", status, rule.spec, rule.percentile, value),
+		Rule:      rule.spec,
+		Status:    status,
+		Phase:     am.stats.CurrentPhase(),
+		Value:     value,
+		Threshold: rule.threshold.String(),
+		Since:     since,
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		payload, err := json.Marshal(alert)
+		if err != nil {
+			printFailure("Failed to marshal alert: %v\n", err)
+			return
+		}
+
+		resp, err := am.client.Post(am.webhook, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			printFailure("Failed to send alert to webhook: %v\n", err)
+			return
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			printWarning("Alert webhook answered with status %d\n", resp.StatusCode)
+		}
+	}()
+}
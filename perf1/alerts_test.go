@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlertEvaluate(t *testing.T) {
+	tests := []struct {
+		name      string
+		latencies []time.Duration
+		inFlight  int
+		wantFired bool
+	}{
+		{name: "slow requests breach", latencies: []time.Duration{2 * time.Second}, wantFired: true},
+		{name: "fast requests pass", latencies: []time.Duration{100 * time.Millisecond}},
+		{name: "no requests while idle", inFlight: 0},
+		{name: "no requests while some are in flight", inFlight: 3, wantFired: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := NewTestStats()
+			stats.StartAlertWindow()
+			for _, latency := range tt.latencies {
+				stats.RecordOperation("Create User", latency, 201)
+			}
+			stats.inFlight = tt.inFlight
+
+			rule, err := parseAlertRule("p95>1s/0s")
+			if err != nil {
+				t.Fatal(err)
+			}
+			monitor := &alertMonitor{rules: []*alertRule{rule}, stats: stats}
+			monitor.evaluate(time.Now())
+
+			if rule.firing != tt.wantFired {
+				t.Errorf("firing = %v, want %v", rule.firing, tt.wantFired)
+			}
+		})
+	}
+}
+
+func TestAlertWindowSurvivesSummaries(t *testing.T) {
+	stats := NewTestStats()
+	stats.StartAlertWindow()
+	stats.RecordOperation("Create User", 3*time.Second, 201)
+	stats.RecordOperation("Create User", time.Second, 201)
+	stats.TakeAlertWindow()
+
+	// Summaries sort the operation latencies in place, which must not affect the next window
+	SummarizeLatencies(stats.Operations["Create User"].Latencies)
+	stats.RecordOperation("Create User", 2*time.Second, 201)
+
+	latencies, _ := stats.TakeAlertWindow()
+	if len(latencies) != 1 || latencies[0] != 2*time.Second {
+		t.Errorf("window = %v, want [2s]", latencies)
+	}
+}
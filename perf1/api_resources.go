@@ -0,0 +1,528 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiResourcePolicy is the authorization policy the application is authorized for the API resources
+// with. Unlike role based access control it grants every requested scope, so the tokens measure
+// scope validation without roles having to carry the scopes.
+const apiResourcePolicy = "No Policy"
+
+// apiResourceStats holds the outcome of registering the API resources and of the tokens requested
+// with their scopes
+type apiResourceStats struct {
+	created         int
+	createFailed    int
+	authorized      int
+	authorizeFailed int
+	createLatencies []time.Duration
+	issued          int
+	tokenFailed     int
+	requestedScopes int
+	grantedScopes   int
+	tokenLatencies  []time.Duration
+	mutex           sync.Mutex
+}
+
+// apiTokenJob is the scoped token request of one user in one tenant
+type apiTokenJob struct {
+	TenantIndex int
+	UserOffset  int
+	Username    string
+}
+
+// ExecuteAPIResourcePhase registers the configured API resources and their scopes in every tenant
+// and then requests tokens for the created users with those scopes, reporting registration and
+// token latency separately
+func (te *TestExecutor) ExecuteAPIResourcePhase() error {
+	cfg := te.config.APIResources
+	if cfg.PerTenant < 1 {
+		return fmt.Errorf("API resources per tenant must be positive, got %d", cfg.PerTenant)
+	}
+	if cfg.ScopesPerResource < 1 {
+		return fmt.Errorf("scopes per API resource must be positive, got %d", cfg.ScopesPerResource)
+	}
+	if total := cfg.PerTenant * cfg.ScopesPerResource; cfg.TokenScopes < 0 || cfg.TokenScopes > total {
+		return fmt.Errorf("scopes per token must be between 0 and the %d registered scopes, got %d", total, cfg.TokenScopes)
+	}
+
+	fmt.Println("Starting API resource phase...")
+	fmt.Printf("- API Resources: %d per tenant with %d scopes each\n", cfg.PerTenant, cfg.ScopesPerResource)
+	fmt.Printf("- Tenants: %d\n", te.config.Execution.NoOfTenants)
+	fmt.Printf("- Authorize Application: %t\n", cfg.AuthorizeApplication)
+	fmt.Printf("- Scopes Per Token: %d\n", cfg.TokenScopes)
+
+	stats := &apiResourceStats{}
+
+	startTime := time.Now()
+	if err := te.registerAPIResources(stats); err != nil {
+		return err
+	}
+	if te.interrupted() {
+		stats.print(0)
+		return ErrInterrupted
+	}
+	fmt.Printf("API resource registration completed in %v\n", time.Since(startTime))
+
+	var tokenElapsed time.Duration
+	if cfg.TokenScopes > 0 {
+		tokenStart := time.Now()
+		te.requestScopedTokens(stats)
+		tokenElapsed = time.Since(tokenStart)
+		if te.interrupted() {
+			fmt.Printf("Scoped token requests interrupted after %v\n", tokenElapsed)
+			stats.print(tokenElapsed)
+			return ErrInterrupted
+		}
+		fmt.Printf("Scoped token requests completed in %v\n", tokenElapsed)
+	}
+
+	stats.print(tokenElapsed)
+	return nil
+}
+
+// registerAPIResources creates the API resources of every tenant using multiple threads, authorizes
+// the application for each one when configured, and records their IDs to CSV
+func (te *TestExecutor) registerAPIResources(stats *apiResourceStats) error {
+	cfg := te.config.APIResources
+
+	var appIDs map[int]string
+	if cfg.AuthorizeApplication {
+		var err error
+		if appIDs, err = te.findApplicationIDs(); err != nil {
+			return err
+		}
+	}
+
+	apiWriter, err := NewCSVWriter(cfg.CsvPath)
+	if err != nil {
+		return fmt.Errorf("failed to create API resource ID CSV writer: %v", err)
+	}
+	apiWriter.csvOutputOptions = te.outputOptions()
+
+	var wg sync.WaitGroup
+	for _, task := range te.perTenantTasks(cfg.PerTenant) {
+		task.Client = te.newHTTPClient()
+
+		wg.Add(1)
+		go te.apiResourceWorker(task, appIDs, apiWriter, stats, &wg)
+	}
+
+	wg.Wait()
+
+	if err := apiWriter.Close(); err != nil {
+		printFailure("Failed to close API resource ID CSV writer: %v\n", err)
+	}
+	return nil
+}
+
+// findApplicationIDs returns the ID of the application of the OAuth2 client in every tenant, looked
+// up once before the workers authorize it
+func (te *TestExecutor) findApplicationIDs() (map[int]string, error) {
+	client := te.newHTTPClient()
+	appIDs := make(map[int]string)
+
+	tenantStart := te.config.Execution.TenantStartNumber
+	for tenantIndex := tenantStart; tenantIndex < tenantStart+te.config.Execution.NoOfTenants; tenantIndex++ {
+		id, err := client.FindApplicationID(te.ctx, tenantIndex, te.config.OAuth.ClientID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the application of client %s in tenant %d: %v", te.config.OAuth.ClientID, tenantIndex, err)
+		}
+		if id == "" {
+			return nil, fmt.Errorf("no application with client ID %s in tenant %d", te.config.OAuth.ClientID, tenantIndex)
+		}
+		appIDs[tenantIndex] = id
+	}
+	return appIDs, nil
+}
+
+// apiResourceWorker registers the API resources of the task's range, carried in its user range, for
+// all of the task's tenants
+func (te *TestExecutor) apiResourceWorker(task WorkerTask, appIDs map[int]string, apiWriter *CSVWriter, stats *apiResourceStats, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	waitForRampUp(te.ctx, task.StartDelay)
+
+	startTime := time.Now()
+	fmt.Printf("Thread %d: Registering API resources %d-%d for tenants %d-%d\n",
+		task.ThreadID, task.UserStart, task.UserEnd, task.TenantStart, task.TenantEnd-1)
+
+	for resourceIndex := task.UserStart; resourceIndex <= task.UserEnd && !te.interrupted(); resourceIndex++ {
+		for tenantIndex := task.TenantStart; tenantIndex < task.TenantEnd; tenantIndex++ {
+			requestStart := time.Now()
+			id, err := task.Client.CreateAPIResource(te.ctx, tenantIndex, resourceIndex)
+			if err != nil && te.interrupted() {
+				return
+			}
+			stats.recordCreate(time.Since(requestStart), err)
+			te.stats.RecordError(err)
+
+			if err != nil {
+				printFailure("Thread %d: Failed to register API resource %d for tenant %d: %v\n",
+					task.ThreadID, resourceIndex, tenantIndex, err)
+				continue
+			}
+
+			if err := apiWriter.WriteScimID(tenantIndex, id); err != nil {
+				printFailure("Failed to write API resource ID to CSV: %v\n", err)
+			}
+
+			if appIDs == nil {
+				continue
+			}
+
+			err = task.Client.AuthorizeAPIResource(te.ctx, tenantIndex, appIDs[tenantIndex], id, te.config.apiResourceScopes(resourceIndex))
+			if err != nil && te.interrupted() {
+				return
+			}
+			stats.recordAuthorize(err)
+			te.stats.RecordError(err)
+
+			if err != nil {
+				printFailure("Thread %d: Failed to authorize the application for API resource %d in tenant %d: %v\n",
+					task.ThreadID, resourceIndex, tenantIndex, err)
+			}
+		}
+	}
+
+	fmt.Printf("Thread %d: Completed API resources %d-%d for tenants %d-%d in %v\n",
+		task.ThreadID, task.UserStart, task.UserEnd, task.TenantStart, task.TenantEnd-1, time.Since(startTime))
+}
+
+// requestScopedTokens requests a password grant token for every created user in every tenant, each
+// asking for its share of the registered scopes
+func (te *TestExecutor) requestScopedTokens(stats *apiResourceStats) {
+	fmt.Printf("Requesting tokens with %d registered scopes each for %d users in %d tenants...\n",
+		te.config.APIResources.TokenScopes, te.config.Execution.NoOfUsers, te.config.Execution.NoOfTenants)
+
+	jobs := make(chan apiTokenJob)
+
+	// Queue the requests user by user across all tenants, stopping when the run is interrupted
+	go func() {
+		defer close(jobs)
+
+		tenantStart := te.config.Execution.TenantStartNumber
+		for offset := 0; offset < te.config.Execution.NoOfUsers; offset++ {
+			username := te.config.GetTestUsername(te.config.Execution.UserStartNumber + offset)
+			for tenantIndex := tenantStart; tenantIndex < tenantStart+te.config.Execution.NoOfTenants; tenantIndex++ {
+				select {
+				case jobs <- apiTokenJob{TenantIndex: tenantIndex, UserOffset: offset, Username: username}:
+				case <-te.ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		wg.Add(1)
+		go te.scopedTokenWorker(threadID, jobs, stats, &wg)
+	}
+
+	wg.Wait()
+}
+
+// scopedTokenWorker requests the tokens it takes from the queue until the queue is closed
+func (te *TestExecutor) scopedTokenWorker(threadID int, jobs <-chan apiTokenJob, stats *apiResourceStats, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	waitForRampUp(te.ctx, te.rampUpStartDelay(threadID, te.config.Execution.NoOfThreads))
+	client := te.newHTTPClient()
+
+	for job := range jobs {
+		if te.interrupted() {
+			return
+		}
+
+		scopes := te.config.apiTokenScopes(job.UserOffset)
+		scope := strings.Join(scopes, " ")
+		if te.config.OAuth.Scope != "" {
+			scope = te.config.OAuth.Scope + " " + scope
+		}
+
+		requestStart := time.Now()
+		tokenResp, err := client.RequestPasswordGrantWithScope(te.ctx, job.TenantIndex, job.Username, te.userPassword(job.TenantIndex, job.Username), scope)
+		duration := time.Since(requestStart)
+
+		// A request aborted by the interruption is not counted
+		if err != nil && te.interrupted() {
+			return
+		}
+
+		stats.recordToken(duration, scopes, tokenResp, err)
+		te.stats.RecordError(err)
+
+		if err != nil {
+			printFailure("Thread %d: Failed to obtain a scoped token for user %s in tenant %d: %v\n", threadID, job.Username, job.TenantIndex, err)
+		}
+	}
+}
+
+// apiScopeName returns the name of a scope of a test API resource
+func (c *Config) apiScopeName(resourceIndex, scopeIndex int) string {
+	return fmt.Sprintf("%s:scope%d", c.GetTestAPIResourceName(resourceIndex), scopeIndex)
+}
+
+// apiResourceScopes returns the names of the scopes of a test API resource
+func (c *Config) apiResourceScopes(resourceIndex int) []string {
+	scopes := make([]string, c.APIResources.ScopesPerResource)
+	for i := range scopes {
+		scopes[i] = c.apiScopeName(resourceIndex, i+1)
+	}
+	return scopes
+}
+
+// apiTokenScopes returns the registered scopes the token of the user at the given offset asks for:
+// consecutive scopes starting where those of the previous user ended, so the tokens of enough users
+// cover the scopes of every API resource
+func (c *Config) apiTokenScopes(userOffset int) []string {
+	cfg := c.APIResources
+	total := cfg.PerTenant * cfg.ScopesPerResource
+
+	scopes := make([]string, cfg.TokenScopes)
+	for i := range scopes {
+		k := (userOffset*cfg.TokenScopes + i) % total
+		scopes[i] = c.apiScopeName(k/cfg.ScopesPerResource+1, k%cfg.ScopesPerResource+1)
+	}
+	return scopes
+}
+
+// recordCreate records the outcome and latency of an API resource registration
+func (as *apiResourceStats) recordCreate(duration time.Duration, err error) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	if err != nil {
+		as.createFailed++
+		return
+	}
+	as.created++
+	as.createLatencies = append(as.createLatencies, duration)
+}
+
+// recordAuthorize records the outcome of authorizing the application for an API resource
+func (as *apiResourceStats) recordAuthorize(err error) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	if err != nil {
+		as.authorizeFailed++
+		return
+	}
+	as.authorized++
+}
+
+// recordToken records the outcome and latency of a scoped token request and how many of the
+// requested scopes the token was granted
+func (as *apiResourceStats) recordToken(duration time.Duration, requested []string, tokenResp *TokenResponse, err error) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	if err != nil {
+		as.tokenFailed++
+		return
+	}
+	as.issued++
+	as.tokenLatencies = append(as.tokenLatencies, duration)
+
+	granted := make(map[string]bool)
+	for _, scope := range strings.Fields(tokenResp.Scope) {
+		granted[scope] = true
+	}
+	as.requestedScopes += len(requested)
+	for _, scope := range requested {
+		if granted[scope] {
+			as.grantedScopes++
+		}
+	}
+}
+
+// print prints the API resource phase summary
+func (as *apiResourceStats) print(tokenElapsed time.Duration) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	fmt.Println("\n=== API Resource Statistics ===")
+	fmt.Printf("API Resources - Total: %d, Created: %d, Failed: %d\n", as.created+as.createFailed, as.created, as.createFailed)
+	if len(as.createLatencies) > 0 {
+		fmt.Printf("Registration Latency - %s\n", SummarizeLatencies(as.createLatencies))
+	}
+	if as.authorized+as.authorizeFailed > 0 {
+		fmt.Printf("Application Authorizations - Total: %d, Successful: %d, Failed: %d\n",
+			as.authorized+as.authorizeFailed, as.authorized, as.authorizeFailed)
+	}
+
+	if as.issued+as.tokenFailed > 0 {
+		fmt.Printf("Scoped Tokens - Total: %d, Issued: %d, Failed: %d, Rate: %.2f/s\n",
+			as.issued+as.tokenFailed, as.issued, as.tokenFailed, float64(as.issued)/tokenElapsed.Seconds())
+		if len(as.tokenLatencies) > 0 {
+			fmt.Printf("Token Latency - %s\n", SummarizeLatencies(as.tokenLatencies))
+		}
+		if as.requestedScopes > 0 {
+			fmt.Printf("Scopes - Requested: %d, Granted: %d (%.1f%%)\n",
+				as.requestedScopes, as.grantedScopes, float64(as.grantedScopes)*100/float64(as.requestedScopes))
+		}
+		if as.issued > 0 && as.grantedScopes == 0 {
+			printWarning("No requested scope was granted; check that the application is authorized for the API resources\n")
+		}
+	}
+	fmt.Println("===============================")
+}
+
+// APIResource is an API resource of the API Resource Management API, with the fields the client sets
+type APIResource struct {
+	ID                    string             `json:"id,omitempty"`
+	Name                  string             `json:"name"`
+	Identifier            string             `json:"identifier"`
+	Description           string             `json:"description,omitempty"`
+	RequiresAuthorization bool               `json:"requiresAuthorization"`
+	Scopes                []APIResourceScope `json:"scopes,omitempty"`
+}
+
+// APIResourceScope is a scope of an API resource
+type APIResourceScope struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	Description string `json:"description,omitempty"`
+}
+
+// APIResourceListResponse represents the response of an API resource search
+type APIResourceListResponse struct {
+	APIResources []APIResource `json:"apiResources"`
+}
+
+// ApplicationListResponse represents the response of an application search, with the fields the
+// client reads
+type ApplicationListResponse struct {
+	Applications []struct {
+		ID       string `json:"id"`
+		ClientID string `json:"clientId"`
+	} `json:"applications"`
+}
+
+// apiResourceIdentifier returns the identifier, the audience, of a test API resource
+func apiResourceIdentifier(name string) string {
+	return "https://api.example.com/" + url.PathEscape(name)
+}
+
+// apiResourceURL returns the URL of the API Resource Management API of the tenant, followed by the
+// given suffix
+func (h *HTTPClient) apiResourceURL(tenantIndex int, suffix string) string {
+	return h.config.GetServerURL() + h.config.GetTenantPath(h.config.APIResources.APIPath, tenantIndex) + suffix
+}
+
+// CreateAPIResource registers a test API resource with its scopes and returns its ID
+func (h *HTTPClient) CreateAPIResource(ctx context.Context, tenantIndex, resourceIndex int) (string, error) {
+	name := h.config.GetTestAPIResourceName(resourceIndex)
+	resource := APIResource{
+		Name:                  name,
+		Identifier:            apiResourceIdentifier(name),
+		Description:           "go-perf test API resource",
+		RequiresAuthorization: true,
+	}
+	for _, scope := range h.config.apiResourceScopes(resourceIndex) {
+		resource.Scopes = append(resource.Scopes, APIResourceScope{Name: scope, DisplayName: scope})
+	}
+
+	payload, err := json.Marshal(resource)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal API resource JSON: %v", err)
+	}
+
+	body, err := h.sendManagementRequest(ctx, tenantIndex, "POST", h.apiResourceURL(tenantIndex, ""), "API resource creation", payload, http.StatusCreated)
+	if err != nil {
+		return "", err
+	}
+
+	var created APIResource
+	if err := json.Unmarshal(body, &created); err != nil {
+		return "", fmt.Errorf("failed to unmarshal API resource response: %v", err)
+	}
+	return created.ID, nil
+}
+
+// FindAPIResourceID returns the ID of the test API resource with the given name, or "" if there is
+// none
+func (h *HTTPClient) FindAPIResourceID(ctx context.Context, tenantIndex int, name string) (string, error) {
+	identifier := apiResourceIdentifier(name)
+	filter := url.QueryEscape(fmt.Sprintf("identifier eq %s", identifier))
+	body, err := h.sendManagementRequest(ctx, tenantIndex, "GET", h.apiResourceURL(tenantIndex, "?filter="+filter), "API resource search", nil, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+
+	var list APIResourceListResponse
+	if err := json.Unmarshal(body, &list); err != nil {
+		return "", fmt.Errorf("failed to unmarshal API resource search response: %v", err)
+	}
+	for _, resource := range list.APIResources {
+		if resource.Identifier == identifier {
+			return resource.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// DeleteAPIResource deletes the test API resource with the given name, treating a missing one as
+// already deleted. The server removes the application authorizations with it.
+func (h *HTTPClient) DeleteAPIResource(ctx context.Context, tenantIndex int, name string) error {
+	id, err := h.FindAPIResourceID(ctx, tenantIndex, name)
+	if err != nil || id == "" {
+		return err
+	}
+
+	_, err = h.sendManagementRequest(ctx, tenantIndex, "DELETE", h.apiResourceURL(tenantIndex, "/"+url.PathEscape(id)), "API resource deletion", nil, http.StatusNoContent, http.StatusNotFound)
+	return err
+}
+
+// applicationURL returns the URL of the application management API of the tenant, followed by the
+// given suffix
+func (h *HTTPClient) applicationURL(tenantIndex int, suffix string) string {
+	return h.config.GetServerURL() + h.config.GetTenantPath(h.config.APIResources.ApplicationsPath, tenantIndex) + suffix
+}
+
+// FindApplicationID returns the ID of the application with the given OAuth2 client ID, or "" if
+// there is none
+func (h *HTTPClient) FindApplicationID(ctx context.Context, tenantIndex int, clientID string) (string, error) {
+	filter := url.QueryEscape(fmt.Sprintf("clientId eq %s", clientID))
+	body, err := h.sendManagementRequest(ctx, tenantIndex, "GET", h.applicationURL(tenantIndex, "?filter="+filter), "application search", nil, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+
+	var list ApplicationListResponse
+	if err := json.Unmarshal(body, &list); err != nil {
+		return "", fmt.Errorf("failed to unmarshal application search response: %v", err)
+	}
+	for _, app := range list.Applications {
+		if app.ClientID == clientID {
+			return app.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// AuthorizeAPIResource authorizes an application for the given scopes of an API resource
+func (h *HTTPClient) AuthorizeAPIResource(ctx context.Context, tenantIndex int, appID, resourceID string, scopes []string) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":               resourceID,
+		"policyIdentifier": apiResourcePolicy,
+		"scopes":           scopes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal API authorization JSON: %v", err)
+	}
+
+	reqURL := h.applicationURL(tenantIndex, "/"+url.PathEscape(appID)+"/authorized-apis")
+	_, err = h.sendManagementRequest(ctx, tenantIndex, "POST", reqURL, "API authorization", payload, http.StatusOK, http.StatusCreated, http.StatusNoContent)
+	return err
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// attributeSweepStep holds the latencies measured for one attribute count
+type attributeSweepStep struct {
+	attributeCount  int
+	createLatencies []time.Duration
+	readLatencies   []time.Duration
+	createFailed    int
+	readFailed      int
+	mutex           sync.Mutex
+}
+
+// ExecuteAttributeSweep creates and reads users with an increasing number of custom attributes
+// in one campaign and reports creation and read latency per attribute count
+func (te *TestExecutor) ExecuteAttributeSweep() error {
+	cfg := te.config.AttributeSweep
+	runID := time.Now().Unix()
+
+	fmt.Println("Starting attribute count sweep...")
+	fmt.Printf("- Attribute Counts: %v\n", cfg.AttributeCounts)
+	fmt.Printf("- Users Per Step: %d\n", cfg.UsersPerStep)
+	fmt.Printf("- Threads: %d\n", te.config.Execution.NoOfThreads)
+
+	var steps []*attributeSweepStep
+	startTime := time.Now()
+
+	for _, attributeCount := range cfg.AttributeCounts {
+		if te.interrupted() {
+			break
+		}
+		fmt.Printf("Running step with %d attributes...\n", attributeCount)
+
+		step := &attributeSweepStep{attributeCount: attributeCount}
+		te.runAttributeSweepStep(step, runID)
+		steps = append(steps, step)
+	}
+
+	duration := time.Since(startTime)
+	if te.interrupted() {
+		fmt.Printf("\nAttribute count sweep interrupted after %v\n", duration)
+	} else {
+		fmt.Printf("\nAttribute count sweep completed in %v\n", duration)
+	}
+
+	fmt.Println("\n=== Latency vs Attribute Count ===")
+	for _, step := range steps {
+		fmt.Printf("%d Attributes - Create (%d ok, %d failed): %s\n",
+			step.attributeCount, len(step.createLatencies), step.createFailed, SummarizeLatencies(step.createLatencies))
+		fmt.Printf("%d Attributes - Read (%d ok, %d failed): %s\n",
+			step.attributeCount, len(step.readLatencies), step.readFailed, SummarizeLatencies(step.readLatencies))
+	}
+	fmt.Println("==================================")
+
+	if te.interrupted() {
+		return ErrInterrupted
+	}
+
+	te.stats.PrintStats()
+
+	return nil
+}
+
+// runAttributeSweepStep creates and reads the users of one step, spreading them over the tenants
+func (te *TestExecutor) runAttributeSweepStep(step *attributeSweepStep, runID int64) {
+	cfg := te.config.AttributeSweep
+
+	attributes := make(map[string]string, step.attributeCount)
+	for i := 1; i <= step.attributeCount; i++ {
+		attributes[fmt.Sprintf("%s%d", cfg.AttributePrefix, i)] = fmt.Sprintf("value_%d", i)
+	}
+
+	numbers := make(chan int, te.config.Execution.NoOfThreads)
+	go func() {
+		defer close(numbers)
+
+		for n := 0; n < cfg.UsersPerStep; n++ {
+			select {
+			case numbers <- n:
+			case <-te.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		wg.Add(1)
+		go func(threadID int) {
+			defer wg.Done()
+
+			client := te.newHTTPClient()
+			for n := range numbers {
+				if te.interrupted() {
+					continue
+				}
+
+				tenantIndex := te.config.Execution.TenantStartNumber + n%te.config.Execution.NoOfTenants
+				username := fmt.Sprintf("%sattrs%d_%d_%d", te.config.Test.UsernamePrefix, step.attributeCount, runID, n)
+
+				requestStart := time.Now()
+				userResp, err := client.CreateUserWithAttributes(te.ctx, tenantIndex, username, attributes)
+				createTime := time.Since(requestStart)
+
+				// A request aborted by the interruption is not counted
+				if err != nil && te.interrupted() {
+					continue
+				}
+				te.stats.IncrementUser(err == nil)
+				te.stats.RecordError(err)
+
+				if err != nil {
+					step.record(&step.createLatencies, &step.createFailed, 0, false)
+					printFailure("Thread %d: Failed to create user %s for tenant %d: %v\n", threadID, username, tenantIndex, err)
+					continue
+				}
+				step.record(&step.createLatencies, &step.createFailed, createTime, true)
+
+				requestStart = time.Now()
+				_, err = client.GetUser(te.ctx, tenantIndex, userResp.ID, "")
+				if err != nil && te.interrupted() {
+					continue
+				}
+				step.record(&step.readLatencies, &step.readFailed, time.Since(requestStart), err == nil)
+
+				if err != nil {
+					printFailure("Thread %d: Failed to read user %s for tenant %d: %v\n", threadID, username, tenantIndex, err)
+				}
+			}
+		}(threadID)
+	}
+
+	wg.Wait()
+}
+
+// record adds a latency sample or a failure to one of the step's series
+func (s *attributeSweepStep) record(latencies *[]time.Duration, failed *int, duration time.Duration, success bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if success {
+		*latencies = append(*latencies, duration)
+	} else {
+		*failed++
+	}
+}
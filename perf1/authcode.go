@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+)
+
+// browserSession is a cookie-carrying client that does not follow redirects, used to drive
+// browser-based flows step by step. The cookies hold the server-side login session.
+type browserSession struct {
+	client *http.Client
+}
+
+// newBrowserSession creates a browser session sharing the transport and SCIM timeout of the client
+func (h *HTTPClient) newBrowserSession() *browserSession {
+	jar, _ := cookiejar.New(nil)
+
+	return &browserSession{
+		client: &http.Client{
+			Transport: h.client.Transport,
+			Timeout:   h.client.Timeout,
+			Jar:       jar,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// get issues a GET and returns the response status and Location header
+func (b *browserSession) get(ctx context.Context, reqURL string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	return b.send(req)
+}
+
+// postForm issues a form POST and returns the response status and Location header
+func (b *browserSession) postForm(ctx context.Context, reqURL string, form url.Values) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return b.send(req)
+}
+
+// send sends a request in the session and returns the response status and Location header
+func (b *browserSession) send(req *http.Request) (int, string, error) {
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode, resp.Header.Get("Location"), nil
+}
+
+// PKCEPair holds a PKCE code verifier and its S256 challenge
+type PKCEPair struct {
+	Verifier  string
+	Challenge string
+}
+
+// NewPKCEPair generates a random code verifier and its S256 code challenge
+func NewPKCEPair() (*PKCEPair, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to generate code verifier: %v", err)
+	}
+
+	verifier := base64.RawURLEncoding.EncodeToString(random)
+	digest := sha256.Sum256([]byte(verifier))
+
+	return &PKCEPair{
+		Verifier:  verifier,
+		Challenge: base64.RawURLEncoding.EncodeToString(digest[:]),
+	}, nil
+}
+
+// AuthorizationCodeLogin logs a tenant user in through the authorization endpoint in the given
+// browser session and exchanges the issued code for tokens, using PKCE when configured. With a
+// federated IdP configured the server sends the browser to that IdP instead of the login form.
+func (h *HTTPClient) AuthorizationCodeLogin(ctx context.Context, session *browserSession, tenantIndex int, username, password string) (*TokenResponse, error) {
+	var pkce *PKCEPair
+	if h.config.OAuth.PKCE {
+		var err error
+		if pkce, err = NewPKCEPair(); err != nil {
+			return nil, err
+		}
+	}
+
+	state := randomState()
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", h.config.OAuth.ClientID)
+	query.Set("redirect_uri", h.config.OAuth.RedirectURI)
+	query.Set("scope", h.config.OAuth.Scope)
+	query.Set("state", state)
+	if pkce != nil {
+		query.Set("code_challenge", pkce.Challenge)
+		query.Set("code_challenge_method", "S256")
+	}
+	if h.config.OAuth.FederatedIdP != "" {
+		query.Set("fidp", h.config.OAuth.FederatedIdP)
+	}
+
+	authorizeURL := h.config.GetServerURL() + h.config.GetTenantPath(h.config.OAuth.AuthorizePath, tenantIndex)
+	location, err := h.followToCallback(ctx, session, tenantIndex, authorizeURL+"?"+query.Encode(), username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	callback, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid callback location %q: %v", location, err)
+	}
+
+	params := callback.Query()
+	if errCode := params.Get("error"); errCode != "" {
+		return nil, fmt.Errorf("authorization failed: %s %s", errCode, params.Get("error_description"))
+	}
+	if params.Get("state") != state {
+		return nil, fmt.Errorf("state mismatch in authorization response")
+	}
+
+	code := params.Get("code")
+	if code == "" {
+		return nil, fmt.Errorf("no authorization code in callback %q", location)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", h.config.OAuth.RedirectURI)
+	if pkce != nil {
+		form.Set("code_verifier", pkce.Verifier)
+	}
+
+	return h.requestToken(ctx, tenantIndex, form)
+}
+
+// followToCallback walks the redirects of the authorization request, submitting the login form
+// and approving consent when asked, until the server redirects to the client callback
+func (h *HTTPClient) followToCallback(ctx context.Context, session *browserSession, tenantIndex int, startURL, username, password string) (string, error) {
+	status, location, err := session.get(ctx, startURL)
+
+	return h.followLogin(ctx, session, tenantIndex, status, location, err, username, password, func(location string) bool {
+		return strings.HasPrefix(location, h.config.OAuth.RedirectURI)
+	})
+}
+
+// followLogin follows a browser login from the given response, submitting the login form and
+// approving consent when asked, until done reports that a redirect location ends the flow
+func (h *HTTPClient) followLogin(ctx context.Context, session *browserSession, tenantIndex int, status int, location string, err error, username, password string, done func(location string) bool) (string, error) {
+	// A handful of hops covers authorize -> login -> commonauth -> authorize -> consent -> callback,
+	// or authorize -> external IdP -> commonauth -> authorize -> consent -> callback when federated
+	for hop := 0; hop < 10; hop++ {
+		if err != nil {
+			return "", fmt.Errorf("authorization request failed: %v", err)
+		}
+
+		if status != http.StatusFound && status != http.StatusSeeOther && status != http.StatusMovedPermanently {
+			return "", fmt.Errorf("unexpected status %d during authorization", status)
+		}
+
+		if done(location) {
+			return location, nil
+		}
+
+		next, parseErr := url.Parse(location)
+		if parseErr != nil {
+			return "", fmt.Errorf("invalid redirect location %q: %v", location, parseErr)
+		}
+		params := next.Query()
+
+		switch {
+		case params.Get("sessionDataKeyConsent") != "":
+			form := url.Values{}
+			form.Set("sessionDataKeyConsent", params.Get("sessionDataKeyConsent"))
+			form.Set("consent", "approve")
+			status, location, err = session.postForm(ctx, h.absoluteURL(h.config.GetTenantPath(h.config.OAuth.AuthorizePath, tenantIndex)), form)
+		case strings.Contains(next.Path, "login.do") && params.Get("sessionDataKey") != "":
+			form := url.Values{}
+			form.Set("username", h.config.GetTenantQualifiedUsername(username, tenantIndex))
+			form.Set("password", password)
+			form.Set("sessionDataKey", params.Get("sessionDataKey"))
+			status, location, err = session.postForm(ctx, h.absoluteURL(h.config.GetTenantPath(h.config.OAuth.CommonAuthPath, tenantIndex)), form)
+		default:
+			status, location, err = session.get(ctx, h.absoluteURL(location))
+		}
+	}
+
+	return "", fmt.Errorf("too many redirects during authorization")
+}
+
+// absoluteURL resolves a server-relative location against the server URL
+func (h *HTTPClient) absoluteURL(location string) string {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return location
+	}
+	return h.config.GetServerURL() + location
+}
+
+// randomState returns a random value for the OAuth2 state parameter
+func randomState() string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	return base64.RawURLEncoding.EncodeToString(random)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bearerTokenSource provides a pre-issued access token minted outside the tool, e.g. by a vault
+// agent or sidecar, read from a file, standard input or an environment variable. Tokens from a file
+// or the environment are re-read once the reload interval has passed, so rotated tokens are picked up.
+type bearerTokenSource struct {
+	file     string
+	env      string
+	reload   time.Duration
+	token    string
+	loadedAt time.Time
+	mutex    sync.Mutex
+}
+
+// newBearerTokenSource creates a token source from the server configuration and loads the first
+// token, or returns nil if no bearer token is configured
+func newBearerTokenSource(config *ServerConfig) (*bearerTokenSource, error) {
+	if config.BearerTokenFile == "" && config.BearerTokenEnv == "" {
+		return nil, nil
+	}
+
+	source := &bearerTokenSource{
+		file:   config.BearerTokenFile,
+		env:    config.BearerTokenEnv,
+		reload: time.Duration(config.BearerTokenReload) * time.Second,
+	}
+
+	if err := source.load(); err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// Token returns the current token, re-reading it first if the reload interval has passed. If a
+// re-read fails the previous token is kept, since the source may be mid-rotation.
+func (s *bearerTokenSource) Token() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.reload > 0 && s.file != "-" && time.Since(s.loadedAt) >= s.reload {
+		if err := s.load(); err != nil {
+			printWarning("Failed to reload bearer token, keeping the previous one: %v\n", err)
+			s.loadedAt = time.Now()
+		}
+	}
+
+	return s.token
+}
+
+// load reads the token from its source
+func (s *bearerTokenSource) load() error {
+	var token string
+
+	switch {
+	case s.file == "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read bearer token from stdin: %v", err)
+		}
+		token = string(data)
+	case s.file != "":
+		data, err := os.ReadFile(s.file)
+		if err != nil {
+			return fmt.Errorf("failed to read bearer token file: %v", err)
+		}
+		token = string(data)
+	default:
+		token = os.Getenv(s.env)
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return fmt.Errorf("bearer token is empty")
+	}
+
+	s.token = token
+	s.loadedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+// SCIMBulkRequest represents a SCIM2 Bulk request
+type SCIMBulkRequest struct {
+	Schemas      []string            `json:"schemas"`
+	FailOnErrors int                 `json:"failOnErrors,omitempty"`
+	Operations   []SCIMBulkOperation `json:"Operations"`
+}
+
+// SCIMBulkOperation represents a single operation of a SCIM2 Bulk request
+type SCIMBulkOperation struct {
+	Method string      `json:"method"`
+	BulkID string      `json:"bulkId"`
+	Path   string      `json:"path"`
+	Data   interface{} `json:"data"`
+}
+
+// BulkCreateUsers creates the given users with the test role, varied by the configured payload
+// variants, in a single SCIM2 Bulk request with SCIM2 user payloads, using
+// the usernames as bulk IDs. failOnErrors asks the server to stop after that many failed
+// operations (0 to process all of them).
+func (h *HTTPClient) BulkCreateUsers(ctx context.Context, tenantIndex int, usernames []string, failOnErrors int) (*SCIMBulkResponse, error) {
+	request := SCIMBulkRequest{
+		Schemas:      []string{"urn:ietf:params:scim:api:messages:2.0:BulkRequest"},
+		FailOnErrors: failOnErrors,
+	}
+	for _, username := range usernames {
+		request.Operations = append(request.Operations, SCIMBulkOperation{
+			Method: "POST",
+			BulkID: username,
+			Path:   "/Users",
+			Data:   h.newVariantUser(tenantIndex, username).scim2(),
+		})
+	}
+
+	body, err := h.sendSCIMJSON(ctx, tenantIndex, "POST", "/Bulk", "bulk user creation", request, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var bulkResp SCIMBulkResponse
+	if err := json.Unmarshal(body, &bulkResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bulk response: %v", err)
+	}
+
+	return &bulkResp, nil
+}
+
+// bulkBatch is one bulk request's worth of users of a tenant
+type bulkBatch struct {
+	TenantIndex int
+	Users       []int // user indices
+}
+
+// ExecuteBulk creates the roles and then the users in batches through the SCIM2 Bulk endpoint,
+// for comparison with creating users one request at a time
+func (te *TestExecutor) ExecuteBulk() error {
+	fmt.Printf("Starting SCIM2 bulk user creation: batch size %d, failOnErrors %d, %d retries\n",
+		te.config.Bulk.BatchSize, te.config.Bulk.FailOnErrors, te.config.Bulk.Retries)
+
+	if te.config.Bulk.BatchSize < 1 {
+		return fmt.Errorf("bulk batch size must be positive, got %d", te.config.Bulk.BatchSize)
+	}
+
+	startTime := time.Now()
+
+	te.setPhase("roles")
+	if err := te.ExecuteRoleCreation(); err != nil {
+		return fmt.Errorf("role creation failed: %v", err)
+	}
+
+	te.setPhase("users")
+	report := newBulkReport()
+	if err := te.ExecuteBulkUserCreation(report); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nBulk execution completed in %v\n", time.Since(startTime))
+	te.stats.PrintStats()
+	report.print()
+
+	return nil
+}
+
+// ExecuteBulkUserCreation creates the configured users of every tenant in bulk requests. Users whose
+// operation failed, or was skipped because of failOnErrors, are sent again in later batches up to
+// the configured number of retries; only their final outcome is counted.
+func (te *TestExecutor) ExecuteBulkUserCreation(report *bulkReport) error {
+	// Start result processor
+	resultChan := make(chan TestResult, te.config.Bulk.BatchSize)
+	resultsDone := make(chan struct{})
+	go te.processResults(resultChan, resultsDone)
+
+	pending := make(map[int][]int)
+	tenantStart := te.config.Execution.TenantStartNumber
+	for tenantIndex := tenantStart; tenantIndex < tenantStart+te.config.Execution.NoOfTenants; tenantIndex++ {
+		for i := 0; i < te.config.Execution.NoOfUsers; i++ {
+			pending[tenantIndex] = append(pending[tenantIndex], te.config.Execution.UserStartNumber+i)
+		}
+	}
+
+	startTime := time.Now()
+	users := te.config.Execution.NoOfUsers * te.config.Execution.NoOfTenants
+	for round := 0; round <= te.config.Bulk.Retries && len(pending) > 0 && !te.interrupted(); round++ {
+		if round > 0 {
+			retried := 0
+			for _, userIndices := range pending {
+				retried += len(userIndices)
+			}
+			fmt.Printf("Retrying %d users in bulk (retry %d of %d)\n", retried, round, te.config.Bulk.Retries)
+		}
+
+		pending = te.runBulkRound(round, pending, round == te.config.Bulk.Retries, report, resultChan)
+	}
+
+	close(resultChan)
+	<-resultsDone
+
+	if te.interrupted() {
+		return ErrInterrupted
+	}
+
+	duration := time.Since(startTime)
+	fmt.Printf("Bulk user creation completed in %v (%.2f users/s)\n", duration, float64(users)/duration.Seconds())
+	return nil
+}
+
+// runBulkRound sends the pending users of every tenant in batches spread over the threads and
+// returns the users that failed. In the final round failed users are counted instead. The ramp-up
+// only applies to the first round.
+func (te *TestExecutor) runBulkRound(round int, pending map[int][]int, final bool, report *bulkReport, resultChan chan<- TestResult) map[int][]int {
+	batches := make(chan bulkBatch)
+	go func() {
+		defer close(batches)
+
+		for tenantIndex, userIndices := range pending {
+			for start := 0; start < len(userIndices); start += te.config.Bulk.BatchSize {
+				end := start + te.config.Bulk.BatchSize
+				if end > len(userIndices) {
+					end = len(userIndices)
+				}
+
+				select {
+				case batches <- bulkBatch{TenantIndex: tenantIndex, Users: userIndices[start:end]}:
+				case <-te.ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	failed := make(map[int][]int)
+	var failedMutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		wg.Add(1)
+		go func(threadID int) {
+			defer wg.Done()
+
+			if round == 0 {
+				waitForRampUp(te.ctx, te.rampUpStartDelay(threadID, te.config.Execution.NoOfThreads))
+			}
+			client := te.newHTTPClient()
+
+			for batch := range batches {
+				for _, userIndex := range te.sendBulkBatch(threadID, client, batch, final, report, resultChan) {
+					failedMutex.Lock()
+					failed[batch.TenantIndex] = append(failed[batch.TenantIndex], userIndex)
+					failedMutex.Unlock()
+				}
+			}
+		}(threadID)
+	}
+
+	wg.Wait()
+	return failed
+}
+
+// sendBulkBatch sends one batch and credits every user with the outcome of its own operation. It returns
+// the users that failed unless this is the final round, in which case their failure is counted.
+func (te *TestExecutor) sendBulkBatch(threadID int, client *HTTPClient, batch bulkBatch, final bool, report *bulkReport, resultChan chan<- TestResult) []int {
+	usernames := make([]string, len(batch.Users))
+	for i, userIndex := range batch.Users {
+		usernames[i] = te.config.GetTestUsername(userIndex)
+	}
+
+	err := te.readiness.Wait(te.ctx, client, batch.TenantIndex)
+
+	var bulkResp *SCIMBulkResponse
+	if err == nil {
+		bulkResp, err = client.BulkCreateUsers(te.ctx, batch.TenantIndex, usernames, te.config.Bulk.FailOnErrors)
+	}
+
+	if err != nil && te.interrupted() {
+		return nil
+	}
+
+	// Match the operation results to the users by bulk ID
+	results := make(map[string]SCIMBulkOperationResult)
+	if err == nil {
+		for _, op := range bulkResp.Operations {
+			results[op.BulkID] = op
+		}
+
+		// The same usernames exist in every tenant, so the report tells them apart by tenant
+		statuses := make(map[string]int, len(usernames))
+		for _, username := range usernames {
+			statuses[fmt.Sprintf("%s (tenant %d)", username, batch.TenantIndex)] = results[username].StatusCode()
+		}
+		report.RecordBatch(statuses)
+	} else {
+		printFailure("Thread %d: Failed to create %d users in bulk for tenant %d: %v\n", threadID, len(usernames), batch.TenantIndex, err)
+	}
+
+	var failed []int
+	for i, userIndex := range batch.Users {
+		result := TestResult{TenantIndex: batch.TenantIndex, UserIndex: userIndex, ThreadID: threadID}
+
+		opErr := err
+		if opErr == nil {
+			op, ok := results[usernames[i]]
+			switch status := op.StatusCode(); {
+			case !ok:
+				opErr = fmt.Errorf("bulk response has no result for user %s", usernames[i])
+			case status != http.StatusCreated && status != http.StatusOK:
+				opErr = &StatusError{Operation: "bulk user creation", StatusCode: status, Body: string(op.Response)}
+			default:
+				result.ScimID = path.Base(op.Location)
+			}
+		}
+
+		if opErr != nil && !final {
+			failed = append(failed, userIndex)
+			continue
+		}
+
+		result.Success = opErr == nil
+		result.Error = opErr
+		if opErr != nil && te.failedUsersWriter != nil {
+			if csvErr := te.failedUsersWriter.WriteFailedUser(newFailedUser(batch.TenantIndex, usernames[i], opErr)); csvErr != nil {
+				printFailure("Thread %d: Failed to write failed user (Tenant:%d, Username:%s) to CSV: %v\n", threadID, batch.TenantIndex, usernames[i], csvErr)
+			}
+		}
+
+		resultChan <- result
+	}
+
+	return failed
+}
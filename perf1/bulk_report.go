@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SCIMBulkResponse represents the response from a SCIM2 Bulk request
+type SCIMBulkResponse struct {
+	Schemas    []string                  `json:"schemas"`
+	Operations []SCIMBulkOperationResult `json:"Operations"`
+}
+
+// SCIMBulkOperationResult represents the outcome of a single operation inside a SCIM2 Bulk response.
+// Status is a string in the SCIM2 specification, but some servers send it as an object with a code.
+type SCIMBulkOperationResult struct {
+	Method   string          `json:"method"`
+	BulkID   string          `json:"bulkId"`
+	Location string          `json:"location"`
+	Status   interface{}     `json:"status"`
+	Response json.RawMessage `json:"response,omitempty"` // error details of a failed operation
+}
+
+// StatusCode returns the HTTP status code of the operation, or 0 if it cannot be parsed
+func (r SCIMBulkOperationResult) StatusCode() int {
+	switch status := r.Status.(type) {
+	case string:
+		code, _ := strconv.Atoi(strings.TrimSpace(status))
+		return code
+	case float64:
+		return int(status)
+	case map[string]interface{}:
+		if code, ok := status["code"]; ok {
+			return SCIMBulkOperationResult{Status: code}.StatusCode()
+		}
+	}
+	return 0
+}
+
+// bulkUserHistory tracks how a user fared across the batches it was sent in
+type bulkUserHistory struct {
+	attempts int
+	failures int
+}
+
+// bulkReport collects the per-operation status distribution inside bulk responses and the users
+// that fail in every batch they are part of
+type bulkReport struct {
+	batches      int
+	statusCounts map[int]int
+	mixedBatches int
+	users        map[string]*bulkUserHistory
+	mutex        sync.Mutex
+}
+
+// newBulkReport creates an empty bulk report
+func newBulkReport() *bulkReport {
+	return &bulkReport{
+		statusCounts: make(map[int]int),
+		users:        make(map[string]*bulkUserHistory),
+	}
+}
+
+// RecordBatch records the per-operation statuses of one bulk response. statuses maps each username
+// in the batch to the status of its operation; a status of 0 means the response had no entry for it.
+func (br *bulkReport) RecordBatch(statuses map[string]int) {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+
+	br.batches++
+
+	succeeded, failed := 0, 0
+	for username, status := range statuses {
+		br.statusCounts[status]++
+
+		history, ok := br.users[username]
+		if !ok {
+			history = &bulkUserHistory{}
+			br.users[username] = history
+		}
+		history.attempts++
+
+		if status >= 200 && status < 300 {
+			succeeded++
+		} else {
+			failed++
+			history.failures++
+		}
+	}
+
+	if succeeded > 0 && failed > 0 {
+		br.mixedBatches++
+	}
+}
+
+// PoisonUsers returns the users that were sent in more than one batch and failed in every one of them
+func (br *bulkReport) PoisonUsers() []string {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+
+	var poison []string
+	for username, history := range br.users {
+		if history.attempts > 1 && history.failures == history.attempts {
+			poison = append(poison, username)
+		}
+	}
+	sort.Strings(poison)
+
+	return poison
+}
+
+// print prints the status distribution and the poison users
+func (br *bulkReport) print() {
+	poison := br.PoisonUsers()
+
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+
+	fmt.Println("\n=== Bulk Operation Statistics ===")
+	fmt.Printf("Batches - Total: %d, Partially Failed: %d\n", br.batches, br.mixedBatches)
+
+	codes := make([]int, 0, len(br.statusCounts))
+	for code := range br.statusCounts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	for _, code := range codes {
+		label := strconv.Itoa(code)
+		if code == 0 {
+			label = "missing"
+		}
+		fmt.Printf("Operation Status %s: %d\n", label, br.statusCounts[code])
+	}
+
+	if len(poison) > 0 {
+		fmt.Printf("Poison Users (failed in every batch): %d\n", len(poison))
+		for _, username := range poison {
+			history := br.users[username]
+			fmt.Printf("  %s - failed %d of %d batches\n", username, history.failures, history.attempts)
+		}
+	}
+	fmt.Println("=================================")
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrInterrupted is returned by phases that stopped early because the run was interrupted
+var ErrInterrupted = errors.New("run interrupted")
+
+// Checkpoint records how far an interrupted run got, so a later run can resume from there
+type Checkpoint struct {
+	// Phase is the phase that was interrupted; the phases before it completed
+	Phase string `json:"phase"`
+	// Ranges are the users not yet created, when the users phase was interrupted
+	Ranges  []UserRange `json:"ranges,omitempty"`
+	SavedAt time.Time   `json:"savedAt"`
+}
+
+// LoadCheckpoint reads a checkpoint file
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %v", err)
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file: %v", err)
+	}
+
+	return &checkpoint, nil
+}
+
+// SetContext sets the context whose cancellation interrupts the run. Workers stop taking new work
+// once it is cancelled and requests in flight are aborted. The run can also abort itself, see abort.
+func (te *TestExecutor) SetContext(ctx context.Context) {
+	te.ctx, te.cancel = context.WithCancelCause(ctx)
+}
+
+// interrupted reports whether the run has been interrupted
+func (te *TestExecutor) interrupted() bool {
+	return te.ctx.Err() != nil
+}
+
+// resumePhase returns the phase the run resumes at, or an empty string for a fresh run
+func (te *TestExecutor) resumePhase() string {
+	if te.resume == nil {
+		return ""
+	}
+	return te.resume.Phase
+}
+
+// saveCheckpoint writes the checkpoint of a run interrupted in the given phase and returns
+// ErrInterrupted, or the write error
+func (te *TestExecutor) saveCheckpoint(phase string, ranges []UserRange) error {
+	checkpoint := Checkpoint{Phase: phase, Ranges: ranges, SavedAt: time.Now()}
+	if err := writeFileAtomic(te.config.Execution.CheckpointFile, checkpoint); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+
+	printWarning("Checkpoint written to %s, continue with -resume\n", te.config.Execution.CheckpointFile)
+	return ErrInterrupted
+}
+
+// clearCheckpoint removes the checkpoint once the run it belongs to has completed
+func (te *TestExecutor) clearCheckpoint() {
+	if err := os.Remove(te.config.Execution.CheckpointFile); err != nil && !os.IsNotExist(err) {
+		printWarning("Failed to remove checkpoint file: %v\n", err)
+	}
+}
+
+// recordUnfinished records a range of users left uncreated by an interruption
+func (te *TestExecutor) recordUnfinished(remaining UserRange) {
+	te.unfinishedMutex.Lock()
+	defer te.unfinishedMutex.Unlock()
+
+	te.unfinished = append(te.unfinished, remaining)
+}
+
+// PrintStats prints the statistics gathered so far
+func (te *TestExecutor) PrintStats() {
+	te.stats.PrintStats()
+}
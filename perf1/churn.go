@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// churnStats holds the aggregated outcome of the churn workload
+type churnStats struct {
+	cycles        int
+	createSuccess int
+	createFailed  int
+	deleteSuccess int
+	deleteFailed  int
+	createTime    time.Duration
+	deleteTime    time.Duration
+	mutex         sync.Mutex
+}
+
+// ExecuteChurn runs a workload where every thread repeatedly creates a user and immediately
+// deletes it again, stressing ID allocation and index maintenance instead of table growth
+func (te *TestExecutor) ExecuteChurn() error {
+	duration := time.Duration(te.config.Churn.Duration) * time.Second
+
+	fmt.Println("Starting create-then-delete churn workload...")
+	fmt.Printf("- Threads: %d\n", te.config.Execution.NoOfThreads)
+	fmt.Printf("- Duration: %v\n", duration)
+	fmt.Printf("- Cycles Per Second Per Thread: %.2f\n", te.config.Churn.CyclesPerSecond)
+
+	stats := &churnStats{}
+	deadline := time.Now().Add(duration)
+	runID := time.Now().Unix()
+
+	var wg sync.WaitGroup
+
+	// Each worker delays its own start to apply the ramp-up
+	startTime := time.Now()
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		wg.Add(1)
+		go te.churnWorker(threadID, te.rampUpStartDelay(threadID, te.config.Execution.NoOfThreads), runID, deadline, stats, &wg)
+	}
+
+	wg.Wait()
+
+	elapsed := time.Since(startTime)
+	if te.interrupted() {
+		fmt.Printf("\nChurn workload interrupted after %v\n", elapsed)
+		stats.print(elapsed)
+		return ErrInterrupted
+	}
+	fmt.Printf("\nChurn workload completed in %v\n", elapsed)
+
+	stats.print(elapsed)
+
+	return nil
+}
+
+// churnWorker creates and deletes users until the deadline, pacing cycles at the configured rate
+func (te *TestExecutor) churnWorker(threadID int, startDelay time.Duration, runID int64, deadline time.Time, stats *churnStats, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	waitForRampUp(te.ctx, startDelay)
+
+	client := te.newHTTPClient()
+
+	var interval time.Duration
+	if te.config.Churn.CyclesPerSecond > 0 {
+		interval = time.Duration(float64(time.Second) / te.config.Churn.CyclesPerSecond)
+	}
+
+	tenantStart := te.config.Execution.TenantStartNumber
+	nextCycle := time.Now()
+
+	for cycle := 0; time.Now().Before(deadline) && !te.interrupted(); cycle++ {
+		tenantIndex := tenantStart + cycle%te.config.Execution.NoOfTenants
+		username := fmt.Sprintf("%schurn_%d_%d_%d", te.config.Test.UsernamePrefix, runID, threadID, cycle)
+
+		createStart := time.Now()
+		userResp, err := client.CreateUserWithName(te.ctx, tenantIndex, username)
+		createTime := time.Since(createStart)
+
+		var deleteErr error
+		var deleteTime time.Duration
+		if err == nil {
+			deleteStart := time.Now()
+			deleteErr = client.DeleteUserByID(te.ctx, tenantIndex, userResp.ID)
+			deleteTime = time.Since(deleteStart)
+		}
+
+		stats.record(err, createTime, deleteErr, deleteTime)
+
+		if err != nil {
+			printFailure("Thread %d: Failed to create churn user %s for tenant %d: %v\n", threadID, username, tenantIndex, err)
+		} else if deleteErr != nil {
+			printFailure("Thread %d: Failed to delete churn user %s for tenant %d: %v\n", threadID, username, tenantIndex, deleteErr)
+		}
+
+		// Pace cycles against a fixed schedule so slow cycles don't lower the offered rate
+		if interval > 0 {
+			nextCycle = nextCycle.Add(interval)
+			if wait := time.Until(nextCycle); wait > 0 {
+				client.sleep(te.ctx, wait)
+				client.paced(wait)
+			}
+		}
+	}
+}
+
+// record adds the outcome of one create-then-delete cycle to the statistics
+func (cs *churnStats) record(createErr error, createTime time.Duration, deleteErr error, deleteTime time.Duration) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	cs.cycles++
+	if createErr != nil {
+		cs.createFailed++
+		return
+	}
+
+	cs.createSuccess++
+	cs.createTime += createTime
+
+	if deleteErr != nil {
+		cs.deleteFailed++
+	} else {
+		cs.deleteSuccess++
+		cs.deleteTime += deleteTime
+	}
+}
+
+// print prints the churn workload summary
+func (cs *churnStats) print(elapsed time.Duration) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	fmt.Println("\n=== Churn Statistics ===")
+	fmt.Printf("Cycles - Total: %d, Rate: %.2f/s\n", cs.cycles, float64(cs.cycles)/elapsed.Seconds())
+	fmt.Printf("Creates - Success: %d, Failed: %d\n", cs.createSuccess, cs.createFailed)
+	fmt.Printf("Deletes - Success: %d, Failed: %d\n", cs.deleteSuccess, cs.deleteFailed)
+
+	if cs.createSuccess > 0 {
+		fmt.Printf("Create Avg Time: %v\n", cs.createTime/time.Duration(cs.createSuccess))
+	}
+	if cs.deleteSuccess > 0 {
+		fmt.Printf("Delete Avg Time: %v\n", cs.deleteTime/time.Duration(cs.deleteSuccess))
+	}
+	fmt.Println("========================")
+}
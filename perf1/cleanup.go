@@ -0,0 +1,487 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Cleanup sources, selecting how the users to delete are found
+const (
+	// CleanupSourceNames deletes the users of the configured user range, looking up each by username
+	CleanupSourceNames = "names"
+	// CleanupSourceCSV deletes the users recorded in the SCIM ID CSV by ID, without searching
+	CleanupSourceCSV = "csv"
+	// CleanupSourcePrefix deletes every user whose username starts with the username prefix
+	CleanupSourcePrefix = "prefix"
+)
+
+// cleanupSearchPageSize is the number of users fetched per search request with the prefix source
+const cleanupSearchPageSize = 100
+
+// cleanupItem identifies a single resource to delete. ScimID is set when the resource is already
+// known by ID, in which case Name is only used for reporting.
+type cleanupItem struct {
+	TenantIndex int
+	Name        string
+	ScimID      string
+}
+
+// cleanupStage describes how one resource type is deleted and verified.
+// Stages run strictly in order so that dependent resources are removed
+// before the resources they reference (groups before users before roles).
+type cleanupStage struct {
+	name    string
+	threads int
+	items   []cleanupItem
+	delete  func(client *HTTPClient, item cleanupItem) error
+	exists  func(client *HTTPClient, item cleanupItem) (bool, error)
+}
+
+// cleanupStageStats holds the outcome of a single cleanup stage
+type cleanupStageStats struct {
+	name      string
+	total     int
+	deleted   int
+	failed    int
+	remaining int
+	duration  time.Duration
+	mutex     sync.Mutex
+}
+
+// ExecuteCleanup deletes the groups, users and roles created by previous runs
+func (te *TestExecutor) ExecuteCleanup() error {
+	fmt.Println("Starting cleanup...")
+
+	startTime := time.Now()
+	stages, err := te.cleanupStages()
+	if err != nil {
+		return err
+	}
+
+	var results []*cleanupStageStats
+	for _, stage := range stages {
+		if te.interrupted() {
+			break
+		}
+		te.setPhase("delete " + stage.name)
+		results = append(results, te.runCleanupStage(stage))
+	}
+
+	if te.config.Cleanup.Verify && !te.interrupted() {
+		fmt.Println("Starting cleanup verification pass...")
+		for i, stage := range stages {
+			if te.interrupted() {
+				break
+			}
+			te.setPhase("verify " + stage.name)
+			te.verifyCleanupStage(stage, results[i])
+		}
+	}
+
+	// An interrupted cleanup reports what it deleted so far, without the incomplete verification
+	if te.interrupted() {
+		fmt.Printf("\nCleanup interrupted after %v\n", time.Since(startTime))
+		printCleanupStats(results, false)
+		return ErrInterrupted
+	}
+
+	duration := time.Since(startTime)
+	fmt.Printf("\nCleanup completed in %v\n", duration)
+
+	printCleanupStats(results, te.config.Cleanup.Verify)
+
+	return nil
+}
+
+// cleanupStages returns the cleanup stages in dependency order
+func (te *TestExecutor) cleanupStages() ([]cleanupStage, error) {
+	var roleItems []cleanupItem
+
+	tenantStart := te.config.Execution.TenantStartNumber
+	tenantEnd := tenantStart + te.config.Execution.NoOfTenants
+
+	// Roles managed outside the test are left in place
+	for tenantIndex := tenantStart; tenantIndex < tenantEnd && !te.config.Test.SkipRoleCreation; tenantIndex++ {
+		roleNames := append([]string{te.config.GetTenantRoleName(tenantIndex)}, te.config.extraRoleNames()...)
+		for _, roleName := range roleNames {
+			roleItems = append(roleItems, cleanupItem{
+				TenantIndex: tenantIndex,
+				Name:        roleName,
+			})
+		}
+	}
+
+	groupItems, err := te.cleanupGroupItems()
+	if err != nil {
+		return nil, err
+	}
+
+	userItems, err := te.cleanupUserItems()
+	if err != nil {
+		return nil, err
+	}
+
+	// Groups reference users, and users reference roles, so they go in that order
+	stages := []cleanupStage{
+		{
+			name:    "Groups",
+			threads: te.config.Cleanup.GroupThreads,
+			items:   groupItems,
+			delete: func(client *HTTPClient, item cleanupItem) error {
+				return client.DeleteGroup(te.ctx, item.TenantIndex, item.ScimID)
+			},
+			exists: func(client *HTTPClient, item cleanupItem) (bool, error) {
+				return client.GroupExists(te.ctx, item.TenantIndex, item.ScimID)
+			},
+		},
+		{
+			name:    "Users",
+			threads: te.config.Cleanup.UserThreads,
+			items:   userItems,
+			delete: func(client *HTTPClient, item cleanupItem) error {
+				if item.ScimID != "" {
+					return client.DeleteUserByID(te.ctx, item.TenantIndex, item.ScimID)
+				}
+				return client.DeleteUser(te.ctx, item.TenantIndex, item.Name)
+			},
+			exists: func(client *HTTPClient, item cleanupItem) (bool, error) {
+				if item.ScimID != "" {
+					return client.UserExists(te.ctx, item.TenantIndex, item.ScimID)
+				}
+				scimID, err := client.FindUserID(te.ctx, item.TenantIndex, item.Name)
+				return scimID != "", err
+			},
+		},
+		{
+			name:    "Roles",
+			threads: te.config.Cleanup.RoleThreads,
+			items:   roleItems,
+			delete: func(client *HTTPClient, item cleanupItem) error {
+				return client.DeleteNamedRole(te.ctx, item.TenantIndex, item.Name)
+			},
+			exists: func(client *HTTPClient, item cleanupItem) (bool, error) {
+				return client.NamedRoleExists(te.ctx, item.TenantIndex, item.Name)
+			},
+		},
+	}
+
+	// Identity providers are only looked for when the run creates them, by their names
+	if te.config.IdPs.PerTenant > 0 {
+		var idpItems []cleanupItem
+		for tenantIndex := tenantStart; tenantIndex < tenantEnd; tenantIndex++ {
+			for idpIndex := 1; idpIndex <= te.config.IdPs.PerTenant; idpIndex++ {
+				idpItems = append(idpItems, cleanupItem{TenantIndex: tenantIndex, Name: te.config.GetTestIdPName(idpIndex)})
+			}
+		}
+		stages = append(stages, cleanupStage{
+			name:    "IdPs",
+			threads: te.config.Cleanup.IdPThreads,
+			items:   idpItems,
+			delete: func(client *HTTPClient, item cleanupItem) error {
+				return client.DeleteIdP(te.ctx, item.TenantIndex, item.Name)
+			},
+			exists: func(client *HTTPClient, item cleanupItem) (bool, error) {
+				id, err := client.FindIdPID(te.ctx, item.TenantIndex, item.Name)
+				return id != "", err
+			},
+		})
+	}
+
+	// API resources likewise, by their identifiers
+	if te.config.APIResources.PerTenant > 0 {
+		var apiItems []cleanupItem
+		for tenantIndex := tenantStart; tenantIndex < tenantEnd; tenantIndex++ {
+			for resourceIndex := 1; resourceIndex <= te.config.APIResources.PerTenant; resourceIndex++ {
+				apiItems = append(apiItems, cleanupItem{TenantIndex: tenantIndex, Name: te.config.GetTestAPIResourceName(resourceIndex)})
+			}
+		}
+		stages = append(stages, cleanupStage{
+			name:    "API resources",
+			threads: te.config.Cleanup.APIResourceThreads,
+			items:   apiItems,
+			delete: func(client *HTTPClient, item cleanupItem) error {
+				return client.DeleteAPIResource(te.ctx, item.TenantIndex, item.Name)
+			},
+			exists: func(client *HTTPClient, item cleanupItem) (bool, error) {
+				id, err := client.FindAPIResourceID(te.ctx, item.TenantIndex, item.Name)
+				return id != "", err
+			},
+		})
+	}
+
+	return stages, nil
+}
+
+// cleanupGroupItems returns the groups to delete: those recorded by the group phase in the group ID
+// CSV, if it exists, and the giant groups of group scale tests, found by their name prefix
+func (te *TestExecutor) cleanupGroupItems() ([]cleanupItem, error) {
+	var items []cleanupItem
+
+	path := te.config.Groups.CsvPath
+	if _, err := os.Stat(path); err == nil {
+		recorded, err := readScimIDs(path)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Read %d group IDs from %s\n", len(recorded), path)
+		items = append(items, recorded...)
+	}
+
+	// Group scale tests name their group after the run, so every run's group is looked for
+	prefix := te.config.GroupScale.GroupName + "_"
+	client := te.newHTTPClient()
+
+	tenantStart := te.config.Execution.TenantStartNumber
+	tenantEnd := tenantStart + te.config.Execution.NoOfTenants
+	for tenantIndex := tenantStart; tenantIndex < tenantEnd; tenantIndex++ {
+		if te.interrupted() {
+			return nil, ErrInterrupted
+		}
+
+		for startIndex := 1; ; {
+			page, err := client.SearchGroupsByPrefix(te.ctx, tenantIndex, prefix, startIndex, cleanupSearchPageSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to search groups of tenant %d: %v", tenantIndex, err)
+			}
+
+			for _, group := range page.Resources {
+				items = append(items, cleanupItem{
+					TenantIndex: tenantIndex,
+					Name:        group.DisplayName,
+					ScimID:      group.ID,
+				})
+			}
+
+			startIndex += len(page.Resources)
+			if len(page.Resources) == 0 || startIndex > page.TotalResults {
+				break
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// cleanupUserItems returns the users to delete from the configured cleanup source
+func (te *TestExecutor) cleanupUserItems() ([]cleanupItem, error) {
+	tenantStart := te.config.Execution.TenantStartNumber
+	tenantEnd := tenantStart + te.config.Execution.NoOfTenants
+
+	switch te.config.Cleanup.Source {
+	case "", CleanupSourceNames:
+		var items []cleanupItem
+		for tenantIndex := tenantStart; tenantIndex < tenantEnd; tenantIndex++ {
+			userStart := te.config.Execution.UserStartNumber
+			for userIndex := userStart; userIndex < userStart+te.config.Execution.NoOfUsers; userIndex++ {
+				items = append(items, cleanupItem{
+					TenantIndex: tenantIndex,
+					Name:        te.config.GetTestUsername(userIndex),
+				})
+			}
+		}
+		return items, nil
+
+	case CleanupSourceCSV:
+		items, err := readScimIDs(te.config.Execution.ScimIdCsvPath)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Read %d SCIM IDs from %s\n", len(items), te.config.Execution.ScimIdCsvPath)
+		return items, nil
+
+	case CleanupSourcePrefix:
+		client := te.newHTTPClient()
+
+		var items []cleanupItem
+		for tenantIndex := tenantStart; tenantIndex < tenantEnd; tenantIndex++ {
+			if te.interrupted() {
+				return nil, ErrInterrupted
+			}
+
+			// Collect every match before deleting so deletions do not shift the pages being read
+			for startIndex := 1; ; {
+				page, err := client.SearchUsersByPrefix(te.ctx, tenantIndex, te.config.Test.UsernamePrefix, startIndex, cleanupSearchPageSize)
+				if err != nil {
+					return nil, fmt.Errorf("failed to search users of tenant %d: %v", tenantIndex, err)
+				}
+
+				for _, user := range page.Resources {
+					items = append(items, cleanupItem{
+						TenantIndex: tenantIndex,
+						Name:        user.UserName,
+						ScimID:      user.ID,
+					})
+				}
+
+				startIndex += len(page.Resources)
+				if len(page.Resources) == 0 || startIndex > page.TotalResults {
+					break
+				}
+			}
+		}
+		fmt.Printf("Found %d users with prefix %s\n", len(items), te.config.Test.UsernamePrefix)
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("unknown cleanup source %q, expected %s, %s or %s",
+		te.config.Cleanup.Source, CleanupSourceNames, CleanupSourceCSV, CleanupSourcePrefix)
+}
+
+// readScimIDs reads the users recorded in a SCIM ID CSV file with the tenant each belongs to
+func readScimIDs(path string) ([]cleanupItem, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SCIM ID CSV file: %v", err)
+	}
+	defer file.Close()
+
+	reader := newCSVReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SCIM ID CSV file: %v", err)
+	}
+
+	var items []cleanupItem
+	for i, record := range records {
+		if i == 0 && record[0] == "scim_id" {
+			continue
+		}
+
+		if len(record) < 2 {
+			return nil, fmt.Errorf("SCIM ID CSV file %s has no tenant on line %d, use the names or prefix cleanup source", path, i+1)
+		}
+
+		tenantIndex, err := strconv.Atoi(record[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenant %q on line %d of %s", record[1], i+1, path)
+		}
+
+		items = append(items, cleanupItem{
+			TenantIndex: tenantIndex,
+			Name:        record[0],
+			ScimID:      record[0],
+		})
+	}
+
+	return items, nil
+}
+
+// runCleanupStage deletes all items of a stage using the stage's own thread count
+func (te *TestExecutor) runCleanupStage(stage cleanupStage) *cleanupStageStats {
+	fmt.Printf("Deleting %s (%d items, %d threads)...\n", stage.name, len(stage.items), stage.threads)
+
+	stats := &cleanupStageStats{name: stage.name, total: len(stage.items)}
+	startTime := time.Now()
+
+	te.forEachCleanupItem(stage, func(threadID int, client *HTTPClient, item cleanupItem) {
+		err := stage.delete(client, item)
+
+		// A request aborted by the interruption is neither deleted nor failed
+		if err != nil && te.interrupted() {
+			return
+		}
+
+		stats.mutex.Lock()
+		if err != nil {
+			stats.failed++
+		} else {
+			stats.deleted++
+		}
+		stats.mutex.Unlock()
+
+		if err != nil {
+			printFailure("Thread %d: Failed to delete %s '%s' for tenant %d: %v\n",
+				threadID, stage.name, item.Name, item.TenantIndex, err)
+		}
+	})
+
+	stats.duration = time.Since(startTime)
+	fmt.Printf("%s deletion completed in %v\n", stage.name, stats.duration)
+	return stats
+}
+
+// verifyCleanupStage checks that no item of a stage still exists on the server
+func (te *TestExecutor) verifyCleanupStage(stage cleanupStage, stats *cleanupStageStats) {
+	te.forEachCleanupItem(stage, func(threadID int, client *HTTPClient, item cleanupItem) {
+		exists, err := stage.exists(client, item)
+		if err != nil && te.interrupted() {
+			return
+		}
+		if err != nil {
+			printFailure("Thread %d: Failed to verify %s '%s' for tenant %d: %v\n",
+				threadID, stage.name, item.Name, item.TenantIndex, err)
+		}
+
+		// Treat an unverifiable item as remaining so it is not silently reported as clean
+		if exists || err != nil {
+			stats.mutex.Lock()
+			stats.remaining++
+			stats.mutex.Unlock()
+		}
+	})
+}
+
+// forEachCleanupItem distributes stage items across the stage's threads
+func (te *TestExecutor) forEachCleanupItem(stage cleanupStage, fn func(threadID int, client *HTTPClient, item cleanupItem)) {
+	threads := stage.threads
+	if threads < 1 {
+		threads = 1
+	}
+
+	itemsPerThread := len(stage.items) / threads
+	remainingItems := len(stage.items) % threads
+
+	var wg sync.WaitGroup
+	itemStart := 0
+
+	for threadID := 0; threadID < threads; threadID++ {
+		threadItems := itemsPerThread
+		if threadID < remainingItems {
+			threadItems++ // Distribute remaining items to first few threads
+		}
+
+		if threadItems == 0 {
+			continue
+		}
+
+		items := stage.items[itemStart : itemStart+threadItems]
+		itemStart += threadItems
+
+		// Create a separate HTTP client for this thread
+		threadClient := te.newHTTPClient()
+
+		// Each thread delays its own start to apply the ramp-up
+		wg.Add(1)
+		go func(threadID int, client *HTTPClient, items []cleanupItem, startDelay time.Duration) {
+			defer wg.Done()
+
+			waitForRampUp(te.ctx, startDelay)
+
+			for _, item := range items {
+				if te.interrupted() {
+					return
+				}
+				fn(threadID, client, item)
+			}
+		}(threadID, threadClient, items, te.rampUpStartDelay(threadID, threads))
+	}
+
+	wg.Wait()
+}
+
+// printCleanupStats prints the per-stage cleanup summary
+func printCleanupStats(results []*cleanupStageStats, verified bool) {
+	fmt.Println("\n=== Cleanup Statistics ===")
+	for _, stats := range results {
+		fmt.Printf("%s - Total: %d, Deleted: %d, Failed: %d, Duration: %v\n",
+			stats.name, stats.total, stats.deleted, stats.failed, stats.duration)
+		if verified {
+			fmt.Printf("%s - Remaining after verification: %d\n", stats.name, stats.remaining)
+		}
+	}
+	fmt.Println("==========================")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ANSI escape codes of the status colors
+const (
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorReset  = "\033[0m"
+)
+
+// colorOutput enables colored status output. It is on when stdout is a terminal and the NO_COLOR
+// environment variable is not set, and can be turned off with -no-color.
+var colorOutput = isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+
+// isTerminal reports whether the file is a terminal rather than a pipe or regular file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize wraps the text in the given color, keeping leading and trailing newlines outside the
+// color so a colored line does not bleed into the next one
+func colorize(color, text string) string {
+	if !colorOutput {
+		return text
+	}
+
+	body := strings.Trim(text, "\n")
+	if body == "" {
+		return text
+	}
+
+	start := strings.Index(text, body)
+	return text[:start] + color + body + colorReset + text[start+len(body):]
+}
+
+// printSuccess prints a success message in green
+func printSuccess(format string, args ...interface{}) {
+	fmt.Print(colorize(colorGreen, fmt.Sprintf(format, args...)))
+}
+
+// printFailure prints a failure message in red
+func printFailure(format string, args ...interface{}) {
+	fmt.Print(colorize(colorRed, fmt.Sprintf(format, args...)))
+}
+
+// printWarning prints a warning in yellow
+func printWarning(format string, args ...interface{}) {
+	fmt.Print(colorize(colorYellow, fmt.Sprintf(format, args...)))
+}
+
+// rateColor is the color of a success rate: green when nothing failed, red otherwise
+func rateColor(failed int) string {
+	if failed > 0 {
+		return colorRed
+	}
+	return colorGreen
+}
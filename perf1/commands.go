@@ -0,0 +1,419 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cliCommand is a subcommand of the client. Test commands run an execution mode; utility commands
+// have a run function and send no load.
+type cliCommand struct {
+	name  string
+	args  string // positional arguments in the usage line
+	usage string
+	mode  ExecutionMode
+
+	// run executes a utility command, nil for test commands
+	run func(cmd *cliCommand, args []string) error
+
+	// loadsConfig registers the configuration flags, which test commands always do
+	loadsConfig bool
+
+	// legacy is the flag that selected the command before there were subcommands
+	legacy string
+
+	// noFlags marks commands that take arguments only
+	noFlags bool
+}
+
+// defaultCommand runs when the command line names no command
+const defaultCommand = "create"
+
+// cliCommands lists the commands in the order of the usage
+var cliCommands = []*cliCommand{
+	{name: "create", mode: ModeCreate, usage: "Create roles and then users in every tenant (the default command)"},
+	{name: "retry", mode: ModeRetryFailed, usage: "Retry only the failed users in the failed users CSV", legacy: "retry-failed"},
+	{name: "retry-roles", mode: ModeRetryRoles, usage: "Retry only the failed roles in the failed roles CSV", legacy: "retry-failed-roles"},
+	{name: "cleanup", mode: ModeCleanup, usage: "Delete the groups, users and roles created by previous runs", legacy: "cleanup"},
+	{name: "verify", mode: ModeRead, usage: "Read back the users created by previous runs", legacy: "read"},
+	{name: "race-test", mode: ModeRace, usage: "Create the same username from several workers at once and verify a single winner", legacy: "race-test"},
+	{name: "churn", mode: ModeChurn, usage: "Repeatedly create and immediately delete users for the churn duration", legacy: "churn"},
+	{name: "growth", mode: ModeGrowth, usage: "Create users continuously and report latency per dataset size checkpoint", legacy: "growth"},
+	{name: "group-scale", mode: ModeGroupScale, usage: "Add a large number of members to a single group and report latency by group size", legacy: "group-scale"},
+	{name: "role-scale", mode: ModeRoleScale, usage: "Assign many roles to every user and report creation and login latency", legacy: "role-scale"},
+	{name: "attribute-sweep", mode: ModeAttributeSweep, usage: "Sweep the number of custom attributes per user and report latency per count", legacy: "attribute-sweep"},
+	{name: "token", mode: ModeToken, usage: "Obtain tokens for created users and validate the issued JWTs", legacy: "token"},
+	{name: "logout", mode: ModeLogout, usage: "Log created users in and measure OIDC logout throughput", legacy: "logout"},
+	{name: "session-soak", mode: ModeSessionSoak, usage: "Build up active sessions without logging out and report login latency by session count", legacy: "session-soak"},
+	{name: "update", mode: ModeUpdate, usage: "Update created users with If-Match and verify that stale ETags are rejected", legacy: "update"},
+	{name: "patch", mode: ModePatch, usage: "Patch the users in the SCIM ID CSV with the configured operations", legacy: "patch"},
+	{name: "mix", mode: ModeMix, usage: "Run a weighted random mix of scenarios as virtual users for the mix duration", legacy: "mix"},
+	{name: "groups", mode: ModeGroups, usage: "Create SCIM2 groups in every tenant only", legacy: "groups"},
+	{name: "idps", mode: ModeIdPs, usage: "Create identity providers in every tenant only"},
+	{name: "api-resources", mode: ModeAPIResources, usage: "Register API resources with scopes in every tenant and request tokens with those scopes"},
+	{name: "bulk", mode: ModeBulk, usage: "Create roles and then users in batches through the SCIM2 Bulk endpoint", legacy: "bulk"},
+	{name: "login", mode: ModeLogin, usage: "Log in the users created by previous runs and measure authentication throughput", legacy: "login"},
+	{name: "report", args: "[summary file]", usage: "Print the summary file of a previous run", run: runReportCommand, loadsConfig: true},
+	{name: "split-failed", args: "<shards>", usage: "Split the failed users CSV into shards for retry on several machines", run: runSplitFailedCommand, loadsConfig: true, legacy: "split-failed"},
+	{name: "merge-failed", args: "<shard files>", usage: "Merge shard files back into the failed users CSV", run: runMergeFailedCommand, loadsConfig: true, legacy: "merge-failed"},
+	{name: "stub-idp", usage: "Serve a stub OpenID Connect provider that approves every login, for load testing federated logins", run: runStubIdPCommand, loadsConfig: true},
+	{name: "generate-config", usage: "Write the default configuration, or that of a scenario, to the -config file", run: runGenerateConfigCommand, legacy: "generate-config"},
+	{name: "generate-schema", args: "[file]", usage: "Write the JSON Schema of the configuration file, config.schema.json unless a file is given", run: runGenerateSchemaCommand, noFlags: true},
+	{name: "scenarios", args: "list | describe <name>", usage: "List or describe the built-in scenario presets", run: runScenariosCommand, noFlags: true},
+	{name: "version", args: "[json]", usage: "Print the version, commit and build date of the client", run: runVersionCommand, noFlags: true},
+	{name: "help", args: "[command]", usage: "Show the commands, or the flags of a command", noFlags: true},
+}
+
+func init() {
+	// Set here because the help command looks up the other commands
+	findCommand("help").run = runHelpCommand
+}
+
+// findCommand returns the command with the given name, or nil
+func findCommand(name string) *cliCommand {
+	for _, cmd := range cliCommands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// commandOptions holds the flags of a command that are not configuration values
+type commandOptions struct {
+	configPath    string
+	scenarioName  string
+	noColor       bool
+	strict        bool
+	resume        bool
+	retryRounds   int
+	retryBackoff  time.Duration
+	startAt       string
+	healthAddr    string
+	exportPlan    string
+	fromPlan      string
+	dryRun        bool
+	dryRunLatency time.Duration
+
+	scenario *loadScenario
+}
+
+// flagSet creates the flag set of the command, binding its options to opts and, for commands
+// that load the configuration, the configuration flags to config
+func (c *cliCommand) flagSet(opts *commandOptions, config *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	fs.Usage = func() { c.printUsage(fs.Output(), fs) }
+	if c.noFlags {
+		return fs
+	}
+
+	configUsage := "Path to configuration file (JSON)"
+	if c.name == "generate-config" {
+		configUsage = "Path to write the configuration file to (default config.json)"
+	}
+	fs.StringVar(&opts.configPath, "config", "", configUsage)
+	fs.StringVar(&opts.scenarioName, "scenario", "", "Use the settings of a built-in scenario preset (see: scenarios list)")
+	fs.BoolVar(&opts.noColor, "no-color", false, "Disable colored output (also disabled when stdout is not a terminal or NO_COLOR is set)")
+
+	if c.run == nil {
+		if c.mode == ModeCreate {
+			fs.BoolVar(&opts.resume, "resume", false, "Resume user creation from the checkpoint of an interrupted run")
+		}
+		if c.mode == ModeRetryFailed {
+			fs.IntVar(&opts.retryRounds, "retry-rounds", 1, "Retry the users that still fail again, up to this many rounds in total, until none are left")
+			fs.DurationVar(&opts.retryBackoff, "retry-round-backoff", 30*time.Second, "Wait before the second retry round, doubled before each further round")
+		}
+		fs.StringVar(&opts.exportPlan, "export-plan", "", "Write the resolved execution plan to this JSON file for review instead of running")
+		fs.StringVar(&opts.fromPlan, "from-plan", "", "Run the reviewed execution plan in this JSON file, ignoring configuration flags and the command's mode")
+		fs.BoolVar(&opts.dryRun, "dry-run", false, "Print when the workers of each phase start, the expected request rate and the estimated duration instead of running")
+		fs.DurationVar(&opts.dryRunLatency, "dry-run-latency", 100*time.Millisecond, "Latency per request assumed by -dry-run to estimate request rates and durations")
+		fs.StringVar(&opts.startAt, "start-at", "", "Wait until this RFC3339 time (e.g. 2024-05-01T10:00:00Z) before starting, to start several clients together")
+		fs.StringVar(&opts.healthAddr, "health-addr", "", "Address to serve /healthz and /readyz on, e.g. :8081 (empty to disable)")
+	}
+
+	if c.run == nil || c.loadsConfig {
+		fs.BoolVar(&opts.strict, "strict", true, "Reject configuration files with unknown fields, such as misspelled ones (false to only warn)")
+		bindConfigFlags(fs, config)
+	}
+	return fs
+}
+
+// printUsage prints the usage line, description and flags of the command
+func (c *cliCommand) printUsage(w io.Writer, fs *flag.FlagSet) {
+	usage := "go-perf " + c.name + " [flags]"
+	if c.args != "" {
+		usage += " " + c.args
+	}
+	fmt.Fprintf(w, "Usage: %s\n\n%s\n", usage, c.usage)
+	if !c.noFlags {
+		fmt.Fprintf(w, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+}
+
+// parseOptions parses the command's flags for its options, filling in the options not given from
+// their ISPERF_ environment variables. The configuration flags are parsed again by loadConfig once
+// the configuration file is known, so they override the file wherever they appear on the line.
+func (c *cliCommand) parseOptions(args []string) (*commandOptions, error) {
+	opts := &commandOptions{}
+	fs := c.flagSet(opts, DefaultConfig())
+	fs.Parse(args)
+
+	if err := applyEnvOverrides(fs); err != nil {
+		return nil, fmt.Errorf("failed to apply environment overrides: %v", err)
+	}
+
+	if opts.noColor {
+		colorOutput = false
+	}
+
+	if opts.scenarioName != "" {
+		scenario, err := findScenario(opts.scenarioName)
+		if err != nil {
+			return nil, err
+		}
+		opts.scenario = scenario
+	}
+	return opts, nil
+}
+
+// loadConfig loads the configuration file and scenario named by the options with their ISPERF_
+// environment variables and applies the configuration flags over them, returning the configuration
+// and the positional arguments of the command
+func (c *cliCommand) loadConfig(opts *commandOptions, args []string) (*Config, []string, error) {
+	config, err := LoadConfig(opts.configPath, opts.scenario, opts.strict)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fs := c.flagSet(&commandOptions{}, config)
+	fs.Parse(args)
+	return config, fs.Args(), nil
+}
+
+// parseCommandLine returns the command named by the command line and its arguments, and whether
+// the command was named rather than defaulted. The flags that selected a command before there
+// were subcommands, such as -retry-failed or -split-failed 4, are still accepted with a warning.
+func parseCommandLine(args []string) (*cliCommand, []string, bool, error) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd := findCommand(args[0])
+		if cmd == nil {
+			return nil, nil, false, fmt.Errorf("unknown command %q, run go-perf help for the commands", args[0])
+		}
+		return cmd, args[1:], true, nil
+	}
+
+	var cmd *cliCommand
+	var rest, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		legacy := legacyCommand(name)
+		if !strings.HasPrefix(arg, "-") || legacy == nil {
+			rest = append(rest, arg)
+			continue
+		}
+
+		if legacy.args == "" {
+			// A boolean flag, which selects nothing when set to false
+			if hasValue {
+				set, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, nil, false, fmt.Errorf("invalid boolean value %q for -%s", value, name)
+				}
+				if !set {
+					continue
+				}
+			}
+		} else if !hasValue {
+			// A flag with a value, which becomes the positional argument of the command
+			if i+1 == len(args) {
+				return nil, nil, false, fmt.Errorf("flag -%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+
+		if cmd != nil && cmd != legacy {
+			return nil, nil, false, fmt.Errorf("-%s and -%s select different commands", cmd.legacy, name)
+		}
+		cmd = legacy
+		if legacy.args != "" {
+			positional = append(positional, value)
+		}
+		printWarning("-%s is deprecated, use the %s command instead: %s\n", name, cmd.name, strings.TrimSpace("go-perf "+cmd.name+" [flags] "+cmd.args))
+	}
+
+	if cmd == nil {
+		return findCommand(defaultCommand), rest, false, nil
+	}
+	return cmd, append(rest, positional...), true, nil
+}
+
+// legacyCommand returns the command selected by a flag from before there were subcommands
+func legacyCommand(flagName string) *cliCommand {
+	for _, cmd := range cliCommands {
+		if cmd.legacy != "" && cmd.legacy == flagName {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// printCommands prints the usage of the client with its commands
+func printCommands(w io.Writer) {
+	fmt.Fprintf(w, "Usage: go-perf [command] [flags] [arguments]\n\nCommands:\n")
+	for _, cmd := range cliCommands {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(w, "\nEvery command takes -config. Run go-perf help <command> for the flags of a command.\n")
+}
+
+// runHelpCommand prints the commands, or the usage of the named command
+func runHelpCommand(_ *cliCommand, args []string) error {
+	if len(args) == 0 {
+		printCommands(os.Stdout)
+		return nil
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	fs := cmd.flagSet(&commandOptions{}, DefaultConfig())
+	fs.SetOutput(os.Stdout)
+	cmd.printUsage(os.Stdout, fs)
+	return nil
+}
+
+// runScenariosCommand lists or describes the built-in scenario presets
+func runScenariosCommand(_ *cliCommand, args []string) error {
+	return RunScenariosCommand(args)
+}
+
+// runGenerateConfigCommand writes the default configuration, with the scenario's settings applied
+// if one is given
+func runGenerateConfigCommand(cmd *cliCommand, args []string) error {
+	opts, err := cmd.parseOptions(args)
+	if err != nil {
+		return err
+	}
+
+	configPath := opts.configPath
+	if configPath == "" {
+		configPath = "config.json"
+	}
+
+	config := DefaultConfig()
+	if opts.scenario != nil {
+		if err := opts.scenario.Apply(config); err != nil {
+			return fmt.Errorf("failed to generate config file: %v", err)
+		}
+	}
+	if err := config.SaveConfig(configPath); err != nil {
+		return fmt.Errorf("failed to generate config file: %v", err)
+	}
+
+	fmt.Printf("Default configuration saved to: %s\n", configPath)
+	if opts.scenario != nil {
+		fmt.Printf("Includes the settings of scenario %s, which runs in %s mode\n", opts.scenario.Name, opts.scenario.Mode)
+	}
+	fmt.Println("You can modify this file and run with -config flag")
+	return nil
+}
+
+// runSplitFailedCommand splits the failed users CSV into the given number of shards
+func runSplitFailedCommand(cmd *cliCommand, args []string) error {
+	config, positional, err := cmd.loadCommandConfig(args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: go-perf %s [flags] %s", cmd.name, cmd.args)
+	}
+
+	shards, err := strconv.Atoi(positional[0])
+	if err != nil || shards < 1 {
+		return fmt.Errorf("invalid number of shards %q", positional[0])
+	}
+	if _, err := SplitFailedUsers(config.Execution.FailedUsersCsvPath, shards); err != nil {
+		return fmt.Errorf("failed to split failed users: %v", err)
+	}
+	return nil
+}
+
+// runMergeFailedCommand merges shard files, given as arguments or comma-separated, into the
+// failed users CSV
+func runMergeFailedCommand(cmd *cliCommand, args []string) error {
+	config, positional, err := cmd.loadCommandConfig(args)
+	if err != nil {
+		return err
+	}
+
+	var inputs []string
+	for _, arg := range positional {
+		for _, input := range strings.Split(arg, ",") {
+			if input != "" {
+				inputs = append(inputs, input)
+			}
+		}
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("usage: go-perf %s [flags] %s", cmd.name, cmd.args)
+	}
+
+	if err := MergeFailedUsers(config.Execution.FailedUsersCsvPath, inputs); err != nil {
+		return fmt.Errorf("failed to merge failed users: %v", err)
+	}
+	return nil
+}
+
+// runReportCommand prints a run summary file, by default the summaryFile of the configuration
+func runReportCommand(cmd *cliCommand, args []string) error {
+	config, positional, err := cmd.loadCommandConfig(args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return fmt.Errorf("usage: go-perf %s [flags] %s", cmd.name, cmd.args)
+	}
+
+	path := config.Execution.SummaryFile
+	if len(positional) == 1 {
+		path = positional[0]
+	}
+	if path == "" {
+		return fmt.Errorf("no summary file given and summaryFile is not configured")
+	}
+
+	summary, err := ReadRunSummary(path)
+	if err != nil {
+		return err
+	}
+	summary.Print(path)
+	return nil
+}
+
+// loadCommandConfig parses the flags of a utility command and loads its configuration
+func (c *cliCommand) loadCommandConfig(args []string) (*Config, []string, error) {
+	opts, err := c.parseOptions(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config, positional, err := c.loadConfig(opts, args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %v", err)
+	}
+	return config, positional, nil
+}
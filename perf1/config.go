@@ -1,11 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config represents the configuration for the SCIM2 test
@@ -18,14 +23,147 @@ type Config struct {
 	
 	// User Defined Variables
 	Execution ExecutionConfig `json:"execution"`
+
+	// Request retry Variables
+	Retry RetryConfig `json:"retry"`
+
+	// Cleanup Variables
+	Cleanup CleanupConfig `json:"cleanup"`
+
+	// Read Variables
+	Read ReadConfig `json:"read"`
+
+	// Update Variables
+	Update UpdateConfig `json:"update"`
+
+	// Patch load test Variables
+	Patch PatchConfig `json:"patch"`
+
+	// Duplicate-create race Variables
+	Race RaceConfig `json:"race"`
+
+	// Create-then-delete churn Variables
+	Churn ChurnConfig `json:"churn"`
+
+	// Steady-state growth Variables
+	Growth GrowthConfig `json:"growth"`
+
+	// Group creation Variables
+	Groups GroupsConfig `json:"groups"`
+
+	// Identity provider creation Variables
+	IdPs IdPsConfig `json:"idps"`
+
+	// Stub OpenID Connect provider Variables
+	StubIdP StubIdPConfig `json:"stubIdp"`
+
+	// API resource and scope registration Variables
+	APIResources APIResourcesConfig `json:"apiResources"`
+
+	// Bulk user creation Variables
+	Bulk BulkConfig `json:"bulk"`
+
+	// Group membership scale Variables
+	GroupScale GroupScaleConfig `json:"groupScale"`
+
+	// Role count scaling Variables
+	RoleScale RoleScaleConfig `json:"roleScale"`
+
+	// Attribute count sweep Variables
+	AttributeSweep AttributeSweepConfig `json:"attributeSweep"`
+
+	// Payload variant Variables
+	Variants VariantConfig `json:"variants"`
+
+	// Locale attribute Variables
+	Locale LocaleConfig `json:"locale"`
+
+	// OAuth2 Variables
+	OAuth OAuthConfig `json:"oauth"`
+
+	// Login phase Variables
+	Login LoginConfig `json:"login"`
+
+	// Session buildup soak Variables
+	SessionSoak SessionSoakConfig `json:"sessionSoak"`
+
+	// Weighted scenario mix Variables
+	Mix MixConfig `json:"mix"`
+
+	// Alerting Variables
+	Alerts AlertsConfig `json:"alerts"`
+
+	// Metric reporter Variables
+	Reporters ReportersConfig `json:"reporters"`
+
+	// Global Variables, the initial values of the variable store shared by all workers
+	Variables map[string]string `json:"variables"`
+
+	// Offline guarantees that no request goes to a host other than the target server
+	Offline bool `json:"offline"`
 }
 
 // ServerConfig holds server connection details
 type ServerConfig struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	ScimTimeout int    `json:"scimTimeout"`
+	SoapTimeout int    `json:"soapTimeout"`
+
+	// RequestDeadline aborts a request that has not completed after that many milliseconds, which
+	// can be shorter than the timeouts above. RequestDeadlines sets it per operation label, e.g.
+	// "SOAP addRole" or "POST /t/{tenant}/scim2/Users", with 0 for no deadline.
+	RequestDeadline  int            `json:"requestDeadline"`
+	RequestDeadlines map[string]int `json:"requestDeadlines,omitempty"`
+
+	// SCIM endpoint base paths, which may contain a {tenant} placeholder for tenant-qualified URLs:
+	// ScimBasePath serves the user endpoints and Scim2BasePath the SCIM2-only group, bulk, search,
+	// update and /Me endpoints. Identity Server 7.x serves both at /scim2 or /t/{tenant}/scim2.
+	ScimBasePath  string `json:"scimBasePath"`
+	Scim2BasePath string `json:"scim2BasePath"`
+
+	// SOAP transport options, for load balancers that misbehave with persistent SOAP connections
+	SoapKeepAlive bool `json:"soapKeepAlive"`
+	SoapChunked   bool `json:"soapChunked"`
+
+	// Limits of the connection pool shared by all workers: idle connections kept in total and per
+	// host (0 per host keeps one per thread), connections per host (0 for no limit), and seconds an
+	// idle connection is kept
+	MaxIdleConns        int `json:"maxIdleConns"`
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost"`
+	MaxConnsPerHost     int `json:"maxConnsPerHost"`
+	IdleConnTimeout     int `json:"idleConnTimeout"`
+
+	// TLS settings of the connections to the server: certificates are verified only with TLSVerify,
+	// against the system roots and the PEM bundle in TLSCACertFile; TLSMinVersion is 1.0 to 1.3
+	// (empty for the Go default) and TLSCipherSuites the IANA names of the TLS 1.2 suites offered.
+	// TLSClientCertFile and TLSClientKeyFile hold the PEM certificate and key presented for mutual
+	// TLS; the key file may be left empty when the certificate file holds the key as well.
+	TLSVerify         bool     `json:"tlsVerify"`
+	TLSCACertFile     string   `json:"tlsCaCertFile"`
+	TLSMinVersion     string   `json:"tlsMinVersion"`
+	TLSCipherSuites   []string `json:"tlsCipherSuites"`
+	TLSClientCertFile string   `json:"tlsClientCertFile"`
+	TLSClientKeyFile  string   `json:"tlsClientKeyFile"`
+
+	// PreemptiveAuth sends credentials up front instead of waiting for a 401 challenge
+	PreemptiveAuth bool `json:"preemptiveAuth"`
+
+	// SoapSessionAuth logs in once per worker and tenant and reuses the admin session cookie for SOAP calls
+	SoapSessionAuth bool `json:"soapSessionAuth"`
+
+	// Bearer token auth with an externally managed access token, read from a file ("-" for stdin)
+	// or an environment variable and re-read every BearerTokenReload seconds (0 to read once)
+	BearerTokenFile   string `json:"bearerTokenFile"`
+	BearerTokenEnv    string `json:"bearerTokenEnv"`
+	BearerTokenReload int    `json:"bearerTokenReload"`
+
+	// AuthMode selects how SCIM requests authenticate: basic, or a bearer token obtained per tenant from
+	// the token endpoint with the OAuth client's client_credentials or the tenant admin's password grant
+	AuthMode  string `json:"authMode"`
+	AuthScope string `json:"authScope"`
 }
 
 // TestConfig holds test-specific parameters
@@ -34,6 +172,48 @@ type TestConfig struct {
 	UserPassword   string `json:"userPassword"`
 	RoleName       string `json:"roleName"`
 	TenantPrefix   string `json:"tenantPrefix"`
+	
+	// ExistingRoleOK counts a role that already exists, found by the check before creating it or
+	// reported by a RoleAlreadyExisting fault, as created
+	ExistingRoleOK bool `json:"existingRoleOk"`
+	
+	// SkipRoleCreation leaves out the role phase for roles managed outside the test, which must
+	// exist before the run and are never deleted by cleanup
+	SkipRoleCreation bool `json:"skipRoleCreation"`
+	
+	// TenantRoles maps a tenant number or domain to the role its users get instead of RoleName
+	TenantRoles map[string]string `json:"tenantRoles,omitempty"`
+	
+	// RolePermissions are the permission resource IDs of the test role and of defined roles
+	// without permissions of their own
+	RolePermissions []string `json:"rolePermissions"`
+	
+	// Roles are further roles created in every tenant along with the test role
+	Roles []RoleDefinition `json:"roles,omitempty"`
+	
+	// PasswordPolicies give cohorts of users passwords of their own pattern instead of UserPassword
+	PasswordPolicies []PasswordPolicy `json:"passwordPolicies,omitempty"`
+	
+	// PasswordMode sends passwords as they are (plaintext), as their PasswordHash digest
+	// (prehashed), or half of them each way (compare). Pre-hashed users log in with the digest,
+	// which must satisfy the server's password policy, PasswordPolicyRegex.
+	PasswordMode        string `json:"passwordMode"`
+	PasswordHash        string `json:"passwordHash"`
+	PasswordPolicyRegex string `json:"passwordPolicyRegex"`
+}
+
+// PasswordPolicy describes the passwords of a cohort of users, e.g. the pattern Aa{11}9{2}#
+type PasswordPolicy struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"` // A upper case, a lower case, 9 digit, # symbol, {n} repeats, \ escapes
+	Weight  int    `json:"weight"`  // share of the users in the cohort
+}
+
+// RoleDefinition describes a role created in every tenant
+type RoleDefinition struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions,omitempty"` // resource IDs, rolePermissions when empty
+	Assign      bool     `json:"assign"`                // give the role to every test user
 }
 
 // ExecutionConfig holds execution parameters
@@ -44,25 +224,477 @@ type ExecutionConfig struct {
 	RampUpPeriod      int    `json:"rampUpPeriod"`
 	ScimIdCsvPath     string `json:"scimIdCsvPath"`
 	FailedUsersCsvPath string `json:"failedUsersCsvPath"`
+	FailedRolesCsvPath string `json:"failedRolesCsvPath"` // empty to disable
 	NoOfTenants       int    `json:"noOfTenants"`
 	UserStartNumber   int    `json:"userStartNumber"`
 	TenantStartNumber int    `json:"tenantStartNumber"`
+	ProgressFile      string `json:"progressFile"`
+	ProgressInterval  int    `json:"progressInterval"` // seconds
+	ProgressPrintInterval int `json:"progressPrintInterval"` // seconds between console progress lines, 0 to disable
+	ParallelTenants   bool   `json:"parallelTenants"`
+	WriteScimIds      bool   `json:"writeScimIds"`
+	HeatmapFile       string `json:"heatmapFile"`
+	HeatmapInterval   int    `json:"heatmapInterval"` // seconds
+	HeatmapBuckets    []int  `json:"heatmapBuckets"`  // latency bucket upper bounds in milliseconds
+
+	// TargetTPS paces user creation to a constant rate across all threads with a token bucket
+	// allowing bursts of TPSBurst requests (0 to send as fast as possible)
+	TargetTPS float64 `json:"targetTps"`
+	TPSBurst  int     `json:"tpsBurst"`
+
+	// RampProfile runs user creation in steps of threads:duration, e.g. 10:2m,20:2m,50:5m, instead
+	// of ramping noOfThreads up over rampUpPeriod; the phase ends with the last step
+	RampProfile string `json:"rampProfile"`
+
+	// ArrivalRate creates users as an open workload: arrivals at this mean rate per second with
+	// Poisson inter-arrival times, each sent on arrival by up to ArrivalConcurrency requests in
+	// flight rather than by a fixed number of threads (0 to use the threads). Up to ArrivalQueue
+	// arrivals wait for a free request; arrivals beyond them are dropped and recorded as failed.
+	ArrivalRate        float64 `json:"arrivalRate"`
+	ArrivalConcurrency int     `json:"arrivalConcurrency"`
+	ArrivalQueue       int     `json:"arrivalQueue"`
+
+	// PipelineTenants starts creating the users of a tenant as soon as its role exists, running the
+	// role and user phases at the same time
+	PipelineTenants bool `json:"pipelineTenants"`
+
+	// ProbeTenants checks that every tenant is active before creating users in it, retrying the
+	// probe ProbeAttempts times ProbeInterval seconds apart before failing the tenant's users
+	ProbeTenants  bool `json:"probeTenants"`
+	ProbeAttempts int  `json:"probeAttempts"`
+	ProbeInterval int  `json:"probeInterval"`
+
+	// RoleDelay pauses a role creation thread for milliseconds after it created a test role, like
+	// the fixed 5 seconds of the JMX plan. WaitForRole instead polls until the role is visible, for
+	// up to RoleWaitTimeout seconds.
+	RoleDelay       int  `json:"roleDelay"`
+	WaitForRole     bool `json:"waitForRole"`
+	RoleWaitTimeout int  `json:"roleWaitTimeout"`
+
+	// AbortErrorPercent aborts the run when more than that percentage of the requests completed in
+	// an interval of AbortInterval seconds failed, once the interval has AbortMinRequests requests
+	AbortErrorPercent float64 `json:"abortErrorPercent"`
+	AbortMinRequests  int     `json:"abortMinRequests"`
+	AbortInterval     int     `json:"abortInterval"`
+
+	// TopErrors is the number of most frequent error patterns listed in the summary (0 for all)
+	TopErrors int `json:"topErrors"`
+
+	// SummaryFile receives the machine-readable summary of the default run (empty to disable)
+	SummaryFile string `json:"summaryFile"`
+
+	// ReportFile receives the HTML report of each run, with throughput, latency and error charts
+	// (empty to disable)
+	ReportFile string `json:"reportFile"`
+
+	// AccessLogFile receives every request in the Apache combined log format (empty to disable),
+	// with the request duration in microseconds appended when AccessLogLatency is set
+	AccessLogFile    string `json:"accessLogFile"`
+	AccessLogLatency bool   `json:"accessLogLatency"`
+	
+	// FallbackDir receives the rest of a CSV output whose disk fills up mid-run (empty to stop
+	// writing it instead)
+	FallbackDir string `json:"fallbackDir"`
+	
+	// OutputManifests writes a manifest with the row count and SHA-256 checksum of every CSV output
+	// file next to it once the file is complete, so truncated files of crashed runs can be detected
+	OutputManifests bool `json:"outputManifests"`
+	
+	// CheckpointFile records how far an interrupted run got; Resume continues from it
+	CheckpointFile string `json:"checkpointFile"`
+	Resume         bool   `json:"-"`
+	
+	// RetryRounds retries the failed users again until none are left or the rounds run out, waiting
+	// RetryRoundBackoff before the second round and twice as long before each further one
+	RetryRounds       int           `json:"-"`
+	RetryRoundBackoff time.Duration `json:"-"`
+}
+
+// RetryConfig holds the retry policy of role and user creation requests that fail with a transient
+// server error
+type RetryConfig struct {
+	MaxAttempts    int   `json:"maxAttempts"`    // attempts per request including the first, 1 disables retries
+	RoleAttempts   int   `json:"roleAttempts"`   // attempts per role, retrying any failure once the role is found missing
+	InitialBackoff int   `json:"initialBackoff"` // milliseconds before the first retry, doubled for each further retry
+	MaxBackoff     int   `json:"maxBackoff"`     // upper bound of the backoff in milliseconds
+	StatusCodes    []int `json:"statusCodes"`    // response status codes that are retried
+}
+
+// CleanupConfig holds teardown parameters, with a separate thread count per resource type
+type CleanupConfig struct {
+	GroupThreads       int  `json:"groupThreads"`
+	UserThreads        int  `json:"userThreads"`
+	RoleThreads        int  `json:"roleThreads"`
+	IdPThreads         int  `json:"idpThreads"`
+	APIResourceThreads int  `json:"apiResourceThreads"`
+	Verify             bool `json:"verify"`
+
+	// Source selects which users are deleted: names (the configured user range), csv (the SCIM IDs
+	// recorded in scimIdCsvPath) or prefix (every user whose username starts with usernamePrefix)
+	Source string `json:"source"`
+}
+
+// ReadConfig holds parameters for the user read phase
+type ReadConfig struct {
+	Passes              int  `json:"passes"`
+	ConditionalRequests bool `json:"conditionalRequests"`
+	CacheBusting        bool `json:"cacheBusting"`
+}
+
+// UpdateConfig holds parameters for the user update phase
+type UpdateConfig struct {
+	Conditional   bool    `json:"conditional"`
+	StaleFraction float64 `json:"staleFraction"`
+}
+
+// PatchConfig holds parameters for the SCIM2 PATCH load test of the users in the SCIM ID CSV
+type PatchConfig struct {
+	Threads    int                    `json:"threads"` // concurrent PATCH workers, 0 uses noOfThreads
+	Passes     int                    `json:"passes"`  // times every user is patched
+	Operations []PatchOperationConfig `json:"operations"`
+}
+
+// PatchOperationConfig is an operation of every PATCH request. Value is a payload template that is
+// also given the user's .ScimID, .Tenant and .Pass, and is sent as JSON when it expands to an object
+// or array.
+type PatchOperationConfig struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+// RaceConfig holds parameters for the duplicate-create race test
+type RaceConfig struct {
+	Contenders int `json:"contenders"`
+	Rounds     int `json:"rounds"`
+}
+
+// ChurnConfig holds parameters for the create-then-delete churn workload
+type ChurnConfig struct {
+	Duration        int     `json:"duration"`
+	CyclesPerSecond float64 `json:"cyclesPerSecond"`
+}
+
+// GrowthConfig holds parameters for the steady-state database growth benchmark
+type GrowthConfig struct {
+	TotalUsers         int `json:"totalUsers"`
+	CheckpointInterval int `json:"checkpointInterval"`
+}
+
+// GroupsConfig holds parameters for the SCIM2 group creation phase
+type GroupsConfig struct {
+	PerTenant  int    `json:"perTenant"` // 0 skips the phase in the default run
+	NamePrefix string `json:"namePrefix"`
+	CsvPath    string `json:"csvPath"`
+}
+
+// IdPsConfig holds parameters for the identity provider creation phase, which creates federation
+// configurations through the IdP management REST API at APIPath
+type IdPsConfig struct {
+	PerTenant  int    `json:"perTenant"` // 0 skips the phase in the default run
+	NamePrefix string `json:"namePrefix"`
+	APIPath    string `json:"apiPath"` // may contain a {tenant} placeholder
+	CsvPath    string `json:"csvPath"`
+}
+
+// StubIdPConfig holds parameters for the stub OpenID Connect provider served by the stub-idp
+// command, which approves every login so federated logins need no real external IdP
+type StubIdPConfig struct {
+	Address       string `json:"address"`
+	URL           string `json:"url"`      // base URL and issuer of the stub; IdPs federate to it when set
+	CertPath      string `json:"certPath"` // empty serves plain HTTP
+	KeyPath       string `json:"keyPath"`
+	Users         int    `json:"users"` // federated users the stub logs in as
+	UserPrefix    string `json:"userPrefix"`
+	TokenLifetime int    `json:"tokenLifetime"` // seconds
+}
+
+// APIResourcesConfig holds parameters for the API resource phase, which registers API resources with
+// their scopes through the API Resource Management REST API at APIPath and then requests tokens
+// with those scopes
+type APIResourcesConfig struct {
+	PerTenant         int    `json:"perTenant"` // 0 also leaves the resources out of cleanup
+	ScopesPerResource int    `json:"scopesPerResource"`
+	NamePrefix        string `json:"namePrefix"`
+	APIPath           string `json:"apiPath"` // may contain a {tenant} placeholder
+	CsvPath           string `json:"csvPath"`
+
+	// The server only grants the scopes of an API resource to applications authorized for it, so
+	// the application of the OAuth2 client is authorized for every registered resource
+	AuthorizeApplication bool   `json:"authorizeApplication"`
+	ApplicationsPath     string `json:"applicationsPath"` // may contain a {tenant} placeholder
+
+	TokenScopes int `json:"tokenScopes"` // registered scopes requested per token, 0 skips the tokens
+}
+
+// BulkConfig holds parameters for creating users through the SCIM2 Bulk endpoint
+type BulkConfig struct {
+	BatchSize    int `json:"batchSize"`
+	FailOnErrors int `json:"failOnErrors"` // 0 lets the server process every operation
+	Retries      int `json:"retries"`      // times failed operations are sent again in a later batch
+}
+
+// GroupScaleConfig holds parameters for the single giant group membership scale test
+type GroupScaleConfig struct {
+	GroupName      string `json:"groupName"`
+	Members        int    `json:"members"`
+	BatchSize      int    `json:"batchSize"`
+	ReportInterval int    `json:"reportInterval"`
+}
+
+// RoleScaleConfig holds parameters for the per-user role count scaling test
+type RoleScaleConfig struct {
+	RolesPerUser int `json:"rolesPerUser"`
+	Users        int `json:"users"`
+}
+
+// AttributeSweepConfig holds parameters for the custom attribute count sweep
+type AttributeSweepConfig struct {
+	AttributeCounts []int  `json:"attributeCounts"`
+	UsersPerStep    int    `json:"usersPerStep"`
+	AttributePrefix string `json:"attributePrefix"`
+}
+
+// VariantConfig gives a share of the created users a payload that differs from the default one, so
+// the dataset is heterogeneous rather than identical rows. Each percentage selects its users
+// independently of the others.
+type VariantConfig struct {
+	AttributesPercent float64  `json:"attributesPercent"` // users with extra custom attributes
+	Attributes        int      `json:"attributes"`        // extra custom attributes per selected user
+	AttributePrefix   string   `json:"attributePrefix"`
+	RolesPercent      float64  `json:"rolesPercent"` // users that also get the variant roles
+	Roles             []string `json:"roles"`        // created in every tenant along with the test role
+	LockedPercent     float64  `json:"lockedPercent"` // users created with a locked account
+	Seed              int64    `json:"seed"`          // changes which users are selected
+}
+
+// LocaleConfig holds the weighted values the locale, timezone and preferredLanguage attributes of
+// created users are drawn from, e.g. {"en_US": 70, "fr_FR": 30}. An attribute without values is not sent.
+type LocaleConfig struct {
+	Locales            map[string]int `json:"locales"`
+	Timezones          map[string]int `json:"timezones"`
+	PreferredLanguages map[string]int `json:"preferredLanguages"`
+}
+
+// LoginConfig holds parameters for the login phase that authenticates created users
+type LoginConfig struct {
+	Percent float64 `json:"percent"` // created users logged in after provisioning, 0 skips the phase in the default run
+	Method  string  `json:"method"`  // password (password grant) or me (Basic-auth GET /scim2/Me)
+}
+
+// OAuthConfig holds the OAuth2 client and endpoint settings used by token phases.
+// Endpoint paths may contain a {tenant} placeholder that is replaced with the tenant domain.
+type OAuthConfig struct {
+	ClientID       string   `json:"clientId"`
+	ClientSecret   string   `json:"clientSecret"`
+	Scope          string   `json:"scope"`
+	TokenPath      string   `json:"tokenPath"`
+	JWKSPath       string   `json:"jwksPath"`
+	ValidateTokens bool     `json:"validateTokens"`
+	RequiredClaims []string `json:"requiredClaims"`
+
+	// Client authentication: client_secret_basic, client_secret_post, private_key_jwt or none
+	ClientAuthMethod string `json:"clientAuthMethod"`
+	PrivateKeyPath   string `json:"privateKeyPath"`
+	KeyID            string `json:"keyId"`
+	SigningAlgorithm string `json:"signingAlgorithm"`
+
+	// Grant used by the token phase: password, authorization_code or device_code
+	Grant          string `json:"grant"`
+	RedirectURI    string `json:"redirectUri"`
+	AuthorizePath  string `json:"authorizePath"`
+	CommonAuthPath string `json:"commonAuthPath"`
+	PKCE           bool   `json:"pkce"`
+
+	// Device authorization grant endpoints and the longest time to poll for a token
+	DeviceAuthorizePath string `json:"deviceAuthorizePath"`
+	DevicePath          string `json:"devicePath"`
+	DevicePollTimeout   int    `json:"devicePollTimeout"` // seconds
+
+	// OIDC RP-initiated logout endpoint; RedirectURI doubles as the post logout redirect URI
+	LogoutPath string `json:"logoutPath"`
+
+	// Identity provider the authorization code flow logs in through, sent as the fidp parameter
+	FederatedIdP string `json:"federatedIdp"`
+}
+
+// SessionSoakConfig holds parameters for the session count buildup and idle session soak
+type SessionSoakConfig struct {
+	Sessions           int `json:"sessions"`
+	CheckpointInterval int `json:"checkpointInterval"`
+	HoldDuration       int `json:"holdDuration"` // seconds
+	ProbeLogins        int `json:"probeLogins"`
+}
+
+// MixConfig holds parameters for the weighted scenario mix soak
+type MixConfig struct {
+	Duration int            `json:"duration"` // seconds
+	Weights  map[string]int `json:"weights"`
+
+	// DataSets binds scenario steps to external CSV files, by scenario name
+	DataSets map[string]DataSetConfig `json:"dataSets"`
+
+	// Attributes are payload templates of the attributes set by profile-update, by attribute name
+	Attributes map[string]string `json:"attributes"`
+}
+
+// DataSetConfig describes an external CSV file whose rows drive a scenario step
+type DataSetConfig struct {
+	File      string            `json:"file"`
+	Share     string            `json:"share"`     // "all" (default) or "thread"
+	StopAtEnd bool              `json:"stopAtEnd"` // stop the thread at the end of the file instead of starting over
+	Variables map[string]string `json:"variables"` // variable name to column name; every column by its own name when empty
+}
+
+// AlertsConfig holds live response time alert rules, such as p95>1s/60s, evaluated every Interval
+// seconds over the requests completed in that interval
+type AlertsConfig struct {
+	Rules    []string `json:"rules"`
+	Webhook  string   `json:"webhook"`
+	Interval int      `json:"interval"` // seconds
+	Abort    bool     `json:"abort"`    // abort the run when a rule fires
+}
+
+// ReportersConfig selects the registered metric reporters that receive the result of every request
+type ReportersConfig struct {
+	Enabled       []string                     `json:"enabled"`       // reporter names
+	FlushInterval int                          `json:"flushInterval"` // seconds
+	Options       map[string]map[string]string `json:"options"`       // settings of each reporter, by reporter name
+}
+
+// intListFlag is a comma-separated list of integers usable as a command line flag
+type intListFlag struct {
+	values *[]int
+}
+
+func (f intListFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	parts := make([]string, len(*f.values))
+	for i, v := range *f.values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f intListFlag) Set(value string) error {
+	var values []int
+	for _, part := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q in list", part)
+		}
+		values = append(values, v)
+	}
+	*f.values = values
+	return nil
+}
+
+// stringListFlag is a comma-separated list of strings usable as a command line flag
+type stringListFlag struct {
+	values *[]string
+}
+
+func (f stringListFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, ",")
+}
+
+func (f stringListFlag) Set(value string) error {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	*f.values = values
+	return nil
+}
+
+// weightsFlag is a comma-separated list of name=weight pairs usable as a command line flag
+type weightsFlag struct {
+	values *map[string]int
+}
+
+func (f weightsFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	names := make([]string, 0, len(*f.values))
+	for name := range *f.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%d", name, (*f.values)[name])
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f weightsFlag) Set(value string) error {
+	values := make(map[string]int)
+	for _, part := range strings.Split(value, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return fmt.Errorf("invalid weight %q, expected name=weight", part)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil {
+			return fmt.Errorf("invalid weight %q for %s", weight, name)
+		}
+		values[name] = w
+	}
+	*f.values = values
+	return nil
 }
 
 // DefaultConfig returns a configuration with default values matching the JMX file
 func DefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Host:     "localhost",
-			Port:     9443,
-			Username: "admin@wso2.com",
-			Password: "tpass",
+			Host:                "localhost",
+			Port:                9443,
+			Username:            "admin@wso2.com",
+			Password:            "tpass",
+			ScimTimeout:         30,
+			ScimBasePath:        "/wso2/scim",
+			Scim2BasePath:       "/scim2",
+			SoapTimeout:         120,
+			SoapKeepAlive:       true,
+			SoapChunked:         false,
+			MaxIdleConns:        0,
+			MaxIdleConnsPerHost: 0,
+			MaxConnsPerHost:     0,
+			IdleConnTimeout:     90,
+			TLSVerify:           false,
+			TLSCACertFile:       "",
+			TLSMinVersion:       "",
+			TLSClientCertFile:   "",
+			TLSClientKeyFile:    "",
+			PreemptiveAuth:      true,
+			SoapSessionAuth:     false,
+			AuthMode:            AuthModeBasic,
+			AuthScope:           "internal_user_mgt_create internal_user_mgt_list internal_user_mgt_view internal_user_mgt_update internal_user_mgt_delete internal_group_mgt_create internal_group_mgt_view internal_group_mgt_update internal_group_mgt_delete internal_bulk_resource_create",
 		},
 		Test: TestConfig{
 			UsernamePrefix: "isTestUser_",
 			UserPassword:   "Password_1",
 			RoleName:       "isTestUserRole",
 			TenantPrefix:   "tenant",
+			ExistingRoleOK: true,
+			PasswordMode:   PasswordModePlaintext,
+			PasswordHash:   "SHA-1",
+			PasswordPolicyRegex: `^[\S]{5,30}$`,
+			RolePermissions: []string{
+				"/permission/admin/login",
+				"/permission/admin/configure/",
+				"/permission/admin/manage/",
+			},
 		},
 		Execution: ExecutionConfig{
 			NoOfThreads:        1,
@@ -71,15 +703,206 @@ func DefaultConfig() *Config {
 			RampUpPeriod:       10,
 			ScimIdCsvPath:      "scimIDs.csv",
 			FailedUsersCsvPath: "failedUsers.csv",
+			FailedRolesCsvPath: "failedRoles.csv",
 			NoOfTenants:        5,
 			UserStartNumber:    1,
 			TenantStartNumber:  1,
+			ProgressFile:       "progress.json",
+			ProgressInterval:   5,
+			ProgressPrintInterval: 10,
+			ParallelTenants:    false,
+			WriteScimIds:       false,
+			OutputManifests:    true,
+			HeatmapFile:        "",
+			HeatmapInterval:    10,
+			HeatmapBuckets:     []int{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000},
+			TargetTPS:          0,
+			RampProfile:        "",
+			ArrivalRate:        0,
+			ArrivalConcurrency: 1000,
+			ArrivalQueue:       10000,
+			TPSBurst:           1,
+			PipelineTenants:    false,
+			ProbeTenants:       false,
+			ProbeAttempts:      10,
+			ProbeInterval:      5,
+			RoleDelay:          0,
+			WaitForRole:        false,
+			RoleWaitTimeout:    30,
+			AbortErrorPercent:  0,
+			AbortMinRequests:   50,
+			AbortInterval:      10,
+			TopErrors:          10,
+			CheckpointFile:     "checkpoint.json",
+			SummaryFile:        "summary.json",
+			ReportFile:         "report.html",
+			AccessLogFile:      "",
+			AccessLogLatency:   false,
+		},
+		Retry: RetryConfig{
+			MaxAttempts:    3,
+			RoleAttempts:   5,
+			InitialBackoff: 500,
+			MaxBackoff:     10000,
+			StatusCodes:    []int{429, 502, 503, 504},
+		},
+		Cleanup: CleanupConfig{
+			GroupThreads:       1,
+			UserThreads:        1,
+			RoleThreads:        1,
+			IdPThreads:         1,
+			APIResourceThreads: 1,
+			Verify:             true,
+			Source:             CleanupSourceNames,
+		},
+		Read: ReadConfig{
+			Passes:              2,
+			ConditionalRequests: true,
+			CacheBusting:        false,
+		},
+		Update: UpdateConfig{
+			Conditional:   true,
+			StaleFraction: 0.1,
+		},
+		Patch: PatchConfig{
+			Threads: 0,
+			Passes:  1,
+			Operations: []PatchOperationConfig{
+				{Op: "replace", Path: "nickName", Value: `perf-{{.Pass}}-{{seq "patch"}}`},
+				{Op: "replace", Path: "emails", Value: `[{"value": "patch{{.Pass}}_{{.ScimID}}@perf.example.com", "type": "work"}]`},
+			},
+		},
+		Race: RaceConfig{
+			Contenders: 5,
+			Rounds:     10,
+		},
+		Churn: ChurnConfig{
+			Duration:        60,
+			CyclesPerSecond: 1,
+		},
+		Growth: GrowthConfig{
+			TotalUsers:         1000000,
+			CheckpointInterval: 100000,
+		},
+		Groups: GroupsConfig{
+			PerTenant:  0,
+			NamePrefix: "isTestGroup_",
+			CsvPath:    "groupIDs.csv",
+		},
+		IdPs: IdPsConfig{
+			PerTenant:  0,
+			NamePrefix: "isTestIdP_",
+			APIPath:    "/t/{tenant}/api/server/v1/identity-providers",
+			CsvPath:    "idpIDs.csv",
+		},
+		APIResources: APIResourcesConfig{
+			PerTenant:            0,
+			ScopesPerResource:    5,
+			NamePrefix:           "isTestAPI_",
+			APIPath:              "/t/{tenant}/api/server/v1/api-resources",
+			CsvPath:              "apiResourceIDs.csv",
+			AuthorizeApplication: true,
+			ApplicationsPath:     "/t/{tenant}/api/server/v1/applications",
+			TokenScopes:          10,
+		},
+		StubIdP: StubIdPConfig{
+			Address:       ":9444",
+			URL:           "",
+			Users:         1000,
+			UserPrefix:    "federatedUser_",
+			TokenLifetime: 3600,
+		},
+		Login: LoginConfig{
+			Percent: 0,
+			Method:  LoginMethodPassword,
+		},
+		Bulk: BulkConfig{
+			BatchSize:    100,
+			FailOnErrors: 0,
+			Retries:      1,
+		},
+		GroupScale: GroupScaleConfig{
+			GroupName:      "isTestGiantGroup",
+			Members:        200000,
+			BatchSize:      100,
+			ReportInterval: 10000,
+		},
+		RoleScale: RoleScaleConfig{
+			RolesPerUser: 50,
+			Users:        1000,
+		},
+		AttributeSweep: AttributeSweepConfig{
+			AttributeCounts: []int{5, 25, 100},
+			UsersPerStep:    1000,
+			AttributePrefix: "perfAttr",
+		},
+		Variants: VariantConfig{
+			AttributesPercent: 0,
+			Attributes:        5,
+			AttributePrefix:   "perfAttr",
+			RolesPercent:      0,
+			Roles:             []string{"isTestUserRole_variant"},
+			LockedPercent:     0,
+			Seed:              0,
+		},
+		OAuth: OAuthConfig{
+			Scope:          "openid",
+			TokenPath:      "/t/{tenant}/oauth2/token",
+			JWKSPath:       "/t/{tenant}/oauth2/jwks",
+			ValidateTokens: true,
+			RequiredClaims: []string{"iss", "sub", "aud", "exp", "iat"},
+
+			ClientAuthMethod: "client_secret_basic",
+			SigningAlgorithm: "PS256",
+
+			Grant:          "password",
+			RedirectURI:    "http://localhost:8080/callback",
+			AuthorizePath:  "/t/{tenant}/oauth2/authorize",
+			CommonAuthPath: "/commonauth",
+			PKCE:           true,
+
+			DeviceAuthorizePath: "/t/{tenant}/oauth2/device_authorize",
+			DevicePath:          "/t/{tenant}/oauth2/device",
+			DevicePollTimeout:   60,
+
+			LogoutPath: "/t/{tenant}/oidc/logout",
+
+			FederatedIdP: "",
+		},
+		SessionSoak: SessionSoakConfig{
+			Sessions:           10000,
+			CheckpointInterval: 1000,
+			HoldDuration:       600,
+			ProbeLogins:        100,
+		},
+		Alerts: AlertsConfig{
+			Interval: 10,
+		},
+		Reporters: ReportersConfig{
+			FlushInterval: 10,
+		},
+		Mix: MixConfig{
+			Duration: 300,
+			Weights: map[string]int{
+				"login":           80,
+				"profile-update":  15,
+				"password-change": 5,
+			},
 		},
 	}
 }
 
-// LoadConfig loads configuration from file or returns default config
-func LoadConfig(configPath string) (*Config, error) {
+// configFile is the layout of a configuration file: the configuration and the schema the file
+// names for editors, which the client does not use
+type configFile struct {
+	Schema string `json:"$schema"`
+	*Config
+}
+
+// LoadConfig loads configuration from file or returns default config, with the scenario preset's
+// settings, if one is given, applied over the file. Command line flags are applied by the caller.
+// In strict mode a file with unknown fields is rejected, otherwise they are ignored with a warning.
+func LoadConfig(configPath string, scenario *loadScenario, strict bool) (*Config, error) {
 	config := DefaultConfig()
 	
 	if configPath != "" {
@@ -94,39 +917,233 @@ func LoadConfig(configPath string) (*Config, error) {
 			return nil, fmt.Errorf("failed to read config file: %v", err)
 		}
 		
-		if err := json.Unmarshal(data, config); err != nil {
+		// Notepad saves UTF-8 files with a byte order mark, which the JSON decoder rejects
+		data = trimBOM(data)
+		
+		// Misspelled fields would silently leave their setting at the default for the whole run
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		if strict {
+			decoder.DisallowUnknownFields()
+		}
+		if err := decoder.Decode(&configFile{Config: config}); err != nil {
+			// Name every unknown field rather than only the first the decoder stops at
+			if unknown := unknownConfigFields(data); strict && len(unknown) > 0 {
+				return nil, fmt.Errorf("unknown fields in config file %s: %s (fix their names, or use -strict=false to ignore them)",
+					configPath, strings.Join(unknown, ", "))
+			}
 			return nil, fmt.Errorf("failed to parse config file: %v", err)
 		}
+		if decoder.More() {
+			return nil, fmt.Errorf("failed to parse config file: unexpected data after the configuration")
+		}
+		
+		if !strict {
+			for _, field := range unknownConfigFields(data) {
+				printWarning("Unknown field %s in %s is ignored\n", field, configPath)
+			}
+		}
+	}
+	
+	if scenario != nil {
+		if err := scenario.Apply(config); err != nil {
+			return nil, err
+		}
 	}
 	
-	// Override with command line flags if provided
-	parseFlags(config)
+	// ISPERF_ environment variables override the file, e.g. to keep passwords out of it
+	if err := applyConfigEnv(config); err != nil {
+		return nil, err
+	}
 	
 	return config, nil
 }
 
-// parseFlags parses command line flags and overrides config values
-func parseFlags(config *Config) {
-	flag.StringVar(&config.Server.Host, "host", config.Server.Host, "Server host")
-	flag.IntVar(&config.Server.Port, "port", config.Server.Port, "Server port")
-	flag.StringVar(&config.Server.Username, "username", config.Server.Username, "Admin username")
-	flag.StringVar(&config.Server.Password, "password", config.Server.Password, "Admin password")
+// bindConfigFlags registers a flag for each overridable config value on a command's flag set,
+// defaulting to and setting the value in config
+func bindConfigFlags(fs *flag.FlagSet, config *Config) {
+	fs.StringVar(&config.Server.Host, "host", config.Server.Host, "Server host")
+	fs.IntVar(&config.Server.Port, "port", config.Server.Port, "Server port")
+	fs.StringVar(&config.Server.Username, "username", config.Server.Username, "Admin username")
+	fs.StringVar(&config.Server.Password, "password", config.Server.Password, "Admin password")
+	fs.IntVar(&config.Server.ScimTimeout, "scimTimeout", config.Server.ScimTimeout, "Timeout in seconds for SCIM requests")
+	fs.IntVar(&config.Server.SoapTimeout, "soapTimeout", config.Server.SoapTimeout, "Timeout in seconds for SOAP admin service requests")
+	fs.IntVar(&config.Server.RequestDeadline, "requestDeadline", config.Server.RequestDeadline, "Milliseconds after which a request in flight is aborted (0 for the timeouts only)")
+	fs.StringVar(&config.Server.ScimBasePath, "scimBasePath", config.Server.ScimBasePath, "Base path of the SCIM user endpoints, e.g. /scim2 or /t/{tenant}/scim2")
+	fs.StringVar(&config.Server.Scim2BasePath, "scim2BasePath", config.Server.Scim2BasePath, "Base path of the SCIM2 group, bulk, search and /Me endpoints, e.g. /t/{tenant}/scim2")
+	fs.BoolVar(&config.Server.SoapKeepAlive, "soapKeepAlive", config.Server.SoapKeepAlive, "Reuse connections for SOAP requests (false sends Connection: close)")
+	fs.BoolVar(&config.Server.SoapChunked, "soapChunked", config.Server.SoapChunked, "Send SOAP request bodies with chunked transfer encoding instead of Content-Length")
+	fs.IntVar(&config.Server.MaxIdleConns, "maxIdleConns", config.Server.MaxIdleConns, "Idle connections kept by the shared connection pool (0 for no limit)")
+	fs.IntVar(&config.Server.MaxIdleConnsPerHost, "maxIdleConnsPerHost", config.Server.MaxIdleConnsPerHost, "Idle connections kept per host (0 keeps one per thread)")
+	fs.IntVar(&config.Server.MaxConnsPerHost, "maxConnsPerHost", config.Server.MaxConnsPerHost, "Connections per host, idle or in use (0 for no limit)")
+	fs.IntVar(&config.Server.IdleConnTimeout, "idleConnTimeout", config.Server.IdleConnTimeout, "Seconds an idle connection is kept before it is closed (0 to keep it)")
+	fs.BoolVar(&config.Server.TLSVerify, "tlsVerify", config.Server.TLSVerify, "Verify the server certificate (off by default for self-signed test servers)")
+	fs.StringVar(&config.Server.TLSCACertFile, "tlsCaCertFile", config.Server.TLSCACertFile, "PEM bundle of CA certificates trusted in addition to the system roots when verifying")
+	fs.StringVar(&config.Server.TLSMinVersion, "tlsMinVersion", config.Server.TLSMinVersion, "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3 (empty for the Go default)")
+	fs.Var(stringListFlag{&config.Server.TLSCipherSuites}, "tlsCipherSuites", "Comma-separated IANA names of the TLS 1.2 cipher suites offered, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256")
+	fs.StringVar(&config.Server.TLSClientCertFile, "tlsClientCertFile", config.Server.TLSClientCertFile, "PEM client certificate presented for mutual TLS (empty to present none)")
+	fs.StringVar(&config.Server.TLSClientKeyFile, "tlsClientKeyFile", config.Server.TLSClientKeyFile, "PEM private key of the client certificate (empty when the certificate file holds the key)")
+	fs.BoolVar(&config.Server.PreemptiveAuth, "preemptiveAuth", config.Server.PreemptiveAuth, "Send basic auth credentials up front (false waits for a 401 challenge)")
+	fs.StringVar(&config.Server.BearerTokenFile, "bearerTokenFile", config.Server.BearerTokenFile, "File holding a pre-issued access token to send as a bearer token instead of basic auth (- for stdin)")
+	fs.StringVar(&config.Server.BearerTokenEnv, "bearerTokenEnv", config.Server.BearerTokenEnv, "Environment variable holding a pre-issued access token to send as a bearer token")
+	fs.IntVar(&config.Server.BearerTokenReload, "bearerTokenReload", config.Server.BearerTokenReload, "Seconds between re-reads of the bearer token (0 to read once)")
+	fs.StringVar(&config.Server.AuthMode, "authMode", config.Server.AuthMode, "SCIM authentication: basic, client_credentials or password (bearer token from the token endpoint)")
+	fs.StringVar(&config.Server.AuthScope, "authScope", config.Server.AuthScope, "Scopes requested for SCIM access tokens")
+	fs.BoolVar(&config.Server.SoapSessionAuth, "soapSessionAuth", config.Server.SoapSessionAuth, "Authenticate SOAP calls with a reused admin session cookie instead of basic auth")
+	
+	fs.StringVar(&config.Test.UsernamePrefix, "usernamePrefix", config.Test.UsernamePrefix, "Username prefix for test users")
+	fs.StringVar(&config.Test.UserPassword, "userPassword", config.Test.UserPassword, "Password for test users")
+	fs.StringVar(&config.Test.PasswordMode, "passwordMode", config.Test.PasswordMode, "How user passwords are sent: plaintext, prehashed or compare (half of the users each way)")
+	fs.StringVar(&config.Test.PasswordHash, "passwordHash", config.Test.PasswordHash, "Digest of pre-hashed passwords: SHA-1, SHA-256 or SHA-512")
+	fs.StringVar(&config.Test.PasswordPolicyRegex, "passwordPolicyRegex", config.Test.PasswordPolicyRegex, "Password policy of the server that pre-hashed password digests must match (empty to skip the check)")
+	fs.StringVar(&config.Test.RoleName, "userRole", config.Test.RoleName, "Role name for test users")
+	fs.StringVar(&config.Test.TenantPrefix, "tenantPrefix", config.Test.TenantPrefix, "Tenant prefix")
+	fs.BoolVar(&config.Test.SkipRoleCreation, "skipRoleCreation", config.Test.SkipRoleCreation, "Skip the role phase and give users roles that already exist, managed outside the test")
+	fs.Var(stringListFlag{&config.Test.RolePermissions}, "rolePermissions", "Comma-separated permission resource IDs of the test role, e.g. /permission/admin/login,/permission/admin/manage/identity/usermgt")
+	fs.BoolVar(&config.Test.ExistingRoleOK, "existingRoleOk", config.Test.ExistingRoleOK, "Count a role that already exists as created (false reports it as a failed role)")
+	
+	fs.IntVar(&config.Execution.NoOfThreads, "concurrency", config.Execution.NoOfThreads, "Number of concurrent threads")
+	fs.IntVar(&config.Execution.NoOfUsers, "userCount", config.Execution.NoOfUsers, "Total number of users to create")
+	fs.IntVar(&config.Execution.LoopCount, "loopCount", config.Execution.LoopCount, "Loop count")
+	fs.IntVar(&config.Execution.RampUpPeriod, "rampUpPeriod", config.Execution.RampUpPeriod, "Ramp up period in seconds")
+	fs.StringVar(&config.Execution.ScimIdCsvPath, "scimIdCsvPath", config.Execution.ScimIdCsvPath, "Path to SCIM ID CSV file")
+	fs.BoolVar(&config.Execution.WriteScimIds, "writeScimIds", config.Execution.WriteScimIds, "Record the SCIM IDs of created users in the SCIM ID CSV file")
+	fs.StringVar(&config.Execution.FailedUsersCsvPath, "failedUsersCsvPath", config.Execution.FailedUsersCsvPath, "Path to failed users CSV file")
+	fs.StringVar(&config.Execution.FailedRolesCsvPath, "failedRolesCsvPath", config.Execution.FailedRolesCsvPath, "Path to the CSV file recording failed role creations (empty to disable)")
+	fs.StringVar(&config.Execution.FallbackDir, "fallbackDir", config.Execution.FallbackDir, "Directory that receives the rest of a CSV file whose disk fills up (empty to stop writing it)")
+	fs.BoolVar(&config.Execution.OutputManifests, "outputManifests", config.Execution.OutputManifests, "Write the row count and checksum of every CSV output file to a .manifest.json file next to it")
+	fs.StringVar(&config.Execution.ProgressFile, "progressFile", config.Execution.ProgressFile, "Path to the JSON progress file for external orchestrators (empty to disable)")
+	fs.IntVar(&config.Execution.ProgressInterval, "progressInterval", config.Execution.ProgressInterval, "Seconds between progress file updates")
+	fs.IntVar(&config.Execution.ProgressPrintInterval, "progressPrintInterval", config.Execution.ProgressPrintInterval, "Seconds between progress lines with throughput and ETA printed to the console (0 to disable)")
+	fs.Float64Var(&config.Execution.TargetTPS, "targetTps", config.Execution.TargetTPS, "Target user creations per second across all threads (0 for as fast as possible)")
+	fs.StringVar(&config.Execution.RampProfile, "rampProfile", config.Execution.RampProfile, "Create users in steps of threads:duration, e.g. 10:2m,20:2m,50:5m, instead of the linear ramp-up (empty to disable)")
+	fs.Float64Var(&config.Execution.ArrivalRate, "arrivalRate", config.Execution.ArrivalRate, "Create users as an open workload with Poisson arrivals at this mean rate per second instead of a fixed thread count (0 to disable)")
+	fs.IntVar(&config.Execution.ArrivalConcurrency, "arrivalConcurrency", config.Execution.ArrivalConcurrency, "Most requests in flight with arrivalRate; arrivals beyond it queue and their wait counts in the response time")
+	fs.IntVar(&config.Execution.ArrivalQueue, "arrivalQueue", config.Execution.ArrivalQueue, "Most arrivals waiting for one of the arrivalConcurrency requests; arrivals beyond it are dropped and recorded as failed")
+	fs.IntVar(&config.Execution.TPSBurst, "tpsBurst", config.Execution.TPSBurst, "Requests allowed in a burst above the target rate")
+	fs.BoolVar(&config.Execution.PipelineTenants, "pipelineTenants", config.Execution.PipelineTenants, "Start creating the users of a tenant as soon as its role exists instead of after all roles")
+	fs.BoolVar(&config.Execution.ProbeTenants, "probeTenants", config.Execution.ProbeTenants, "Check that every tenant is active before creating users in it")
+	fs.IntVar(&config.Execution.ProbeAttempts, "probeAttempts", config.Execution.ProbeAttempts, "Tenant readiness probe attempts before the tenant's users fail")
+	fs.IntVar(&config.Execution.ProbeInterval, "probeInterval", config.Execution.ProbeInterval, "Seconds between tenant readiness probe attempts")
+	fs.IntVar(&config.Execution.RoleDelay, "roleDelay", config.Execution.RoleDelay, "Milliseconds to pause after creating a test role (the JMX plan paused 5000)")
+	fs.BoolVar(&config.Execution.WaitForRole, "waitForRole", config.Execution.WaitForRole, "Poll after creating a test role until it is visible instead of pausing")
+	fs.IntVar(&config.Execution.RoleWaitTimeout, "roleWaitTimeout", config.Execution.RoleWaitTimeout, "Seconds to poll for a created test role before going ahead without it")
+	fs.Float64Var(&config.Execution.AbortErrorPercent, "abortErrorPercent", config.Execution.AbortErrorPercent, "Abort the run when more than this percentage of the requests in an interval fail (0 to never abort)")
+	fs.IntVar(&config.Execution.AbortMinRequests, "abortMinRequests", config.Execution.AbortMinRequests, "Requests an interval needs before its error rate can abort the run")
+	fs.IntVar(&config.Execution.AbortInterval, "abortInterval", config.Execution.AbortInterval, "Seconds over which the error rate is evaluated")
+	fs.StringVar(&config.Execution.CheckpointFile, "checkpointFile", config.Execution.CheckpointFile, "Path to the checkpoint file written when a run is interrupted")
+	fs.StringVar(&config.Execution.SummaryFile, "summaryFile", config.Execution.SummaryFile, "Path to write the JSON run summary to at the end of the default run (empty to disable)")
+	fs.StringVar(&config.Execution.ReportFile, "reportFile", config.Execution.ReportFile, "Path to write the HTML report with throughput, latency and error charts to at the end of each run (empty to disable)")
+	fs.StringVar(&config.Execution.AccessLogFile, "accessLogFile", config.Execution.AccessLogFile, "Path to append every request to in the Apache combined log format (empty to disable)")
+	fs.BoolVar(&config.Execution.AccessLogLatency, "accessLogLatency", config.Execution.AccessLogLatency, "Append the request duration in microseconds to each access log line")
+	fs.IntVar(&config.Execution.TopErrors, "topErrors", config.Execution.TopErrors, "Most frequent error patterns listed in the summary (0 for all)")
+	fs.StringVar(&config.Execution.HeatmapFile, "heatmapFile", config.Execution.HeatmapFile, "Path to export the latency heatmap CSV to (empty to disable)")
+	fs.IntVar(&config.Execution.HeatmapInterval, "heatmapInterval", config.Execution.HeatmapInterval, "Seconds per time bucket of the latency heatmap")
+	fs.Var(intListFlag{&config.Execution.HeatmapBuckets}, "heatmapBuckets", "Comma-separated latency bucket upper bounds of the heatmap in milliseconds")
+	fs.BoolVar(&config.Execution.ParallelTenants, "parallelTenants", config.Execution.ParallelTenants, "Give every tenant its own thread pool during user creation instead of iterating tenants in each thread")
+	fs.IntVar(&config.Execution.NoOfTenants, "noOfTenants", config.Execution.NoOfTenants, "Number of tenants")
+	fs.IntVar(&config.Execution.UserStartNumber, "userStartNumber", config.Execution.UserStartNumber, "Starting user number")
+	fs.IntVar(&config.Execution.TenantStartNumber, "tenantStartNumber", config.Execution.TenantStartNumber, "Starting tenant number")
+	
+	fs.IntVar(&config.Retry.MaxAttempts, "retryAttempts", config.Retry.MaxAttempts, "Attempts per user creation request before it fails (1 to disable retries)")
+	fs.IntVar(&config.Retry.RoleAttempts, "roleAttempts", config.Retry.RoleAttempts, "Attempts to create a role, retrying any failure unless the role turns out to exist (1 to disable retries)")
+	fs.IntVar(&config.Retry.InitialBackoff, "retryBackoff", config.Retry.InitialBackoff, "Milliseconds before the first retry of a request, doubled for each further retry")
+	fs.IntVar(&config.Retry.MaxBackoff, "retryMaxBackoff", config.Retry.MaxBackoff, "Maximum milliseconds between retries of a request")
+	fs.Var(intListFlag{&config.Retry.StatusCodes}, "retryStatusCodes", "Comma-separated response status codes that are retried")
+	
+	fs.IntVar(&config.Cleanup.GroupThreads, "cleanupGroupThreads", config.Cleanup.GroupThreads, "Number of concurrent threads deleting groups during cleanup")
+	fs.IntVar(&config.Cleanup.UserThreads, "cleanupUserThreads", config.Cleanup.UserThreads, "Number of concurrent threads deleting users during cleanup")
+	fs.IntVar(&config.Cleanup.RoleThreads, "cleanupRoleThreads", config.Cleanup.RoleThreads, "Number of concurrent threads deleting roles during cleanup")
+	fs.IntVar(&config.Cleanup.IdPThreads, "cleanupIdPThreads", config.Cleanup.IdPThreads, "Number of concurrent threads deleting identity providers during cleanup")
+	fs.IntVar(&config.Cleanup.APIResourceThreads, "cleanupAPIResourceThreads", config.Cleanup.APIResourceThreads, "Number of concurrent threads deleting API resources during cleanup")
+	fs.BoolVar(&config.Cleanup.Verify, "cleanupVerify", config.Cleanup.Verify, "Verify that every resource is gone after cleanup")
+	fs.StringVar(&config.Cleanup.Source, "cleanupSource", config.Cleanup.Source, "Users to delete during cleanup: names, csv or prefix")
+	
+	fs.IntVar(&config.Read.Passes, "readPasses", config.Read.Passes, "Number of times each user is read in the read phase")
+	fs.BoolVar(&config.Read.ConditionalRequests, "readConditional", config.Read.ConditionalRequests, "Send If-None-Match with the ETag of the previous read")
+	fs.BoolVar(&config.Read.CacheBusting, "readCacheBusting", config.Read.CacheBusting, "Append a unique query parameter to every read")
+	
+	fs.BoolVar(&config.Update.Conditional, "updateConditional", config.Update.Conditional, "Send If-Match with the current ETag on every update")
+	fs.Float64Var(&config.Update.StaleFraction, "updateStaleFraction", config.Update.StaleFraction, "Fraction of users updated again with a stale ETag")
+	
+	fs.IntVar(&config.Patch.Threads, "patchThreads", config.Patch.Threads, "Concurrent workers of the patch phase (0 uses noOfThreads)")
+	fs.IntVar(&config.Patch.Passes, "patchPasses", config.Patch.Passes, "Times every user in the SCIM ID CSV is patched")
+	
+	fs.IntVar(&config.Race.Contenders, "raceContenders", config.Race.Contenders, "Number of workers creating the same username simultaneously")
+	fs.IntVar(&config.Race.Rounds, "raceRounds", config.Race.Rounds, "Number of duplicate-create race rounds")
+	
+	fs.IntVar(&config.Churn.Duration, "churnDuration", config.Churn.Duration, "Duration of the churn workload in seconds")
+	fs.Float64Var(&config.Churn.CyclesPerSecond, "churnRate", config.Churn.CyclesPerSecond, "Create-then-delete cycles per second per thread (0 for unthrottled)")
+	
+	fs.IntVar(&config.Growth.TotalUsers, "growthUsers", config.Growth.TotalUsers, "Total users created by the growth benchmark")
+	fs.IntVar(&config.Growth.CheckpointInterval, "growthCheckpoint", config.Growth.CheckpointInterval, "Users per latency checkpoint in the growth benchmark")
+	
+	fs.IntVar(&config.Groups.PerTenant, "groupsPerTenant", config.Groups.PerTenant, "SCIM2 groups created per tenant (0 to skip the group phase)")
+	fs.StringVar(&config.Groups.CsvPath, "groupIdCsvPath", config.Groups.CsvPath, "Path to group ID CSV file")
+	
+	fs.IntVar(&config.IdPs.PerTenant, "idpsPerTenant", config.IdPs.PerTenant, "Identity providers created per tenant (0 to skip the IdP phase)")
+	fs.StringVar(&config.IdPs.CsvPath, "idpIdCsvPath", config.IdPs.CsvPath, "Path to IdP ID CSV file")
+	
+	fs.IntVar(&config.APIResources.PerTenant, "apiResourcesPerTenant", config.APIResources.PerTenant, "API resources registered per tenant by the api-resources command")
+	fs.IntVar(&config.APIResources.ScopesPerResource, "scopesPerApiResource", config.APIResources.ScopesPerResource, "Scopes of every registered API resource")
+	fs.IntVar(&config.APIResources.TokenScopes, "apiTokenScopes", config.APIResources.TokenScopes, "Registered scopes requested per token after registration (0 to only register)")
+	fs.StringVar(&config.APIResources.CsvPath, "apiResourceIdCsvPath", config.APIResources.CsvPath, "Path to API resource ID CSV file")
 	
-	flag.StringVar(&config.Test.UsernamePrefix, "usernamePrefix", config.Test.UsernamePrefix, "Username prefix for test users")
-	flag.StringVar(&config.Test.UserPassword, "userPassword", config.Test.UserPassword, "Password for test users")
-	flag.StringVar(&config.Test.RoleName, "userRole", config.Test.RoleName, "Role name for test users")
-	flag.StringVar(&config.Test.TenantPrefix, "tenantPrefix", config.Test.TenantPrefix, "Tenant prefix")
+	fs.StringVar(&config.StubIdP.Address, "stubIdpAddress", config.StubIdP.Address, "Address the stub-idp command listens on")
+	fs.StringVar(&config.StubIdP.URL, "stubIdpUrl", config.StubIdP.URL, "Base URL of the stub IdP, which created IdPs federate to when set")
+	fs.IntVar(&config.StubIdP.Users, "stubIdpUsers", config.StubIdP.Users, "Federated users the stub IdP logs in as")
 	
-	flag.IntVar(&config.Execution.NoOfThreads, "concurrency", config.Execution.NoOfThreads, "Number of concurrent threads")
-	flag.IntVar(&config.Execution.NoOfUsers, "userCount", config.Execution.NoOfUsers, "Total number of users to create")
-	flag.IntVar(&config.Execution.LoopCount, "loopCount", config.Execution.LoopCount, "Loop count")
-	flag.IntVar(&config.Execution.RampUpPeriod, "rampUpPeriod", config.Execution.RampUpPeriod, "Ramp up period in seconds")
-	flag.StringVar(&config.Execution.ScimIdCsvPath, "scimIdCsvPath", config.Execution.ScimIdCsvPath, "Path to SCIM ID CSV file")
-	flag.IntVar(&config.Execution.NoOfTenants, "noOfTenants", config.Execution.NoOfTenants, "Number of tenants")
-	flag.IntVar(&config.Execution.UserStartNumber, "userStartNumber", config.Execution.UserStartNumber, "Starting user number")
-	flag.IntVar(&config.Execution.TenantStartNumber, "tenantStartNumber", config.Execution.TenantStartNumber, "Starting tenant number")
+	fs.Float64Var(&config.Login.Percent, "loginPercent", config.Login.Percent, "Percentage of the created users logged in after provisioning (0 to skip the login phase)")
+	fs.StringVar(&config.Login.Method, "loginMethod", config.Login.Method, "Login method of the login phase: password or me")
 	
-	flag.Parse()
+	fs.IntVar(&config.Bulk.BatchSize, "bulkBatchSize", config.Bulk.BatchSize, "Users per SCIM2 Bulk request")
+	fs.IntVar(&config.Bulk.FailOnErrors, "bulkFailOnErrors", config.Bulk.FailOnErrors, "failOnErrors value of SCIM2 Bulk requests (0 to process every operation)")
+	fs.IntVar(&config.Bulk.Retries, "bulkRetries", config.Bulk.Retries, "Times failed bulk operations are sent again in a later batch")
+	
+	fs.IntVar(&config.GroupScale.Members, "groupScaleMembers", config.GroupScale.Members, "Members added to the giant group")
+	fs.IntVar(&config.GroupScale.BatchSize, "groupScaleBatch", config.GroupScale.BatchSize, "Members added per PATCH request")
+	
+	fs.IntVar(&config.RoleScale.RolesPerUser, "rolesPerUser", config.RoleScale.RolesPerUser, "Roles assigned to each user in the role scale test")
+	fs.IntVar(&config.RoleScale.Users, "roleScaleUsers", config.RoleScale.Users, "Users created per tenant in the role scale test")
+	
+	fs.Var(intListFlag{&config.AttributeSweep.AttributeCounts}, "attributeCounts", "Comma-separated custom attribute counts swept by the attribute sweep")
+	fs.IntVar(&config.AttributeSweep.UsersPerStep, "attributeSweepUsers", config.AttributeSweep.UsersPerStep, "Users created for each attribute count in the attribute sweep")
+	
+	fs.Float64Var(&config.Variants.AttributesPercent, "variantAttributesPercent", config.Variants.AttributesPercent, "Percentage of created users that get extra custom attributes")
+	fs.IntVar(&config.Variants.Attributes, "variantAttributes", config.Variants.Attributes, "Extra custom attributes of the users selected by variantAttributesPercent")
+	fs.Float64Var(&config.Variants.RolesPercent, "variantRolesPercent", config.Variants.RolesPercent, "Percentage of created users that also get the variant roles")
+	fs.Var(stringListFlag{&config.Variants.Roles}, "variantRoles", "Comma-separated variant roles, created in every tenant along with the test role")
+	fs.Float64Var(&config.Variants.LockedPercent, "variantLockedPercent", config.Variants.LockedPercent, "Percentage of created users whose account is created locked")
+	fs.Int64Var(&config.Variants.Seed, "variantSeed", config.Variants.Seed, "Seed that changes which users get payload variants")
+	fs.Var(weightsFlag{&config.Locale.Locales}, "locales", "Comma-separated weighted locales of created users, e.g. en_US=70,fr_FR=30")
+	fs.Var(weightsFlag{&config.Locale.Timezones}, "timezones", "Comma-separated weighted timezones of created users, e.g. America/New_York=60,Europe/Paris=40")
+	fs.Var(weightsFlag{&config.Locale.PreferredLanguages}, "preferredLanguages", "Comma-separated weighted preferred languages of created users, e.g. en=70,fr=30")
+	
+	fs.StringVar(&config.OAuth.ClientID, "clientId", config.OAuth.ClientID, "OAuth2 client ID")
+	fs.StringVar(&config.OAuth.ClientSecret, "clientSecret", config.OAuth.ClientSecret, "OAuth2 client secret")
+	fs.StringVar(&config.OAuth.ClientAuthMethod, "clientAuthMethod", config.OAuth.ClientAuthMethod, "Token endpoint client authentication (client_secret_basic, client_secret_post, private_key_jwt)")
+	fs.StringVar(&config.OAuth.PrivateKeyPath, "privateKeyPath", config.OAuth.PrivateKeyPath, "PEM RSA private key for private_key_jwt client assertions")
+	fs.StringVar(&config.OAuth.Grant, "grant", config.OAuth.Grant, "Grant used by the token phase (password, authorization_code, device_code)")
+	fs.BoolVar(&config.OAuth.PKCE, "pkce", config.OAuth.PKCE, "Use S256 PKCE in the authorization code flow")
+	fs.StringVar(&config.OAuth.FederatedIdP, "federatedIdp", config.OAuth.FederatedIdP, "Identity provider the authorization code flow logs in through (empty for the login form)")
+	fs.BoolVar(&config.OAuth.ValidateTokens, "validateTokens", config.OAuth.ValidateTokens, "Validate issued JWTs against the JWKS")
+	
+	fs.IntVar(&config.SessionSoak.Sessions, "soakSessions", config.SessionSoak.Sessions, "Sessions built up by the session soak")
+	fs.IntVar(&config.SessionSoak.CheckpointInterval, "soakCheckpoint", config.SessionSoak.CheckpointInterval, "Sessions per latency checkpoint in the session soak")
+	fs.IntVar(&config.SessionSoak.HoldDuration, "soakHold", config.SessionSoak.HoldDuration, "Seconds to hold the sessions idle in the session soak")
+	fs.IntVar(&config.SessionSoak.ProbeLogins, "soakProbes", config.SessionSoak.ProbeLogins, "Logins measured after the idle hold in the session soak")
+	
+	fs.Var(stringListFlag{&config.Alerts.Rules}, "alerts", "Comma-separated response time alert rules, e.g. p95>1s/60s,p99>3s/30s")
+	fs.StringVar(&config.Alerts.Webhook, "alertWebhook", config.Alerts.Webhook, "URL alerts are posted to as JSON when they fire or resolve")
+	fs.IntVar(&config.Alerts.Interval, "alertInterval", config.Alerts.Interval, "Seconds between alert rule evaluations")
+	fs.BoolVar(&config.Alerts.Abort, "alertAbort", config.Alerts.Abort, "Abort the run when an alert rule fires")
+	
+	fs.Var(stringListFlag{&config.Reporters.Enabled}, "reporters", "Comma-separated metric reporters that receive every request result, e.g. jsonl")
+	fs.IntVar(&config.Reporters.FlushInterval, "reporterFlushInterval", config.Reporters.FlushInterval, "Seconds between reporter flushes")
+	
+	fs.BoolVar(&config.Offline, "offline", config.Offline, "Refuse any network call other than to the target server, for isolated labs")
+	
+	fs.IntVar(&config.Mix.Duration, "mixDuration", config.Mix.Duration, "Duration of the scenario mix in seconds")
+	fs.Var(weightsFlag{&config.Mix.Weights}, "mixWeights", "Comma-separated scenario weights of the scenario mix, e.g. login=80,profile-update=15,password-change=5")
 }
 
 // SaveConfig saves the current configuration to a file
@@ -146,7 +1163,59 @@ func (c *Config) SaveConfig(configPath string) error {
 // GetTenantUsername returns the tenant-specific username
 func (c *Config) GetTenantUsername(tenantIndex int) string {
 	// Format: admin@wso2.com@aorg_11.com (base@tenantPrefix+tenantIndex+.com)
-	return fmt.Sprintf("%s@%s%d.com", c.Server.Username, c.Test.TenantPrefix, tenantIndex)
+	return c.GetTenantQualifiedUsername(c.Server.Username, tenantIndex)
+}
+
+// GetTenantQualifiedUsername returns the given username qualified with the tenant domain
+func (c *Config) GetTenantQualifiedUsername(username string, tenantIndex int) string {
+	return fmt.Sprintf("%s@%s", username, c.GetTenantDomain(tenantIndex))
+}
+
+// GetTenantDomain returns the domain of a tenant
+func (c *Config) GetTenantDomain(tenantIndex int) string {
+	return fmt.Sprintf("%s%d.com", c.Test.TenantPrefix, tenantIndex)
+}
+
+// GetTenantRoleName returns the role the users of a tenant get: the role configured for the tenant
+// by its number or domain, or the test role
+func (c *Config) GetTenantRoleName(tenantIndex int) string {
+	if role, ok := c.Test.TenantRoles[strconv.Itoa(tenantIndex)]; ok {
+		return role
+	}
+	if role, ok := c.Test.TenantRoles[c.GetTenantDomain(tenantIndex)]; ok {
+		return role
+	}
+	return c.Test.RoleName
+}
+
+// GetTenantPath replaces the {tenant} placeholder in an endpoint path with the tenant domain
+func (c *Config) GetTenantPath(path string, tenantIndex int) string {
+	return strings.ReplaceAll(path, "{tenant}", c.GetTenantDomain(tenantIndex))
+}
+
+// GetSCIMURL returns the URL of a resource under the SCIM base path of the tenant
+func (c *Config) GetSCIMURL(resource string, tenantIndex int) string {
+	return c.GetServerURL() + c.GetTenantPath(c.Server.ScimBasePath, tenantIndex) + resource
+}
+
+// GetSCIM2URL returns the URL of a resource under the SCIM2 base path of the tenant
+func (c *Config) GetSCIM2URL(resource string, tenantIndex int) string {
+	return c.GetServerURL() + c.GetTenantPath(c.Server.Scim2BasePath, tenantIndex) + resource
+}
+
+// GetTestGroupName returns the test group display name
+func (c *Config) GetTestGroupName(groupIndex int) string {
+	return fmt.Sprintf("%s%d", c.Groups.NamePrefix, groupIndex)
+}
+
+// GetTestAPIResourceName returns the name of the API resource with the given index
+func (c *Config) GetTestAPIResourceName(resourceIndex int) string {
+	return fmt.Sprintf("%s%d", c.APIResources.NamePrefix, resourceIndex)
+}
+
+// GetTestIdPName returns the test identity provider name
+func (c *Config) GetTestIdPName(idpIndex int) string {
+	return fmt.Sprintf("%s%d", c.IdPs.NamePrefix, idpIndex)
 }
 
 // GetTestUsername returns the test user username
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// envPrefix prefixes the environment variables that override configuration fields and command
+// line options
+const envPrefix = "ISPERF_"
+
+// applyConfigEnv overrides configuration fields with environment variables named after the JSON
+// path of the field in upper snake case with the ISPERF_ prefix, e.g. ISPERF_SERVER_PASSWORD for
+// server.password and ISPERF_EXECUTION_NO_OF_THREADS for execution.noOfThreads, so secrets can be
+// kept out of configuration files. Flags given on the command line override them.
+func applyConfigEnv(config *Config) error {
+	return applyConfigEnvFields(reflect.ValueOf(config).Elem(), envPrefix)
+}
+
+// applyConfigEnvFields overrides the fields of a configuration section, descending into subsections
+func applyConfigEnvFields(section reflect.Value, prefix string) error {
+	for i := 0; i < section.NumField(); i++ {
+		name, _, _ := strings.Cut(section.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		field := section.Field(i)
+		key := prefix + upperSnake(name)
+		if field.Kind() == reflect.Struct {
+			if err := applyConfigEnvFields(field, key+"_"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if err := setConfigField(field, value); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// setConfigField parses an environment value into a field: strings as is, lists and weights in the
+// syntax of their flags unless given as JSON, and anything else as JSON
+func setConfigField(field reflect.Value, value string) error {
+	trimmed := strings.TrimSpace(value)
+
+	switch p := field.Addr().Interface().(type) {
+	case *string:
+		*p = value
+		return nil
+	case *[]string:
+		if !strings.HasPrefix(trimmed, "[") {
+			return stringListFlag{p}.Set(value)
+		}
+	case *[]int:
+		if !strings.HasPrefix(trimmed, "[") {
+			return intListFlag{p}.Set(value)
+		}
+	case *map[string]int:
+		if !strings.HasPrefix(trimmed, "{") {
+			return weightsFlag{p}.Set(value)
+		}
+	}
+
+	// Decode into a fresh value so a partial decode never leaves the field half overwritten
+	decoded := reflect.New(field.Type())
+	if err := json.Unmarshal([]byte(trimmed), decoded.Interface()); err != nil {
+		return err
+	}
+	field.Set(decoded.Elem())
+	return nil
+}
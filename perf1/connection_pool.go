@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// connectionPool holds the transports shared by all HTTP clients of an executor, so workers reuse
+// each other's idle connections instead of each holding a pool of its own. With a transport per
+// worker, hundreds of threads open far more connections than they keep busy, run out of ephemeral
+// ports, and pay for a TLS handshake on most requests.
+type connectionPool struct {
+	scim *http.Transport
+	soap *http.Transport
+}
+
+// newConnectionPool creates the SCIM and SOAP transports with the configured pool limits and TLS
+// settings
+func newConnectionPool(config *Config) (*connectionPool, error) {
+	tlsConfig, err := newTLSConfig(config.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	idlePerHost := config.Server.MaxIdleConnsPerHost
+	if idlePerHost <= 0 {
+		// Enough for every worker to park its connection between requests
+		idlePerHost = config.Execution.NoOfThreads
+	}
+
+	newTransport := func() *http.Transport {
+		return &http.Transport{
+			TLSClientConfig:     tlsConfig.Clone(),
+			MaxIdleConns:        config.Server.MaxIdleConns,
+			MaxIdleConnsPerHost: idlePerHost,
+			MaxConnsPerHost:     config.Server.MaxConnsPerHost,
+			IdleConnTimeout:     time.Duration(config.Server.IdleConnTimeout) * time.Second,
+		}
+	}
+
+	// SOAP calls get their own transport so their connection handling can be tuned independently of SCIM
+	soap := newTransport()
+	soap.DisableKeepAlives = !config.Server.SoapKeepAlive
+
+	return &connectionPool{scim: newTransport(), soap: soap}, nil
+}
+
+// Close closes the idle connections of the pool
+func (p *connectionPool) Close() {
+	p.scim.CloseIdleConnections()
+	p.soap.CloseIdleConnections()
+}
@@ -2,17 +2,51 @@ package main
 
 import (
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
-// CSVWriter handles writing SCIM IDs to CSV file
+// scimIDBufferSize is the number of SCIM IDs that can be queued before WriteScimID blocks
+const scimIDBufferSize = 10000
+
+// csvOutputOptions holds the settings shared by the CSV writers
+type csvOutputOptions struct {
+	// fallbackDir receives the rest of the file when its disk fills up; without one the writer
+	// pauses and counts the dropped rows
+	fallbackDir string
+	
+	// manifest writes the row count and checksum of the file next to it when the writer is closed
+	manifest bool
+}
+
+// scimIDHeader is the header row of the SCIM ID CSV
+var scimIDHeader = []string{"scim_id", "tenant_index"}
+
+// CSVWriter handles writing SCIM IDs to CSV file. IDs are queued and written by a background
+// goroutine, so recording them does not hold up the result pipeline.
 type CSVWriter struct {
 	filename string
 	file     *os.File
 	writer   *csv.Writer
 	mutex    sync.Mutex
+	ids      chan []string
+	done     chan struct{}
+	err      error
+	
+	// closed is set by the first Close; sending holds closing off so no ID is queued after it
+	closed  bool
+	sending sync.RWMutex
+	
+	csvOutputOptions
+	pending [][]string // records written since the last successful flush
+	paused      bool
+	dropped     int
 }
 
 // NewCSVWriter creates a new CSV writer for SCIM IDs
@@ -20,13 +54,13 @@ func NewCSVWriter(filename string) (*CSVWriter, error) {
 	// Delete file if it exists
 	if _, err := os.Stat(filename); err == nil {
 		if err := os.Remove(filename); err != nil {
-			return nil, fmt.Errorf("failed to remove existing CSV file: %v", err)
+			return nil, fmt.Errorf("failed to remove existing CSV file: %v", fileError(err))
 		}
 	}
 	
 	file, err := os.Create(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create CSV file: %v", err)
+		return nil, fmt.Errorf("failed to create CSV file: %v", fileError(err))
 	}
 	
 	writer := csv.NewWriter(file)
@@ -42,51 +76,196 @@ func NewCSVWriter(filename string) (*CSVWriter, error) {
 		return nil, err
 	}
 	
+	csvWriter.start()
+	
 	return csvWriter, nil
 }
 
+// NewCSVWriterAppend creates a CSV writer for SCIM IDs that appends to an existing file, writing
+// the header only if the file is new or empty
+func NewCSVWriterAppend(filename string) (*CSVWriter, error) {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open/create CSV file: %v", fileError(err))
+	}
+	
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to get file stats: %v", err)
+	}
+	
+	csvWriter := &CSVWriter{
+		filename: filename,
+		file:     file,
+		writer:   csv.NewWriter(file),
+	}
+	
+	if stat.Size() == 0 {
+		if err := csvWriter.writeHeader(); err != nil {
+			file.Close()
+			return nil, err
+		}
+	} else if err := terminateLastLine(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	
+	csvWriter.start()
+	
+	return csvWriter, nil
+}
+
+// start starts the background goroutine that writes queued SCIM IDs
+func (c *CSVWriter) start() {
+	c.ids = make(chan []string, scimIDBufferSize)
+	c.done = make(chan struct{})
+	go c.run()
+}
+
 // writeHeader writes the CSV header
 func (c *CSVWriter) writeHeader() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	
-	return c.writer.Write([]string{"scim_id"})
+	return c.writer.Write(scimIDHeader)
 }
 
-// WriteScimID writes a SCIM ID to the CSV file
-func (c *CSVWriter) WriteScimID(scimID string) error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// WriteScimID queues a SCIM ID and the tenant it belongs to to be written to the CSV file, blocking
+// only when the queue is full. Write errors are reported by Close.
+func (c *CSVWriter) WriteScimID(tenantIndex int, scimID string) error {
+	c.sending.RLock()
+	defer c.sending.RUnlock()
+	
+	if c.closed {
+		return fmt.Errorf("SCIM ID CSV writer is closed")
+	}
+	c.ids <- []string{scimID, strconv.Itoa(tenantIndex)}
+	return nil
+}
+
+// run writes queued SCIM IDs, flushing whenever the queue runs empty so the file stays current
+func (c *CSVWriter) run() {
+	defer close(c.done)
 	
-	if err := c.writer.Write([]string{scimID}); err != nil {
-		return fmt.Errorf("failed to write SCIM ID to CSV: %v", err)
+	for record := range c.ids {
+		c.mutex.Lock()
+		c.write(record)
+		
+		if len(c.ids) == 0 || len(c.pending) >= csvPendingLimit {
+			c.flush()
+		}
+		c.mutex.Unlock()
+	}
+}
+
+// write writes a record, or counts it as dropped while the writer is paused
+func (c *CSVWriter) write(record []string) {
+	if c.paused {
+		c.dropped++
+		return
+	}
+	
+	c.pending = append(c.pending, record)
+	if err := c.writer.Write(record); err != nil {
+		c.fail(err)
+	}
+}
+
+// flush flushes the written records to the file
+func (c *CSVWriter) flush() {
+	if c.paused {
+		return
 	}
 	
-	// Flush to ensure data is written
 	c.writer.Flush()
-	return c.writer.Error()
+	if err := c.writer.Error(); err != nil {
+		c.fail(err)
+		return
+	}
+	c.pending = c.pending[:0]
 }
 
-// Close closes the CSV writer and file
+// fail handles a write error. When the disk is full the pending records move to the fallback
+// directory along with the rest of the file, or the writer pauses if they cannot.
+func (c *CSVWriter) fail(err error) {
+	if !isDiskFull(err) {
+		if c.err == nil {
+			c.err = fmt.Errorf("failed to write SCIM ID to CSV: %v", err)
+		}
+		c.pending = c.pending[:0]
+		return
+	}
+	
+	file, writer, fallbackErr := moveCSVToFallback(c.filename, c.fallbackDir, scimIDHeader, c.pending)
+	c.file.Close()
+	if fallbackErr != nil {
+		c.file = nil
+		c.paused = true
+		c.dropped += len(c.pending)
+		c.pending = nil
+		return
+	}
+	
+	c.file, c.writer, c.filename = file, writer, file.Name()
+	c.pending = c.pending[:0]
+}
+
+// Close writes the remaining queued SCIM IDs and closes the CSV writer and file. Closing it again
+// does nothing.
 func (c *CSVWriter) Close() error {
+	c.sending.Lock()
+	if c.closed {
+		c.sending.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.ids)
+	c.sending.Unlock()
+	<-c.done
+	
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	
-	c.writer.Flush()
-	if err := c.writer.Error(); err != nil {
-		c.file.Close()
-		return fmt.Errorf("CSV writer error: %v", err)
+	c.flush()
+	if c.dropped > 0 {
+		printWarning("%d IDs were not written to %s because the disk is full\n", c.dropped, c.filename)
+	}
+	
+	var err error
+	if c.file != nil {
+		err = c.file.Close()
 	}
 	
-	return c.file.Close()
+	if c.err != nil {
+		return c.err
+	}
+	
+	if err == nil && !c.paused {
+		c.writeManifest(c.filename)
+	}
+	return err
 }
 
+// failedUsersHeader is the header row of the failed users CSV. The status code, response snippet and
+// attempts columns come last, so files written before they were added are still read.
+var failedUsersHeader = []string{"TenantID", "Username", "Error", "Timestamp", "StatusCode", "ResponseSnippet", "Attempts"}
+
+// responseSnippetLength caps the response body kept in the failed users CSV
+const responseSnippetLength = 200
+
 // FailedUsersCSVWriter handles writing failed user creation attempts to CSV file
 type FailedUsersCSVWriter struct {
 	filename string
 	file     *os.File
 	writer   *csv.Writer
 	mutex    sync.Mutex
+	
+	csvOutputOptions
+	paused  bool
+	dropped int
+	written int  // rows written, including those moved to the fallback directory
+	closed  bool // set by the first Close
 }
 
 // NewFailedUsersCSVWriter creates a new CSV writer for failed users
@@ -94,19 +273,19 @@ func NewFailedUsersCSVWriter(filename string) (*FailedUsersCSVWriter, error) {
 	// Delete file if it exists
 	if _, err := os.Stat(filename); err == nil {
 		if err := os.Remove(filename); err != nil {
-			return nil, fmt.Errorf("failed to remove existing failed users CSV file: %v", err)
+			return nil, fmt.Errorf("failed to remove existing failed users CSV file: %v", fileError(err))
 		}
 	}
 	
 	file, err := os.Create(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create failed users CSV file: %v", err)
+		return nil, fmt.Errorf("failed to create failed users CSV file: %v", fileError(err))
 	}
 	
 	writer := csv.NewWriter(file)
 	
 	// Write header
-	if err := writer.Write([]string{"TenantID", "Username", "Error", "Timestamp"}); err != nil {
+	if err := writer.Write(failedUsersHeader); err != nil {
 		file.Close()
 		return nil, fmt.Errorf("failed to write CSV header: %v", err)
 	}
@@ -124,7 +303,7 @@ func NewFailedUsersCSVWriterAppend(filename string) (*FailedUsersCSVWriter, erro
 	// Open file in append mode, create if it doesn't exist
 	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open/create failed users CSV file: %v", err)
+		return nil, fmt.Errorf("failed to open/create failed users CSV file: %v", fileError(err))
 	}
 	
 	writer := csv.NewWriter(file)
@@ -138,11 +317,14 @@ func NewFailedUsersCSVWriterAppend(filename string) (*FailedUsersCSVWriter, erro
 	
 	if stat.Size() == 0 {
 		// File is empty, write header
-		if err := writer.Write([]string{"TenantID", "Username", "Error", "Timestamp"}); err != nil {
+		if err := writer.Write(failedUsersHeader); err != nil {
 			file.Close()
 			return nil, fmt.Errorf("failed to write CSV header: %v", err)
 		}
 		writer.Flush()
+	} else if err := terminateLastLine(file); err != nil {
+		file.Close()
+		return nil, err
 	}
 	
 	return &FailedUsersCSVWriter{
@@ -152,38 +334,142 @@ func NewFailedUsersCSVWriterAppend(filename string) (*FailedUsersCSVWriter, erro
 	}, nil
 }
 
+// newFailedUser describes a failed user creation attempt made now, with the HTTP status code and a
+// snippet of the response when the server answered
+func newFailedUser(tenantID int, username string, err error) FailedUser {
+	user := FailedUser{
+		TenantID:  tenantID,
+		Username:  username,
+		Error:     err.Error(),
+		Timestamp: time.Now().Format("2006-01-02 15:04:05"),
+		Attempts:  1,
+	}
+	
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		user.StatusCode = statusErr.StatusCode
+		user.ResponseSnippet = responseSnippet(statusErr.Body)
+	}
+	
+	return user
+}
+
+// responseSnippet condenses an error response for triage: the scimType and detail of a SCIM error,
+// or the start of the body on one line for any other response
+func responseSnippet(body string) string {
+	var scimErr struct {
+		ScimType string `json:"scimType"`
+		Detail   string `json:"detail"`
+	}
+	if json.Unmarshal([]byte(body), &scimErr) == nil && (scimErr.ScimType != "" || scimErr.Detail != "") {
+		if scimErr.ScimType == "" {
+			return scimErr.Detail
+		}
+		if scimErr.Detail == "" {
+			return scimErr.ScimType
+		}
+		return scimErr.ScimType + ": " + scimErr.Detail
+	}
+	
+	snippet := strings.Join(strings.Fields(body), " ")
+	if len(snippet) > responseSnippetLength {
+		snippet = strings.ToValidUTF8(snippet[:responseSnippetLength], "") + "..."
+	}
+	return snippet
+}
+
 // WriteFailedUser writes a failed user creation attempt to the CSV file
-func (fw *FailedUsersCSVWriter) WriteFailedUser(tenantID int, username, errorMsg, timestamp string) error {
+func (fw *FailedUsersCSVWriter) WriteFailedUser(user FailedUser) error {
 	fw.mutex.Lock()
 	defer fw.mutex.Unlock()
 	
+	statusCode := ""
+	if user.StatusCode != 0 {
+		statusCode = strconv.Itoa(user.StatusCode)
+	}
+	
 	record := []string{
-		fmt.Sprintf("%d", tenantID),
-		username,
-		errorMsg,
-		timestamp,
+		fmt.Sprintf("%d", user.TenantID),
+		user.Username,
+		user.Error,
+		user.Timestamp,
+		statusCode,
+		user.ResponseSnippet,
+		strconv.Itoa(max(user.Attempts, 1)),
 	}
 	
-	if err := fw.writer.Write(record); err != nil {
-		return fmt.Errorf("failed to write failed user record: %v", err)
+	if fw.paused {
+		fw.dropped++
+		return nil
 	}
 	
-	fw.writer.Flush()
-	return fw.writer.Error()
+	err := fw.writer.Write(record)
+	if err == nil {
+		fw.writer.Flush()
+		err = fw.writer.Error()
+	}
+	
+	if isDiskFull(err) {
+		// The alert is printed once here instead of an error by every caller for every row
+		file, writer, fallbackErr := moveCSVToFallback(fw.filename, fw.fallbackDir, failedUsersHeader, [][]string{record})
+		fw.file.Close()
+		if fallbackErr != nil {
+			fw.file = nil
+			fw.paused = true
+			fw.dropped++
+			return nil
+		}
+		fw.file, fw.writer, fw.filename = file, writer, file.Name()
+		fw.written++
+		return nil
+	}
+	
+	if err != nil {
+		return fmt.Errorf("failed to write failed user record: %v", err)
+	}
+	fw.written++
+	return nil
+}
+
+// closeComplete closes the writer and reports an error unless every row was written to its file,
+// for commands whose output is useless when truncated
+func (fw *FailedUsersCSVWriter) closeComplete() error {
+	filename := fw.filename
+	if err := fw.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %v", filename, fileError(err))
+	}
+	if fw.paused || fw.dropped > 0 || fw.filename != filename {
+		return fmt.Errorf("not every row was written to %s because the disk is full", filename)
+	}
+	return nil
 }
 
-// Close closes the failed users CSV writer
+// Close closes the failed users CSV writer. Closing it again does nothing.
 func (fw *FailedUsersCSVWriter) Close() error {
 	fw.mutex.Lock()
 	defer fw.mutex.Unlock()
 	
-	if fw.writer != nil {
+	if fw.closed {
+		return nil
+	}
+	fw.closed = true
+	
+	if fw.writer != nil && !fw.paused {
 		fw.writer.Flush()
 	}
 	
-	if fw.file != nil {
-		return fw.file.Close()
+	if fw.dropped > 0 {
+		printWarning("%d failed users were not written to %s because the disk is full\n", fw.dropped, fw.filename)
 	}
 	
-	return nil
+	if fw.file == nil {
+		return nil
+	}
+	
+	err := fw.file.Close()
+	fw.file = nil
+	if err == nil && !fw.paused {
+		fw.writeManifest(fw.filename)
+	}
+	return err
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Data set share modes, following JMeter's CSV Data Set Config
+const (
+	// DataShareAll makes all threads read the file through a single cursor, so each row is used once per pass
+	DataShareAll = "all"
+	// DataShareThread gives every thread its own cursor over the whole file
+	DataShareThread = "thread"
+)
+
+// csvDataSet is the rows of an external CSV file with its columns bound to scenario step variables
+type csvDataSet struct {
+	file    string
+	rows    []map[string]string
+	share   string
+	recycle bool
+
+	// shared is the cursor of all threads in DataShareAll mode
+	shared *dataCursor
+}
+
+// loadCSVDataSet reads a data set file and binds its columns to variables. Without explicit bindings
+// every column is bound to the variable of the same name.
+func loadCSVDataSet(cfg DataSetConfig) (*csvDataSet, error) {
+	if cfg.Share == "" {
+		cfg.Share = DataShareAll
+	}
+	if cfg.Share != DataShareAll && cfg.Share != DataShareThread {
+		return nil, fmt.Errorf("invalid share mode %q for data set %s, expected %q or %q", cfg.Share, cfg.File, DataShareAll, DataShareThread)
+	}
+
+	file, err := os.Open(cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data set file: %v", err)
+	}
+	defer file.Close()
+
+	records, err := newCSVReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data set file %s: %v", cfg.File, err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("data set file %s has no rows after the header", cfg.File)
+	}
+
+	columns := make(map[string]int)
+	for i, column := range records[0] {
+		columns[column] = i
+	}
+
+	variables := cfg.Variables
+	if len(variables) == 0 {
+		variables = make(map[string]string)
+		for column := range columns {
+			variables[column] = column
+		}
+	}
+
+	for variable, column := range variables {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("data set file %s has no column %q for variable %s", cfg.File, column, variable)
+		}
+	}
+
+	ds := &csvDataSet{
+		file:    cfg.File,
+		share:   cfg.Share,
+		recycle: !cfg.StopAtEnd,
+	}
+
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(variables))
+		for variable, column := range variables {
+			if i := columns[column]; i < len(record) {
+				row[variable] = record[i]
+			}
+		}
+		ds.rows = append(ds.rows, row)
+	}
+
+	ds.shared = &dataCursor{dataSet: ds}
+
+	return ds, nil
+}
+
+// cursor returns the cursor a thread reads the data set through
+func (ds *csvDataSet) cursor() *dataCursor {
+	if ds.share == DataShareAll {
+		return ds.shared
+	}
+	return &dataCursor{dataSet: ds}
+}
+
+// dataCursor is a position in a data set
+type dataCursor struct {
+	dataSet *csvDataSet
+	next    int
+	mutex   sync.Mutex
+}
+
+// nextRow returns the next row, starting over at the end of the file when the data set recycles.
+// It returns false once the file is used up.
+func (c *dataCursor) nextRow() (map[string]string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.next >= len(c.dataSet.rows) {
+		if !c.dataSet.recycle {
+			return nil, false
+		}
+		c.next = 0
+	}
+
+	row := c.dataSet.rows[c.next]
+	c.next++
+	return row, true
+}
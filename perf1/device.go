@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DeviceAuthorization represents the response from the device authorization endpoint
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// oauthError represents an OAuth2 error response body
+type oauthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// DeviceCodeLogin obtains a token for a tenant user with the device authorization grant: it requests
+// a device code, approves the user code in a fresh browser session as the user would on a second
+// device, and polls the token endpoint until the token is issued
+func (h *HTTPClient) DeviceCodeLogin(ctx context.Context, tenantIndex int, username, password string) (*TokenResponse, error) {
+	authorization, err := h.RequestDeviceAuthorization(ctx, tenantIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.ApproveUserCode(ctx, h.newBrowserSession(), tenantIndex, authorization.UserCode, username, password); err != nil {
+		return nil, err
+	}
+
+	return h.PollDeviceToken(ctx, tenantIndex, authorization)
+}
+
+// RequestDeviceAuthorization requests a device code and user code for the configured client
+func (h *HTTPClient) RequestDeviceAuthorization(ctx context.Context, tenantIndex int) (*DeviceAuthorization, error) {
+	form := url.Values{}
+	form.Set("client_id", h.config.OAuth.ClientID)
+	if h.config.OAuth.Scope != "" {
+		form.Set("scope", h.config.OAuth.Scope)
+	}
+
+	reqURL := h.config.GetServerURL() + h.config.GetTenantPath(h.config.OAuth.DeviceAuthorizePath, tenantIndex)
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create device authorization request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute device authorization request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Operation: "device authorization request", StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var authorization DeviceAuthorization
+	if err := json.Unmarshal(body, &authorization); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal device authorization response: %v", err)
+	}
+
+	return &authorization, nil
+}
+
+// ApproveUserCode enters the user code on the device page and logs the user in, approving consent
+// when asked, until the server reports that the device has been authorized
+func (h *HTTPClient) ApproveUserCode(ctx context.Context, session *browserSession, tenantIndex int, userCode, username, password string) error {
+	form := url.Values{}
+	form.Set("user_code", userCode)
+
+	status, location, err := session.postForm(ctx, h.absoluteURL(h.config.GetTenantPath(h.config.OAuth.DevicePath, tenantIndex)), form)
+
+	location, err = h.followLogin(ctx, session, tenantIndex, status, location, err, username, password, func(location string) bool {
+		return strings.Contains(location, "device_success") || strings.Contains(location, "device.do") ||
+			strings.HasPrefix(location, h.config.OAuth.RedirectURI)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The device page is shown again with an error when the user code is not accepted
+	if strings.Contains(location, "device.do") {
+		return fmt.Errorf("user code %s was not accepted: %s", userCode, location)
+	}
+
+	return nil
+}
+
+// PollDeviceToken polls the token endpoint at the advertised interval until the token is issued,
+// backing off when asked to slow down
+func (h *HTTPClient) PollDeviceToken(ctx context.Context, tenantIndex int, authorization *DeviceAuthorization) (*TokenResponse, error) {
+	interval := time.Duration(authorization.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(h.config.OAuth.DevicePollTimeout) * time.Second)
+
+	for {
+		form := url.Values{}
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
+		form.Set("device_code", authorization.DeviceCode)
+
+		tokenResp, err := h.requestToken(ctx, tenantIndex, form)
+		if err == nil {
+			return tokenResp, nil
+		}
+
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) {
+			return nil, err
+		}
+
+		var oauthErr oauthError
+		json.Unmarshal([]byte(statusErr.Body), &oauthErr)
+
+		switch oauthErr.Error {
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return nil, err
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return nil, fmt.Errorf("device code was not authorized within %ds", h.config.OAuth.DevicePollTimeout)
+		}
+		if !h.sleep(ctx, interval) {
+			return nil, fmt.Errorf("device code polling aborted: %v", ctx.Err())
+		}
+	}
+}
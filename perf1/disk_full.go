@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// csvPendingLimit is the number of rows a CSV writer keeps for rewriting before it flushes, bounding
+// what it holds in memory while the queue never runs empty
+const csvPendingLimit = 1000
+
+// moveCSVToFallback handles a CSV output whose disk filled up. The rows written since the last
+// successful flush are written again to a file of the same name in the fallback directory, after
+// the header when that file is new, and the new file and writer are returned. Without a fallback
+// directory, or when it cannot take the rows either, it returns an error and the caller pauses the
+// output. Either way a single alert is printed, so a full disk does not log an error for every row.
+func moveCSVToFallback(filename, fallbackDir string, header []string, rows [][]string) (*os.File, *csv.Writer, error) {
+	printFailure("\n[ALERT] Disk full while writing %s\n", filename)
+
+	if fallbackDir == "" {
+		printFailure("No fallback directory is configured, so no more rows are written to %s. The run continues and its statistics and report are kept.\n", filename)
+		return nil, nil, fmt.Errorf("disk full and no fallback directory configured")
+	}
+
+	path := filepath.Join(fallbackDir, filepath.Base(filename))
+	if abs, err := filepath.Abs(path); err == nil {
+		if orig, err := filepath.Abs(filename); err == nil && samePath(abs, orig) {
+			printFailure("The fallback directory holds %s itself, so no more rows are written to it.\n", filename)
+			return nil, nil, fmt.Errorf("fallback file is the full file")
+		}
+	}
+
+	file, writer, err := openFallbackCSV(path, header, rows)
+	if err != nil {
+		printFailure("Failed to switch %s to the fallback directory: %v. No more rows are written to it.\n", filename, err)
+		return nil, nil, err
+	}
+
+	printWarning("Writing the rest of %s to %s; rows already in %s stay there, possibly ending in a partial row.\n", filename, path, filename)
+	return file, writer, nil
+}
+
+// openFallbackCSV opens a fallback file for appending and writes the header, if the file is new,
+// and the given rows to it
+func openFallbackCSV(path string, header []string, rows [][]string) (*os.File, *csv.Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create fallback directory: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open fallback file: %v", err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to get file stats: %v", err)
+	}
+
+	writer := csv.NewWriter(file)
+	if stat.Size() == 0 {
+		writer.Write(header)
+	}
+	for _, row := range rows {
+		writer.Write(row)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to write fallback file: %v", err)
+	}
+	return file, writer, nil
+}
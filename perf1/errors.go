@@ -0,0 +1,86 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxErrorPatternLength caps the length of a normalized error message, so long response bodies do
+// not make otherwise identical errors distinct
+const maxErrorPatternLength = 200
+
+// errorNormalizers replace the variable parts of error messages with placeholders, in order
+var errorNormalizers = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "{uuid}"},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "{time}"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{16,}\b`), "{hex}"},
+	{regexp.MustCompile(`\s+`), " "},
+}
+
+// errorNumber matches the numbers of an error message, along with the error codes such as
+// SCIM-10001 that a number can be part of
+var errorNumber = regexp.MustCompile(`\b[A-Za-z][A-Za-z0-9]*-\d+\b|\d+(\.\d+)?`)
+
+// maskNumber replaces a number of an error message with a placeholder, keeping HTTP status codes
+// and error codes, which tell failures apart rather than name what failed
+func maskNumber(number string) string {
+	if number[0] < '0' || number[0] > '9' {
+		return number // an error code
+	}
+	if code, err := strconv.Atoi(number); err == nil && len(number) == 3 && code >= 100 && code <= 599 {
+		return number
+	}
+	return "{n}"
+}
+
+// normalizeError reduces an error message to its pattern by stripping IDs, timestamps and numbers,
+// so failures with the same root cause are counted together
+func normalizeError(message string) string {
+	for _, n := range errorNormalizers {
+		message = n.pattern.ReplaceAllString(message, n.replacement)
+	}
+	message = errorNumber.ReplaceAllStringFunc(message, maskNumber)
+
+	// Cut long messages at the start of a character, so multi-byte characters stay intact
+	message = strings.TrimSpace(message)
+	if len(message) > maxErrorPatternLength {
+		cut := maxErrorPatternLength
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		message = message[:cut] + "..."
+	}
+	return message
+}
+
+// errorPatternCount is the number of failures with one normalized error message
+type errorPatternCount struct {
+	Pattern string
+	Count   int
+}
+
+// topErrors returns the n most frequent error patterns, most frequent first
+func topErrors(counts map[string]int, n int) []errorPatternCount {
+	patterns := make([]errorPatternCount, 0, len(counts))
+	for pattern, count := range counts {
+		patterns = append(patterns, errorPatternCount{pattern, count})
+	}
+
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Count != patterns[j].Count {
+			return patterns[i].Count > patterns[j].Count
+		}
+		return patterns[i].Pattern < patterns[j].Pattern
+	})
+
+	if n > 0 && len(patterns) > n {
+		patterns = patterns[:n]
+	}
+	return patterns
+}
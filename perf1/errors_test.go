@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNormalizeError(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "status code kept, username number masked",
+			message: `user creation failed with status 409: User isTestUser_1234 already exists.`,
+			want:    `user creation failed with status 409: User isTestUser_{n} already exists.`,
+		},
+		{
+			name:    "error code kept",
+			message: `role creation failed: UMM-00011 role tenantRole_7 exists`,
+			want:    `role creation failed: UMM-00011 role tenantRole_{n} exists`,
+		},
+		{
+			name:    "port and version masked",
+			message: `Post "https://localhost:9443/Users": HTTP/1.1 timeout`,
+			want:    `Post "https://localhost:{n}/Users": HTTP/{n} timeout`,
+		},
+		{
+			name:    "three digits outside the status range masked",
+			message: `took 750 ms after 99 retries`,
+			want:    `took {n} ms after {n} retries`,
+		},
+		{
+			name:    "uuid, timestamp and hex replaced",
+			message: `user 0f8fad5b-d9cb-469f-a165-70867728950e at 2026-10-16T19:09:46Z trace 0123456789abcdef0123`,
+			want:    `user {uuid} at {time} trace {hex}`,
+		},
+		{
+			name:    "decimal with three characters masked",
+			message: `urn:ietf:params:scim:api:messages:2.0:Error`,
+			want:    `urn:ietf:params:scim:api:messages:{n}:Error`,
+		},
+		{
+			name:    "whitespace collapsed",
+			message: "  line one\n\tline two  ",
+			want:    "line one line two",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeError(tt.message); got != tt.want {
+				t.Errorf("normalizeError(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeErrorTruncatesOnRuneBoundary(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{"ascii", strings.Repeat("x", 300)},
+		{"two-byte runes", strings.Repeat("é", 150)},
+		{"three-byte runes offset by one", "a" + strings.Repeat("錯", 100)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeError(tt.message)
+			if !utf8.ValidString(got) {
+				t.Errorf("normalizeError cut a character: %q", got)
+			}
+			if !strings.HasSuffix(got, "...") || len(got) > maxErrorPatternLength+len("...") {
+				t.Errorf("normalizeError(%d bytes) = %d bytes, want at most %d plus ...", len(tt.message), len(got), maxErrorPatternLength)
+			}
+		})
+	}
+}
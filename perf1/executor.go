@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -10,76 +12,439 @@ type TestExecutor struct {
 	config            *Config
 	csvWriter         *CSVWriter
 	failedUsersWriter *FailedUsersCSVWriter
+	failedRolesWriter *FailedRolesCSVWriter // open during role creation
 	stats             *TestStats
+	progress          *progressReporter
+	bearer            *bearerTokenSource
+	adminTokens       *adminTokenCache
+	vars              *VariableStore
+	alerts            *alertMonitor
+	reporters         *reporterSet
+	pool              *connectionPool
+	limiter           *rateLimiter
+	readiness         *tenantReadiness
+	accessLog         *accessLog
+	locales           *localeAttributes
+	passwords         *passwordPolicies
+	
+	// roleGate opens a tenant for user creation once its role exists, while the phases are pipelined
+	roleGate *tenantGate
+	
+	// missingRoles holds the tenants whose test role could not be created
+	missingRoles      map[int]error
+	missingRolesMutex sync.Mutex
+	
+	// rampSteps is the ramp profile of user creation, nil for the linear ramp-up
+	rampSteps []rampStep
+	
+	// ctx is cancelled when the run is interrupted, and cancel aborts the run with a cause
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	
+	// errorBudget aborts the run when too many requests fail
+	errorBudget *errorBudget
+	
+	// resume is the checkpoint a resumed run continues from
+	resume *Checkpoint
+	
+	// unfinished collects the users left uncreated by an interruption
+	unfinished      []UserRange
+	unfinishedMutex sync.Mutex
+}
+
+// ExecutionMode selects the workflow run by the executor
+type ExecutionMode int
+
+const (
+	// ModeCreate creates roles and users
+	ModeCreate ExecutionMode = iota
+	// ModeRetryFailed retries users recorded in the failed users CSV
+	ModeRetryFailed
+	// ModeCleanup deletes resources created by previous runs
+	ModeCleanup
+	// ModeRead reads users created by previous runs
+	ModeRead
+	// ModeRace creates the same username from several workers at once
+	ModeRace
+	// ModeChurn repeatedly creates and deletes users
+	ModeChurn
+	// ModeGrowth creates users continuously with per-checkpoint latency reporting
+	ModeGrowth
+	// ModeGroupScale grows a single group to a large member count
+	ModeGroupScale
+	// ModeRoleScale assigns many roles to every user
+	ModeRoleScale
+	// ModeAttributeSweep sweeps the number of custom attributes per user
+	ModeAttributeSweep
+	// ModeToken obtains and validates tokens for created users
+	ModeToken
+	// ModeLogout logs created users in and terminates their sessions
+	ModeLogout
+	// ModeSessionSoak builds up active sessions and soaks them idle
+	ModeSessionSoak
+	// ModeUpdate updates created users with conditional and stale-ETag requests
+	ModeUpdate
+	// ModeMix runs a weighted mix of scenarios as virtual users
+	ModeMix
+	// ModeGroups creates SCIM2 groups only
+	ModeGroups
+	// ModeBulk creates roles and then users through the SCIM2 Bulk endpoint
+	ModeBulk
+	// ModeLogin authenticates users created by previous runs
+	ModeLogin
+	// ModePatch patches the users recorded in the SCIM ID CSV with templated operations
+	ModePatch
+	// ModeRetryRoles retries roles recorded in the failed roles CSV
+	ModeRetryRoles
+	// ModeIdPs creates identity providers only
+	ModeIdPs
+	// ModeAPIResources registers API resources and requests tokens with their scopes
+	ModeAPIResources
+)
+
+// modeNames holds the name of each execution mode, as reported in the progress file
+var modeNames = map[ExecutionMode]string{
+	ModeCreate:         "create",
+	ModeRetryFailed:    "retry-failed",
+	ModeCleanup:        "cleanup",
+	ModeRead:           "read",
+	ModeRace:           "race-test",
+	ModeChurn:          "churn",
+	ModeGrowth:         "growth",
+	ModeGroupScale:     "group-scale",
+	ModeRoleScale:      "role-scale",
+	ModeAttributeSweep: "attribute-sweep",
+	ModeToken:          "token",
+	ModeLogout:         "logout",
+	ModeSessionSoak:    "session-soak",
+	ModeUpdate:         "update",
+	ModeMix:            "mix",
+	ModeGroups:         "groups",
+	ModeBulk:           "bulk",
+	ModeLogin:          "login",
+	ModePatch:          "patch",
+	ModeRetryRoles:     "retry-roles",
+	ModeIdPs:           "idps",
+	ModeAPIResources:   "api-resources",
+}
+
+// validateExecution checks the execution settings every phase divides the work by
+func validateExecution(execution ExecutionConfig) error {
+	if execution.NoOfTenants < 1 {
+		return fmt.Errorf("number of tenants must be positive, got %d", execution.NoOfTenants)
+	}
+	if execution.NoOfThreads < 1 {
+		return fmt.Errorf("number of threads must be positive, got %d", execution.NoOfThreads)
+	}
+	return nil
+}
+
+func (m ExecutionMode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("mode-%d", int(m))
 }
 
 // NewTestExecutor creates a new test executor
-func NewTestExecutor(config *Config, retryMode bool) (*TestExecutor, error) {
-	csvWriter, err := NewCSVWriter(config.Execution.ScimIdCsvPath)
+func NewTestExecutor(config *Config, mode ExecutionMode) (*TestExecutor, error) {
+	if err := checkOffline(config); err != nil {
+		return nil, err
+	}
+	if err := validateExecution(config.Execution); err != nil {
+		return nil, err
+	}
+
+	// Settings that only take effect once the run starts are checked here, before any output file
+	// of a previous run is truncated
+	if _, err := parseAlertRules(config.Alerts); err != nil {
+		return nil, err
+	}
+	if err := validateErrorBudget(config.Execution); err != nil {
+		return nil, err
+	}
+	if err := validateReporters(config.Reporters); err != nil {
+		return nil, err
+	}
+	
+	stats := NewTestStats()
+	stats.SetPhase(mode.String())
+	stats.topErrors = config.Execution.TopErrors
+	stats.scimIDs = newSCIMIDCheck(config.Execution.NoOfUsers * config.Execution.NoOfTenants)
+	
+	if config.Execution.HeatmapFile != "" {
+		heatmap, err := newLatencyHeatmap(time.Duration(config.Execution.HeatmapInterval)*time.Second, config.Execution.HeatmapBuckets)
+		if err != nil {
+			return nil, err
+		}
+		stats.heatmap = heatmap
+	}
+	
+	if config.Execution.ReportFile != "" {
+		stats.timeline = newRequestTimeline()
+	}
+	
+	bearer, err := newBearerTokenSource(&config.Server)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create CSV writer: %v", err)
+		return nil, fmt.Errorf("failed to load bearer token: %v", err)
+	}
+	
+	adminTokens, err := newAdminTokenCache(&config.Server)
+	if err != nil {
+		return nil, err
+	}
+	
+	locales, err := newLocaleAttributes(config.Locale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid locale attributes: %v", err)
 	}
 	
-	var failedUsersWriter *FailedUsersCSVWriter
+	passwords, err := newPasswordPolicies(config.Test.PasswordPolicies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid password policies: %v", err)
+	}
+	if err := validatePasswordMode(config.Test); err != nil {
+		return nil, err
+	}
+	
+	te := &TestExecutor{
+		config:      config,
+		stats:       stats,
+		bearer:      bearer,
+		adminTokens: adminTokens,
+		vars:        NewVariableStore(config.Variables),
+		limiter:     newRateLimiter(config.Execution.TargetTPS, config.Execution.TPSBurst),
+		readiness:   newTenantReadiness(config.Execution.ProbeTenants, config.Execution.ProbeAttempts, config.Execution.ProbeInterval),
+		locales:     locales,
+		passwords:   passwords,
+		ctx:         context.Background(),
+	}
+	
+	te.pool, err = newConnectionPool(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS settings: %v", err)
+	}
+	
+	if mode == ModeCreate {
+		te.rampSteps, err = parseRampProfile(config.Execution.RampProfile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ramp profile: %v", err)
+		}
+		if len(te.rampSteps) > 0 && (config.Execution.ParallelTenants || config.Execution.PipelineTenants) {
+			return nil, fmt.Errorf("rampProfile cannot be combined with parallelTenants or pipelineTenants")
+		}
+		if err := validateArrivalRate(config.Execution); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateRoleDefinitions(config.Test); err != nil {
+		return nil, err
+	}
+	
+	if config.Execution.AccessLogFile != "" {
+		te.accessLog, err = newAccessLog(config.Execution.AccessLogFile, config.Execution.AccessLogLatency)
+		if err != nil {
+			return nil, err
+		}
+	}
+	
+	// Only create, bulk and retry modes produce output files, so leave those of previous runs untouched otherwise
+	if mode != ModeCreate && mode != ModeBulk && mode != ModeRetryFailed {
+		return te, nil
+	}
+	
+	// A resumed run adds to the output files of the run it continues
+	resume := config.Execution.Resume && mode == ModeCreate
+	if resume {
+		te.resume, err = LoadCheckpoint(config.Execution.CheckpointFile)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Resuming the %s phase from checkpoint saved at %s\n", te.resume.Phase, te.resume.SavedAt.Format(time.RFC3339))
+	}
+	
+	if resume {
+		te.csvWriter, err = NewCSVWriterAppend(config.Execution.ScimIdCsvPath)
+	} else {
+		te.csvWriter, err = NewCSVWriter(config.Execution.ScimIdCsvPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSV writer: %v", err)
+	}
+	te.csvWriter.csvOutputOptions = te.outputOptions()
 	
 	// Only create failed users writer if NOT in retry mode (to avoid truncating existing file)
-	if !retryMode {
-		failedUsersWriter, err = NewFailedUsersCSVWriter(config.Execution.FailedUsersCsvPath)
+	if mode != ModeRetryFailed {
+		if resume {
+			te.failedUsersWriter, err = NewFailedUsersCSVWriterAppend(config.Execution.FailedUsersCsvPath)
+		} else {
+			te.failedUsersWriter, err = NewFailedUsersCSVWriter(config.Execution.FailedUsersCsvPath)
+		}
 		if err != nil {
-			csvWriter.Close() // Clean up the first writer if second fails
+			te.csvWriter.Close() // Clean up the first writer if second fails
 			return nil, fmt.Errorf("failed to create failed users CSV writer: %v", err)
 		}
+		te.failedUsersWriter.csvOutputOptions = te.outputOptions()
 	}
 	
-	stats := NewTestStats()
-	
-	return &TestExecutor{
-		config:            config,
-		csvWriter:         csvWriter,
-		failedUsersWriter: failedUsersWriter,
-		stats:             stats,
-	}, nil
+	return te, nil
+}
+
+// outputOptions returns the settings of the CSV writers from the configuration
+func (te *TestExecutor) outputOptions() csvOutputOptions {
+	return csvOutputOptions{
+		fallbackDir: te.config.Execution.FallbackDir,
+		manifest:    te.config.Execution.OutputManifests,
+	}
+}
+
+// newHTTPClient creates an HTTP client for a worker that reports into the executor statistics
+func (te *TestExecutor) newHTTPClient() *HTTPClient {
+	client := NewHTTPClient(te.config, te.pool)
+	client.stats = te.stats
+	client.worker = te.stats.newWorker()
+	client.bearer = te.bearer
+	client.adminTokens = te.adminTokens
+	client.accessLog = te.accessLog
+	client.locales = te.locales
+	client.passwords = te.passwords
+	return client
 }
 
 // Close cleans up resources
 func (te *TestExecutor) Close() error {
-	var err1, err2 error
+	var err1, err2, err3 error
 	if te.csvWriter != nil {
 		err1 = te.csvWriter.Close()
 	}
 	if te.failedUsersWriter != nil {
 		err2 = te.failedUsersWriter.Close()
 	}
+	if te.accessLog != nil {
+		err3 = te.accessLog.Close()
+		te.accessLog = nil
+	}
+	if te.pool != nil {
+		te.pool.Close()
+	}
 	
 	if err1 != nil {
 		return err1
 	}
-	return err2
+	if err2 != nil {
+		return err2
+	}
+	return err3
 }
 
 // Execute runs the complete test execution
-func (te *TestExecutor) Execute() error {
+func (te *TestExecutor) Execute() (err error) {
 	fmt.Printf("Starting SCIM2 test execution with config:\n")
 	fmt.Printf("- Threads: %d\n", te.config.Execution.NoOfThreads)
 	fmt.Printf("- Users: %d\n", te.config.Execution.NoOfUsers)
 	fmt.Printf("- User Start Number: %d\n", te.config.Execution.UserStartNumber)
 	fmt.Printf("- Tenants: %d\n", te.config.Execution.NoOfTenants)
 	fmt.Printf("- Tenant Start Number: %d\n", te.config.Execution.TenantStartNumber)
+	if te.limiter != nil {
+		fmt.Printf("- Target Throughput: %.2f users/s\n", te.config.Execution.TargetTPS)
+	}
+	if te.config.Execution.ArrivalRate > 0 {
+		fmt.Printf("- Arrival Rate: %.2f users/s (Poisson), up to %d in flight and %d queued\n", te.config.Execution.ArrivalRate, te.config.Execution.ArrivalConcurrency, te.config.Execution.ArrivalQueue)
+	}
+	if te.config.Variants.enabled() {
+		fmt.Printf("- Payload Variants: %s\n", te.config.Variants)
+	}
+	if te.passwords != nil {
+		fmt.Printf("- Password Policies: %s\n", te.passwords)
+	}
+	switch te.config.Test.PasswordMode {
+	case PasswordModePrehashed:
+		fmt.Printf("- Passwords: pre-hashed with %s\n", te.config.Test.PasswordHash)
+	case PasswordModeCompare:
+		fmt.Printf("- Passwords: half plaintext, half pre-hashed with %s\n", te.config.Test.PasswordHash)
+	}
+	if te.locales != nil {
+		fmt.Printf("- Locale Attributes: %s\n", te.locales)
+	}
+	if te.readiness != nil {
+		fmt.Printf("- Tenant Probe: %d attempts, %ds apart\n", te.readiness.attempts, te.config.Execution.ProbeInterval)
+	}
 	fmt.Printf("- Server: %s\n", te.config.GetServerURL())
 	fmt.Println()
 	
 	startTime := time.Now()
 	
+	// Write the machine-readable summary however the run ends
+	defer func() {
+		te.writeSummary(ModeCreate, startTime, err)
+	}()
+	
+	// A resumed run skips the phases completed before the interruption
+	resumePhase := te.resumePhase()
+	
+	// Phases 1 and 2 pipelined: create the users of each tenant as soon as its role exists
+	pipelined := te.config.Execution.PipelineTenants && (resumePhase == "" || resumePhase == "roles" || resumePhase == phaseRolesAndUsers)
+	if pipelined {
+		te.setPhase(phaseRolesAndUsers)
+		if err := te.executeRolesAndUsers(); err != nil {
+			return err
+		}
+	}
+	
 	// Phase 1: Create roles
-	if err := te.ExecuteRoleCreation(); err != nil {
-		return fmt.Errorf("role creation failed: %v", err)
+	if !pipelined && (resumePhase == "" || resumePhase == "roles" || resumePhase == phaseRolesAndUsers) {
+		te.setPhase("roles")
+		if err := te.ExecuteRoleCreation(); err != nil {
+			return fmt.Errorf("role creation failed: %v", err)
+		}
+		if te.interrupted() {
+			return te.saveCheckpoint("roles", nil)
+		}
 	}
 	
 	// Phase 2: Create users
-	if err := te.ExecuteUserCreation(); err != nil {
-		return fmt.Errorf("user creation failed: %v", err)
+	if !pipelined && resumePhase != "groups" && resumePhase != "idps" && resumePhase != "login" {
+		te.setPhase("users")
+		if err := te.ExecuteUserCreation(); err != nil {
+			return fmt.Errorf("user creation failed: %w", err)
+		}
 	}
 	
+	// Phase 3: Create groups
+	if te.config.Groups.PerTenant > 0 && resumePhase != "idps" && resumePhase != "login" {
+		te.setPhase("groups")
+		if err := te.ExecuteGroupCreation(); err != nil {
+			return fmt.Errorf("group creation failed: %v", err)
+		}
+		if te.interrupted() {
+			return te.saveCheckpoint("groups", nil)
+		}
+	}
+	
+	// Phase 4: Create identity providers, so logins run with that many federation configurations
+	if te.config.IdPs.PerTenant > 0 && resumePhase != "login" {
+		te.setPhase("idps")
+		if err := te.ExecuteIdPCreation(); err != nil {
+			return fmt.Errorf("identity provider creation failed: %v", err)
+		}
+		if te.interrupted() {
+			return te.saveCheckpoint("idps", nil)
+		}
+	}
+	
+	// Phase 5: Log in a share of the created users
+	if te.config.Login.Percent > 0 {
+		te.setPhase("login")
+		if err := te.ExecuteLoginPhase(); err != nil {
+			return fmt.Errorf("login phase failed: %v", err)
+		}
+		if te.interrupted() {
+			return te.saveCheckpoint("login", nil)
+		}
+	}
+	
+	te.clearCheckpoint()
+	
 	duration := time.Since(startTime)
 	fmt.Printf("\nTest execution completed in %v\n", duration)
 	
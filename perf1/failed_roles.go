@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// failedRolesHeader is the header row of the failed roles CSV
+var failedRolesHeader = []string{"TenantID", "RoleName", "Error", "Timestamp", "StatusCode", "FaultCode", "FaultString"}
+
+// FailedRole represents a role that could not be created
+type FailedRole struct {
+	TenantID    int
+	RoleName    string
+	Error       string
+	Timestamp   string
+	StatusCode  int
+	FaultCode   string
+	FaultString string
+}
+
+// failedRoleKey identifies a failed role, which may be listed more than once
+type failedRoleKey struct {
+	tenantID int
+	roleName string
+}
+
+// newFailedRole describes a failed role creation made now, with the status and fault of the
+// response when the server answered
+func newFailedRole(tenantIndex int, roleName string, err error) FailedRole {
+	role := FailedRole{
+		TenantID:  tenantIndex,
+		RoleName:  roleName,
+		Error:     err.Error(),
+		Timestamp: time.Now().Format("2006-01-02 15:04:05"),
+	}
+
+	var fault *SOAPFaultError
+	var statusErr *StatusError
+	if errors.As(err, &fault) {
+		role.StatusCode, role.FaultCode, role.FaultString = fault.StatusCode, fault.Code, fault.String
+	} else if errors.As(err, &statusErr) {
+		role.StatusCode, role.FaultString = statusErr.StatusCode, responseSnippet(statusErr.Body)
+	}
+	return role
+}
+
+// readFailedRoles reads the failed roles CSV, each (tenant, role) pair once
+func readFailedRoles(path string) ([]FailedRole, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open failed roles CSV file: %v", fileError(err))
+	}
+	defer file.Close()
+
+	reader := newCSVReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read failed roles CSV file: %v", err)
+	}
+
+	var roles []FailedRole
+	seen := make(map[failedRoleKey]bool)
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && record[0] == failedRolesHeader[0] {
+			continue
+		}
+		if len(record) < 4 {
+			continue // Skip malformed records
+		}
+		tenantID, err := strconv.Atoi(record[0])
+		if err != nil {
+			printWarning("Invalid tenant ID in %s: %s\n", path, record[0])
+			continue
+		}
+
+		key := failedRoleKey{tenantID, record[1]}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		role := FailedRole{TenantID: tenantID, RoleName: record[1], Error: record[2], Timestamp: record[3]}
+		if len(record) >= 7 {
+			role.StatusCode, _ = strconv.Atoi(record[4])
+			role.FaultCode, role.FaultString = record[5], record[6]
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// FailedRolesCSVWriter records the roles that could not be created, with the SOAP fault the admin
+// service answered with, so the tenants to fix before a retry can be found without the console log
+type FailedRolesCSVWriter struct {
+	filename string
+	file     *os.File
+	writer   *csv.Writer
+	mutex    sync.Mutex
+	written  int // rows written, including those moved to the fallback directory
+
+	csvOutputOptions
+	paused  bool
+	dropped int
+}
+
+// NewFailedRolesCSVWriter creates the failed roles CSV with the given output options, replacing
+// the file of an earlier run
+func NewFailedRolesCSVWriter(filename string, options csvOutputOptions) (*FailedRolesCSVWriter, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create failed roles CSV file: %v", fileError(err))
+	}
+
+	writer := csv.NewWriter(file)
+	writer.Write(failedRolesHeader)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write CSV header: %v", err)
+	}
+
+	return &FailedRolesCSVWriter{filename: filename, file: file, writer: writer, csvOutputOptions: options}, nil
+}
+
+// WriteFailedRole writes a failed role creation to the CSV file
+func (fw *FailedRolesCSVWriter) WriteFailedRole(role FailedRole) error {
+	statusCode := ""
+	if role.StatusCode != 0 {
+		statusCode = strconv.Itoa(role.StatusCode)
+	}
+	record := []string{strconv.Itoa(role.TenantID), role.RoleName, role.Error, role.Timestamp, statusCode, role.FaultCode, role.FaultString}
+
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+
+	if fw.paused {
+		fw.dropped++
+		return nil
+	}
+
+	fw.writer.Write(record)
+	fw.writer.Flush()
+	err := fw.writer.Error()
+	if isDiskFull(err) {
+		// Like the failed users, the rest of the file moves to the fallback directory, if any
+		file, writer, fallbackErr := moveCSVToFallback(fw.filename, fw.fallbackDir, failedRolesHeader, [][]string{record})
+		fw.file.Close()
+		if fallbackErr != nil {
+			fw.file = nil
+			fw.paused = true
+			fw.dropped++
+			return nil
+		}
+		fw.file, fw.writer, fw.filename = file, writer, file.Name()
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write failed role record: %v", err)
+	}
+	fw.written++
+	return nil
+}
+
+// complete reports whether every failed role was written to the file the writer was created for
+func (fw *FailedRolesCSVWriter) complete(filename string) bool {
+	return !fw.paused && fw.dropped == 0 && fw.filename == filename
+}
+
+// Close closes the failed roles CSV writer
+func (fw *FailedRolesCSVWriter) Close() error {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+
+	if fw.dropped > 0 {
+		printWarning("%d failed roles were not written to %s because the disk is full\n", fw.dropped, fw.filename)
+	}
+	if fw.file == nil {
+		return nil
+	}
+
+	fw.writer.Flush()
+	err := fw.file.Close()
+	if err == nil && !fw.paused {
+		fw.writeManifest(fw.filename)
+	}
+	return err
+}
+
+// openFailedRoles starts the failed roles CSV of a role creation phase, unless it is disabled
+func (te *TestExecutor) openFailedRoles() error {
+	path := te.config.Execution.FailedRolesCsvPath
+	if path == "" {
+		return nil
+	}
+
+	writer, err := NewFailedRolesCSVWriter(path, te.outputOptions())
+	if err != nil {
+		return err
+	}
+	te.failedRolesWriter = writer
+	return nil
+}
+
+// closeFailedRoles closes the failed roles CSV of the role creation phase
+func (te *TestExecutor) closeFailedRoles() {
+	if te.failedRolesWriter == nil {
+		return
+	}
+	writer := te.failedRolesWriter
+	te.failedRolesWriter = nil
+	if err := writer.Close(); err != nil {
+		printWarning("Failed to close %s: %v\n", writer.filename, err)
+	} else if writer.written > 0 {
+		printWarning("%d roles could not be created, see %s; retry them with the retry-roles command\n", writer.written, writer.filename)
+	}
+}
+
+// recordRoleResult counts the outcome of creating a role and records a failure in the failed roles
+// CSV
+func (te *TestExecutor) recordRoleResult(tenantIndex int, roleName string, err error) {
+	te.stats.IncrementRole(err == nil)
+	te.stats.RecordError(err)
+	if err == nil || te.failedRolesWriter == nil {
+		return
+	}
+
+	if writeErr := te.failedRolesWriter.WriteFailedRole(newFailedRole(tenantIndex, roleName, err)); writeErr != nil {
+		printWarning("%v\n", writeErr)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// failedUserKey identifies a user in a failed users file
+type failedUserKey struct {
+	tenantID int
+	username string
+}
+
+// mergingSuffix is appended to the output of a merge to name the file the shards are streamed to,
+// until it replaces the output at the end
+const mergingSuffix = ".merging"
+
+// SplitFailedUsers splits a failed users file into shards for parallel retry on several machines.
+// All rows of a user go to the same shard, along with the attempts column that counts the user's
+// failed creations. The file is streamed twice, once to deal out the users and once to write the
+// shards, so only the shard of every user is kept in memory. Shard files are named after the input
+// file and their paths are returned.
+func SplitFailedUsers(path string, shards int) ([]string, error) {
+	if shards < 1 {
+		return nil, fmt.Errorf("shard count must be positive, got %d", shards)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open failed users CSV file: %v", err)
+	}
+	defer file.Close()
+
+	// Users are dealt out round-robin in order of first appearance, which balances the shards
+	assignment := make(map[failedUserKey]int)
+	err = scanFailedUsers(file, func(user FailedUser) bool {
+		key := failedUserKey{user.TenantID, user.Username}
+		if _, ok := assignment[key]; !ok {
+			assignment[key] = len(assignment) % shards
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind failed users CSV file: %v", err)
+	}
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+
+	paths := make([]string, shards)
+	writers := make([]*FailedUsersCSVWriter, shards)
+	defer func() {
+		for _, writer := range writers {
+			if writer != nil {
+				writer.Close()
+			}
+		}
+	}()
+
+	for shard := range writers {
+		paths[shard] = fmt.Sprintf("%s.shard%d%s", base, shard+1, ext)
+		writer, err := NewFailedUsersCSVWriter(paths[shard])
+		if err != nil {
+			return nil, err
+		}
+		writers[shard] = writer
+	}
+
+	rows := 0
+	var writeErr error
+	err = scanFailedUsers(file, func(user FailedUser) bool {
+		shard, ok := assignment[failedUserKey{user.TenantID, user.Username}]
+		if !ok {
+			writeErr = fmt.Errorf("%s changed while it was split", path)
+			return false
+		}
+		if writeErr = writers[shard].WriteFailedUser(user); writeErr != nil {
+			return false
+		}
+		rows++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if writeErr != nil {
+		return nil, writeErr
+	}
+
+	for _, writer := range writers {
+		if err := writer.closeComplete(); err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Printf("Split %d failed user rows (%d users) from %s into %d shards\n",
+		rows, len(assignment), path, shards)
+
+	return paths, nil
+}
+
+// MergeFailedUsers merges shard files back into a single failed users file with one header.
+// Rows are kept in shard order with their attempts column, so every failure recorded by a shard is
+// preserved; rows that are exact duplicates, as when the same shard is merged twice, are written
+// only once. Each shard is streamed to a file next to the output that replaces it at the end, so
+// only the keys of the rows written are kept in memory and the output may be one of the inputs.
+func MergeFailedUsers(output string, inputs []string) error {
+	type rowKey struct {
+		user      failedUserKey
+		error     string
+		timestamp string
+	}
+	seen := make(map[rowKey]struct{})
+
+	merging := output + mergingSuffix
+	writer, err := NewFailedUsersCSVWriter(merging)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if writer != nil {
+			writer.Close()
+			os.Remove(merging)
+		}
+	}()
+
+	rows := 0
+	for _, input := range inputs {
+		if err := mergeFailedUsersShard(input, writer, func(user FailedUser) bool {
+			key := rowKey{failedUserKey{user.TenantID, user.Username}, user.Error, user.Timestamp}
+			if _, ok := seen[key]; ok {
+				return false
+			}
+			seen[key] = struct{}{}
+			rows++
+			return true
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.closeComplete(); err != nil {
+		return err
+	}
+	writer = nil
+
+	if err := renameFile(merging, output); err != nil {
+		os.Remove(merging)
+		return fmt.Errorf("failed to replace %s with the merged failed users: %v", output, fileError(err))
+	}
+
+	fmt.Printf("Merged %d failed user rows from %d shards into %s\n", rows, len(inputs), output)
+
+	return nil
+}
+
+// mergeFailedUsersShard streams the rows of a shard file that keep accepts to the writer
+func mergeFailedUsersShard(input string, writer *FailedUsersCSVWriter, keep func(FailedUser) bool) error {
+	file, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("failed to read shard %s: %v", input, err)
+	}
+	defer file.Close()
+
+	var writeErr error
+	err = scanFailedUsers(file, func(user FailedUser) bool {
+		if !keep(user) {
+			return true
+		}
+		writeErr = writer.WriteFailedUser(user)
+		return writeErr == nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read shard %s: %v", input, err)
+	}
+	return writeErr
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+)
+
+// utf8BOM is the byte order mark Windows editors such as Notepad and Excel put at the start of
+// UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// newCSVReader creates a CSV reader that skips a leading UTF-8 byte order mark, so files saved on
+// Windows are read like any other. Lines may end in LF or CRLF.
+func newCSVReader(r io.Reader) *csv.Reader {
+	buffered := bufio.NewReader(r)
+	if prefix, err := buffered.Peek(len(utf8BOM)); err == nil && bytes.Equal(prefix, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+	}
+	return csv.NewReader(buffered)
+}
+
+// trimBOM removes a leading UTF-8 byte order mark from file contents
+func trimBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, utf8BOM)
+}
+
+// terminateLastLine ends the last line of a file opened for appending when it lacks a line break,
+// as files edited by hand often do, so the next row does not run into it
+func terminateLastLine(file *os.File) error {
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to get file stats: %v", err)
+	}
+	if stat.Size() == 0 {
+		return nil
+	}
+
+	reader, err := os.Open(file.Name())
+	if err != nil {
+		return fmt.Errorf("failed to open file: %v", err)
+	}
+	defer reader.Close()
+
+	last := make([]byte, 1)
+	if _, err := reader.ReadAt(last, stat.Size()-1); err != nil {
+		return fmt.Errorf("failed to read file: %v", err)
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+
+	if _, err := file.WriteString("\n"); err != nil {
+		return fmt.Errorf("failed to end the last line: %v", err)
+	}
+	return nil
+}
+
+// fileError explains an error opening, replacing or removing an output file that another program
+// holds open, which Windows does not allow
+func fileError(err error) error {
+	if isFileLocked(err) {
+		return fmt.Errorf("%v (the file is open in another program, such as Excel; close it and try again)", err)
+	}
+	return err
+}
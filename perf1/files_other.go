@@ -0,0 +1,30 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// isDiskFull reports whether a write failed because the disk has no space left
+func isDiskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
+
+// isFileLocked reports whether another program holding a file open caused the error, which
+// does not happen outside Windows
+func isFileLocked(err error) bool {
+	return false
+}
+
+// renameFile renames a file, replacing the target if it exists
+func renameFile(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// samePath reports whether two absolute paths name the same file
+func samePath(a, b string) bool {
+	return a == b
+}
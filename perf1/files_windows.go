@@ -0,0 +1,58 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Windows error codes not defined by the syscall package
+const (
+	errorSharingViolation = syscall.Errno(32)
+	errorLockViolation    = syscall.Errno(33)
+	errorHandleDiskFull   = syscall.Errno(39)
+	errorDiskFull         = syscall.Errno(112)
+)
+
+// renameAttempts and renameRetryDelay bound how long renameFile waits for another program to
+// release the target
+const (
+	renameAttempts   = 10
+	renameRetryDelay = 100 * time.Millisecond
+)
+
+// isDiskFull reports whether a write failed because the disk has no space left
+func isDiskFull(err error) bool {
+	return errors.Is(err, errorDiskFull) || errors.Is(err, errorHandleDiskFull) || errors.Is(err, syscall.ENOSPC)
+}
+
+// isFileLocked reports whether another program holding a file open caused the error
+func isFileLocked(err error) bool {
+	return errors.Is(err, errorSharingViolation) || errors.Is(err, errorLockViolation)
+}
+
+// renameFile renames a file, replacing the target if it exists. Windows refuses to replace a file
+// another program has open, and virus scanners and search indexers briefly open new files, so the
+// rename is retried for a second before giving up.
+func renameFile(oldpath, newpath string) error {
+	var err error
+	for attempt := 0; attempt < renameAttempts; attempt++ {
+		if err = os.Rename(oldpath, newpath); err == nil {
+			return nil
+		}
+		if !isFileLocked(err) && !errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
+			return err
+		}
+		time.Sleep(renameRetryDelay)
+	}
+	return err
+}
+
+// samePath reports whether two absolute paths name the same file, ignoring case as Windows does
+func samePath(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ExecuteGroupScale grows a single group to a large member count in PATCH batches and reports
+// how member-add latency changes with the size of the group
+func (te *TestExecutor) ExecuteGroupScale() error {
+	cfg := te.config.GroupScale
+	if cfg.BatchSize < 1 || cfg.ReportInterval < 1 {
+		return fmt.Errorf("group scale batch size and report interval must be positive")
+	}
+
+	tenantIndex := te.config.Execution.TenantStartNumber
+	runID := time.Now().Unix()
+
+	fmt.Println("Starting group membership scale test...")
+	fmt.Printf("- Tenant: %d\n", tenantIndex)
+	fmt.Printf("- Members: %d\n", cfg.Members)
+	fmt.Printf("- Batch Size: %d\n", cfg.BatchSize)
+	fmt.Printf("- Threads Creating Members: %d\n", te.config.Execution.NoOfThreads)
+
+	client := te.newHTTPClient()
+
+	groupName := fmt.Sprintf("%s_%d", cfg.GroupName, runID)
+	group, err := client.CreateGroup(te.ctx, tenantIndex, groupName)
+	if err != nil {
+		return fmt.Errorf("failed to create group %s: %v", groupName, err)
+	}
+	fmt.Printf("Group '%s' created with ID %s\n", groupName, group.ID)
+
+	var patchLatencies []time.Duration
+	var intervalLatencies []time.Duration
+	groupSize := 0
+	failedBatches := 0
+	nextReport := cfg.ReportInterval
+
+	startTime := time.Now()
+	fmt.Println("\n=== Member Add Latency vs Group Size ===")
+
+	for created := 0; created < cfg.Members && !te.interrupted(); created += cfg.BatchSize {
+		batchSize := cfg.BatchSize
+		if created+batchSize > cfg.Members {
+			batchSize = cfg.Members - created
+		}
+
+		members := te.createGroupScaleMembers(tenantIndex, runID, created, batchSize)
+		if te.interrupted() {
+			break
+		}
+		if len(members) == 0 {
+			failedBatches++
+			continue
+		}
+
+		requestStart := time.Now()
+		err := client.AddGroupMembers(te.ctx, tenantIndex, group.ID, members)
+		duration := time.Since(requestStart)
+
+		if err != nil && te.interrupted() {
+			break
+		}
+		if err != nil {
+			failedBatches++
+			printFailure("Failed to add %d members at group size %d: %v\n", len(members), groupSize, err)
+			continue
+		}
+
+		groupSize += len(members)
+		patchLatencies = append(patchLatencies, duration)
+		intervalLatencies = append(intervalLatencies, duration)
+
+		if groupSize >= nextReport {
+			fmt.Printf("Group Size %d - Batches: %d, %s\n", groupSize, len(intervalLatencies), SummarizeLatencies(intervalLatencies))
+			intervalLatencies = nil
+			for nextReport <= groupSize {
+				nextReport += cfg.ReportInterval
+			}
+		}
+	}
+
+	if len(intervalLatencies) > 0 {
+		fmt.Printf("Group Size %d - Batches: %d, %s\n", groupSize, len(intervalLatencies), SummarizeLatencies(intervalLatencies))
+	}
+	fmt.Println("========================================")
+
+	if te.interrupted() {
+		fmt.Printf("\nGroup membership scale test interrupted at group size %d\n", groupSize)
+		return ErrInterrupted
+	}
+
+	duration := time.Since(startTime)
+	fmt.Printf("\nGroup membership scale test completed in %v\n", duration)
+	fmt.Printf("Final Group Size: %d, Failed Batches: %d\n", groupSize, failedBatches)
+	if len(patchLatencies) > 0 {
+		fmt.Printf("Overall Member Add Latency - %s\n", SummarizeLatencies(patchLatencies))
+	}
+
+	te.stats.PrintStats()
+
+	return nil
+}
+
+// createGroupScaleMembers creates the users for one batch concurrently and returns them as group members
+func (te *TestExecutor) createGroupScaleMembers(tenantIndex int, runID int64, offset, count int) []SCIMGroupMember {
+	threads := te.config.Execution.NoOfThreads
+	if threads > count {
+		threads = count
+	}
+
+	usernames := make(chan string, count)
+	for i := 0; i < count; i++ {
+		usernames <- fmt.Sprintf("%smember_%d_%d", te.config.Test.UsernamePrefix, runID, offset+i)
+	}
+	close(usernames)
+
+	var members []SCIMGroupMember
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for threadID := 0; threadID < threads; threadID++ {
+		wg.Add(1)
+		go func(threadID int) {
+			defer wg.Done()
+
+			client := te.newHTTPClient()
+			for username := range usernames {
+				if te.interrupted() {
+					return
+				}
+
+				userResp, err := client.CreateUserWithName(te.ctx, tenantIndex, username)
+				if err != nil && te.interrupted() {
+					return
+				}
+				te.stats.IncrementUser(err == nil)
+				te.stats.RecordError(err)
+
+				if err != nil {
+					printFailure("Thread %d: Failed to create member %s for tenant %d: %v\n", threadID, username, tenantIndex, err)
+					continue
+				}
+
+				mutex.Lock()
+				members = append(members, SCIMGroupMember{Value: userResp.ID, Display: username})
+				mutex.Unlock()
+			}
+		}(threadID)
+	}
+
+	wg.Wait()
+	return members
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ExecuteGroupPhase runs group creation on its own and prints the statistics
+func (te *TestExecutor) ExecuteGroupPhase() error {
+	if err := te.ExecuteGroupCreation(); err != nil {
+		return err
+	}
+
+	te.stats.PrintStats()
+	return nil
+}
+
+// ExecuteGroupCreation creates the configured number of SCIM2 groups in every tenant using multiple
+// threads and records the group IDs to CSV
+func (te *TestExecutor) ExecuteGroupCreation() error {
+	fmt.Println("Starting group creation phase...")
+
+	cfg := te.config.Groups
+	if cfg.PerTenant < 1 {
+		return fmt.Errorf("groups per tenant must be positive, got %d", cfg.PerTenant)
+	}
+
+	groupWriter, err := NewCSVWriter(cfg.CsvPath)
+	if err != nil {
+		return fmt.Errorf("failed to create group ID CSV writer: %v", err)
+	}
+	groupWriter.csvOutputOptions = te.outputOptions()
+
+	var wg sync.WaitGroup
+
+	startTime := time.Now()
+	for _, task := range te.groupTasks() {
+		task.Client = te.newHTTPClient()
+
+		wg.Add(1)
+		go te.groupCreationWorker(task, groupWriter, &wg)
+	}
+
+	wg.Wait()
+
+	if err := groupWriter.Close(); err != nil {
+		printFailure("Failed to close group ID CSV writer: %v\n", err)
+	}
+
+	fmt.Printf("Group creation completed in %v\n", time.Since(startTime))
+	return nil
+}
+
+// groupTasks divides the groups over the threads, carrying each thread's group range in its user
+// range, and leaves out threads without groups
+func (te *TestExecutor) groupTasks() []WorkerTask {
+	return te.perTenantTasks(te.config.Groups.PerTenant)
+}
+
+// perTenantTasks divides the given number of resources per tenant over the threads, carrying each
+// thread's range in its user range, and leaves out threads without resources
+func (te *TestExecutor) perTenantTasks(perTenant int) []WorkerTask {
+	// Calculate groups per thread
+	threads := te.config.Execution.NoOfThreads
+	groupsPerThread := perTenant / threads
+	remainingGroups := perTenant % threads
+
+	var tasks []WorkerTask
+	groupStart := 1
+	for threadID := 0; threadID < threads; threadID++ {
+		threadGroups := groupsPerThread
+		if threadID < remainingGroups {
+			threadGroups++ // Distribute remaining groups to first few threads
+		}
+
+		if threadGroups == 0 {
+			continue
+		}
+
+		groupEnd := groupStart + threadGroups - 1
+
+		tasks = append(tasks, WorkerTask{
+			UserStart:   groupStart,
+			UserEnd:     groupEnd,
+			ThreadID:    threadID,
+			TenantStart: te.config.Execution.TenantStartNumber,
+			TenantEnd:   te.config.Execution.TenantStartNumber + te.config.Execution.NoOfTenants,
+			StartDelay:  te.rampUpStartDelay(threadID, threads),
+		})
+
+		groupStart = groupEnd + 1
+	}
+
+	return tasks
+}
+
+// groupCreationWorker creates the groups of the task's range, carried in its user range, for all of
+// the task's tenants
+func (te *TestExecutor) groupCreationWorker(task WorkerTask, groupWriter *CSVWriter, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	waitForRampUp(te.ctx, task.StartDelay)
+
+	startTime := time.Now()
+	fmt.Printf("Thread %d: Creating groups %d-%d for tenants %d-%d\n",
+		task.ThreadID, task.UserStart, task.UserEnd, task.TenantStart, task.TenantEnd-1)
+
+	for groupIndex := task.UserStart; groupIndex <= task.UserEnd; groupIndex++ {
+		for tenantIndex := task.TenantStart; tenantIndex < task.TenantEnd; tenantIndex++ {
+			groupResp, err := task.Client.CreateGroup(te.ctx, tenantIndex, te.config.GetTestGroupName(groupIndex))
+			te.stats.IncrementGroup(err == nil)
+			te.stats.RecordError(err)
+
+			if err != nil {
+				printFailure("Thread %d: Failed to create group %d for tenant %d: %v\n",
+					task.ThreadID, groupIndex, tenantIndex, err)
+				continue
+			}
+
+			if err := groupWriter.WriteScimID(tenantIndex, groupResp.ID); err != nil {
+				printFailure("Failed to write group ID to CSV: %v\n", err)
+			}
+		}
+	}
+
+	fmt.Printf("Thread %d: Completed groups %d-%d for tenants %d-%d in %v\n",
+		task.ThreadID, task.UserStart, task.UserEnd, task.TenantStart, task.TenantEnd-1, time.Since(startTime))
+}
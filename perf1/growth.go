@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// growthRecorder collects latencies into fixed-size checkpoints of a growing quantity, such as
+// the number of users in the dataset or of active sessions on the server
+type growthRecorder struct {
+	title       string
+	unit        string
+	interval    int
+	checkpoints [][]time.Duration
+	failed      []int
+	summaries   []LatencySummary
+	mutex       sync.Mutex
+}
+
+// ExecuteGrowth creates users continuously and reports create latency per checkpoint of the
+// dataset size, producing a latency-vs-dataset-size curve
+func (te *TestExecutor) ExecuteGrowth() error {
+	totalUsers := te.config.Growth.TotalUsers
+	interval := te.config.Growth.CheckpointInterval
+	if interval < 1 {
+		return fmt.Errorf("growth checkpoint interval must be positive, got %d", interval)
+	}
+
+	fmt.Println("Starting steady-state growth benchmark...")
+	fmt.Printf("- Threads: %d\n", te.config.Execution.NoOfThreads)
+	fmt.Printf("- Users: %d\n", totalUsers)
+	fmt.Printf("- Checkpoint Interval: %d users\n", interval)
+
+	recorder := newGrowthRecorder("Latency vs Dataset Size", "Users", totalUsers, interval)
+
+	// Sequence numbers are handed out in order, so each checkpoint covers a contiguous slice of the
+	// dataset, until the run is interrupted
+	sequence := make(chan int, te.config.Execution.NoOfThreads)
+	go func() {
+		defer close(sequence)
+
+		for n := 0; n < totalUsers; n++ {
+			select {
+			case sequence <- n:
+			case <-te.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	// Each worker delays its own start to apply the ramp-up
+	startTime := time.Now()
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		wg.Add(1)
+		go te.growthWorker(threadID, te.rampUpStartDelay(threadID, te.config.Execution.NoOfThreads), sequence, recorder, &wg)
+	}
+
+	wg.Wait()
+
+	// An interrupted benchmark still prints the curve up to where it stopped
+	if te.interrupted() {
+		recorder.print()
+		return ErrInterrupted
+	}
+
+	duration := time.Since(startTime)
+	fmt.Printf("\nGrowth benchmark completed in %v\n", duration)
+
+	recorder.print()
+	te.stats.PrintStats()
+
+	return nil
+}
+
+// growthWorker creates the users whose sequence numbers it receives, spreading them over the tenants
+func (te *TestExecutor) growthWorker(threadID int, startDelay time.Duration, sequence <-chan int, recorder *growthRecorder, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	waitForRampUp(te.ctx, startDelay)
+
+	client := te.newHTTPClient()
+	noOfTenants := te.config.Execution.NoOfTenants
+
+	for n := range sequence {
+		if te.interrupted() {
+			continue
+		}
+
+		tenantIndex := te.config.Execution.TenantStartNumber + n%noOfTenants
+		userIndex := te.config.Execution.UserStartNumber + n/noOfTenants
+
+		requestStart := time.Now()
+		_, err := client.CreateUser(te.ctx, tenantIndex, userIndex)
+		duration := time.Since(requestStart)
+
+		// A request aborted by the interruption is not counted
+		if err != nil && te.interrupted() {
+			continue
+		}
+
+		te.stats.IncrementUser(err == nil)
+		te.stats.RecordError(err)
+		recorder.record(n, duration, err == nil)
+
+		if err != nil {
+			printFailure("Thread %d: Failed to create user %d for tenant %d: %v\n", threadID, userIndex, tenantIndex, err)
+		}
+	}
+}
+
+// newGrowthRecorder creates a recorder for total samples in checkpoints of interval samples
+func newGrowthRecorder(title, unit string, total, interval int) *growthRecorder {
+	checkpoints := (total + interval - 1) / interval
+	return &growthRecorder{
+		title:       title,
+		unit:        unit,
+		interval:    interval,
+		checkpoints: make([][]time.Duration, checkpoints),
+		failed:      make([]int, checkpoints),
+		summaries:   make([]LatencySummary, checkpoints),
+	}
+}
+
+// record adds a result to its checkpoint and prints the checkpoint once it is complete
+func (gr *growthRecorder) record(n int, duration time.Duration, success bool) {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+
+	checkpoint := n / gr.interval
+	if success {
+		gr.checkpoints[checkpoint] = append(gr.checkpoints[checkpoint], duration)
+	} else {
+		gr.failed[checkpoint]++
+	}
+
+	if len(gr.checkpoints[checkpoint])+gr.failed[checkpoint] == gr.interval {
+		gr.closeCheckpoint(checkpoint)
+		fmt.Printf("Checkpoint %d (%d %s): %s\n",
+			checkpoint+1, (checkpoint+1)*gr.interval, strings.ToLower(gr.unit), gr.summaries[checkpoint])
+	}
+}
+
+// closeCheckpoint summarizes a checkpoint and releases its raw samples
+func (gr *growthRecorder) closeCheckpoint(checkpoint int) {
+	gr.summaries[checkpoint] = SummarizeLatencies(gr.checkpoints[checkpoint])
+	gr.checkpoints[checkpoint] = nil
+}
+
+// print prints the latency curve over the checkpoints
+func (gr *growthRecorder) print() {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+
+	header := fmt.Sprintf("=== %s ===", gr.title)
+	fmt.Println("\n" + header)
+	for checkpoint := range gr.summaries {
+		// The last checkpoint may be partial and is never closed by record
+		if gr.checkpoints[checkpoint] != nil {
+			gr.closeCheckpoint(checkpoint)
+		}
+
+		// Checkpoints an interrupted run never reached are left out
+		summary := gr.summaries[checkpoint]
+		if summary.Count+gr.failed[checkpoint] == 0 {
+			continue
+		}
+		fmt.Printf("%s %d-%d - Success: %d, Failed: %d, %s\n",
+			gr.unit, checkpoint*gr.interval+1, checkpoint*gr.interval+summary.Count+gr.failed[checkpoint],
+			summary.Count, gr.failed[checkpoint], summary)
+	}
+	fmt.Println(strings.Repeat("=", len(header)))
+}
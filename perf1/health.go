@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// healthServer serves liveness and readiness probes for running as a containerized workload
+type healthServer struct {
+	ready atomic.Bool
+}
+
+// StartHealthServer serves /healthz and /readyz on the given address. /healthz answers 200 as long
+// as the process is alive; /readyz answers 503 until SetReady is called. The address is bound before
+// returning, so a port that is taken or an invalid address is reported to the caller.
+func StartHealthServer(addr string) (*healthServer, error) {
+	hs := &healthServer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !hs.ready.Load() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ready")
+	})
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			printWarning("Health server stopped: %v\n", err)
+		}
+	}()
+
+	fmt.Printf("Health endpoints listening on %s\n", listener.Addr())
+	return hs, nil
+}
+
+// SetReady marks the workload as ready or not ready
+func (hs *healthServer) SetReady(ready bool) {
+	if hs != nil {
+		hs.ready.Store(ready)
+	}
+}
+
+// applyEnvOverrides sets every option flag of a command that was not given on the command line from
+// its environment variable, if present. The variable name is the flag name in upper snake case with
+// the ISPERF_ prefix, e.g. ISPERF_CONFIG for -config and ISPERF_HEALTH_ADDR for -health-addr. Flags
+// bound to configuration values are skipped, as applyConfigEnv overrides those under the name of
+// their configuration path, so each setting has a single variable.
+func applyEnvOverrides(fs *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	configFlags := configFlagNames()
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if explicit[f.Name] || configFlags[f.Name] || err != nil {
+			return
+		}
+
+		value, ok := os.LookupEnv(envVarName(f.Name))
+		if !ok {
+			return
+		}
+
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %v", value, envVarName(f.Name), setErr)
+		}
+	})
+
+	return err
+}
+
+// configFlagNames returns the names of the flags bound to configuration values
+func configFlagNames() map[string]bool {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	bindConfigFlags(fs, DefaultConfig())
+
+	names := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) {
+		names[f.Name] = true
+	})
+	return names
+}
+
+// envVarName converts a flag name to its environment variable name
+func envVarName(flagName string) string {
+	return envPrefix + upperSnake(flagName)
+}
+
+// upperSnake converts a camel case or dashed name to upper snake case, e.g. scimTimeout to SCIM_TIMEOUT
+func upperSnake(camel string) string {
+	var name strings.Builder
+	for i, r := range camel {
+		switch {
+		case r == '-':
+			name.WriteRune('_')
+		case unicode.IsUpper(r) && i > 0:
+			name.WriteRune('_')
+			name.WriteRune(r)
+		default:
+			name.WriteRune(unicode.ToUpper(r))
+		}
+	}
+
+	return name.String()
+}
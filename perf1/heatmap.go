@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// latencyHeatmap counts request latencies by the time bucket in which the request completed and
+// by latency bucket. It is guarded by the mutex of the TestStats it belongs to.
+type latencyHeatmap struct {
+	start    time.Time
+	interval time.Duration
+	bounds   []time.Duration // upper bounds of the latency buckets, ascending
+	rows     [][]int         // per time bucket, the count per latency bucket plus one for overflow
+}
+
+// newLatencyHeatmap creates a heatmap with time buckets of the given interval and latency buckets
+// bounded by the given milliseconds
+func newLatencyHeatmap(interval time.Duration, boundsMillis []int) (*latencyHeatmap, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("heatmap interval must be positive, got %v", interval)
+	}
+	if len(boundsMillis) == 0 {
+		return nil, fmt.Errorf("heatmap needs at least one latency bucket")
+	}
+
+	bounds := make([]time.Duration, len(boundsMillis))
+	for i, ms := range boundsMillis {
+		bounds[i] = time.Duration(ms) * time.Millisecond
+		if i > 0 && bounds[i] <= bounds[i-1] {
+			return nil, fmt.Errorf("heatmap latency buckets must be ascending, got %v", boundsMillis)
+		}
+	}
+
+	return &latencyHeatmap{start: time.Now(), interval: interval, bounds: bounds}, nil
+}
+
+// record counts a latency in the time bucket of now
+func (hm *latencyHeatmap) record(now time.Time, latency time.Duration) {
+	row := int(now.Sub(hm.start) / hm.interval)
+	for len(hm.rows) <= row {
+		hm.rows = append(hm.rows, make([]int, len(hm.bounds)+1))
+	}
+
+	bucket := len(hm.bounds)
+	for i, bound := range hm.bounds {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+	hm.rows[row][bucket]++
+}
+
+// write writes the heatmap as CSV, one row per time bucket including empty ones and one column per
+// latency bucket
+func (hm *latencyHeatmap) write(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create heatmap file: %v", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	header := []string{"time", "elapsed_seconds"}
+	for _, bound := range hm.bounds {
+		header = append(header, "le_"+bound.String())
+	}
+	header = append(header, "gt_"+hm.bounds[len(hm.bounds)-1].String())
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write heatmap header: %v", err)
+	}
+
+	for i, counts := range hm.rows {
+		offset := time.Duration(i) * hm.interval
+		record := []string{
+			hm.start.Add(offset).Format(time.RFC3339),
+			strconv.FormatFloat(offset.Seconds(), 'f', -1, 64),
+		}
+		for _, count := range counts {
+			record = append(record, strconv.Itoa(count))
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write heatmap row: %v", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write heatmap file: %v", err)
+	}
+
+	return file.Close()
+}
+
+// WriteHeatmap exports the latency heatmap of the run to the configured file, if one is configured
+func (te *TestExecutor) WriteHeatmap() error {
+	te.stats.mutex.Lock()
+	defer te.stats.mutex.Unlock()
+
+	if te.stats.heatmap == nil {
+		return nil
+	}
+
+	if err := te.stats.heatmap.write(te.config.Execution.HeatmapFile); err != nil {
+		return err
+	}
+	te.outputOptions().writeManifest(te.config.Execution.HeatmapFile)
+
+	fmt.Printf("Latency heatmap written to: %s\n", te.config.Execution.HeatmapFile)
+	return nil
+}
@@ -2,108 +2,341 @@ package main
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
+	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 // HTTPClient represents an HTTP client with authentication
 type HTTPClient struct {
-	client   *http.Client
-	config   *Config
-	username string
-	password string
+	client     *http.Client
+	soapClient *http.Client
+	config     *Config
+	stats      *TestStats
+
+	// sessions holds admin session cookies keyed by tenant username, used when SOAP session auth is enabled
+	sessions     map[string]*http.Cookie
+	sessionMutex sync.Mutex
+
+	// assertionKey signs private_key_jwt client assertions
+	assertionKey *rsa.PrivateKey
+
+	// bearer provides an externally managed access token that replaces basic auth when set
+	bearer *bearerTokenSource
+
+	// adminTokens provides access tokens for SCIM requests when an OAuth2 auth mode is set
+	adminTokens *adminTokenCache
+
+	// accessLog receives a line for every request when access logging is enabled
+	accessLog *accessLog
+
+	// locales sets the locale attributes of created users when they are configured
+	locales *localeAttributes
+	
+	// passwords gives users the passwords of their password policies when they are configured
+	passwords *passwordPolicies
+
+	// worker records where the worker using the client spent its time
+	worker *workerTime
+
+	// scheduled is when the next request was due on the schedule of the target rate, if it was paced
+	scheduled scheduleSlot
+}
+
+// StatusError is returned when the server answers a request with an unexpected HTTP status
+type StatusError struct {
+	Operation  string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // how long the server asked to wait with Retry-After, 0 if it did not
 }
 
-// NewHTTPClient creates a new HTTP client with the given configuration
-func NewHTTPClient(config *Config) *HTTPClient {
-	// Create HTTP client with TLS skip verification (for testing)
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s failed with status %d: %s", e.Operation, e.StatusCode, e.Body)
+}
+
+// NewHTTPClient creates a new HTTP client with the given configuration, sending its requests over
+// the connections of the given pool
+func NewHTTPClient(config *Config, pool *connectionPool) *HTTPClient {
+	client := &http.Client{
+		Transport: pool.scim,
+		Timeout:   time.Duration(config.Server.ScimTimeout) * time.Second,
 	}
 	
-	client := &http.Client{
-		Transport: tr,
-		Timeout:   30 * time.Second,
+	// SOAP calls get their own deadline since the admin services are slower
+	soapClient := &http.Client{
+		Transport: pool.soap,
+		Timeout:   time.Duration(config.Server.SoapTimeout) * time.Second,
 	}
 	
-	return &HTTPClient{
-		client:   client,
-		config:   config,
-		username: config.Server.Username,
-		password: config.Server.Password,
+	h := &HTTPClient{
+		client:     client,
+		soapClient: soapClient,
+		config:     config,
 	}
+	
+	// Time every request so each operation gets latency metrics in the attached statistics
+	client.Transport = &operationTransport{next: offlineGuard(config, pool.scim), owner: h}
+	soapClient.Transport = &operationTransport{next: offlineGuard(config, pool.soap), owner: h}
+	
+	return h
+}
+
+// tenantContextKey is the request context key of the tenant a request is sent for
+type tenantContextKey struct{}
+
+// withTenant returns the request sent with the credentials of the tenant's admin. The tenant
+// travels with the request rather than the client, so one client can send the requests of
+// several tenants at once.
+func withTenant(req *http.Request, tenantIndex int) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), tenantContextKey{}, tenantIndex))
+}
+
+// newTenantRequest creates a request sent with the credentials of the tenant's admin
+func newTenantRequest(ctx context.Context, tenantIndex int, method, reqURL string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return nil, err
+	}
+	return withTenant(req, tenantIndex), nil
+}
+
+// requestTenantIndex returns the tenant a request is sent for, false for a request sent with the
+// credentials of the server admin
+func requestTenantIndex(req *http.Request) (int, bool) {
+	tenantIndex, ok := req.Context().Value(tenantContextKey{}).(int)
+	return tenantIndex, ok
 }
 
-// SetTenantCredentials sets the tenant-specific credentials
-func (h *HTTPClient) SetTenantCredentials(tenantIndex int) {
-	h.username = h.config.GetTenantUsername(tenantIndex)
-	h.password = h.config.Server.Password
+// tenantCredentials returns the username and password of the tenant's admin
+func (h *HTTPClient) tenantCredentials(tenantIndex int) (string, string) {
+	return h.config.GetTenantUsername(tenantIndex), h.config.Server.Password
 }
 
-// getBasicAuthHeader returns the basic authentication header value
-func (h *HTTPClient) getBasicAuthHeader() string {
-	credentials := fmt.Sprintf("%s:%s", h.username, h.password)
+// getBasicAuthHeader returns the basic authentication header value of the request: that of the
+// admin of its tenant, or of the server admin
+func (h *HTTPClient) getBasicAuthHeader(req *http.Request) string {
+	username, password := h.config.Server.Username, h.config.Server.Password
+	if tenantIndex, ok := requestTenantIndex(req); ok {
+		username, password = h.tenantCredentials(tenantIndex)
+	}
+	credentials := fmt.Sprintf("%s:%s", username, password)
 	encoded := base64.StdEncoding.EncodeToString([]byte(credentials))
 	return "Basic " + encoded
 }
 
-// CreateRole creates a role using SOAP API
-func (h *HTTPClient) CreateRole(tenantIndex int) error {
-	h.SetTenantCredentials(tenantIndex)
+// do sends a request with basic authentication. In preemptive mode the Authorization header is
+// sent up front; otherwise the request goes out anonymously and is re-sent with credentials
+// after a 401 challenge, recording the cost of the extra round-trip. A configured bearer token
+// replaces basic authentication entirely, and an OAuth2 auth mode replaces it for SCIM requests.
+func (h *HTTPClient) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	if h.bearer != nil {
+		req.Header.Set("Authorization", "Bearer "+h.bearer.Token())
+		return client.Do(req)
+	}
 	
-	soapBody := fmt.Sprintf(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:ser="http://service.ws.um.carbon.wso2.org" xmlns:xsd="http://dao.service.ws.um.carbon.wso2.org/xsd">
-   <soapenv:Header/>
-   <soapenv:Body>
-      <ser:addRole>
-         <ser:roleName>%s</ser:roleName>       
-           <ser:permissions>
-            <xsd:action>ui.execute</xsd:action>
-            <xsd:resourceId>/permission/admin/login</xsd:resourceId>
-         </ser:permissions>
-          <ser:permissions>
-            <xsd:action>ui.execute</xsd:action>
-            <xsd:resourceId>/permission/admin/configure/</xsd:resourceId>
-         </ser:permissions>
-           <ser:permissions>
-            <xsd:action>ui.execute</xsd:action>
-             <xsd:resourceId>/permission/admin/manage/</xsd:resourceId>
-         </ser:permissions>        
-      </ser:addRole> 
-
-   </soapenv:Body>
-</soapenv:Envelope>`, h.config.Test.RoleName)
+	if h.adminTokens != nil && client == h.client {
+		return h.doWithAccessToken(client, req)
+	}
+	
+	if h.config.Server.PreemptiveAuth {
+		req.Header.Set("Authorization", h.getBasicAuthHeader(req))
+		return client.Do(req)
+	}
+	
+	challengeStart := time.Now()
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	
+	if h.stats != nil {
+		h.stats.RecordAuthChallenge(time.Since(challengeStart))
+	}
+	
+	retryReq, err := rewindRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	retryReq.Header.Set("Authorization", h.getBasicAuthHeader(retryReq))
+	
+	return client.Do(retryReq)
+}
 
-	url := fmt.Sprintf("%s/services/RemoteUserStoreManagerService", h.config.GetServerURL())
+// doWithAccessToken sends a request with the access token of its tenant. If the server rejects
+// the token, e.g. because it was revoked before its expiry, the request is re-sent once with a new one.
+func (h *HTTPClient) doWithAccessToken(client *http.Client, req *http.Request) (*http.Response, error) {
+	tenantIndex, _ := requestTenantIndex(req)
+	token, err := h.adminTokens.Token(req.Context(), h, tenantIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain access token: %v", err)
+	}
 	
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(soapBody)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	
+	h.adminTokens.Invalidate(tenantIndex, token)
+	token, err = h.adminTokens.Token(req.Context(), h, tenantIndex)
 	if err != nil {
-		return fmt.Errorf("failed to create role request: %v", err)
+		return nil, fmt.Errorf("failed to obtain access token: %v", err)
 	}
 	
-	req.Header.Set("Content-Type", "text/xml")
-	req.Header.Set("SOAPAction", "urn:addRole")
-	req.Header.Set("Authorization", h.getBasicAuthHeader())
+	retryReq, err := rewindRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	retryReq.Header.Set("Authorization", "Bearer "+token)
 	
-	resp, err := h.client.Do(req)
+	return client.Do(retryReq)
+}
+
+// rewindRequest clones a request with a fresh copy of its body so it can be sent again
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	retryReq := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body for authentication: %v", err)
+		}
+		retryReq.Body = body
+	}
+	return retryReq, nil
+}
+
+// CreateRole makes sure the test role of a tenant exists using SOAP API
+func (h *HTTPClient) CreateRole(ctx context.Context, tenantIndex int) error {
+	roleName := h.config.GetTenantRoleName(tenantIndex)
+	created, err := h.EnsureNamedRole(ctx, tenantIndex, roleName)
 	if err != nil {
-		return fmt.Errorf("failed to execute role creation request: %v", err)
+		return err
 	}
-	defer resp.Body.Close()
 	
-	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("role creation failed with status %d: %s", resp.StatusCode, string(body))
+	if !created {
+		printSuccess("Role '%s' already exists for tenant %d\n", roleName, tenantIndex)
+		return nil
 	}
+	printSuccess("Role '%s' created successfully for tenant %d\n", roleName, tenantIndex)
 	
-	fmt.Printf("Role '%s' created successfully for tenant %d\n", h.config.Test.RoleName, tenantIndex)
+	// Give the role time to reach every node before its users are created, if configured: polling
+	// until it is visible, or a fixed pause like the one of the JMX plan
+	if h.config.Execution.WaitForRole {
+		h.waitForRole(ctx, tenantIndex, roleName)
+	} else if h.config.Execution.RoleDelay > 0 {
+		h.sleep(ctx, time.Duration(h.config.Execution.RoleDelay)*time.Millisecond)
+	}
 	
-	// Add delay as in JMX (5000ms)
-	time.Sleep(5 * time.Second)
+	return nil
+}
+
+// roleWaitInterval is the pause between the checks for a created role
+const roleWaitInterval = 500 * time.Millisecond
+
+// waitForRole polls until a created role is visible, up to the role wait timeout. A role that is
+// still not found is only warned about, as addRole succeeded and its users may well find it.
+func (h *HTTPClient) waitForRole(ctx context.Context, tenantIndex int, roleName string) {
+	start := time.Now()
+	deadline := start.Add(time.Duration(h.config.Execution.RoleWaitTimeout) * time.Second)
+	for {
+		exists, err := h.NamedRoleExists(ctx, tenantIndex, roleName)
+		if err == nil && exists {
+			return
+		}
+		if time.Now().After(deadline) {
+			printWarning("Role '%s' of tenant %d is not visible %v after it was created, creating its users anyway\n", roleName, tenantIndex, time.Since(start).Round(time.Second))
+			return
+		}
+		if !h.sleep(ctx, roleWaitInterval) {
+			return
+		}
+	}
+}
+
+// EnsureNamedRole makes sure a role with the given name exists using SOAP API and returns whether
+// it created the role. The role is checked before every attempt, so an existing role counts as
+// success and a timed out attempt that created it after all is not repeated. Any failed attempt is
+// therefore retried, up to the configured role attempts with the backoff of the retry policy.
+// A role that existed before the first attempt, found by the check or by a RoleAlreadyExisting
+// fault, fails with ErrRoleExists unless existing roles are configured as success.
+func (h *HTTPClient) EnsureNamedRole(ctx context.Context, tenantIndex int, roleName string) (bool, error) {
+	attempts := h.config.Retry.RoleAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	
+	var createErr error
+	for attempt := 1; ; attempt++ {
+		// A failed check leaves it open whether the role exists, so creation goes ahead
+		exists, checkErr := h.NamedRoleExists(ctx, tenantIndex, roleName)
+		if checkErr == nil && exists {
+			return h.existingRole(attempt, ErrRoleExists)
+		}
+		if attempt > attempts {
+			// Only a role known to be missing is reported as such
+			if checkErr != nil {
+				return false, createErr
+			}
+			return false, fmt.Errorf("%w after %d attempts: %w", ErrRoleMissing, attempts, createErr)
+		}
+		
+		if createErr = h.CreateNamedRole(ctx, tenantIndex, roleName); createErr == nil {
+			return true, nil
+		}
+		
+		// The check can miss a role created meanwhile, which the service then rejects with a fault
+		var fault *SOAPFaultError
+		if errors.As(createErr, &fault) && fault.alreadyExists() {
+			return h.existingRole(attempt, fmt.Errorf("%w: %w", ErrRoleExists, createErr))
+		}
+		if attempt < attempts {
+			if h.stats != nil {
+				h.stats.RecordRetry()
+			}
+			if !h.backOff(ctx, attempt) {
+				return false, createErr
+			}
+		}
+	}
+}
+
+// existingRole returns the outcome of ensuring a role that turned out to exist. After a failed
+// attempt the role was most likely created by it, otherwise it was there before.
+func (h *HTTPClient) existingRole(attempt int, existsErr error) (bool, error) {
+	if attempt > 1 {
+		return true, nil
+	}
+	if h.config.Test.ExistingRoleOK {
+		return false, nil
+	}
+	return false, existsErr
+}
+
+// CreateNamedRole creates a role with the given name and its configured permissions using SOAP API
+func (h *HTTPClient) CreateNamedRole(ctx context.Context, tenantIndex int, roleName string) error {
+	soapBody := addRoleEnvelope(roleName, h.config.rolePermissions(roleName))
+	
+	if _, err := h.callUserStoreManager(ctx, tenantIndex, "addRole", soapBody); err != nil {
+		return fmt.Errorf("role creation failed: %w", err)
+	}
 	
 	return nil
 }
@@ -117,6 +350,11 @@ type SCIMUser struct {
 	Wso2Extension SCIMWso2Ext `json:"wso2Extension"`
 	Emails       []SCIMEmail `json:"emails"`
 	Roles        []SCIMRole  `json:"roles"`
+
+	// Locale, Timezone and PreferredLanguage are only sent when configured
+	Locale            string `json:"locale,omitempty"`
+	Timezone          string `json:"timezone,omitempty"`
+	PreferredLanguage string `json:"preferredLanguage,omitempty"`
 }
 
 // SCIMName represents the name part of SCIM user
@@ -128,6 +366,19 @@ type SCIMName struct {
 // SCIMWso2Ext represents WSO2 extension for SCIM user
 type SCIMWso2Ext struct {
 	AccountLocked string `json:"accountLocked"`
+
+	// Custom holds additional custom attributes, serialized alongside accountLocked
+	Custom map[string]string `json:"-"`
+}
+
+// MarshalJSON flattens the custom attributes into the extension object
+func (e SCIMWso2Ext) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]string, len(e.Custom)+1)
+	for name, value := range e.Custom {
+		fields[name] = value
+	}
+	fields["accountLocked"] = e.AccountLocked
+	return json.Marshal(fields)
 }
 
 // SCIMEmail represents email in SCIM user
@@ -149,23 +400,111 @@ type SCIMUserResponse struct {
 	UserName string `json:"userName"`
 }
 
-func (h *HTTPClient) CreateUser(tenantIndex, userIndex int) (*SCIMUserResponse, error) {
+func (h *HTTPClient) CreateUser(ctx context.Context, tenantIndex, userIndex int) (*SCIMUserResponse, error) {
 	username := h.config.GetTestUsername(userIndex)
-	return h.CreateUserWithName(tenantIndex, username)
+	return h.CreateUserWithName(ctx, tenantIndex, username)
+}
+// CreateUserWithName creates a user with the given name and the test role, varied by the configured
+// payload variants, using SCIM2 API
+func (h *HTTPClient) CreateUserWithName(ctx context.Context, tenantIndex int, username string) (*SCIMUserResponse, error) {
+	return h.createUser(ctx, tenantIndex, h.newVariantUser(tenantIndex, username))
+}
+
+// CreateUserWithRoles creates a user with the given name and roles using SCIM2 API
+func (h *HTTPClient) CreateUserWithRoles(ctx context.Context, tenantIndex int, username string, roleNames []string) (*SCIMUserResponse, error) {
+	return h.createUser(ctx, tenantIndex, h.newSCIMUser(tenantIndex, username, roleNames, nil))
+}
+
+// CreateUserWithAttributes creates a user with the test role and the given custom attributes using SCIM2 API
+func (h *HTTPClient) CreateUserWithAttributes(ctx context.Context, tenantIndex int, username string, attributes map[string]string) (*SCIMUserResponse, error) {
+	return h.createUser(ctx, tenantIndex, h.newSCIMUser(tenantIndex, username, []string{h.config.GetTenantRoleName(tenantIndex)}, attributes))
+}
+
+// createUser creates the given user using SCIM2 API
+func (h *HTTPClient) createUser(ctx context.Context, tenantIndex int, user SCIMUser) (*SCIMUserResponse, error) {
+	username := user.UserName
+	
+	userJSON, err := json.Marshal(h.config.userPayload(user))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal user JSON: %v", err)
+	}
+	
+	// Transient server errors are retried according to the retry policy
+	var userResp *SCIMUserResponse
+	attempts := 0
+	err = h.withRetry(ctx, func() error {
+		var err error
+		attempts++
+		userResp, err = h.postUser(ctx, tenantIndex, username, userJSON)
+		if attempts > 1 && isStatus(err, http.StatusConflict) {
+			// The failed attempt before created the user after all
+			if id, findErr := h.FindUserID(ctx, tenantIndex, username); findErr == nil && id != "" {
+				userResp, err = &SCIMUserResponse{ID: id, UserName: username}, nil
+			}
+		}
+		return err
+	})
+	return userResp, err
+}
+
+// postUser sends a SCIM2 user creation request with the given payload
+func (h *HTTPClient) postUser(ctx context.Context, tenantIndex int, username string, userJSON []byte) (*SCIMUserResponse, error) {
+	url := h.config.GetSCIMURL("/Users", tenantIndex)
+	
+	req, err := newTenantRequest(ctx, tenantIndex, "POST", url, bytes.NewBuffer(userJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user request: %v", err)
+	}
+	
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute user creation request: %v", err)
+	}
+	defer resp.Body.Close()
+	
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Operation: "user creation", StatusCode: resp.StatusCode, Body: string(body), RetryAfter: retryAfter(resp)}
+	}
+	
+	var userResp SCIMUserResponse
+	if err := json.Unmarshal(body, &userResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user response: %v", err)
+	}
+	
+	// Verify the username in response
+	if userResp.UserName != username {
+		return nil, fmt.Errorf("username mismatch in response: expected %s, got %s", username, userResp.UserName)
+	}
+	
+	// fmt.Printf("User '%s' created successfully for tenant %d with SCIM ID: %s\n", username, tenantIndex, userResp.ID)
+	
+	return &userResp, nil
 }
-// CreateUser creates a user using SCIM2 API
-func (h *HTTPClient) CreateUserWithName(tenantIndex int, username string) (*SCIMUserResponse, error) {
-	h.SetTenantCredentials(tenantIndex)		
-	user := SCIMUser{
+
+// newSCIMUser builds the SCIM user payload of a test user with the given roles and custom attributes
+func (h *HTTPClient) newSCIMUser(tenantIndex int, username string, roleNames []string, attributes map[string]string) SCIMUser {
+	roles := make([]SCIMRole, 0, len(roleNames))
+	for _, roleName := range roleNames {
+		roles = append(roles, SCIMRole{Type: "default", Value: roleName})
+	}
+	
+	return SCIMUser{
 		Schemas:  []string{},
 		UserName: username,
-		Password: h.config.Test.UserPassword,
+		Password: h.userPassword(tenantIndex, username),
 		Name: SCIMName{
 			FamilyName: h.config.Test.UsernamePrefix + "Family",
 			GivenName:  h.config.Test.UsernamePrefix + "givenName",
 		},
 		Wso2Extension: SCIMWso2Ext{
 			AccountLocked: "false",
+			Custom:        attributes,
 		},
 		Emails: []SCIMEmail{
 			{
@@ -178,55 +517,718 @@ func (h *HTTPClient) CreateUserWithName(tenantIndex int, username string) (*SCIM
 				Type:  "work",
 			},
 		},
-		Roles: []SCIMRole{
-			{
-				Type:  "default",
-				Value: h.config.Test.RoleName,
-			},
+		Roles: roles,
+	}
+}
+
+// SCIMGroup represents a SCIM2 group payload
+type SCIMGroup struct {
+	Schemas     []string          `json:"schemas"`
+	DisplayName string            `json:"displayName"`
+	Members     []SCIMGroupMember `json:"members,omitempty"`
+}
+
+// SCIMGroupMember represents a member reference in a SCIM2 group
+type SCIMGroupMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// SCIMGroupResponse represents the response from SCIM2 group creation
+type SCIMGroupResponse struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+}
+
+// SCIMPatchOp represents a SCIM2 PATCH request
+type SCIMPatchOp struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation represents a single operation in a SCIM2 PATCH request
+type SCIMPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// CreateGroup creates a group using SCIM2 API
+func (h *HTTPClient) CreateGroup(ctx context.Context, tenantIndex int, displayName string) (*SCIMGroupResponse, error) {
+	group := SCIMGroup{
+		Schemas:     []string{"urn:ietf:params:scim:schemas:core:2.0:Group"},
+		DisplayName: displayName,
+	}
+
+	body, err := h.sendSCIMJSON(ctx, tenantIndex, "POST", "/Groups", "group creation", group, http.StatusCreated, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var groupResp SCIMGroupResponse
+	if err := json.Unmarshal(body, &groupResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal group response: %v", err)
+	}
+
+	return &groupResp, nil
+}
+
+// AddGroupMembers adds a batch of members to a group using a SCIM2 PATCH request
+func (h *HTTPClient) AddGroupMembers(ctx context.Context, tenantIndex int, groupID string, members []SCIMGroupMember) error {
+	patch := SCIMPatchOp{
+		Schemas: []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+		Operations: []SCIMPatchOperation{
+			{Op: "add", Path: "members", Value: members},
+		},
+	}
+
+	_, err := h.sendSCIMJSON(ctx, tenantIndex, "PATCH", "/Groups/"+groupID, "group member add", patch, http.StatusOK, http.StatusNoContent)
+	return err
+}
+
+// SCIMGroupListResponse represents the response from a SCIM2 group search
+type SCIMGroupListResponse struct {
+	TotalResults int                 `json:"totalResults"`
+	Resources    []SCIMGroupResponse `json:"Resources"`
+}
+
+// SearchGroupsByPrefix returns one page of the groups whose display name starts with a prefix,
+// using a SCIM2 filter. startIndex is 1-based as in SCIM.
+func (h *HTTPClient) SearchGroupsByPrefix(ctx context.Context, tenantIndex int, prefix string, startIndex, count int) (*SCIMGroupListResponse, error) {
+	query := url.Values{}
+	query.Set("filter", fmt.Sprintf("displayName sw %q", prefix))
+	query.Set("attributes", "displayName")
+	query.Set("startIndex", strconv.Itoa(startIndex))
+	query.Set("count", strconv.Itoa(count))
+	reqURL := h.config.GetSCIM2URL("/Groups?"+query.Encode(), tenantIndex)
+
+	req, err := newTenantRequest(ctx, tenantIndex, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create group search request: %v", err)
+	}
+
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute group search request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	// Older SCIM implementations answer an empty search with 404
+	if resp.StatusCode == http.StatusNotFound {
+		return &SCIMGroupListResponse{}, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Operation: "group search", StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var listResp SCIMGroupListResponse
+	if err := json.Unmarshal(body, &listResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal group search response: %v", err)
+	}
+
+	return &listResp, nil
+}
+
+// GroupExists checks whether a group with the given SCIM ID exists using SCIM2 API
+func (h *HTTPClient) GroupExists(ctx context.Context, tenantIndex int, groupID string) (bool, error) {
+	req, err := newTenantRequest(ctx, tenantIndex, "GET", h.config.GetSCIM2URL("/Groups/"+groupID+"?attributes=id", tenantIndex), nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create group read request: %v", err)
+	}
+
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute group read request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+
+	return false, fmt.Errorf("group read failed with status %d: %s", resp.StatusCode, string(body))
+}
+
+// DeleteGroup deletes a group by SCIM ID using SCIM2 API, treating a missing group as already deleted
+func (h *HTTPClient) DeleteGroup(ctx context.Context, tenantIndex int, groupID string) error {
+	req, err := newTenantRequest(ctx, tenantIndex, "DELETE", h.config.GetSCIM2URL("/Groups/"+groupID, tenantIndex), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create group deletion request: %v", err)
+	}
+
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return fmt.Errorf("failed to execute group deletion request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return &StatusError{Operation: "group deletion", StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return nil
+}
+
+// sendSCIMJSON sends a JSON payload to a resource under the tenant's SCIM2 base path and returns
+// the response body, failing with a StatusError unless the response has one of the accepted status
+// codes
+func (h *HTTPClient) sendSCIMJSON(ctx context.Context, tenantIndex int, method, path, operation string, payload interface{}, accepted ...int) ([]byte, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s JSON: %v", operation, err)
+	}
+
+	req, err := newTenantRequest(ctx, tenantIndex, method, h.config.GetSCIM2URL(path, tenantIndex), bytes.NewBuffer(payloadJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %v", operation, err)
+	}
+
+	req.Header.Set("Content-Type", "application/scim+json")
+
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s request: %v", operation, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	for _, statusCode := range accepted {
+		if resp.StatusCode == statusCode {
+			return body, nil
+		}
+	}
+
+	return nil, &StatusError{Operation: operation, StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+// SCIMListResponse represents the response from a SCIM user search
+type SCIMListResponse struct {
+	TotalResults int                `json:"totalResults"`
+	Resources    []SCIMUserResponse `json:"Resources"`
+}
+
+// FindUserID looks up the SCIM ID of a user by username, returning an empty ID if the user does not exist
+func (h *HTTPClient) FindUserID(ctx context.Context, tenantIndex int, username string) (string, error) {
+	filter := url.QueryEscape(fmt.Sprintf("userName eq %q", username))
+	reqURL := h.config.GetSCIMURL("/Users?filter="+filter, tenantIndex)
+
+	req, err := newTenantRequest(ctx, tenantIndex, "GET", reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create user search request: %v", err)
+	}
+
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute user search request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	// Older SCIM implementations answer an empty search with 404
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("user search failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var listResp SCIMListResponse
+	if err := json.Unmarshal(body, &listResp); err != nil {
+		return "", fmt.Errorf("failed to unmarshal user search response: %v", err)
+	}
+
+	for _, user := range listResp.Resources {
+		if user.UserName == username {
+			return user.ID, nil
+		}
+	}
+
+	return "", nil
+}
+
+// UserReadResult holds the outcome of a user GET
+type UserReadResult struct {
+	StatusCode int
+	ETag       string
+}
+
+// SearchUsersByPrefix returns one page of the users whose username starts with a prefix, using a
+// SCIM2 filter. startIndex is 1-based as in SCIM.
+func (h *HTTPClient) SearchUsersByPrefix(ctx context.Context, tenantIndex int, prefix string, startIndex, count int) (*SCIMListResponse, error) {
+	query := url.Values{}
+	query.Set("filter", fmt.Sprintf("userName sw %q", prefix))
+	query.Set("attributes", "userName")
+	query.Set("startIndex", strconv.Itoa(startIndex))
+	query.Set("count", strconv.Itoa(count))
+	reqURL := h.config.GetSCIM2URL("/Users?"+query.Encode(), tenantIndex)
+
+	req, err := newTenantRequest(ctx, tenantIndex, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user search request: %v", err)
+	}
+
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute user search request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Operation: "user search", StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var listResp SCIMListResponse
+	if err := json.Unmarshal(body, &listResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user search response: %v", err)
+	}
+
+	return &listResp, nil
+}
+
+// UserExists checks whether a user with the given SCIM ID exists
+func (h *HTTPClient) UserExists(ctx context.Context, tenantIndex int, scimID string) (bool, error) {
+	reqURL := h.config.GetSCIMURL("/Users/"+scimID, tenantIndex)
+
+	req, err := newTenantRequest(ctx, tenantIndex, "GET", reqURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create user read request: %v", err)
+	}
+
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute user read request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+
+	return false, fmt.Errorf("user read failed with status %d: %s", resp.StatusCode, string(body))
+}
+
+// GetUser reads a user by SCIM ID under the SCIM base path. A non-empty etag is sent as
+// If-None-Match so the server can answer 304 Not Modified; cache busting appends a unique query
+// parameter to defeat HTTP caches.
+func (h *HTTPClient) GetUser(ctx context.Context, tenantIndex int, scimID, etag string) (*UserReadResult, error) {
+	return h.readUser(ctx, tenantIndex, h.config.GetSCIMURL("/Users/"+scimID, tenantIndex), etag)
+}
+
+// GetSCIM2User reads a user by SCIM ID under the SCIM2 base path, like GetUser. Its ETag is the
+// version UpdateUser compares If-Match with, as both go to the same endpoint.
+func (h *HTTPClient) GetSCIM2User(ctx context.Context, tenantIndex int, scimID, etag string) (*UserReadResult, error) {
+	return h.readUser(ctx, tenantIndex, h.config.GetSCIM2URL("/Users/"+scimID, tenantIndex), etag)
+}
+
+// readUser reads the user at the given URL
+func (h *HTTPClient) readUser(ctx context.Context, tenantIndex int, reqURL, etag string) (*UserReadResult, error) {
+	if h.config.Read.CacheBusting {
+		reqURL = fmt.Sprintf("%s?_=%d", reqURL, time.Now().UnixNano())
+	}
+
+	req, err := newTenantRequest(ctx, tenantIndex, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user read request: %v", err)
+	}
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute user read request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified {
+		return nil, fmt.Errorf("user read failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	result := &UserReadResult{
+		StatusCode: resp.StatusCode,
+		ETag:       resp.Header.Get("ETag"),
+	}
+
+	// A 304 may omit the validator, in which case the cached one is still current
+	if result.ETag == "" {
+		result.ETag = etag
+	}
+
+	return result, nil
+}
+
+// UserUpdateResult holds the outcome of a user PATCH
+type UserUpdateResult struct {
+	StatusCode int
+	ETag       string
+}
+
+// UpdateUser replaces attributes of a user with a SCIM2 PATCH. A non-empty etag is sent as If-Match,
+// so the server rejects the update with 412 Precondition Failed when the user has changed since;
+// a 412 is returned as a result rather than an error so callers can tell it apart from failures.
+func (h *HTTPClient) UpdateUser(ctx context.Context, tenantIndex int, scimID, etag string, attributes map[string]interface{}) (*UserUpdateResult, error) {
+	patch := SCIMPatchOp{
+		Schemas: []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+		Operations: []SCIMPatchOperation{
+			{Op: "replace", Value: attributes},
 		},
 	}
+
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal user update JSON: %v", err)
+	}
+
+	reqURL := h.config.GetSCIM2URL("/Users/"+scimID, tenantIndex)
+	req, err := newTenantRequest(ctx, tenantIndex, "PATCH", reqURL, bytes.NewBuffer(patchJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user update request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/scim+json")
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute user update request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusPreconditionFailed:
+		return &UserUpdateResult{StatusCode: resp.StatusCode, ETag: resp.Header.Get("ETag")}, nil
+	}
+
+	return nil, &StatusError{Operation: "user update", StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+// DeleteUser deletes a user by username using SCIM2 API, treating a missing user as already deleted
+func (h *HTTPClient) DeleteUser(ctx context.Context, tenantIndex int, username string) error {
+	scimID, err := h.FindUserID(ctx, tenantIndex, username)
+	if err != nil {
+		return err
+	}
+
+	if scimID == "" {
+		return nil
+	}
+
+	return h.DeleteUserByID(ctx, tenantIndex, scimID)
+}
+
+// DeleteUserByID deletes a user by SCIM ID using SCIM2 API, treating a missing user as already deleted
+func (h *HTTPClient) DeleteUserByID(ctx context.Context, tenantIndex int, scimID string) error {
+	reqURL := h.config.GetSCIMURL("/Users/"+scimID, tenantIndex)
+
+	req, err := newTenantRequest(ctx, tenantIndex, "DELETE", reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create user deletion request: %v", err)
+	}
+
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return fmt.Errorf("failed to execute user deletion request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("user deletion failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// newSOAPRequest builds a SOAP POST request honouring the SOAP transport options
+func (h *HTTPClient) newSOAPRequest(ctx context.Context, service, action, soapBody string) (*http.Request, error) {
+	reqURL := fmt.Sprintf("%s/services/%s", h.config.GetServerURL(), service)
+
+	// Hiding the body type from http.NewRequest leaves the length unknown, which forces chunked encoding
+	var body io.Reader = strings.NewReader(soapBody)
+	if h.config.Server.SoapChunked {
+		body = io.MultiReader(body)
+	}
 	
-	userJSON, err := json.Marshal(user)
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal user JSON: %v", err)
+		return nil, fmt.Errorf("failed to create %s request: %v", action, err)
+	}
+	
+	// Allow the body to be re-sent after an authentication challenge
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(soapBody)), nil
+	}
+	
+	if !h.config.Server.SoapKeepAlive {
+		req.Close = true
+	}
+
+	req.Header.Set("Content-Type", "text/xml")
+	req.Header.Set("SOAPAction", "urn:"+action)
+	return req, nil
+}
+
+// callUserStoreManager posts a SOAP envelope to the RemoteUserStoreManagerService of the tenant and
+// returns the response body
+func (h *HTTPClient) callUserStoreManager(ctx context.Context, tenantIndex int, action, soapBody string) (string, error) {
+	if h.config.Server.SoapSessionAuth {
+		return h.callUserStoreManagerWithSession(ctx, tenantIndex, action, soapBody)
 	}
 	
-	url := fmt.Sprintf("%s/wso2/scim/Users", h.config.GetServerURL())
+	req, err := h.newSOAPRequest(ctx, "RemoteUserStoreManagerService", action, soapBody)
+	if err != nil {
+		return "", err
+	}
 	
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(userJSON))
+	resp, err := h.do(h.soapClient, withTenant(req, tenantIndex))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user request: %v", err)
+		return "", fmt.Errorf("failed to execute %s request: %v", action, err)
 	}
 	
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", h.getBasicAuthHeader())
+	return readSOAPResponse(action, resp)
+}
+
+// callUserStoreManagerWithSession posts a SOAP envelope using the admin session cookie of the
+// tenant, logging in again once if the session has expired
+func (h *HTTPClient) callUserStoreManagerWithSession(ctx context.Context, tenantIndex int, action, soapBody string) (string, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		cookie, err := h.sessionCookie(ctx, tenantIndex, attempt > 0)
+		if err != nil {
+			return "", err
+		}
+		
+		req, err := h.newSOAPRequest(ctx, "RemoteUserStoreManagerService", action, soapBody)
+		if err != nil {
+			return "", err
+		}
+		req = withTenant(req, tenantIndex)
+		req.AddCookie(cookie)
+		
+		resp, err := h.soapClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("failed to execute %s request: %v", action, err)
+		}
+		
+		// An expired session is rejected as unauthenticated; retry once with a fresh login
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			continue
+		}
+		
+		return readSOAPResponse(action, resp)
+	}
 	
-	resp, err := h.client.Do(req)
+	return "", fmt.Errorf("%s failed: admin session rejected after re-login", action)
+}
+
+// AuthenticateUser logs a tenant user in via the AuthenticationAdmin service
+func (h *HTTPClient) AuthenticateUser(ctx context.Context, tenantIndex int, username, password string) error {
+	tenantUsername := h.config.GetTenantQualifiedUsername(username, tenantIndex)
+	
+	soapBody := loginEnvelope(tenantUsername, password)
+
+	req, err := h.newSOAPRequest(ctx, "AuthenticationAdmin", "login", soapBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute user creation request: %v", err)
+		return err
 	}
-	defer resp.Body.Close()
 	
-	body, err := io.ReadAll(resp.Body)
+	resp, err := h.soapClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return fmt.Errorf("failed to execute login request: %v", err)
 	}
 	
-	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("user creation failed with status %d: %s", resp.StatusCode, string(body))
+	body, err := readSOAPResponse("login", resp)
+	if err != nil {
+		return err
 	}
 	
-	var userResp SCIMUserResponse
-	if err := json.Unmarshal(body, &userResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal user response: %v", err)
+	if !strings.Contains(body, ">true<") {
+		return fmt.Errorf("login rejected for %s", tenantUsername)
 	}
 	
-	// Verify the username in response
-	if userResp.UserName != username {
-		return nil, fmt.Errorf("username mismatch in response: expected %s, got %s", username, userResp.UserName)
+	return nil
+}
+
+// sessionCookie returns the admin session cookie for the tenant, logging in via the
+// AuthenticationAdmin service when no session exists yet or a refresh is forced
+func (h *HTTPClient) sessionCookie(ctx context.Context, tenantIndex int, refresh bool) (*http.Cookie, error) {
+	username, password := h.tenantCredentials(tenantIndex)
+	
+	h.sessionMutex.Lock()
+	cookie, ok := h.sessions[username]
+	h.sessionMutex.Unlock()
+	if ok && !refresh {
+		return cookie, nil
 	}
 	
-	// fmt.Printf("User '%s' created successfully for tenant %d with SCIM ID: %s\n", username, tenantIndex, userResp.ID)
+	soapBody := loginEnvelope(username, password)
+
+	req, err := h.newSOAPRequest(ctx, "AuthenticationAdmin", "login", soapBody)
+	if err != nil {
+		return nil, err
+	}
 	
-	return &userResp, nil
+	resp, err := h.soapClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute admin login request: %v", err)
+	}
+	
+	var sessionCookie *http.Cookie
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "JSESSIONID" {
+			sessionCookie = cookie
+		}
+	}
+	
+	body, err := readSOAPResponse("login", resp)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !strings.Contains(body, ">true<") || sessionCookie == nil {
+		return nil, fmt.Errorf("admin login failed for %s: %s", username, body)
+	}
+	
+	h.sessionMutex.Lock()
+	defer h.sessionMutex.Unlock()
+	if h.sessions == nil {
+		h.sessions = make(map[string]*http.Cookie)
+	}
+	h.sessions[username] = sessionCookie
+	
+	return sessionCookie, nil
+}
+
+// loginEnvelope builds the AuthenticationAdmin login SOAP envelope
+func loginEnvelope(username, password string) string {
+	return fmt.Sprintf(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:aut="http://authentication.services.core.carbon.wso2.org">
+   <soapenv:Header/>
+   <soapenv:Body>
+      <aut:login>
+         <aut:username>%s</aut:username>
+         <aut:password>%s</aut:password>
+         <aut:remoteAddress>127.0.0.1</aut:remoteAddress>
+      </aut:login>
+   </soapenv:Body>
+</soapenv:Envelope>`, html.EscapeString(username), html.EscapeString(password))
+}
+
+// readSOAPResponse reads and closes a SOAP response, returning its body if the call succeeded. A
+// SOAP fault fails the call with a SOAPFaultError whatever the status of the response.
+func readSOAPResponse(action string, resp *http.Response) (string, error) {
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if fault := parseSOAPFault(action, resp.StatusCode, string(respBody)); fault != nil {
+		return "", fault
+	}
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return "", &StatusError{Operation: action, StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return string(respBody), nil
+}
+
+// RoleExists checks whether the test role exists using SOAP API
+func (h *HTTPClient) RoleExists(ctx context.Context, tenantIndex int) (bool, error) {
+	return h.NamedRoleExists(ctx, tenantIndex, h.config.GetTenantRoleName(tenantIndex))
+}
+
+// NamedRoleExists checks whether a role with the given name exists using SOAP API
+func (h *HTTPClient) NamedRoleExists(ctx context.Context, tenantIndex int, roleName string) (bool, error) {
+	soapBody := fmt.Sprintf(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:ser="http://service.ws.um.carbon.wso2.org">
+   <soapenv:Header/>
+   <soapenv:Body>
+      <ser:isExistingRole>
+         <ser:roleName>%s</ser:roleName>
+      </ser:isExistingRole>
+   </soapenv:Body>
+</soapenv:Envelope>`, html.EscapeString(roleName))
+
+	body, err := h.callUserStoreManager(ctx, tenantIndex, "isExistingRole", soapBody)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.Contains(body, ">true<"), nil
+}
+
+// DeleteRole deletes the test role using SOAP API, treating a missing role as already deleted
+func (h *HTTPClient) DeleteRole(ctx context.Context, tenantIndex int) error {
+	return h.DeleteNamedRole(ctx, tenantIndex, h.config.GetTenantRoleName(tenantIndex))
+}
+
+// DeleteNamedRole deletes the role with the given name using SOAP API, treating a missing role as
+// already deleted
+func (h *HTTPClient) DeleteNamedRole(ctx context.Context, tenantIndex int, roleName string) error {
+	exists, err := h.NamedRoleExists(ctx, tenantIndex, roleName)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	soapBody := fmt.Sprintf(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:ser="http://service.ws.um.carbon.wso2.org">
+   <soapenv:Header/>
+   <soapenv:Body>
+      <ser:deleteRole>
+         <ser:roleName>%s</ser:roleName>
+      </ser:deleteRole>
+   </soapenv:Body>
+</soapenv:Envelope>`, html.EscapeString(roleName))
+
+	_, err = h.callUserStoreManager(ctx, tenantIndex, "deleteRole", soapBody)
+	return err
 }
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oidcAuthenticatorID is the ID of the OpenID Connect federated authenticator in the IdP management
+// API, the base64url encoded authenticator name
+const oidcAuthenticatorID = "T3BlbklEQ29ubmVjdEF1dGhlbnRpY2F0b3I"
+
+// ExecuteIdPPhase runs identity provider creation on its own and prints the statistics
+func (te *TestExecutor) ExecuteIdPPhase() error {
+	if err := te.ExecuteIdPCreation(); err != nil {
+		return err
+	}
+
+	te.stats.PrintStats()
+	return nil
+}
+
+// ExecuteIdPCreation creates the configured number of identity providers in every tenant using
+// multiple threads and records their IDs to CSV, so the phases after it measure login with that
+// many federation configurations in place
+func (te *TestExecutor) ExecuteIdPCreation() error {
+	fmt.Println("Starting identity provider creation phase...")
+
+	cfg := te.config.IdPs
+	if cfg.PerTenant < 1 {
+		return fmt.Errorf("identity providers per tenant must be positive, got %d", cfg.PerTenant)
+	}
+
+	idpWriter, err := NewCSVWriter(cfg.CsvPath)
+	if err != nil {
+		return fmt.Errorf("failed to create IdP ID CSV writer: %v", err)
+	}
+	idpWriter.csvOutputOptions = te.outputOptions()
+
+	var wg sync.WaitGroup
+
+	startTime := time.Now()
+	for _, task := range te.perTenantTasks(cfg.PerTenant) {
+		task.Client = te.newHTTPClient()
+
+		wg.Add(1)
+		go te.idpCreationWorker(task, idpWriter, &wg)
+	}
+
+	wg.Wait()
+
+	if err := idpWriter.Close(); err != nil {
+		printFailure("Failed to close IdP ID CSV writer: %v\n", err)
+	}
+
+	if te.interrupted() {
+		return ErrInterrupted
+	}
+	fmt.Printf("Identity provider creation completed in %v\n", time.Since(startTime))
+	return nil
+}
+
+// idpCreationWorker creates the identity providers of the task's range, carried in its user range,
+// for all of the task's tenants
+func (te *TestExecutor) idpCreationWorker(task WorkerTask, idpWriter *CSVWriter, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	waitForRampUp(te.ctx, task.StartDelay)
+
+	startTime := time.Now()
+	fmt.Printf("Thread %d: Creating identity providers %d-%d for tenants %d-%d\n",
+		task.ThreadID, task.UserStart, task.UserEnd, task.TenantStart, task.TenantEnd-1)
+
+	for idpIndex := task.UserStart; idpIndex <= task.UserEnd && !te.interrupted(); idpIndex++ {
+		for tenantIndex := task.TenantStart; tenantIndex < task.TenantEnd && !te.interrupted(); tenantIndex++ {
+			id, err := task.Client.CreateIdP(te.ctx, tenantIndex, te.config.GetTestIdPName(idpIndex))
+			if err != nil && te.interrupted() {
+				return
+			}
+			te.stats.IncrementIdP(err == nil)
+			te.stats.RecordError(err)
+
+			if err != nil {
+				printFailure("Thread %d: Failed to create identity provider %d for tenant %d: %v\n",
+					task.ThreadID, idpIndex, tenantIndex, err)
+				continue
+			}
+
+			if err := idpWriter.WriteScimID(tenantIndex, id); err != nil {
+				printFailure("Failed to write IdP ID to CSV: %v\n", err)
+			}
+		}
+	}
+
+	fmt.Printf("Thread %d: Completed identity providers %d-%d for tenants %d-%d in %v\n",
+		task.ThreadID, task.UserStart, task.UserEnd, task.TenantStart, task.TenantEnd-1, time.Since(startTime))
+}
+
+// IdP is an identity provider of the IdP management API, with the fields the client sets
+type IdP struct {
+	ID                      string                      `json:"id,omitempty"`
+	Name                    string                      `json:"name"`
+	Description             string                      `json:"description,omitempty"`
+	IsPrimary               bool                        `json:"isPrimary"`
+	IsFederationHub         bool                        `json:"isFederationHub"`
+	IdPIssuerName           string                      `json:"idpIssuerName,omitempty"`
+	Certificate             *IdPCertificate             `json:"certificate,omitempty"`
+	FederatedAuthenticators *IdPFederatedAuthenticators `json:"federatedAuthenticators,omitempty"`
+}
+
+// IdPCertificate holds the keys the ID tokens of an identity provider are verified with
+type IdPCertificate struct {
+	JWKSURI string `json:"jwksUri"`
+}
+
+// IdPFederatedAuthenticators lists the federated authenticators of an identity provider
+type IdPFederatedAuthenticators struct {
+	DefaultAuthenticatorID string             `json:"defaultAuthenticatorId"`
+	Authenticators         []IdPAuthenticator `json:"authenticators"`
+}
+
+// IdPAuthenticator configures a federated authenticator of an identity provider
+type IdPAuthenticator struct {
+	AuthenticatorID string        `json:"authenticatorId"`
+	IsEnabled       bool          `json:"isEnabled"`
+	Properties      []IdPProperty `json:"properties"`
+}
+
+// IdPProperty is a key and value of a federated authenticator
+type IdPProperty struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// IdPListResponse represents the response of an identity provider search
+type IdPListResponse struct {
+	TotalResults      int   `json:"totalResults"`
+	IdentityProviders []IdP `json:"identityProviders"`
+}
+
+// newTestIdP builds an identity provider federating to the stub IdP, when its URL is configured, or
+// else to an OpenID Connect provider of its own, with endpoints that are never called since no user
+// logs in through it
+func (h *HTTPClient) newTestIdP(tenantIndex int, name string) IdP {
+	issuer := fmt.Sprintf("https://%s.idp.example.com/oauth2", url.PathEscape(name))
+	if h.config.StubIdP.URL != "" {
+		issuer = strings.TrimSuffix(h.config.StubIdP.URL, "/")
+	}
+
+	idp := IdP{
+		Name:          name,
+		Description:   "go-perf test identity provider",
+		IdPIssuerName: issuer,
+		FederatedAuthenticators: &IdPFederatedAuthenticators{
+			DefaultAuthenticatorID: oidcAuthenticatorID,
+			Authenticators: []IdPAuthenticator{{
+				AuthenticatorID: oidcAuthenticatorID,
+				IsEnabled:       true,
+				Properties: []IdPProperty{
+					{Key: "ClientId", Value: name + "_client"},
+					{Key: "ClientSecret", Value: name + "_secret"},
+					{Key: "OAuth2AuthzEPUrl", Value: issuer + "/authorize"},
+					{Key: "OAuth2TokenEPUrl", Value: issuer + "/token"},
+					{Key: "UserInfoUrl", Value: issuer + "/userinfo"},
+					{Key: "callbackUrl", Value: h.config.GetServerURL() + h.config.GetTenantPath("/t/{tenant}/commonauth", tenantIndex)},
+				},
+			}},
+		},
+	}
+	if h.config.StubIdP.URL != "" {
+		// The server verifies the ID tokens of the stub against the keys it publishes
+		idp.Certificate = &IdPCertificate{JWKSURI: issuer + "/jwks"}
+	}
+	return idp
+}
+
+// idpURL returns the URL of the IdP management API of the tenant, followed by the given suffix
+func (h *HTTPClient) idpURL(tenantIndex int, suffix string) string {
+	return h.config.GetServerURL() + h.config.GetTenantPath(h.config.IdPs.APIPath, tenantIndex) + suffix
+}
+
+// CreateIdP creates an identity provider using the IdP management REST API and returns its ID
+func (h *HTTPClient) CreateIdP(ctx context.Context, tenantIndex int, name string) (string, error) {
+	payload, err := json.Marshal(h.newTestIdP(tenantIndex, name))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal IdP JSON: %v", err)
+	}
+
+	body, err := h.sendManagementRequest(ctx, tenantIndex, "POST", h.idpURL(tenantIndex, ""), "IdP creation", payload, http.StatusCreated)
+	if err != nil {
+		return "", err
+	}
+
+	var idp IdP
+	if err := json.Unmarshal(body, &idp); err != nil {
+		return "", fmt.Errorf("failed to unmarshal IdP response: %v", err)
+	}
+	return idp.ID, nil
+}
+
+// FindIdPID returns the ID of the identity provider with the given name, or "" if there is none
+func (h *HTTPClient) FindIdPID(ctx context.Context, tenantIndex int, name string) (string, error) {
+	filter := url.QueryEscape(fmt.Sprintf("name eq %s", name))
+	body, err := h.sendManagementRequest(ctx, tenantIndex, "GET", h.idpURL(tenantIndex, "?filter="+filter), "IdP search", nil, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+
+	var list IdPListResponse
+	if err := json.Unmarshal(body, &list); err != nil {
+		return "", fmt.Errorf("failed to unmarshal IdP search response: %v", err)
+	}
+	for _, idp := range list.IdentityProviders {
+		if idp.Name == name {
+			return idp.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// DeleteIdP deletes the identity provider with the given name, treating a missing one as already
+// deleted
+func (h *HTTPClient) DeleteIdP(ctx context.Context, tenantIndex int, name string) error {
+	id, err := h.FindIdPID(ctx, tenantIndex, name)
+	if err != nil || id == "" {
+		return err
+	}
+
+	_, err = h.sendManagementRequest(ctx, tenantIndex, "DELETE", h.idpURL(tenantIndex, "/"+url.PathEscape(id)), "IdP deletion", nil, http.StatusNoContent, http.StatusNotFound)
+	return err
+}
+
+// sendIdPRequest sends a request to the IdP management API of the tenant and returns the response
+// body, failing with a StatusError unless the response has one of the accepted status codes
+func (h *HTTPClient) sendManagementRequest(ctx context.Context, tenantIndex int, method, reqURL, operation string, payload []byte, accepted ...int) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := newTenantRequest(ctx, tenantIndex, method, reqURL, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %v", operation, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s request: %v", operation, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	for _, statusCode := range accepted {
+		if resp.StatusCode == statusCode {
+			return body, nil
+		}
+	}
+
+	return nil, &StatusError{Operation: operation, StatusCode: resp.StatusCode, Body: string(body)}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+func init() {
+	RegisterReporter("jsonl", false, newJSONLReporter)
+}
+
+// jsonlReporter writes every request result as a line of JSON to a file, for loading into tools
+// that have no reporter of their own. It also serves as the reference for writing a reporter.
+type jsonlReporter struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+	mutex  sync.Mutex
+}
+
+// jsonlResult is the JSON form of a request result
+type jsonlResult struct {
+	Time       string  `json:"time"`
+	Phase      string  `json:"phase"`
+	Operation  string  `json:"operation"`
+	DurationMs float64 `json:"durationMs"`
+	StatusCode int     `json:"statusCode"`
+	Failed     bool    `json:"failed"`
+}
+
+// newJSONLReporter creates the reporter; the file option names the output file
+func newJSONLReporter(options map[string]string) (Reporter, error) {
+	path := options["file"]
+	if path == "" {
+		path = "results.jsonl"
+	}
+	return &jsonlReporter{path: path}, nil
+}
+
+// Start creates the output file
+func (r *jsonlReporter) Start(run RunInfo) error {
+	file, err := os.Create(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to create results file: %v", err)
+	}
+
+	r.file = file
+	r.writer = bufio.NewWriter(file)
+	return nil
+}
+
+// RecordResult buffers a line for the result
+func (r *jsonlReporter) RecordResult(result RequestResult) {
+	line, _ := json.Marshal(jsonlResult{
+		Time:       result.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Phase:      result.Phase,
+		Operation:  result.Operation,
+		DurationMs: milliseconds(result.Duration),
+		StatusCode: result.StatusCode,
+		Failed:     result.Failed,
+	})
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.writer != nil {
+		r.writer.Write(append(line, '\n'))
+	}
+}
+
+// Flush writes the buffered lines to the file
+func (r *jsonlReporter) Flush() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.writer == nil {
+		return nil
+	}
+	return r.writer.Flush()
+}
+
+// Close flushes and closes the file; results recorded afterwards are dropped
+func (r *jsonlReporter) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.writer == nil {
+		return nil
+	}
+
+	err := r.writer.Flush()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.writer = nil
+	return err
+}
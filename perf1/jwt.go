@@ -0,0 +1,232 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// JWKS represents a JSON Web Key Set published by the server
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK represents a single RSA JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// jwtHeader represents the header of a JWT
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// JWTValidationError describes why a JWT failed validation; Reason is a short, stable
+// category used to group failures in the statistics
+type JWTValidationError struct {
+	Reason string
+	Detail string
+}
+
+func (e *JWTValidationError) Error() string {
+	return fmt.Sprintf("JWT validation failed (%s): %s", e.Reason, e.Detail)
+}
+
+// IsJWT reports whether a token looks like a compact serialized JWS
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// ValidateJWT verifies the signature of a JWT against the key set, checks that the required
+// claims are present and that the token is not expired, and returns its claims
+func ValidateJWT(token string, keys *JWKS, requiredClaims []string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, &JWTValidationError{"malformed", "token does not have three parts"}
+	}
+
+	var header jwtHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, &JWTValidationError{"malformed", fmt.Sprintf("invalid header: %v", err)}
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, &JWTValidationError{"malformed", fmt.Sprintf("invalid payload: %v", err)}
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, &JWTValidationError{"malformed", fmt.Sprintf("invalid signature encoding: %v", err)}
+	}
+
+	publicKey, err := keys.findKey(header.Kid)
+	if err != nil {
+		return nil, &JWTValidationError{"unknown_key", err.Error()}
+	}
+
+	if err := verifyRSASignature(header.Alg, publicKey, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, &JWTValidationError{"bad_signature", err.Error()}
+	}
+
+	for _, claim := range requiredClaims {
+		if _, ok := claims[claim]; !ok {
+			return nil, &JWTValidationError{"missing_claim", fmt.Sprintf("claim %q is missing", claim)}
+		}
+	}
+
+	if exp, ok := claims["exp"].(float64); ok && time.Unix(int64(exp), 0).Before(time.Now()) {
+		return nil, &JWTValidationError{"expired", fmt.Sprintf("token expired at %v", time.Unix(int64(exp), 0))}
+	}
+
+	return claims, nil
+}
+
+// decodeJWTSegment decodes a base64url JSON segment of a JWT
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// findKey returns the RSA public key with the given key ID, or the only key if the token has no key ID
+func (k *JWKS) findKey(kid string) (*rsa.PublicKey, error) {
+	for _, key := range k.Keys {
+		if key.Kty != "RSA" {
+			continue
+		}
+		if key.Kid == kid || (kid == "" && len(k.Keys) == 1) {
+			return key.rsaPublicKey()
+		}
+	}
+	return nil, fmt.Errorf("no RSA key with kid %q in JWKS", kid)
+}
+
+// rsaPublicKey converts the JWK into an RSA public key
+func (k JWK) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus for kid %q: %v", k.Kid, err)
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent for kid %q: %v", k.Kid, err)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+// jwtHashes maps the supported RSA JWS algorithms to their hash functions
+var jwtHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"PS256": crypto.SHA256,
+	"PS384": crypto.SHA384,
+	"PS512": crypto.SHA512,
+}
+
+// verifyRSASignature verifies an RSA JWS signature over the signing input
+func verifyRSASignature(alg string, publicKey *rsa.PublicKey, signingInput string, signature []byte) error {
+	hash, ok := jwtHashes[alg]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	hasher := hash.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	if strings.HasPrefix(alg, "PS") {
+		return rsa.VerifyPSS(publicKey, hash, digest, signature, nil)
+	}
+	return rsa.VerifyPKCS1v15(publicKey, hash, digest, signature)
+}
+
+// SignJWT serializes the claims as a compact JWS signed with an RSA private key
+func SignJWT(claims map[string]interface{}, privateKey *rsa.PrivateKey, alg, kid string) (string, error) {
+	hash, ok := jwtHashes[alg]
+	if !ok {
+		return "", fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	header := jwtHeader{Alg: alg, Kid: kid, Typ: "JWT"}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWT header: %v", err)
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWT claims: %v", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	hasher := hash.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	var signature []byte
+	if strings.HasPrefix(alg, "PS") {
+		signature, err = rsa.SignPSS(rand.Reader, privateKey, hash, digest, nil)
+	} else {
+		signature, err = rsa.SignPKCS1v15(rand.Reader, privateKey, hash, digest)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %v", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// LoadRSAPrivateKey reads a PEM encoded RSA private key in PKCS#1 or PKCS#8 form
+func LoadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key file: %v", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %s", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key in %s is not an RSA key", path)
+	}
+
+	return key, nil
+}
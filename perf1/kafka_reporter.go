@@ -0,0 +1,550 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterReporter("kafka", true, newKafkaReporter)
+}
+
+const (
+	// kafkaAPIProduce and kafkaAPIMetadata are the Kafka protocol API keys used by the reporter
+	kafkaAPIProduce  = 0
+	kafkaAPIMetadata = 3
+	// kafkaProduceVersion is Produce v3, the oldest version brokers from 0.11 to 4.x all accept
+	kafkaProduceVersion = 3
+	// kafkaMetadataVersion is Metadata v4, which can leave topic auto-creation off
+	kafkaMetadataVersion = 4
+	// kafkaMaxBatchBytes keeps every produce request under the default broker message size limit
+	kafkaMaxBatchBytes = 900 * 1024
+	// kafkaMaxPending is the most results buffered between flushes; more are dropped and counted
+	kafkaMaxPending = 500000
+)
+
+// kafkaCRC is the Castagnoli table of the record batch checksum
+var kafkaCRC = crc32.MakeTable(crc32.Castagnoli)
+
+// kafkaReporter publishes every request result as a JSON record to a Kafka topic, so a central data
+// lake ingests runs while they are in progress. Results are buffered and sent on every flush,
+// spread over the partitions of the topic in turn. It speaks the Kafka protocol directly over
+// plaintext connections.
+type kafkaReporter struct {
+	brokers  []string
+	topic    string
+	clientID string
+	acks     int16
+	timeout  time.Duration
+	run      RunInfo
+
+	pending [][]byte
+	dropped int
+	mutex   sync.Mutex
+
+	// sendMutex serializes flushes, which own the connections and the partition metadata
+	sendMutex  sync.Mutex
+	partitions []kafkaPartition
+	next       int
+	conns      map[string]*kafkaConn
+}
+
+// kafkaPartition is a partition of the topic and the address of its leader
+type kafkaPartition struct {
+	id     int32
+	leader string
+}
+
+// kafkaResult is the JSON record of a request result, with the run it belongs to
+type kafkaResult struct {
+	RunStart   string  `json:"runStart"`
+	Mode       string  `json:"mode"`
+	Server     string  `json:"server"`
+	Time       string  `json:"time"`
+	Phase      string  `json:"phase"`
+	Operation  string  `json:"operation"`
+	DurationMs float64 `json:"durationMs"`
+	StatusCode int     `json:"statusCode"`
+	Failed     bool    `json:"failed"`
+}
+
+// newKafkaReporter creates the reporter from its options: brokers (comma-separated host:port),
+// topic, and optionally clientId, acks (0, 1 or -1 for all replicas) and timeout in seconds
+func newKafkaReporter(options map[string]string) (Reporter, error) {
+	r := &kafkaReporter{
+		topic:    options["topic"],
+		clientID: "go-perf",
+		acks:     1,
+		timeout:  10 * time.Second,
+		conns:    make(map[string]*kafkaConn),
+	}
+
+	for _, broker := range strings.Split(options["brokers"], ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			r.brokers = append(r.brokers, broker)
+		}
+	}
+	if len(r.brokers) == 0 {
+		return nil, fmt.Errorf("the brokers option is required")
+	}
+	if r.topic == "" {
+		return nil, fmt.Errorf("the topic option is required")
+	}
+
+	if id := options["clientId"]; id != "" {
+		r.clientID = id
+	}
+	if value := options["acks"]; value != "" {
+		acks, err := strconv.Atoi(value)
+		if err != nil || acks < -1 || acks > 1 {
+			return nil, fmt.Errorf("invalid acks %q, expected 0, 1 or -1", value)
+		}
+		r.acks = int16(acks)
+	}
+	if value := options["timeout"]; value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 1 {
+			return nil, fmt.Errorf("invalid timeout %q, expected a positive number of seconds", value)
+		}
+		r.timeout = time.Duration(seconds) * time.Second
+	}
+
+	return r, nil
+}
+
+// Start looks up the partitions of the topic, so a wrong broker list or topic fails the run at start
+func (r *kafkaReporter) Start(run RunInfo) error {
+	r.run = run
+
+	r.sendMutex.Lock()
+	defer r.sendMutex.Unlock()
+
+	return r.refreshMetadata()
+}
+
+// RecordResult buffers the JSON record of the result until the next flush
+func (r *kafkaReporter) RecordResult(result RequestResult) {
+	value, _ := json.Marshal(kafkaResult{
+		RunStart:   r.run.StartTime.UTC().Format(time.RFC3339),
+		Mode:       r.run.Mode,
+		Server:     r.run.Server,
+		Time:       result.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Phase:      result.Phase,
+		Operation:  result.Operation,
+		DurationMs: milliseconds(result.Duration),
+		StatusCode: result.StatusCode,
+		Failed:     result.Failed,
+	})
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.pending) >= kafkaMaxPending {
+		r.dropped++
+		return
+	}
+	r.pending = append(r.pending, value)
+}
+
+// Flush sends the buffered records in batches, each to the next partition. A failed batch is sent
+// again once after the partition leaders are looked up anew, as leadership may have moved. The
+// records of a batch that still fails, and those after it, are kept for the next flush.
+func (r *kafkaReporter) Flush() error {
+	r.mutex.Lock()
+	records, dropped := r.pending, r.dropped
+	r.pending, r.dropped = nil, 0
+	r.mutex.Unlock()
+
+	r.sendMutex.Lock()
+	defer r.sendMutex.Unlock()
+
+	for len(records) > 0 {
+		n, size := 0, 0
+		for n < len(records) && (n == 0 || size+len(records[n]) < kafkaMaxBatchBytes) {
+			size += len(records[n]) + 32
+			n++
+		}
+
+		err := r.produce(records[:n])
+		if err != nil {
+			if metaErr := r.refreshMetadata(); metaErr == nil {
+				err = r.produce(records[:n])
+			}
+		}
+		if err != nil {
+			r.requeue(records, dropped)
+			return fmt.Errorf("failed to publish %d results to %s, keeping them for the next flush: %v", len(records), r.topic, err)
+		}
+		records = records[n:]
+	}
+
+	if dropped > 0 {
+		return fmt.Errorf("dropped %d results while the buffer was full", dropped)
+	}
+	return nil
+}
+
+// requeue puts records that could not be sent back in front of those buffered since, dropping the
+// newest ones beyond the buffer limit, and keeps the count of dropped records for the next flush
+func (r *kafkaReporter) requeue(records [][]byte, dropped int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.dropped += dropped
+	pending := append(records[:len(records):len(records)], r.pending...)
+	if len(pending) > kafkaMaxPending {
+		r.dropped += len(pending) - kafkaMaxPending
+		pending = pending[:kafkaMaxPending]
+	}
+	r.pending = pending
+}
+
+// Close closes the broker connections
+func (r *kafkaReporter) Close() error {
+	r.sendMutex.Lock()
+	defer r.sendMutex.Unlock()
+
+	for addr, conn := range r.conns {
+		conn.Close()
+		delete(r.conns, addr)
+	}
+	return nil
+}
+
+// produce sends a batch of records to the next partition and checks the broker's answer
+func (r *kafkaReporter) produce(records [][]byte) error {
+	partition := r.partitions[r.next%len(r.partitions)]
+	r.next++
+
+	var body kafkaEncoder
+	body.nullableString("")
+	body.int16(r.acks)
+	body.int32(int32(r.timeout / time.Millisecond))
+	body.int32(1)
+	body.string(r.topic)
+	body.int32(1)
+	body.int32(partition.id)
+	body.bytes(encodeKafkaRecordBatch(records, time.Now()))
+
+	conn, err := r.conn(partition.leader)
+	if err != nil {
+		return err
+	}
+
+	// With acks 0 the broker does not answer
+	response, err := conn.request(kafkaAPIProduce, kafkaProduceVersion, body.buf.Bytes(), r.acks != 0)
+	if err != nil {
+		r.dropConn(partition.leader)
+		return err
+	}
+	if r.acks == 0 {
+		return nil
+	}
+
+	d := kafkaDecoder{buf: response}
+	for topics := d.int32(); topics > 0 && d.err == nil; topics-- {
+		d.string()
+		for partitions := d.int32(); partitions > 0 && d.err == nil; partitions-- {
+			d.int32()
+			if code := d.int16(); code != 0 {
+				return fmt.Errorf("broker %s rejected the batch for partition %d with error code %d", partition.leader, partition.id, code)
+			}
+			d.int64()
+			d.int64()
+		}
+	}
+	return d.err
+}
+
+// refreshMetadata looks up the partitions of the topic and their leaders at the first broker that answers
+func (r *kafkaReporter) refreshMetadata() error {
+	var body kafkaEncoder
+	body.int32(1)
+	body.string(r.topic)
+	body.int8(0) // do not create the topic
+
+	var lastErr error
+	for _, broker := range r.brokers {
+		conn, err := r.conn(broker)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		response, err := conn.request(kafkaAPIMetadata, kafkaMetadataVersion, body.buf.Bytes(), true)
+		if err != nil {
+			r.dropConn(broker)
+			lastErr = err
+			continue
+		}
+
+		partitions, err := decodeKafkaMetadata(response, r.topic)
+		if err != nil {
+			return err
+		}
+		r.partitions = partitions
+		return nil
+	}
+	return fmt.Errorf("no broker answered: %v", lastErr)
+}
+
+// decodeKafkaMetadata returns the partitions of the topic from a Metadata v4 response
+func decodeKafkaMetadata(response []byte, topic string) ([]kafkaPartition, error) {
+	d := kafkaDecoder{buf: response}
+	d.int32() // throttle time
+
+	brokers := make(map[int32]string)
+	for n := d.int32(); n > 0 && d.err == nil; n-- {
+		id := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.string() // cluster ID
+	d.int32()  // controller ID
+
+	var partitions []kafkaPartition
+	for n := d.int32(); n > 0 && d.err == nil; n-- {
+		code := d.int16()
+		name := d.string()
+		d.int8() // internal
+		if name == topic && code != 0 {
+			return nil, fmt.Errorf("topic %s is not available, error code %d", topic, code)
+		}
+
+		for p := d.int32(); p > 0 && d.err == nil; p-- {
+			d.int16()
+			id := d.int32()
+			leader := d.int32()
+			for replicas := d.int32(); replicas > 0 && d.err == nil; replicas-- {
+				d.int32()
+			}
+			for isr := d.int32(); isr > 0 && d.err == nil; isr-- {
+				d.int32()
+			}
+
+			if addr, ok := brokers[leader]; ok && name == topic {
+				partitions = append(partitions, kafkaPartition{id: id, leader: addr})
+			}
+		}
+	}
+
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid metadata response: %v", d.err)
+	}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("topic %s has no partition with a leader", topic)
+	}
+	return partitions, nil
+}
+
+// conn returns the connection to a broker, connecting on first use
+func (r *kafkaReporter) conn(addr string) (*kafkaConn, error) {
+	if conn, ok := r.conns[addr]; ok {
+		return conn, nil
+	}
+
+	netConn, err := net.DialTimeout("tcp", addr, r.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to broker %s: %v", addr, err)
+	}
+
+	conn := &kafkaConn{Conn: netConn, reader: bufio.NewReader(netConn), clientID: r.clientID, timeout: r.timeout}
+	r.conns[addr] = conn
+	return conn, nil
+}
+
+// dropConn closes the connection to a broker after an error, so the next use reconnects
+func (r *kafkaReporter) dropConn(addr string) {
+	if conn, ok := r.conns[addr]; ok {
+		conn.Close()
+		delete(r.conns, addr)
+	}
+}
+
+// kafkaConn is a connection to a broker that sends one request at a time
+type kafkaConn struct {
+	net.Conn
+	reader      *bufio.Reader
+	clientID    string
+	timeout     time.Duration
+	correlation int32
+}
+
+// request sends a request and, if expected, returns the body of the response
+func (c *kafkaConn) request(apiKey, apiVersion int16, body []byte, expectResponse bool) ([]byte, error) {
+	c.correlation++
+
+	var header kafkaEncoder
+	header.int16(apiKey)
+	header.int16(apiVersion)
+	header.int32(c.correlation)
+	header.string(c.clientID)
+
+	message := make([]byte, 4, 4+header.buf.Len()+len(body))
+	binary.BigEndian.PutUint32(message, uint32(header.buf.Len()+len(body)))
+	message = append(message, header.buf.Bytes()...)
+	message = append(message, body...)
+
+	c.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := c.Write(message); err != nil {
+		return nil, err
+	}
+	if !expectResponse {
+		return nil, nil
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(c.reader, size[:]); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(c.reader, response); err != nil {
+		return nil, err
+	}
+
+	if len(response) < 4 || int32(binary.BigEndian.Uint32(response)) != c.correlation {
+		return nil, fmt.Errorf("response does not match request %d", c.correlation)
+	}
+	return response[4:], nil
+}
+
+// encodeKafkaRecordBatch encodes records without keys as an uncompressed record batch (magic 2)
+func encodeKafkaRecordBatch(values [][]byte, now time.Time) []byte {
+	timestamp := now.UnixMilli()
+
+	// Everything after the checksum, which the checksum covers
+	var tail kafkaEncoder
+	tail.int16(0) // attributes: no compression, create time
+	tail.int32(int32(len(values) - 1))
+	tail.int64(timestamp)
+	tail.int64(timestamp)
+	tail.int64(-1) // producer ID
+	tail.int16(-1) // producer epoch
+	tail.int32(-1) // base sequence
+	tail.int32(int32(len(values)))
+	for i, value := range values {
+		var record kafkaEncoder
+		record.int8(0)   // attributes
+		record.varint(0) // timestamp delta
+		record.varint(int64(i))
+		record.varint(-1) // null key
+		record.varint(int64(len(value)))
+		record.buf.Write(value)
+		record.varint(0) // headers
+
+		tail.varint(int64(record.buf.Len()))
+		tail.buf.Write(record.buf.Bytes())
+	}
+
+	var batch kafkaEncoder
+	batch.int64(0)                                 // base offset
+	batch.int32(int32(4 + 1 + 4 + tail.buf.Len())) // length of what follows
+	batch.int32(-1)                                // partition leader epoch
+	batch.int8(2)                                  // magic
+	batch.uint32(crc32.Checksum(tail.buf.Bytes(), kafkaCRC))
+	batch.buf.Write(tail.buf.Bytes())
+	return batch.buf.Bytes()
+}
+
+// kafkaEncoder writes the big-endian primitives of the Kafka protocol
+type kafkaEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *kafkaEncoder) int8(v int8)     { e.buf.WriteByte(byte(v)) }
+func (e *kafkaEncoder) int16(v int16)   { e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(v))) }
+func (e *kafkaEncoder) int32(v int32)   { e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(v))) }
+func (e *kafkaEncoder) uint32(v uint32) { e.buf.Write(binary.BigEndian.AppendUint32(nil, v)) }
+func (e *kafkaEncoder) int64(v int64)   { e.buf.Write(binary.BigEndian.AppendUint64(nil, uint64(v))) }
+
+// varint writes a zigzag-encoded variable length integer
+func (e *kafkaEncoder) varint(v int64) { e.buf.Write(binary.AppendVarint(nil, v)) }
+
+func (e *kafkaEncoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.buf.WriteString(s)
+}
+
+// nullableString writes an empty string as null
+func (e *kafkaEncoder) nullableString(s string) {
+	if s == "" {
+		e.int16(-1)
+		return
+	}
+	e.string(s)
+}
+
+func (e *kafkaEncoder) bytes(b []byte) {
+	e.int32(int32(len(b)))
+	e.buf.Write(b)
+}
+
+// kafkaDecoder reads the big-endian primitives of the Kafka protocol, remembering the first error
+type kafkaDecoder struct {
+	buf []byte
+	err error
+}
+
+// take returns the next n bytes, or nil once the response is exhausted
+func (d *kafkaDecoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.buf) {
+		d.err = errors.New("response truncated")
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *kafkaDecoder) int8() int8 {
+	if b := d.take(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if b := d.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	if b := d.take(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if b := d.take(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// string reads a string, of which a null one is read as empty
+func (d *kafkaDecoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.take(int(n)))
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestEncodeKafkaRecordBatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		values [][]byte
+	}{
+		{name: "one record", values: [][]byte{[]byte(`{"a":1}`)}},
+		{name: "several records", values: [][]byte{[]byte(`{"a":1}`), []byte(`{"b":2}`), []byte(`{}`)}},
+		{name: "record over 63 bytes needs a two-byte varint", values: [][]byte{bytes.Repeat([]byte("x"), 200)}},
+	}
+
+	now := time.UnixMilli(1714557600000)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := encodeKafkaRecordBatch(tt.values, now)
+
+			if got := int64(binary.BigEndian.Uint64(batch[0:])); got != 0 {
+				t.Errorf("base offset = %d, want 0", got)
+			}
+			if got := int(binary.BigEndian.Uint32(batch[8:])); got != len(batch)-12 {
+				t.Errorf("batch length = %d, want %d", got, len(batch)-12)
+			}
+			if batch[16] != 2 {
+				t.Errorf("magic = %d, want 2", batch[16])
+			}
+			if got, want := binary.BigEndian.Uint32(batch[17:]), crc32.Checksum(batch[21:], kafkaCRC); got != want {
+				t.Errorf("crc = %x, want %x", got, want)
+			}
+
+			d := kafkaDecoder{buf: batch[21:]}
+			d.int16() // attributes
+			if got := d.int32(); got != int32(len(tt.values)-1) {
+				t.Errorf("last offset delta = %d, want %d", got, len(tt.values)-1)
+			}
+			if first, max := d.int64(), d.int64(); first != now.UnixMilli() || max != now.UnixMilli() {
+				t.Errorf("timestamps = %d, %d, want %d", first, max, now.UnixMilli())
+			}
+			if id, epoch, sequence := d.int64(), d.int16(), d.int32(); id != -1 || epoch != -1 || sequence != -1 {
+				t.Errorf("producer fields = %d, %d, %d, want -1", id, epoch, sequence)
+			}
+			if got := d.int32(); got != int32(len(tt.values)) {
+				t.Fatalf("record count = %d, want %d", got, len(tt.values))
+			}
+
+			rest := d.buf
+			for i, want := range tt.values {
+				length, n := binary.Varint(rest)
+				record := rest[n : n+int(length)]
+				rest = rest[n+int(length):]
+
+				record = record[1:] // attributes
+				fields := make([]int64, 4)
+				for j := range fields {
+					fields[j], n = binary.Varint(record)
+					record = record[n:]
+				}
+				if fields[0] != 0 || fields[1] != int64(i) || fields[2] != -1 || fields[3] != int64(len(want)) {
+					t.Errorf("record %d header = %v, want [0 %d -1 %d]", i, fields, i, len(want))
+				}
+				if got := record[:len(want)]; !bytes.Equal(got, want) {
+					t.Errorf("record %d value = %q, want %q", i, got, want)
+				}
+				if headers, _ := binary.Varint(record[len(want):]); headers != 0 {
+					t.Errorf("record %d headers = %d, want 0", i, headers)
+				}
+			}
+			if len(rest) != 0 {
+				t.Errorf("%d bytes left after the records", len(rest))
+			}
+		})
+	}
+}
+
+func TestDecodeKafkaMetadata(t *testing.T) {
+	// metadata builds a Metadata v4 response for one topic with the given error code, whose
+	// partitions are led by the given broker IDs
+	metadata := func(topic string, code int16, leaders ...int32) []byte {
+		var e kafkaEncoder
+		e.int32(0) // throttle time
+		e.int32(2)
+		e.int32(1)
+		e.string("kafka-1")
+		e.int32(9092)
+		e.nullableString("")
+		e.int32(2)
+		e.string("kafka-2")
+		e.int32(9093)
+		e.nullableString("")
+		e.nullableString("cluster")
+		e.int32(1) // controller
+		e.int32(1)
+		e.int16(code)
+		e.string(topic)
+		e.int8(0)
+		e.int32(int32(len(leaders)))
+		for i, leader := range leaders {
+			e.int16(0)
+			e.int32(int32(i))
+			e.int32(leader)
+			e.int32(1)
+			e.int32(leader)
+			e.int32(1)
+			e.int32(leader)
+		}
+		return e.buf.Bytes()
+	}
+
+	tests := []struct {
+		name     string
+		response []byte
+		want     []kafkaPartition
+		wantErr  bool
+	}{
+		{
+			name:     "partitions with their leaders",
+			response: metadata("results", 0, 1, 2),
+			want:     []kafkaPartition{{0, "kafka-1:9092"}, {1, "kafka-2:9093"}},
+		},
+		{name: "unknown topic", response: metadata("results", 3), wantErr: true},
+		{name: "leader not among the brokers", response: metadata("results", 0, 7), wantErr: true},
+		{name: "truncated", response: metadata("results", 0, 1)[:40], wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeKafkaMetadata(tt.response, "results")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("partitions = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("partition %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestKafkaConnRequestFraming(t *testing.T) {
+	client, broker := net.Pipe()
+	defer client.Close()
+	defer broker.Close()
+
+	conn := &kafkaConn{Conn: client, reader: bufio.NewReader(client), clientID: "go-perf", timeout: time.Second}
+
+	received := make(chan []byte, 1)
+	go func() {
+		var size [4]byte
+		broker.Read(size[:])
+		request := make([]byte, binary.BigEndian.Uint32(size[:]))
+		for n := 0; n < len(request); {
+			m, err := broker.Read(request[n:])
+			if err != nil {
+				return
+			}
+			n += m
+		}
+		received <- request
+
+		// Answer with the correlation ID of the request and a body
+		response := append(append([]byte{0, 0, 0, 8}, request[4:8]...), 0xCA, 0xFE, 0xBA, 0xBE)
+		broker.Write(response)
+	}()
+
+	body, err := conn.request(kafkaAPIMetadata, kafkaMetadataVersion, []byte{1, 2, 3}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, []byte{0xCA, 0xFE, 0xBA, 0xBE}) {
+		t.Errorf("response body = %x, want cafebabe", body)
+	}
+
+	request := <-received
+	d := kafkaDecoder{buf: request}
+	if key, version, correlation, clientID := d.int16(), d.int16(), d.int32(), d.string(); key != kafkaAPIMetadata ||
+		version != kafkaMetadataVersion || correlation != 1 || clientID != "go-perf" {
+		t.Errorf("header = %d %d %d %q", key, version, correlation, clientID)
+	}
+	if !bytes.Equal(d.buf, []byte{1, 2, 3}) {
+		t.Errorf("request body = %v, want [1 2 3]", d.buf)
+	}
+}
+
+func TestKafkaFlushKeepsUnsentRecords(t *testing.T) {
+	// Nothing listens on the broker address, so every send fails
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	r := &kafkaReporter{
+		brokers:    []string{addr},
+		topic:      "results",
+		timeout:    time.Second,
+		conns:      make(map[string]*kafkaConn),
+		partitions: []kafkaPartition{{0, addr}},
+	}
+	r.RecordResult(RequestResult{Operation: "first"})
+	r.RecordResult(RequestResult{Operation: "second"})
+
+	if err := r.Flush(); err == nil {
+		t.Fatal("flush to an unreachable broker succeeded")
+	}
+	r.RecordResult(RequestResult{Operation: "third"})
+
+	if len(r.pending) != 3 || !bytes.Contains(r.pending[0], []byte("first")) || !bytes.Contains(r.pending[2], []byte("third")) {
+		t.Errorf("pending after a failed flush = %q, want first, second, third", r.pending)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// LatencySummary holds the distribution of a set of request durations
+type LatencySummary struct {
+	Count int
+	Min   time.Duration
+	Avg   time.Duration
+	Max   time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+}
+
+// SummarizeLatencies computes the latency distribution of the given durations.
+// The slice is sorted in place; callers that keep appending to it must pass a copy.
+func SummarizeLatencies(durations []time.Duration) LatencySummary {
+	if len(durations) == 0 {
+		return LatencySummary{}
+	}
+
+	sortDurations(durations)
+
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+
+	return LatencySummary{
+		Count: len(durations),
+		Min:   durations[0],
+		Avg:   total / time.Duration(len(durations)),
+		Max:   durations[len(durations)-1],
+		P50:   percentile(durations, 50),
+		P90:   percentile(durations, 90),
+		P95:   percentile(durations, 95),
+		P99:   percentile(durations, 99),
+	}
+}
+
+// sortDurations sorts durations in ascending order
+func sortDurations(durations []time.Duration) {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// String formats the summary on a single line
+func (ls LatencySummary) String() string {
+	return fmt.Sprintf("Min: %v, Avg: %v, Max: %v, P50: %v, P90: %v, P95: %v, P99: %v",
+		ls.Min.Round(time.Microsecond), ls.Avg.Round(time.Microsecond), ls.Max.Round(time.Microsecond),
+		ls.P50.Round(time.Microsecond), ls.P90.Round(time.Microsecond), ls.P95.Round(time.Microsecond),
+		ls.P99.Round(time.Microsecond))
+}
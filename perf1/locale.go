@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// weightedValue is a value with the cumulative weight of the values up to and including it
+type weightedValue struct {
+	value string
+	bound int
+}
+
+// weightedValues assigns values in proportion to their weights
+type weightedValues struct {
+	attribute string
+	values    []weightedValue
+	total     int
+}
+
+// newWeightedValues validates the weights of an attribute's values and builds a chooser for them,
+// nil when no value is configured
+func newWeightedValues(attribute string, weights map[string]int) (*weightedValues, error) {
+	if len(weights) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := &weightedValues{attribute: attribute}
+	for _, name := range names {
+		if weights[name] < 0 {
+			return nil, fmt.Errorf("weight of %s %q must not be negative", attribute, name)
+		}
+		if weights[name] == 0 {
+			continue
+		}
+
+		w.total += weights[name]
+		w.values = append(w.values, weightedValue{name, w.total})
+	}
+
+	if w.total == 0 {
+		return nil, fmt.Errorf("no %s has a positive weight", attribute)
+	}
+
+	return w, nil
+}
+
+// choose returns the value of the user. The choice is a hash of the user, so a retried or resumed
+// user gets the same value as before, and the attributes are chosen independently of each other.
+func (w *weightedValues) choose(tenantIndex int, username string) string {
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%s/%d/%s", w.attribute, tenantIndex, username)
+	n := int(mix64(hash.Sum64()) % uint64(w.total))
+
+	for _, value := range w.values {
+		if n < value.bound {
+			return value.value
+		}
+	}
+	return w.values[len(w.values)-1].value
+}
+
+// String describes the weights, e.g. "en_US=70,fr_FR=30"
+func (w *weightedValues) String() string {
+	parts := make([]string, len(w.values))
+	previous := 0
+	for i, value := range w.values {
+		parts[i] = fmt.Sprintf("%s=%d", value.value, value.bound-previous)
+		previous = value.bound
+	}
+	return strings.Join(parts, ",")
+}
+
+// localeAttributes sets the locale, timezone and preferredLanguage SCIM attributes of created users,
+// each drawn from its own weighted list
+type localeAttributes struct {
+	locale            *weightedValues
+	timezone          *weightedValues
+	preferredLanguage *weightedValues
+}
+
+// newLocaleAttributes builds the attribute choosers of the configuration, nil when no attribute is
+// configured so the user payload stays as it was
+func newLocaleAttributes(config LocaleConfig) (*localeAttributes, error) {
+	var attributes localeAttributes
+	var err error
+
+	if attributes.locale, err = newWeightedValues("locale", config.Locales); err != nil {
+		return nil, err
+	}
+	if attributes.timezone, err = newWeightedValues("timezone", config.Timezones); err != nil {
+		return nil, err
+	}
+	if attributes.preferredLanguage, err = newWeightedValues("preferredLanguage", config.PreferredLanguages); err != nil {
+		return nil, err
+	}
+
+	if attributes.locale == nil && attributes.timezone == nil && attributes.preferredLanguage == nil {
+		return nil, nil
+	}
+	return &attributes, nil
+}
+
+// apply sets the configured attributes of the user
+func (a *localeAttributes) apply(user *SCIMUser, tenantIndex int) {
+	if a == nil {
+		return
+	}
+	if a.locale != nil {
+		user.Locale = a.locale.choose(tenantIndex, user.UserName)
+	}
+	if a.timezone != nil {
+		user.Timezone = a.timezone.choose(tenantIndex, user.UserName)
+	}
+	if a.preferredLanguage != nil {
+		user.PreferredLanguage = a.preferredLanguage.choose(tenantIndex, user.UserName)
+	}
+}
+
+// String describes the configured distributions, e.g. "locale en_US=70,fr_FR=30; timezone UTC=1"
+func (a *localeAttributes) String() string {
+	var parts []string
+	for _, w := range []*weightedValues{a.locale, a.timezone, a.preferredLanguage} {
+		if w != nil {
+			parts = append(parts, fmt.Sprintf("%s %s", w.attribute, w))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
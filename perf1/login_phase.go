@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Login methods of the login phase
+const (
+	// LoginMethodPassword obtains a token with the password grant at the token endpoint
+	LoginMethodPassword = "password"
+	// LoginMethodMe reads the user's own profile with Basic authentication at /scim2/Me
+	LoginMethodMe = "me"
+)
+
+// loginStats holds the outcome and latency of the logins of the login phase
+type loginStats struct {
+	success   int
+	failed    int
+	latencies []time.Duration
+	mutex     sync.Mutex
+}
+
+// loginJob is the login of one user in one tenant
+type loginJob struct {
+	TenantIndex int
+	Username    string
+}
+
+// ExecuteLoginPhase authenticates the configured percentage of the created users and reports the
+// authentication throughput and latency
+func (te *TestExecutor) ExecuteLoginPhase() error {
+	method := te.config.Login.Method
+	if method != LoginMethodPassword && method != LoginMethodMe {
+		return fmt.Errorf("unknown login method %q, expected %s or %s", method, LoginMethodPassword, LoginMethodMe)
+	}
+
+	percent := te.config.Login.Percent
+	if percent <= 0 || percent > 100 {
+		percent = 100
+	}
+
+	selected := selectPercent(te.config.Execution.NoOfUsers, percent)
+
+	fmt.Println("Starting login phase...")
+	fmt.Printf("- Method: %s\n", method)
+	fmt.Printf("- Users: %d of %d per tenant (%g%%)\n", len(selected), te.config.Execution.NoOfUsers, percent)
+	fmt.Printf("- Tenants: %d\n", te.config.Execution.NoOfTenants)
+
+	stats := &loginStats{}
+	jobs := make(chan loginJob)
+
+	// Queue the logins user by user across all tenants, stopping when the run is interrupted
+	go func() {
+		defer close(jobs)
+
+		tenantStart := te.config.Execution.TenantStartNumber
+		for _, offset := range selected {
+			username := te.config.GetTestUsername(te.config.Execution.UserStartNumber + offset)
+			for tenantIndex := tenantStart; tenantIndex < tenantStart+te.config.Execution.NoOfTenants; tenantIndex++ {
+				select {
+				case jobs <- loginJob{TenantIndex: tenantIndex, Username: username}:
+				case <-te.ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	startTime := time.Now()
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		wg.Add(1)
+		go te.loginPhaseWorker(threadID, method, jobs, stats, &wg)
+	}
+
+	wg.Wait()
+
+	elapsed := time.Since(startTime)
+	if te.interrupted() {
+		fmt.Printf("\nLogin phase interrupted after %v\n", elapsed)
+		stats.print(method, elapsed)
+		return ErrInterrupted
+	}
+	fmt.Printf("\nLogin phase completed in %v\n", elapsed)
+
+	stats.print(method, elapsed)
+
+	return nil
+}
+
+// selectPercent returns the offsets of the given percentage of users, spread evenly over the range
+func selectPercent(users int, percent float64) []int {
+	var selected []int
+	for i := 0; i < users; i++ {
+		if int(float64(i+1)*percent/100) > int(float64(i)*percent/100) {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// loginPhaseWorker logs in the users it takes from the queue until the queue is closed
+func (te *TestExecutor) loginPhaseWorker(threadID int, method string, jobs <-chan loginJob, stats *loginStats, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	waitForRampUp(te.ctx, te.rampUpStartDelay(threadID, te.config.Execution.NoOfThreads))
+	client := te.newHTTPClient()
+
+	for job := range jobs {
+		if te.interrupted() {
+			continue
+		}
+
+		var err error
+		requestStart := time.Now()
+		password := te.userPassword(job.TenantIndex, job.Username)
+		if method == LoginMethodMe {
+			err = client.GetMe(te.ctx, job.TenantIndex, job.Username, password)
+		} else {
+			_, err = client.RequestPasswordGrant(te.ctx, job.TenantIndex, job.Username, password)
+		}
+		duration := time.Since(requestStart)
+		te.recordPasswordPolicy("login", job.TenantIndex, job.Username, duration, err)
+
+		if err != nil && te.interrupted() {
+			continue
+		}
+
+		stats.record(duration, err)
+		te.stats.RecordError(err)
+
+		if err != nil {
+			printFailure("Thread %d: Failed to log in user %s in tenant %d: %v\n", threadID, job.Username, job.TenantIndex, err)
+		}
+	}
+}
+
+// GetMe reads the profile of a tenant user at the SCIM2 /Me endpoint, authenticating as the user with Basic
+// authentication
+func (h *HTTPClient) GetMe(ctx context.Context, tenantIndex int, username, password string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", h.config.GetSCIM2URL("/Me", tenantIndex), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create /Me request: %v", err)
+	}
+
+	req.SetBasicAuth(h.config.GetTenantQualifiedUsername(username, tenantIndex), password)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute /Me request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{Operation: "/Me request", StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return nil
+}
+
+// record records the outcome and latency of a login
+func (ls *loginStats) record(duration time.Duration, err error) {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+
+	if err != nil {
+		ls.failed++
+		return
+	}
+
+	ls.success++
+	ls.latencies = append(ls.latencies, duration)
+}
+
+// print prints the login phase summary
+func (ls *loginStats) print(method string, elapsed time.Duration) {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+
+	fmt.Println("\n=== Login Statistics ===")
+	fmt.Printf("Logins (%s) - Total: %d, Successful: %d, Failed: %d, Rate: %.2f/s\n",
+		method, ls.success+ls.failed, ls.success, ls.failed, float64(ls.success)/elapsed.Seconds())
+	if len(ls.latencies) > 0 {
+		fmt.Printf("Login Latency - %s\n", SummarizeLatencies(ls.latencies))
+	}
+	fmt.Println("========================")
+}
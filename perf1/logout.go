@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logoutStats holds the outcome of the login and logout steps of the logout phase
+type logoutStats struct {
+	loggedIn        int
+	loginFailed     int
+	loggedOut       int
+	logoutFailed    int
+	logoutLatencies []time.Duration
+	mutex           sync.Mutex
+}
+
+// ExecuteLogoutPhase logs the created users in through the authorization code flow, keeping their
+// browser sessions active, and then terminates every session with OIDC RP-initiated logout,
+// measuring logout throughput separately from the logins that set the sessions up
+func (te *TestExecutor) ExecuteLogoutPhase() error {
+	fmt.Println("Starting logout phase...")
+	fmt.Printf("- Users: %d\n", te.config.Execution.NoOfUsers)
+	fmt.Printf("- Tenants: %d\n", te.config.Execution.NoOfTenants)
+
+	stats := &logoutStats{}
+
+	// Calculate users per thread
+	usersPerThread := te.config.Execution.NoOfUsers / te.config.Execution.NoOfThreads
+	remainingUsers := te.config.Execution.NoOfUsers % te.config.Execution.NoOfThreads
+
+	var tasks []WorkerTask
+	userStart := te.config.Execution.UserStartNumber
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		threadUsers := usersPerThread
+		if threadID < remainingUsers {
+			threadUsers++ // Distribute remaining users to first few threads
+		}
+
+		if threadUsers == 0 {
+			continue
+		}
+
+		userEnd := userStart + threadUsers - 1
+		tasks = append(tasks, WorkerTask{
+			UserStart: userStart,
+			UserEnd:   userEnd,
+			ThreadID:  threadID,
+			Client:    te.newHTTPClient(),
+		})
+		userStart = userEnd + 1
+	}
+
+	fmt.Println("Logging users in...")
+	sessions := make([][]*VirtualUser, len(tasks))
+
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(i int, task WorkerTask) {
+			defer wg.Done()
+			sessions[i] = te.loginWorker(task, stats)
+		}(i, task)
+	}
+	wg.Wait()
+
+	if te.interrupted() {
+		stats.print(0)
+		return ErrInterrupted
+	}
+
+	fmt.Println("Logging users out...")
+	startTime := time.Now()
+
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(i int, task WorkerTask) {
+			defer wg.Done()
+			te.logoutWorker(task, sessions[i], stats)
+		}(i, task)
+	}
+	wg.Wait()
+
+	elapsed := time.Since(startTime)
+	if te.interrupted() {
+		fmt.Printf("\nLogout phase interrupted after %v\n", elapsed)
+		stats.print(elapsed)
+		return ErrInterrupted
+	}
+	fmt.Printf("\nLogout phase completed in %v\n", elapsed)
+
+	stats.print(elapsed)
+
+	return nil
+}
+
+// loginWorker logs in the assigned user range in all tenants and returns the logged-in users
+func (te *TestExecutor) loginWorker(task WorkerTask, stats *logoutStats) []*VirtualUser {
+	tenantStart := te.config.Execution.TenantStartNumber
+	tenantEnd := tenantStart + te.config.Execution.NoOfTenants
+
+	var users []*VirtualUser
+	for userIndex := task.UserStart; userIndex <= task.UserEnd && !te.interrupted(); userIndex++ {
+		for tenantIndex := tenantStart; tenantIndex < tenantEnd && !te.interrupted(); tenantIndex++ {
+			user := te.newVirtualUser(tenantIndex, userIndex)
+
+			err := user.Login(te.ctx, task.Client)
+			if err != nil && te.interrupted() {
+				break
+			}
+			if err == nil && user.Tokens.IDToken == "" {
+				err = fmt.Errorf("no ID token issued, is the openid scope requested?")
+			}
+
+			stats.mutex.Lock()
+			if err != nil {
+				stats.loginFailed++
+			} else {
+				stats.loggedIn++
+			}
+			stats.mutex.Unlock()
+
+			if err != nil {
+				printFailure("Thread %d: Failed to log in user %s in tenant %d: %v\n",
+					task.ThreadID, user.Username, tenantIndex, err)
+				continue
+			}
+
+			users = append(users, user)
+		}
+	}
+
+	return users
+}
+
+// logoutWorker logs the given users out one after another
+func (te *TestExecutor) logoutWorker(task WorkerTask, users []*VirtualUser, stats *logoutStats) {
+	for _, user := range users {
+		if te.interrupted() {
+			return
+		}
+
+		requestStart := time.Now()
+		err := user.Logout(te.ctx, task.Client)
+		duration := time.Since(requestStart)
+
+		// A logout aborted by the interruption is not counted
+		if err != nil && te.interrupted() {
+			return
+		}
+
+		stats.mutex.Lock()
+		if err != nil {
+			stats.logoutFailed++
+		} else {
+			stats.loggedOut++
+			stats.logoutLatencies = append(stats.logoutLatencies, duration)
+		}
+		stats.mutex.Unlock()
+
+		if err != nil {
+			printFailure("Thread %d: Failed to log out user %s in tenant %d: %v\n",
+				task.ThreadID, user.Username, user.TenantIndex, err)
+		}
+	}
+}
+
+// Logout performs OIDC RP-initiated logout for a browser session, approving the logout consent
+// when asked, until the server redirects to the post logout redirect URI
+func (h *HTTPClient) Logout(ctx context.Context, session *browserSession, tenantIndex int, idToken string) error {
+	state := randomState()
+	logoutURL := h.absoluteURL(h.config.GetTenantPath(h.config.OAuth.LogoutPath, tenantIndex))
+
+	query := url.Values{}
+	query.Set("id_token_hint", idToken)
+	query.Set("post_logout_redirect_uri", h.config.OAuth.RedirectURI)
+	query.Set("state", state)
+
+	status, location, err := session.get(ctx, logoutURL+"?"+query.Encode())
+
+	// A handful of hops covers logout -> consent -> commonauth -> logout -> redirect
+	for hop := 0; hop < 10; hop++ {
+		if err != nil {
+			return fmt.Errorf("logout request failed: %v", err)
+		}
+
+		if status < 300 || status >= 400 {
+			return fmt.Errorf("unexpected status %d during logout", status)
+		}
+
+		if strings.HasPrefix(location, h.config.OAuth.RedirectURI) {
+			callback, parseErr := url.Parse(location)
+			if parseErr != nil {
+				return fmt.Errorf("invalid post logout location %q: %v", location, parseErr)
+			}
+			if errCode := callback.Query().Get("error"); errCode != "" {
+				return fmt.Errorf("logout failed: %s %s", errCode, callback.Query().Get("error_description"))
+			}
+			return nil
+		}
+
+		if strings.Contains(location, "logout_consent") {
+			form := url.Values{}
+			form.Set("consent", "approve")
+			status, location, err = session.postForm(ctx, logoutURL, form)
+		} else {
+			status, location, err = session.get(ctx, h.absoluteURL(location))
+		}
+	}
+
+	return fmt.Errorf("too many redirects during logout")
+}
+
+// print prints the logout phase summary
+func (ls *logoutStats) print(elapsed time.Duration) {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+
+	fmt.Println("\n=== Logout Statistics ===")
+	fmt.Printf("Logins - Total: %d, Successful: %d, Failed: %d\n",
+		ls.loggedIn+ls.loginFailed, ls.loggedIn, ls.loginFailed)
+	// A run interrupted while logging in has no logout time yet
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(ls.loggedOut) / elapsed.Seconds()
+	}
+	fmt.Printf("Logouts - Total: %d, Successful: %d, Failed: %d, Rate: %.2f/s\n",
+		ls.loggedOut+ls.logoutFailed, ls.loggedOut, ls.logoutFailed, rate)
+	if len(ls.logoutLatencies) > 0 {
+		fmt.Printf("Logout Latency - %s\n", SummarizeLatencies(ls.logoutLatencies))
+	}
+	fmt.Println("=========================")
+}
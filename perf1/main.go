@@ -1,77 +1,324 @@
 package main
 
 import (
-	"flag"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
-	var configPath string
-	var generateConfig bool
-	var retryFailed bool
+	cmd, args, named, err := parseCommandLine(os.Args[1:])
+	if err != nil {
+		printFailure("%v\n\n", err)
+		printCommands(os.Stderr)
+		os.Exit(2)
+	}
 	
-	flag.StringVar(&configPath, "config", "", "Path to configuration file (JSON)")
-	flag.BoolVar(&generateConfig, "generate-config", false, "Generate default configuration file")
-	flag.BoolVar(&retryFailed, "retry-failed", false, "Retry only failed users from failedUsers.csv")
+	// Utility commands send no load
+	if cmd.run != nil {
+		if err := cmd.run(cmd, args); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
 	
-	// Parse flags first to handle help and generate-config
-	flag.Parse()
+	runTest(cmd, args, named)
+}
+
+// runTest runs the execution mode of a test command. A scenario preset selects its own mode when
+// no command is named on the command line.
+func runTest(cmd *cliCommand, args []string, named bool) {
+	opts, err := cmd.parseOptions(args)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	scenario := opts.scenario
 	
-	// Handle generate config option
-	if generateConfig {
-		if configPath == "" {
-			configPath = "config.json"
+	var startTime time.Time
+	if opts.startAt != "" {
+		parsed, parseErr := time.Parse(time.RFC3339, opts.startAt)
+		if parseErr != nil {
+			log.Fatalf("Invalid -start-at time, expected RFC3339 such as 2024-05-01T10:00:00Z: %v", parseErr)
 		}
-		
-		config := DefaultConfig()
-		if err := config.SaveConfig(configPath); err != nil {
-			log.Fatalf("Failed to generate config file: %v", err)
+		startTime = parsed
+	}
+	
+	var health *healthServer
+	if opts.healthAddr != "" {
+		health, err = StartHealthServer(opts.healthAddr)
+		if err != nil {
+			log.Fatalf("Failed to start health server: %v", err)
 		}
-		
-		fmt.Printf("Default configuration saved to: %s\n", configPath)
-		fmt.Println("You can modify this file and run with -config flag")
-		return
 	}
 	
-	// Load configuration
-	config, err := LoadConfig(configPath)
+	// Load configuration, with the configuration flags applied over the file
+	config, positional, err := cmd.loadConfig(opts, args)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if len(positional) > 0 {
+		log.Fatalf("Unexpected argument %q, the %s command takes flags only", positional[0], cmd.name)
+	}
+	config.Execution.Resume = opts.resume
+	config.Execution.RetryRounds = opts.retryRounds
+	config.Execution.RetryRoundBackoff = opts.retryBackoff
+	
+	// An approved plan replaces the configuration and mode, keeping only the secrets of the configuration
+	var planMode ExecutionMode
+	if opts.fromPlan != "" {
+		planConfig, loadedMode, digest, planErr := LoadExecutionPlan(opts.fromPlan, config)
+		if planErr != nil {
+			log.Fatalf("Failed to load execution plan: %v", planErr)
+		}
+		planConfig.Execution.Resume = opts.resume
+		planConfig.Execution.RetryRounds = opts.retryRounds
+		planConfig.Execution.RetryRoundBackoff = opts.retryBackoff
+		config = planConfig
+		planMode = loadedMode
+		fmt.Printf("Executing plan %s (%s mode, sha256 %s)\n", opts.fromPlan, planMode, digest)
+	}
 	
 	// Print configuration summary
 	fmt.Println("=== SCIM2 Test Configuration ===")
+	fmt.Printf("Client: %s\n", clientBuild)
+	if scenario != nil {
+		fmt.Printf("Scenario: %s\n", scenario.Name)
+	}
 	fmt.Printf("Server: %s\n", config.GetServerURL())
 	fmt.Printf("Username: %s\n", config.Server.Username)
 	fmt.Printf("Tenant Prefix: %s\n", config.Test.TenantPrefix)
 	fmt.Printf("Role Name: %s\n", config.Test.RoleName)
+	if len(config.Test.TenantRoles) > 0 {
+		fmt.Printf("Tenant Roles: %d tenants with roles of their own\n", len(config.Test.TenantRoles))
+	}
+	if config.Test.SkipRoleCreation {
+		fmt.Println("Role Creation: skipped, roles are managed outside the test")
+	}
 	fmt.Printf("Username Prefix: %s\n", config.Test.UsernamePrefix)
 	fmt.Printf("Threads: %d\n", config.Execution.NoOfThreads)
 	fmt.Printf("Users: %d\n", config.Execution.NoOfUsers)
 	fmt.Printf("Tenants: %d\n", config.Execution.NoOfTenants)
 	fmt.Printf("Ramp-up Period: %d seconds\n", config.Execution.RampUpPeriod)
 	fmt.Printf("CSV Output: %s\n", config.Execution.ScimIdCsvPath)
+	if config.Offline {
+		fmt.Printf("Offline: only %s is contacted\n", config.GetServerURL())
+	}
 	fmt.Println("===============================")
 	fmt.Println()
 	
+	mode := cmd.mode
+	if scenario != nil && !named {
+		mode = scenario.Mode
+	}
+	if opts.fromPlan != "" {
+		mode = planMode
+	}
+	
+	if opts.exportPlan != "" {
+		digest, planErr := SaveExecutionPlan(opts.exportPlan, BuildExecutionPlan(config, mode))
+		if planErr != nil {
+			log.Fatalf("Failed to export execution plan: %v", planErr)
+		}
+		
+		fmt.Printf("Execution plan saved to: %s (sha256 %s)\n", opts.exportPlan, digest)
+		fmt.Println("Run it after review with -from-plan")
+		return
+	}
+	
+	if opts.dryRun {
+		PrintRampSchedule(BuildExecutionPlan(config, mode), opts.dryRunLatency)
+		return
+	}
+	
 	// Create and execute test
-	executor, err := NewTestExecutor(config, retryFailed)
+	executor, err := NewTestExecutor(config, mode)
 	if err != nil {
 		log.Fatalf("Failed to create test executor: %v", err)
 	}
 	defer executor.Close()
+	
+	// The first SIGINT or SIGTERM stops the workers gracefully, a second one exits immediately
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		printWarning("\nReceived %v, stopping workers (send again to exit immediately)\n", sig)
+		cancel()
+		signal.Reset(syscall.SIGINT, syscall.SIGTERM)
+	}()
+	executor.SetContext(ctx)
+	
+	// Wait for the agreed start time, so independent clients begin generating load together
+	if !startTime.IsZero() {
+		if err := waitForStart(ctx, startTime); err != nil {
+			executor.Close()
+			os.Exit(130)
+		}
+	}
+	
+	runStart := time.Now()
+	
+	// fail reports the partial results of an interrupted run and exits, or aborts on any other error.
+	// Either way the background goroutines are stopped and the executor is closed first, so queued
+	// SCIM IDs, failed users and the CSV manifests are written before the process exits.
+	fail := func(message string, err error) {
+		executor.StopAlerts()
+		executor.StopErrorBudget()
+		executor.StopReporters()
+		executor.StopProgress()
+		
+		if !errors.Is(err, ErrInterrupted) {
+			executor.Close()
+			log.Printf("%s: %v", message, err)
+			os.Exit(1)
+		}
+		
+		printWarning("\n=== Partial Results ===\n")
+		executor.PrintStats()
+		if err := executor.WriteReport(mode, runStart); err != nil {
+			printWarning("Failed to write HTML report: %v\n", err)
+		}
+		executor.Close()
+		
+		// A run that aborted itself failed, unlike one stopped by a signal
+		if cause := executor.abortCause(); cause != nil {
+			printFailure("Run aborted: %v\n", cause)
+			os.Exit(1)
+		}
+		os.Exit(130)
+	}
+	
+	executor.StartProgress(mode.String())
+	if err := executor.StartAlerts(); err != nil {
+		fail("Failed to start alerts", err)
+	}
+	if err := executor.StartErrorBudget(); err != nil {
+		fail("Failed to start error budget", err)
+	}
+	if err := executor.StartReporters(mode.String()); err != nil {
+		fail("Failed to start reporters", err)
+	}
+	health.SetReady(true)
 
 	// Execute the test
-	if retryFailed {
+	switch mode {
+	case ModeRetryFailed:
 		if err := executor.ExecuteRetryFailed(); err != nil {
-			log.Fatalf("Retry failed users execution failed: %v", err)
+			fail("Retry failed users execution failed", err)
+		}
+	case ModeRetryRoles:
+		if err := executor.ExecuteRetryFailedRoles(); err != nil {
+			fail("Retry failed roles execution failed", err)
+		}
+	case ModeCleanup:
+		if err := executor.ExecuteCleanup(); err != nil {
+			fail("Cleanup failed", err)
+		}
+	case ModeRead:
+		if err := executor.ExecuteUserRead(); err != nil {
+			fail("User read failed", err)
+		}
+	case ModeRace:
+		if err := executor.ExecuteDuplicateRace(); err != nil {
+			fail("Duplicate-create race failed", err)
+		}
+	case ModeChurn:
+		if err := executor.ExecuteChurn(); err != nil {
+			fail("Churn workload failed", err)
+		}
+	case ModeGrowth:
+		if err := executor.ExecuteGrowth(); err != nil {
+			fail("Growth benchmark failed", err)
+		}
+	case ModeGroupScale:
+		if err := executor.ExecuteGroupScale(); err != nil {
+			fail("Group membership scale test failed", err)
+		}
+	case ModeRoleScale:
+		if err := executor.ExecuteRoleScale(); err != nil {
+			fail("Role count scaling test failed", err)
 		}
-	} else {
+	case ModeAttributeSweep:
+		if err := executor.ExecuteAttributeSweep(); err != nil {
+			fail("Attribute count sweep failed", err)
+		}
+	case ModeToken:
+		if err := executor.ExecuteTokenPhase(); err != nil {
+			fail("Token phase failed", err)
+		}
+	case ModeLogout:
+		if err := executor.ExecuteLogoutPhase(); err != nil {
+			fail("Logout phase failed", err)
+		}
+	case ModeSessionSoak:
+		if err := executor.ExecuteSessionSoak(); err != nil {
+			fail("Session soak failed", err)
+		}
+	case ModeUpdate:
+		if err := executor.ExecuteUpdatePhase(); err != nil {
+			fail("User update phase failed", err)
+		}
+	case ModeMix:
+		if err := executor.ExecuteScenarioMix(); err != nil {
+			fail("Scenario mix failed", err)
+		}
+	case ModeGroups:
+		if err := executor.ExecuteGroupPhase(); err != nil {
+			fail("Group creation failed", err)
+		}
+	case ModeIdPs:
+		if err := executor.ExecuteIdPPhase(); err != nil {
+			fail("Identity provider creation failed", err)
+		}
+	case ModeAPIResources:
+		if err := executor.ExecuteAPIResourcePhase(); err != nil {
+			fail("API resource phase failed", err)
+		}
+	case ModeBulk:
+		if err := executor.ExecuteBulk(); err != nil {
+			fail("Bulk user creation failed", err)
+		}
+	case ModeLogin:
+		if err := executor.ExecuteLoginPhase(); err != nil {
+			fail("Login phase failed", err)
+		}
+	case ModePatch:
+		if err := executor.ExecutePatchPhase(); err != nil {
+			fail("User patch phase failed", err)
+		}
+	default:
 		if err := executor.Execute(); err != nil {
-			log.Fatalf("Test execution failed: %v", err)
+			fail("Test execution failed", err)
 		}
 	}
+	
+	// A phase that was already finishing when the run was interrupted or aborted still reports it
+	if executor.interrupted() {
+		fail("Test execution interrupted", ErrInterrupted)
+	}
 
-	fmt.Println("Test execution completed successfully!")
+	executor.StopAlerts()
+	executor.StopErrorBudget()
+	executor.StopReporters()
+	executor.StopProgress()
+	
+	// The run itself succeeded, so report files that cannot be written only warn and the deferred
+	// Close still flushes the other outputs
+	if err := executor.WriteHeatmap(); err != nil {
+		printWarning("Failed to write latency heatmap: %v\n", err)
+	}
+	
+	if err := executor.WriteReport(mode, runStart); err != nil {
+		printWarning("Failed to write HTML report: %v\n", err)
+	}
+	
+	printSuccess("Test execution completed successfully!\n")
 }
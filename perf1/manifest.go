@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// manifestSuffix is appended to the name of an output file to name its manifest
+const manifestSuffix = ".manifest.json"
+
+// OutputManifest describes a CSV output file as it was when the run closed it. A file that was
+// cut short by a crash has no manifest, or a stale one that no longer matches its size or checksum.
+type OutputManifest struct {
+	File     string    `json:"file"`
+	Rows     int       `json:"rows"` // data rows, without the header
+	Bytes    int64     `json:"bytes"`
+	SHA256   string    `json:"sha256"`
+	ClosedAt time.Time `json:"closedAt"`
+	Client   BuildInfo `json:"client"` // the build that wrote the file
+}
+
+// writeOutputManifest counts the rows of a CSV output file with a header, checksums it and writes
+// the manifest next to it
+func writeOutputManifest(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	counter := &countingReader{r: io.TeeReader(file, hash)}
+	reader := csv.NewReader(counter)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	records := 0
+	for {
+		if _, err := reader.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		records++
+	}
+
+	manifest := OutputManifest{
+		File:     path,
+		Bytes:    counter.n,
+		SHA256:   hex.EncodeToString(hash.Sum(nil)),
+		ClosedAt: time.Now(),
+		Client:   clientBuild,
+	}
+	if records > 0 {
+		manifest.Rows = records - 1
+	}
+
+	return writeFileAtomic(path+manifestSuffix, manifest)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeManifest writes the manifest of a closed output file when manifests are enabled, warning
+// rather than failing the run when it cannot
+func (o csvOutputOptions) writeManifest(path string) {
+	if !o.manifest {
+		return
+	}
+	if err := writeOutputManifest(path); err != nil {
+		printWarning("Failed to write the manifest of %s: %v\n", path, err)
+	}
+}
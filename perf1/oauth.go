@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TokenResponse represents the response from the OAuth2 token endpoint
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope"`
+}
+
+// RequestPasswordGrant obtains a token for a tenant user using the resource owner password grant
+func (h *HTTPClient) RequestPasswordGrant(ctx context.Context, tenantIndex int, username, password string) (*TokenResponse, error) {
+	return h.RequestPasswordGrantWithScope(ctx, tenantIndex, username, password, h.config.OAuth.Scope)
+}
+
+// RequestPasswordGrantWithScope obtains a token for a tenant user using the resource owner password
+// grant, requesting the given scope instead of the configured one
+func (h *HTTPClient) RequestPasswordGrantWithScope(ctx context.Context, tenantIndex int, username, password, scope string) (*TokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "password")
+	form.Set("username", h.config.GetTenantQualifiedUsername(username, tenantIndex))
+	form.Set("password", password)
+	if scope != "" {
+		form.Set("scope", scope)
+	}
+
+	return h.requestToken(ctx, tenantIndex, form)
+}
+
+// requestToken posts a grant to the token endpoint, authenticating with the configured client credentials
+func (h *HTTPClient) requestToken(ctx context.Context, tenantIndex int, form url.Values) (*TokenResponse, error) {
+	reqURL := h.config.GetServerURL() + h.config.GetTenantPath(h.config.OAuth.TokenPath, tenantIndex)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	switch h.config.OAuth.ClientAuthMethod {
+	case "private_key_jwt":
+		assertion, err := h.clientAssertion(reqURL)
+		if err != nil {
+			return nil, err
+		}
+		form.Set("client_id", h.config.OAuth.ClientID)
+		form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		form.Set("client_assertion", assertion)
+	case "none":
+		// Public clients such as mobile apps only identify themselves
+		form.Set("client_id", h.config.OAuth.ClientID)
+	case "client_secret_post":
+		form.Set("client_id", h.config.OAuth.ClientID)
+		form.Set("client_secret", h.config.OAuth.ClientSecret)
+	default:
+		req.SetBasicAuth(h.config.OAuth.ClientID, h.config.OAuth.ClientSecret)
+	}
+
+	// The form is only complete once client authentication has been added
+	encoded := form.Encode()
+	req.Body = io.NopCloser(strings.NewReader(encoded))
+	req.ContentLength = int64(len(encoded))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute token request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Operation: "token request", StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var tokenResp TokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token response: %v", err)
+	}
+
+	return &tokenResp, nil
+}
+
+// clientAssertion builds a private_key_jwt client assertion for the token endpoint.
+// The signing key is loaded on first use and kept for the lifetime of the client.
+func (h *HTTPClient) clientAssertion(audience string) (string, error) {
+	if h.assertionKey == nil {
+		key, err := LoadRSAPrivateKey(h.config.OAuth.PrivateKeyPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to load client assertion key: %v", err)
+		}
+		h.assertionKey = key
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("failed to generate assertion ID: %v", err)
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": h.config.OAuth.ClientID,
+		"sub": h.config.OAuth.ClientID,
+		"aud": audience,
+		"jti": hex.EncodeToString(jti),
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+
+	return SignJWT(claims, h.assertionKey, h.config.OAuth.SigningAlgorithm, h.config.OAuth.KeyID)
+}
+
+// FetchJWKS downloads the key set used to sign tokens issued for a tenant
+func (h *HTTPClient) FetchJWKS(ctx context.Context, tenantIndex int) (*JWKS, error) {
+	reqURL := h.config.GetServerURL() + h.config.GetTenantPath(h.config.OAuth.JWKSPath, tenantIndex)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %v", err)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute JWKS request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Operation: "JWKS request", StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var keys JWKS
+	if err := json.Unmarshal(body, &keys); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JWKS: %v", err)
+	}
+
+	return &keys, nil
+}
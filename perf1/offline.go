@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// checkOffline rejects, in offline mode, every setting that would send data to a host other than
+// the target server, so a run in an isolated lab fails at start rather than reaching out mid-run.
+// Any exporter or notifier added to the client must be checked here.
+func checkOffline(config *Config) error {
+	if !config.Offline {
+		return nil
+	}
+
+	if config.Alerts.Webhook != "" {
+		return fmt.Errorf("offline mode forbids the alert webhook %s; remove alertWebhook or set offline to false", config.Alerts.Webhook)
+	}
+	for _, name := range config.Reporters.Enabled {
+		if reporterRegistry[name].remote {
+			return fmt.Errorf("offline mode forbids the remote reporter %s; remove it from the reporters or set offline to false", name)
+		}
+	}
+	return nil
+}
+
+// offlineTransport refuses every request to a host other than the target server, so even a
+// redirect to another host cannot leave the lab
+type offlineTransport struct {
+	next   http.RoundTripper
+	target string
+}
+
+// offlineGuard returns the transport restricted to the target server in offline mode, and the
+// transport itself otherwise
+func offlineGuard(config *Config, next http.RoundTripper) http.RoundTripper {
+	if !config.Offline {
+		return next
+	}
+
+	target, _ := url.Parse(config.GetServerURL())
+	return &offlineTransport{next: next, target: hostPort(target)}
+}
+
+// RoundTrip sends the request if it is addressed to the target server
+func (t *offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if host := hostPort(req.URL); host != t.target {
+		return nil, fmt.Errorf("offline mode refused a request to %s, only %s may be contacted", host, t.target)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// hostPort returns the lower-case host and port a URL connects to, with the default port of its
+// scheme when it has none
+func hostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lateArrival is how long after its scheduled arrival a request may start before it counts as late
+const lateArrival = 100 * time.Millisecond
+
+// ErrArrivalDropped fails a user whose arrival found every request busy and the queue full
+var ErrArrivalDropped = errors.New("arrival dropped as the arrival queue was full")
+
+// validateArrivalRate checks the open workload settings. The arrivals set the pace on their own, so
+// they do not combine with the other ways of shaping user creation.
+func validateArrivalRate(execution ExecutionConfig) error {
+	if execution.ArrivalRate < 0 {
+		return fmt.Errorf("arrivalRate must not be negative")
+	}
+	if execution.ArrivalRate == 0 {
+		return nil
+	}
+	if execution.ArrivalConcurrency < 1 {
+		return fmt.Errorf("arrivalConcurrency must be at least 1 with arrivalRate")
+	}
+	if execution.ArrivalQueue < 0 {
+		return fmt.Errorf("arrivalQueue must not be negative")
+	}
+	if execution.TargetTPS > 0 || execution.RampProfile != "" {
+		return fmt.Errorf("arrivalRate cannot be combined with targetTps or rampProfile")
+	}
+	if execution.ParallelTenants || execution.PipelineTenants {
+		return fmt.Errorf("arrivalRate cannot be combined with parallelTenants or pipelineTenants")
+	}
+	return nil
+}
+
+// arrivalGenerator creates users as an open workload. Users arrive at a mean rate with
+// exponentially distributed gaps, a Poisson process like independent users, on a schedule that
+// never waits for responses. A slow server therefore builds up requests in flight instead of
+// slowing the arrivals, and every response time is measured from the scheduled arrival, so time
+// spent waiting for a slot counts too and coordinated omission does not hide the slowdown. The
+// arrivals waiting for a slot are bounded as well, so an unresponsive server cannot pile up
+// goroutines without limit; arrivals beyond the queue are dropped.
+type arrivalGenerator struct {
+	te      *TestExecutor
+	rate    float64
+	random  *rand.Rand
+	slots   chan int      // free request slots, at most arrivalConcurrency requests are in flight
+	pending chan struct{} // arrivals in flight or waiting for a slot
+	clients []*HTTPClient // the client of every slot, created on its first use
+
+	mutex         sync.Mutex
+	arrivals      int
+	dropped       int             // arrivals that found the queue full
+	late          int             // arrivals sent more than lateArrival after they were scheduled
+	responseTimes []time.Duration // from the scheduled arrival to the response
+	queueDelays   []time.Duration // from the scheduled arrival to a free slot
+	maxLag        time.Duration   // how far the generator fell behind its own schedule
+	started       time.Time
+	lastArrival   time.Time
+	ended         time.Time
+}
+
+// newArrivalGenerator creates the generator of the configured arrival rate
+func (te *TestExecutor) newArrivalGenerator() *arrivalGenerator {
+	concurrency := te.config.Execution.ArrivalConcurrency
+	ag := &arrivalGenerator{
+		te:      te,
+		rate:    te.config.Execution.ArrivalRate,
+		random:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		slots:   make(chan int, concurrency),
+		pending: make(chan struct{}, concurrency+te.config.Execution.ArrivalQueue),
+		clients: make([]*HTTPClient, concurrency),
+	}
+	for i := 0; i < concurrency; i++ {
+		ag.slots <- i
+	}
+	fmt.Printf("Open model: Poisson arrivals at %.2f users/s, up to %d requests in flight and %d queued\n",
+		ag.rate, concurrency, te.config.Execution.ArrivalQueue)
+	return ag
+}
+
+// run creates the users of the pool's queue as they arrive and returns once every arrival has its
+// response or the run is interrupted
+func (ag *arrivalGenerator) run(pool int, jobs <-chan userJob, resultChan chan<- TestResult, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	var requests sync.WaitGroup
+	ag.started = time.Now()
+	next := ag.started
+	for job := range jobs {
+		next = next.Add(time.Duration(ag.random.ExpFloat64() / ag.rate * float64(time.Second)))
+		if wait := time.Until(next); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ag.te.ctx.Done():
+				timer.Stop()
+			}
+		} else if -wait > ag.maxLag {
+			ag.maxLag = -wait
+		}
+
+		ag.mutex.Lock()
+		ag.arrivals++
+		ag.lastArrival = next
+		ag.mutex.Unlock()
+
+		select {
+		case ag.pending <- struct{}{}:
+			requests.Add(1)
+			go ag.arrive(next, pool, job, resultChan, &requests)
+		default:
+			ag.drop(pool, job, resultChan)
+		}
+	}
+
+	requests.Wait()
+	ag.ended = time.Now()
+}
+
+// drop fails the user of an arrival that found the queue full, so a retry creates it later. After
+// the interruption the user is left to the resumed run instead.
+func (ag *arrivalGenerator) drop(pool int, job userJob, resultChan chan<- TestResult) {
+	if ag.te.interrupted() {
+		ag.te.recordUnfinishedJob(pool, job)
+		return
+	}
+
+	ag.mutex.Lock()
+	ag.dropped++
+	ag.mutex.Unlock()
+
+	if ag.te.failedUsersWriter != nil {
+		username := ag.te.config.GetTestUsername(job.UserIndex)
+		if err := ag.te.failedUsersWriter.WriteFailedUser(newFailedUser(job.TenantIndex, username, ErrArrivalDropped)); err != nil {
+			printFailure("Failed to write failed user (Tenant:%d, Username:%s) to CSV: %v\n", job.TenantIndex, username, err)
+		}
+	}
+	resultChan <- TestResult{TenantIndex: job.TenantIndex, UserIndex: job.UserIndex, Error: ErrArrivalDropped}
+}
+
+// arrive sends the request of an arrival as soon as a slot is free
+func (ag *arrivalGenerator) arrive(arrival time.Time, pool int, job userJob, resultChan chan<- TestResult, requests *sync.WaitGroup) {
+	defer requests.Done()
+	defer func() { <-ag.pending }()
+
+	var slot int
+	select {
+	case slot = <-ag.slots:
+	case <-ag.te.ctx.Done():
+		ag.te.recordUnfinishedJob(pool, job)
+		return
+	}
+	defer func() { ag.slots <- slot }()
+	queueDelay := time.Since(arrival)
+
+	// A slot's client is only ever used by the arrival holding the slot
+	if ag.clients[slot] == nil {
+		ag.clients[slot] = ag.te.newHTTPClient()
+	}
+	if !ag.te.createQueuedUser(ag.clients[slot], slot, pool, job, resultChan) {
+		return
+	}
+	responseTime := time.Since(arrival)
+
+	ag.mutex.Lock()
+	ag.responseTimes = append(ag.responseTimes, responseTime)
+	ag.queueDelays = append(ag.queueDelays, queueDelay)
+	if queueDelay > lateArrival {
+		ag.late++
+	}
+	ag.mutex.Unlock()
+}
+
+// print prints the achieved arrival rate and the response times measured from the arrivals
+func (ag *arrivalGenerator) print() {
+	ag.mutex.Lock()
+	defer ag.mutex.Unlock()
+
+	// The rate is measured over the arrivals alone, as the responses no longer affect it
+	arrivalTime := ag.lastArrival.Sub(ag.started)
+	rate := 0.0
+	if arrivalTime > 0 {
+		rate = float64(ag.arrivals) / arrivalTime.Seconds()
+	}
+	queueDelays := SummarizeLatencies(ag.queueDelays)
+
+	fmt.Println("\n=== Open Model Arrivals ===")
+	fmt.Printf("Arrivals: %d in %v, %.2f users/s (target %.2f), last response after %v\n",
+		ag.arrivals, arrivalTime.Round(time.Millisecond), rate, ag.rate, ag.ended.Sub(ag.started).Round(time.Millisecond))
+	fmt.Printf("Response Time (from arrival): %s\n", SummarizeLatencies(ag.responseTimes))
+	fmt.Printf("Queueing Delay: %s\n", queueDelays)
+	fmt.Printf("Late Arrivals: %d sent more than %v after their arrival, %d dropped with the queue full\n", ag.late, lateArrival, ag.dropped)
+	fmt.Println("===========================")
+
+	if ag.dropped > 0 {
+		printWarning("%d arrivals were dropped as %d requests were in flight and %d arrivals queued; they are in the failed users CSV\n",
+			ag.dropped, cap(ag.slots), cap(ag.pending)-cap(ag.slots))
+	}
+
+	// Waiting for a slot is part of the response time users would see, but means the arrivals were
+	// held back by the client rather than only by the server
+	if queueDelays.P99 > lateArrival {
+		printWarning("Arrivals waited up to %v for one of the %d request slots; the server cannot keep up with the rate, or raise arrivalConcurrency\n",
+			queueDelays.Max.Round(time.Millisecond), cap(ag.slots))
+	}
+	if ag.maxLag > time.Second {
+		printWarning("The arrival generator fell %v behind its schedule; the client machine may be overloaded\n", ag.maxLag.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// operationTransport times every request sent through an HTTPClient and records the duration in
+// the client's statistics under an operation label derived from the request, so all operations
+// get consistent latency metrics without each worker measuring them
+type operationTransport struct {
+	next  http.RoundTripper
+	owner *HTTPClient
+}
+
+// RoundTrip sends the request and records its duration once the response body is closed, so the
+// measured time includes reading the response. A request with a deadline is aborted once it passes,
+// in addition to when the context it was created with is cancelled.
+func (t *operationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	label := operationLabel(req)
+	deadline := t.owner.requestDeadline(label)
+
+	release := func() {}
+	if deadline > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), deadline)
+		release = cancel
+		req = req.WithContext(ctx)
+	}
+
+	stats := t.owner.stats
+	tenant := t.owner.requestTenant(req)
+	if stats != nil {
+		stats.StartRequest(t.owner.worker)
+	}
+
+	start := time.Now()
+	if !t.owner.scheduled.due.IsZero() {
+		// Only the first attempt of a paced request was due on the schedule
+		if stats != nil {
+			stats.RecordScheduleSlip(t.owner.scheduled, start)
+		}
+		t.owner.scheduled = scheduleSlot{}
+	}
+	resp, err := t.next.RoundTrip(req)
+
+	accessLog := t.owner.accessLog
+	if err != nil {
+		if deadline > 0 && errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("%s exceeded its deadline of %v: %w", label, deadline, err)
+		}
+		release()
+		duration := time.Since(start)
+		if stats != nil {
+			stats.RecordOperation(label, duration, 0)
+			stats.FinishRequest(t.owner.worker, tenant, start, duration)
+		}
+		if accessLog != nil {
+			accessLog.Record(req, start, duration, 0, 0)
+		}
+		return resp, err
+	}
+
+	statusCode := resp.StatusCode
+	resp.Body = &timedBody{ReadCloser: resp.Body, done: func(size int64) {
+		release()
+		duration := time.Since(start)
+		if stats != nil {
+			stats.RecordOperation(label, duration, statusCode)
+			stats.FinishRequest(t.owner.worker, tenant, start, duration)
+		}
+		if accessLog != nil {
+			accessLog.Record(req, start, duration, statusCode, size)
+		}
+	}}
+
+	return resp, nil
+}
+
+// requestDeadline returns how long a request of the given operation may take before it is aborted,
+// 0 for no deadline besides the client timeout
+func (h *HTTPClient) requestDeadline(label string) time.Duration {
+	ms, ok := h.config.Server.RequestDeadlines[label]
+	if !ok {
+		ms = h.config.Server.RequestDeadline
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// timedBody calls done with the number of bytes read the first time the response body is closed
+type timedBody struct {
+	io.ReadCloser
+	once sync.Once
+	read int64
+	done func(read int64)
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *timedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.read) })
+	return err
+}
+
+var (
+	// tenantPathPrefix matches the tenant qualifier at the start of a request path
+	tenantPathPrefix = regexp.MustCompile(`^/t/[^/]+`)
+	// idPathSegment matches path segments that identify a single resource
+	idPathSegment = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F-]{32,36})$`)
+)
+
+// requestTenant returns the domain of the tenant a request is sent to: the tenant qualifier of its
+// path, or otherwise the tenant whose credentials the request is sent with, "" when neither is known
+func (h *HTTPClient) requestTenant(req *http.Request) string {
+	if qualifier := tenantPathPrefix.FindString(req.URL.Path); qualifier != "" {
+		return strings.TrimPrefix(qualifier, "/t/")
+	}
+	if tenantIndex, ok := requestTenantIndex(req); ok {
+		return h.config.GetTenantDomain(tenantIndex)
+	}
+	return ""
+}
+
+// operationLabel names the operation a request performs: the SOAP action for admin service calls,
+// otherwise the method and path with the tenant qualifier and resource IDs replaced by placeholders
+func operationLabel(req *http.Request) string {
+	if action := req.Header.Get("SOAPAction"); action != "" {
+		action = strings.Trim(action, `"`)
+		if i := strings.LastIndexAny(action, ":/"); i >= 0 {
+			action = action[i+1:]
+		}
+		return "SOAP " + action
+	}
+
+	path := tenantPathPrefix.ReplaceAllString(req.URL.Path, "/t/{tenant}")
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if idPathSegment.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+
+	return req.Method + " " + strings.Join(segments, "/")
+}
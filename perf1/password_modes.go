@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"regexp"
+	"time"
+)
+
+// How the password of a test user is sent in the SCIM payload
+const (
+	// PasswordModePlaintext sends the password as it is, for the server to hash
+	PasswordModePlaintext = "plaintext"
+	// PasswordModePrehashed sends the digest of the password, for a user store that takes pre-hashed
+	// passwords and stores them as they are. The digest is then the user's password, so the user
+	// logs in with it as well.
+	PasswordModePrehashed = "prehashed"
+	// PasswordModeCompare sends half of the users' passwords pre-hashed, so the creation throughput
+	// of both modes is measured against the same server in the same run
+	PasswordModeCompare = "compare"
+)
+
+// passwordHashes are the digests a pre-hashed password can be sent with
+var passwordHashes = map[string]func() hash.Hash{
+	"SHA-1":   sha1.New,
+	"SHA-256": sha256.New,
+	"SHA-512": sha512.New,
+}
+
+// validatePasswordMode checks the password mode and the digest of pre-hashed passwords, which must
+// satisfy the server's password policy as the users log in with them
+func validatePasswordMode(test TestConfig) error {
+	switch test.PasswordMode {
+	case "", PasswordModePlaintext:
+		return nil
+	case PasswordModePrehashed, PasswordModeCompare:
+		if _, ok := passwordHashes[test.PasswordHash]; !ok {
+			return fmt.Errorf("unknown password hash %q, expected SHA-1, SHA-256 or SHA-512", test.PasswordHash)
+		}
+		if test.PasswordPolicyRegex == "" {
+			return nil
+		}
+		policy, err := regexp.Compile(test.PasswordPolicyRegex)
+		if err != nil {
+			return fmt.Errorf("invalid password policy regex %q: %v", test.PasswordPolicyRegex, err)
+		}
+		// Every digest of an algorithm has the same length and base64 characters, so one stands for all
+		if digest := hashPassword(test.PasswordHash, test.UserPassword); !policy.MatchString(digest) {
+			return fmt.Errorf("%s digests are %d characters, which the password policy %s rejects; use a shorter digest or set passwordPolicyRegex to the server's policy",
+				test.PasswordHash, len(digest), test.PasswordPolicyRegex)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown password mode %q, expected %s, %s or %s", test.PasswordMode, PasswordModePlaintext, PasswordModePrehashed, PasswordModeCompare)
+}
+
+// prehashed reports whether the user's password is sent pre-hashed. In compare mode the choice is a
+// hash of the user, so a retried user is sent the way it was the first time.
+func (c TestConfig) prehashed(tenantIndex int, username string) bool {
+	switch c.PasswordMode {
+	case PasswordModePrehashed:
+		return true
+	case PasswordModeCompare:
+		hash := fnv.New64a()
+		fmt.Fprintf(hash, "password mode/%d/%s", tenantIndex, username)
+		return mix64(hash.Sum64())%2 == 1
+	}
+	return false
+}
+
+// passwordModeOf returns the mode the user's password is sent in
+func (c TestConfig) passwordModeOf(tenantIndex int, username string) string {
+	if c.prehashed(tenantIndex, username) {
+		return PasswordModePrehashed
+	}
+	return PasswordModePlaintext
+}
+
+// hashPassword returns the base64 encoded digest of the password, the form the JDBC user store
+// keeps passwords in when they are not salted
+func hashPassword(algorithm, password string) string {
+	digest := passwordHashes[algorithm]()
+	digest.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(digest.Sum(nil))
+}
+
+// sentPassword returns the password a test user is created and logs in with: the password of the
+// user, or its digest when the user is sent pre-hashed, as the server stores the digest as it is
+func (c TestConfig) sentPassword(tenantIndex int, username, password string) string {
+	if c.prehashed(tenantIndex, username) {
+		return hashPassword(c.PasswordHash, password)
+	}
+	return password
+}
+
+// recordPasswordMode records the latency of creating a user under the mode its password was sent in,
+// when the modes are compared
+func (te *TestExecutor) recordPasswordMode(tenantIndex int, username string, duration time.Duration, err error) {
+	if te.config.Test.PasswordMode != PasswordModeCompare {
+		return
+	}
+	te.stats.RecordPasswordMode(te.config.Test.passwordModeOf(tenantIndex, username), duration, err == nil)
+}
+
+// RecordPasswordMode records the latency of creating a user with its password sent in the given mode
+func (ts *TestStats) RecordPasswordMode(mode string, duration time.Duration, success bool) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if ts.passwordModes == nil {
+		ts.passwordModes = make(map[string]*policyLatencies)
+	}
+	pl, ok := ts.passwordModes[mode]
+	if !ok {
+		pl = &policyLatencies{}
+		ts.passwordModes[mode] = pl
+	}
+	pl.latencies = append(pl.latencies, duration)
+	if !success {
+		pl.failed++
+	}
+}
+
+// SummaryPasswordMode holds the user creations of the users whose password was sent in a mode
+type SummaryPasswordMode struct {
+	Mode    string         `json:"mode"`
+	Created int            `json:"created"`
+	Failed  int            `json:"failed"`
+	Latency SummaryLatency `json:"latency"`
+
+	// WorkerRate is the creations per second a worker sustains in the mode, one over the average
+	// latency. Both modes share the workers and the run, so their rates compare where their counts
+	// over the run would not.
+	WorkerRate float64 `json:"workerRate"`
+}
+
+// passwordModeSummaries returns the user creations by password mode, plaintext first. Called with
+// the lock held.
+func (ts *TestStats) passwordModeSummaries() []SummaryPasswordMode {
+	var summaries []SummaryPasswordMode
+	for _, mode := range []string{PasswordModePlaintext, PasswordModePrehashed} {
+		pl, ok := ts.passwordModes[mode]
+		if !ok {
+			continue
+		}
+		latency := SummarizeLatencies(pl.latencies)
+		summary := SummaryPasswordMode{
+			Mode:    mode,
+			Created: len(pl.latencies) - pl.failed,
+			Failed:  pl.failed,
+			Latency: newSummaryLatency(latency),
+		}
+		if latency.Avg > 0 {
+			summary.WorkerRate = float64(time.Second) / float64(latency.Avg)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// printPasswordModes prints the user creations by password mode, with the change of the pre-hashed
+// rate against the plaintext one
+func printPasswordModes(summaries []SummaryPasswordMode) {
+	if len(summaries) == 0 {
+		return
+	}
+
+	fmt.Println("User Creation By Password Mode:")
+	for _, s := range summaries {
+		fmt.Printf("  %s (%d created, %d failed) - %.2f users/s per worker - %s\n", s.Mode, s.Created, s.Failed, s.WorkerRate, s.Latency.summary())
+	}
+	if len(summaries) == 2 && summaries[0].WorkerRate > 0 {
+		fmt.Printf("  %s vs %s: %+.1f%% throughput\n", summaries[1].Mode, summaries[0].Mode, (summaries[1].WorkerRate/summaries[0].WorkerRate-1)*100)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Characters the classes of a password pattern are filled with
+const (
+	passwordUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordLower   = "abcdefghijklmnopqrstuvwxyz"
+	passwordDigits  = "0123456789"
+	passwordSymbols = "!@#$%^&*-_=+"
+)
+
+// passwordToken is a run of characters of a password pattern: a class filled with random
+// characters of the class, or a literal character
+type passwordToken struct {
+	chars   string // the class, empty for a literal
+	literal rune
+	repeat  int
+}
+
+// passwordPolicies gives every user the password of its cohort. The cohort and the password are a
+// hash of the user, so the login phases and retries of later runs derive the same password.
+type passwordPolicies struct {
+	cohorts  *weightedValues
+	patterns map[string][]passwordToken
+}
+
+// newPasswordPolicies validates the password policies and builds the passwords of their cohorts,
+// nil when no policy is configured so every user keeps the configured user password
+func newPasswordPolicies(policies []PasswordPolicy) (*passwordPolicies, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	p := &passwordPolicies{patterns: make(map[string][]passwordToken)}
+	weights := make(map[string]int)
+	for _, policy := range policies {
+		if policy.Name == "" {
+			return nil, fmt.Errorf("every password policy needs a name")
+		}
+		if _, ok := p.patterns[policy.Name]; ok {
+			return nil, fmt.Errorf("password policy %q is defined more than once", policy.Name)
+		}
+		tokens, err := parsePasswordPattern(policy.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("password policy %q: %v", policy.Name, err)
+		}
+		p.patterns[policy.Name] = tokens
+		weights[policy.Name] = policy.Weight
+	}
+
+	var err error
+	if p.cohorts, err = newWeightedValues("password policy", weights); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// parsePasswordPattern splits a password pattern into its tokens
+func parsePasswordPattern(pattern string) ([]passwordToken, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern is empty")
+	}
+
+	var tokens []passwordToken
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		token := passwordToken{repeat: 1}
+		switch runes[i] {
+		case 'A':
+			token.chars = passwordUpper
+		case 'a':
+			token.chars = passwordLower
+		case '9':
+			token.chars = passwordDigits
+		case '#':
+			token.chars = passwordSymbols
+		case '\\':
+			if i+1 == len(runes) {
+				return nil, fmt.Errorf("pattern ends with an escape")
+			}
+			i++
+			token.literal = runes[i]
+		case '{':
+			return nil, fmt.Errorf("repeat at position %d follows nothing", i+1)
+		default:
+			token.literal = runes[i]
+		}
+
+		// A repeat count applies to the token before it
+		if i+1 < len(runes) && runes[i+1] == '{' {
+			end := strings.IndexRune(string(runes[i+2:]), '}')
+			if end < 0 {
+				return nil, fmt.Errorf("repeat at position %d is not closed", i+2)
+			}
+			count := string(runes[i+2:])[:end]
+			n, err := strconv.Atoi(count)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("repeat {%s} must be a positive number", count)
+			}
+			token.repeat = n
+			i += 2 + len([]rune(count))
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// policy returns the name of the password policy of the user's cohort
+func (p *passwordPolicies) policy(tenantIndex int, username string) string {
+	return p.cohorts.choose(tenantIndex, username)
+}
+
+// password returns the password of the user, generated from the pattern of its cohort
+func (p *passwordPolicies) password(tenantIndex int, username string) string {
+	policy := p.policy(tenantIndex, username)
+
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "password/%s/%d/%s", policy, tenantIndex, username)
+	random := rand.New(rand.NewSource(int64(mix64(hash.Sum64()))))
+
+	var password strings.Builder
+	for _, token := range p.patterns[policy] {
+		for i := 0; i < token.repeat; i++ {
+			if token.chars == "" {
+				password.WriteRune(token.literal)
+			} else {
+				password.WriteByte(token.chars[random.Intn(len(token.chars))])
+			}
+		}
+	}
+	return password.String()
+}
+
+// String describes the cohorts, e.g. "short=70,long=30"
+func (p *passwordPolicies) String() string {
+	return p.cohorts.String()
+}
+
+// userPassword returns the password of a test user: that of its password policy, if policies are
+// configured, or the configured user password, as its digest when the user is sent pre-hashed
+func (te *TestExecutor) userPassword(tenantIndex int, username string) string {
+	password := te.config.Test.UserPassword
+	if te.passwords != nil {
+		password = te.passwords.password(tenantIndex, username)
+	}
+	return te.config.Test.sentPassword(tenantIndex, username, password)
+}
+
+// userPassword returns the password of a test user, see TestExecutor.userPassword
+func (h *HTTPClient) userPassword(tenantIndex int, username string) string {
+	password := h.config.Test.UserPassword
+	if h.passwords != nil {
+		password = h.passwords.password(tenantIndex, username)
+	}
+	return h.config.Test.sentPassword(tenantIndex, username, password)
+}
+
+// recordPasswordPolicy records the latency of an operation sending the password of a user under the
+// user's password policy, if policies are configured
+func (te *TestExecutor) recordPasswordPolicy(operation string, tenantIndex int, username string, duration time.Duration, err error) {
+	if te.passwords == nil {
+		return
+	}
+	te.stats.RecordPasswordPolicy(te.passwords.policy(tenantIndex, username), operation, duration, err == nil)
+}
+
+// policyLatencies holds the latencies of an operation of the users of a password policy
+type policyLatencies struct {
+	latencies []time.Duration
+	failed    int
+}
+
+// RecordPasswordPolicy records the latency of an operation of a user with the given password
+// policy, so the cost of validating passwords of different strength can be compared
+func (ts *TestStats) RecordPasswordPolicy(policy, operation string, duration time.Duration, success bool) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if ts.passwordPolicies == nil {
+		ts.passwordPolicies = make(map[string]*policyLatencies)
+	}
+	key := policy + "\x00" + operation
+	pl, ok := ts.passwordPolicies[key]
+	if !ok {
+		pl = &policyLatencies{}
+		ts.passwordPolicies[key] = pl
+	}
+	pl.latencies = append(pl.latencies, duration)
+	if !success {
+		pl.failed++
+	}
+}
+
+// SummaryPasswordPolicy holds the latency of an operation of the users of a password policy
+type SummaryPasswordPolicy struct {
+	Policy    string         `json:"policy"`
+	Operation string         `json:"operation"`
+	Failed    int            `json:"failed"`
+	Latency   SummaryLatency `json:"latency"`
+}
+
+// passwordPolicySummaries returns the latencies by password policy and operation, sorted by both.
+// Called with the lock held.
+func (ts *TestStats) passwordPolicySummaries() []SummaryPasswordPolicy {
+	summaries := make([]SummaryPasswordPolicy, 0, len(ts.passwordPolicies))
+	for key, pl := range ts.passwordPolicies {
+		policy, operation, _ := strings.Cut(key, "\x00")
+		summaries = append(summaries, SummaryPasswordPolicy{
+			Policy:    policy,
+			Operation: operation,
+			Failed:    pl.failed,
+			Latency:   newSummaryLatency(SummarizeLatencies(pl.latencies)),
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Operation != summaries[j].Operation {
+			return summaries[i].Operation < summaries[j].Operation
+		}
+		return summaries[i].Policy < summaries[j].Policy
+	})
+	return summaries
+}
+
+// printPasswordPolicies prints the latencies by password policy
+func printPasswordPolicies(summaries []SummaryPasswordPolicy) {
+	if len(summaries) == 0 {
+		return
+	}
+
+	fmt.Println("Latency By Password Policy:")
+	for _, s := range summaries {
+		fmt.Printf("  %s %s (%d, %d failed) - %s\n", s.Operation, s.Policy, s.Latency.Count, s.Failed, s.Latency.summary())
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// patchStats holds the outcome and latency of the PATCH requests of the patch phase
+type patchStats struct {
+	patched   int
+	failed    int
+	latencies []time.Duration
+	mutex     sync.Mutex
+}
+
+// patchJob is one PATCH of a user recorded in the SCIM ID CSV
+type patchJob struct {
+	TenantIndex int
+	ScimID      string
+	Pass        int
+}
+
+// patchTemplateData is the data the operation value templates are executed with, e.g. {{.ScimID}}
+type patchTemplateData struct {
+	ScimID string
+	Tenant int
+	Pass   int
+}
+
+// ExecutePatchPhase patches the users recorded in the SCIM ID CSV of a previous run with the
+// configured operation templates and reports the update throughput and latency, separately from
+// the creates that produced the file
+func (te *TestExecutor) ExecutePatchPhase() error {
+	cfg := te.config.Patch
+	if len(cfg.Operations) == 0 {
+		return fmt.Errorf("no patch operations configured")
+	}
+
+	threads := cfg.Threads
+	if threads <= 0 {
+		threads = te.config.Execution.NoOfThreads
+	}
+	passes := cfg.Passes
+	if passes < 1 {
+		passes = 1
+	}
+
+	users, err := readScimIDs(te.config.Execution.ScimIdCsvPath)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return fmt.Errorf("no SCIM IDs in %s", te.config.Execution.ScimIdCsvPath)
+	}
+
+	// Expand every template once up front, so a broken template fails the phase before any request
+	if _, err := te.patchOperations(patchTemplateData{ScimID: users[0].ScimID, Tenant: users[0].TenantIndex}); err != nil {
+		return err
+	}
+
+	fmt.Println("Starting user patch phase...")
+	fmt.Printf("- Users: %d from %s\n", len(users), te.config.Execution.ScimIdCsvPath)
+	fmt.Printf("- Passes: %d\n", passes)
+	fmt.Printf("- Threads: %d\n", threads)
+	for _, op := range cfg.Operations {
+		fmt.Printf("- Operation: %s %s\n", op.Op, op.Path)
+	}
+
+	stats := &patchStats{}
+	jobs := make(chan patchJob)
+
+	// Queue every user once per pass, stopping when the run is interrupted
+	go func() {
+		defer close(jobs)
+
+		for pass := 0; pass < passes; pass++ {
+			for _, user := range users {
+				select {
+				case jobs <- patchJob{TenantIndex: user.TenantIndex, ScimID: user.ScimID, Pass: pass}:
+				case <-te.ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	startTime := time.Now()
+	for threadID := 0; threadID < threads; threadID++ {
+		wg.Add(1)
+		go te.patchWorker(threadID, threads, jobs, stats, &wg)
+	}
+
+	wg.Wait()
+
+	elapsed := time.Since(startTime)
+	if te.interrupted() {
+		fmt.Printf("\nUser patch phase interrupted after %v\n", elapsed)
+		stats.print(elapsed)
+		return ErrInterrupted
+	}
+	fmt.Printf("\nUser patch phase completed in %v\n", elapsed)
+
+	stats.print(elapsed)
+
+	return nil
+}
+
+// patchWorker patches the users it takes from the queue until the queue is closed
+func (te *TestExecutor) patchWorker(threadID, threads int, jobs <-chan patchJob, stats *patchStats, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	waitForRampUp(te.ctx, te.rampUpStartDelay(threadID, threads))
+	client := te.newHTTPClient()
+
+	for job := range jobs {
+		if te.interrupted() {
+			continue
+		}
+
+		operations, err := te.patchOperations(patchTemplateData{ScimID: job.ScimID, Tenant: job.TenantIndex, Pass: job.Pass})
+		if err != nil {
+			stats.record(0, err)
+			printFailure("Thread %d: Failed to build patch of user %s in tenant %d: %v\n", threadID, job.ScimID, job.TenantIndex, err)
+			continue
+		}
+
+		requestStart := time.Now()
+		err = client.PatchUser(te.ctx, job.TenantIndex, job.ScimID, operations)
+		duration := time.Since(requestStart)
+
+		if err != nil && te.interrupted() {
+			continue
+		}
+
+		stats.record(duration, err)
+		te.stats.RecordError(err)
+
+		if err != nil {
+			printFailure("Thread %d: Failed to patch user %s in tenant %d: %v\n", threadID, job.ScimID, job.TenantIndex, err)
+		}
+	}
+}
+
+// patchOperations expands the configured operation templates for a user. A value that expands to
+// a JSON object or array is sent as that JSON, any other value as a string.
+func (te *TestExecutor) patchOperations(data patchTemplateData) ([]SCIMPatchOperation, error) {
+	operations := make([]SCIMPatchOperation, 0, len(te.config.Patch.Operations))
+	for _, op := range te.config.Patch.Operations {
+		text, err := te.vars.ExpandData(op.Value, data)
+		if err != nil {
+			return nil, err
+		}
+
+		var value interface{} = text
+		if trimmed := strings.TrimSpace(text); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			if err := json.Unmarshal([]byte(trimmed), &value); err != nil {
+				return nil, fmt.Errorf("value of %s operation on %q is not valid JSON: %v", op.Op, op.Path, err)
+			}
+		}
+
+		operations = append(operations, SCIMPatchOperation{Op: op.Op, Path: op.Path, Value: value})
+	}
+	return operations, nil
+}
+
+// PatchUser applies PATCH operations to a user using SCIM2 API
+func (h *HTTPClient) PatchUser(ctx context.Context, tenantIndex int, scimID string, operations []SCIMPatchOperation) error {
+	patch := SCIMPatchOp{
+		Schemas:    []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+		Operations: operations,
+	}
+
+	_, err := h.sendSCIMJSON(ctx, tenantIndex, "PATCH", "/Users/"+scimID, "user patch", patch, http.StatusOK, http.StatusNoContent)
+	return err
+}
+
+// record records the outcome and latency of a PATCH
+func (ps *patchStats) record(duration time.Duration, err error) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if err != nil {
+		ps.failed++
+		return
+	}
+
+	ps.patched++
+	ps.latencies = append(ps.latencies, duration)
+}
+
+// print prints the patch phase summary
+func (ps *patchStats) print(elapsed time.Duration) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	fmt.Println("\n=== Patch Statistics ===")
+	fmt.Printf("Patches - Total: %d, Successful: %d, Failed: %d, Rate: %.2f/s\n",
+		ps.patched+ps.failed, ps.patched, ps.failed, float64(ps.patched)/elapsed.Seconds())
+	if len(ps.latencies) > 0 {
+		fmt.Printf("Patch Latency - %s\n", SummarizeLatencies(ps.latencies))
+	}
+	fmt.Println("========================")
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// phaseRolesAndUsers is the phase of a run creating roles and users at the same time
+const phaseRolesAndUsers = "roles+users"
+
+// tenantGate lets workers wait until a tenant has been opened, e.g. once its role exists
+type tenantGate struct {
+	mutex   sync.Mutex
+	tenants map[int]chan struct{}
+	opened  map[int]bool
+	failed  error
+}
+
+// newTenantGate creates a gate with every tenant closed
+func newTenantGate() *tenantGate {
+	return &tenantGate{tenants: make(map[int]chan struct{}), opened: make(map[int]bool)}
+}
+
+// channel returns the channel that is closed when the tenant is opened. The caller must hold the
+// mutex.
+func (g *tenantGate) channel(tenantIndex int) chan struct{} {
+	ch, ok := g.tenants[tenantIndex]
+	if !ok {
+		ch = make(chan struct{})
+		if g.failed != nil {
+			close(ch)
+		}
+		g.tenants[tenantIndex] = ch
+	}
+	return ch
+}
+
+// Open releases the workers waiting for the tenant. Opening a tenant again has no effect; a nil
+// gate ignores the call.
+func (g *tenantGate) Open(tenantIndex int) {
+	if g == nil {
+		return
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	ch := g.channel(tenantIndex)
+	if !g.opened[tenantIndex] && g.failed == nil {
+		close(ch)
+	}
+	g.opened[tenantIndex] = true
+}
+
+// Fail releases the workers of every tenant not opened yet, now and later, with the given error,
+// e.g. when role creation stopped before reaching their tenants
+func (g *tenantGate) Fail(err error) {
+	if g == nil {
+		return
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.failed != nil {
+		return
+	}
+	for tenantIndex, ch := range g.tenants {
+		if !g.opened[tenantIndex] {
+			close(ch)
+		}
+	}
+	g.failed = err
+}
+
+// Wait blocks until the tenant is opened or the context is cancelled, returning the gate's error if
+// it failed before the tenant was opened. A nil gate never blocks.
+func (g *tenantGate) Wait(ctx context.Context, tenantIndex int) error {
+	if g == nil {
+		return nil
+	}
+
+	g.mutex.Lock()
+	ch := g.channel(tenantIndex)
+	g.mutex.Unlock()
+
+	select {
+	case <-ch:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !g.opened[tenantIndex] {
+		return fmt.Errorf("role creation did not reach tenant %d: %v", tenantIndex, g.failed)
+	}
+	return nil
+}
+
+// executeRolesAndUsers runs the role and user creation phases at the same time, starting the users
+// of each tenant as soon as the tenant's role has been created instead of after all roles
+func (te *TestExecutor) executeRolesAndUsers() error {
+	te.roleGate = newTenantGate()
+	defer func() { te.roleGate = nil }()
+
+	rolesDone := make(chan error, 1)
+	go func() {
+		err := te.ExecuteRoleCreation()
+		// Release the users of the tenants the roles never reached, so they fail instead of
+		// waiting for a role that will not be created
+		if err != nil {
+			te.roleGate.Fail(err)
+		}
+		rolesDone <- err
+	}()
+
+	userErr := te.ExecuteUserCreation()
+
+	if err := <-rolesDone; err != nil {
+		return fmt.Errorf("role creation failed: %v", err)
+	}
+	if userErr != nil {
+		return fmt.Errorf("user creation failed: %w", userErr)
+	}
+	return nil
+}
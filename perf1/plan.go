@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// executionPlanVersion is the version of the execution plan format
+const executionPlanVersion = 1
+
+// ExecutionPlan is the fully resolved description of a run: the mode, the configuration after
+// files, presets and flags were applied, and how each phase divides its work over the threads. It
+// is exported for review before a large run and can be executed exactly as approved.
+type ExecutionPlan struct {
+	Version      int         `json:"version"`
+	Client       *BuildInfo  `json:"client,omitempty"` // the build that exported the plan
+	Mode         string      `json:"mode"`
+	Server       string      `json:"server"`
+	TargetTPS    float64     `json:"targetTps,omitempty"`
+	RampUpPeriod int         `json:"rampUpPeriod"`
+	Phases       []PlanPhase `json:"phases"`
+	// Config is the resolved configuration with its secrets left out; they are taken from the
+	// configuration of the run that executes the plan
+	Config *Config `json:"config"`
+}
+
+// PlanPhase describes one phase of an execution plan
+type PlanPhase struct {
+	Name      string       `json:"name"`
+	Endpoints []string     `json:"endpoints"`
+	Threads   int          `json:"threads"`
+	Requests  int          `json:"requests,omitempty"` // requests sent when none is retried
+	Note      string       `json:"note,omitempty"`
+	Workers   []PlanWorker `json:"workers,omitempty"`
+	Pools     []PlanPool   `json:"pools,omitempty"`
+}
+
+// PlanWorker is the fixed share of a phase's work assigned to one thread
+type PlanWorker struct {
+	Thread      int    `json:"thread"`
+	TenantStart int    `json:"tenantStart"`
+	TenantEnd   int    `json:"tenantEnd"`       // inclusive
+	Start       int    `json:"start,omitempty"` // first item of the thread, e.g. a group number
+	End         int    `json:"end,omitempty"`   // inclusive
+	StartDelay  string `json:"startDelay,omitempty"`
+}
+
+// PlanPool is a set of threads sharing a queue of user ranges
+type PlanPool struct {
+	ID      int         `json:"id"`
+	Threads int         `json:"threads"`
+	Ranges  []UserRange `json:"ranges"`
+}
+
+// BuildExecutionPlan resolves how the given mode would run with the configuration
+func BuildExecutionPlan(config *Config, mode ExecutionMode) *ExecutionPlan {
+	// The planning executor only divides the work, it never sends requests
+	te := &TestExecutor{config: config, ctx: context.Background()}
+
+	plan := &ExecutionPlan{
+		Version:      executionPlanVersion,
+		Mode:         mode.String(),
+		Server:       config.GetServerURL(),
+		TargetTPS:    config.Execution.TargetTPS,
+		RampUpPeriod: config.Execution.RampUpPeriod,
+		Config:       withoutSecrets(config),
+	}
+
+	tenants := config.Execution.NoOfTenants
+	threads := config.Execution.NoOfThreads
+
+	switch mode {
+	case ModeCreate:
+		plan.Phases = append(plan.Phases, te.rolePlanPhase())
+
+		users := PlanPhase{
+			Name:      "users",
+			Endpoints: []string{"POST " + config.Server.ScimBasePath + "/Users"},
+			Threads:   threads,
+			Requests:  config.Execution.NoOfUsers * tenants,
+		}
+		if config.Execution.PipelineTenants {
+			users.Note = "starts in each tenant as soon as its role exists"
+		}
+		steps, _ := parseRampProfile(config.Execution.RampProfile)
+		if len(steps) > 0 {
+			users.Threads = rampProfileThreads(steps)
+			users.Note = describeRampProfile(steps) + "; ends with the last step or when the users run out"
+		}
+		if rate := config.Execution.ArrivalRate; rate > 0 {
+			users.Threads = config.Execution.ArrivalConcurrency
+			users.Note = fmt.Sprintf("open model: Poisson arrivals at %.2f users/s, up to %d requests in flight", rate, users.Threads)
+		}
+		for _, pool := range te.userPools() {
+			if len(steps) > 0 || config.Execution.ArrivalRate > 0 {
+				pool.Threads = users.Threads
+			}
+			users.Pools = append(users.Pools, PlanPool{ID: pool.ID, Threads: pool.Threads, Ranges: pool.Ranges})
+		}
+		plan.Phases = append(plan.Phases, users)
+
+		if config.Groups.PerTenant > 0 {
+			groups := PlanPhase{
+				Name:      "groups",
+				Endpoints: []string{"POST " + config.Server.Scim2BasePath + "/Groups"},
+				Threads:   threads,
+				Requests:  config.Groups.PerTenant * tenants,
+			}
+			for _, task := range te.groupTasks() {
+				groups.Workers = append(groups.Workers, planWorker(task))
+			}
+			plan.Phases = append(plan.Phases, groups)
+		}
+
+		if config.IdPs.PerTenant > 0 {
+			idps := PlanPhase{
+				Name:      "idps",
+				Endpoints: []string{"POST " + config.IdPs.APIPath},
+				Threads:   threads,
+				Requests:  config.IdPs.PerTenant * tenants,
+			}
+			for _, task := range te.perTenantTasks(config.IdPs.PerTenant) {
+				idps.Workers = append(idps.Workers, planWorker(task))
+			}
+			plan.Phases = append(plan.Phases, idps)
+		}
+
+		if config.Login.Percent > 0 {
+			plan.Phases = append(plan.Phases, loginPlanPhase(config))
+		}
+	case ModeBulk:
+		plan.Phases = append(plan.Phases, te.rolePlanPhase())
+
+		batchSize := config.Bulk.BatchSize
+		if batchSize < 1 {
+			batchSize = 1
+		}
+		plan.Phases = append(plan.Phases, PlanPhase{
+			Name:      "users",
+			Endpoints: []string{"POST " + config.Server.Scim2BasePath + "/Bulk"},
+			Threads:   threads,
+			Requests:  (config.Execution.NoOfUsers + batchSize - 1) / batchSize * tenants,
+			Note:      fmt.Sprintf("batches of %d users taken from a shared queue, failed operations retried in up to %d later rounds", batchSize, config.Bulk.Retries),
+		})
+	case ModeLogin:
+		plan.Phases = append(plan.Phases, loginPlanPhase(config))
+	case ModePatch:
+		patchThreads := config.Patch.Threads
+		if patchThreads <= 0 {
+			patchThreads = threads
+		}
+		plan.Phases = append(plan.Phases, PlanPhase{
+			Name:      "patch",
+			Endpoints: []string{"PATCH " + config.Server.Scim2BasePath + "/Users/{id}"},
+			Threads:   patchThreads,
+			Note:      fmt.Sprintf("every user in %s, %d passes, taken from a shared queue", config.Execution.ScimIdCsvPath, config.Patch.Passes),
+		})
+	default:
+		plan.Phases = append(plan.Phases, PlanPhase{
+			Name:      mode.String(),
+			Endpoints: []string{config.GetServerURL()},
+			Threads:   threads,
+			Note:      "work is divided over the threads at run time",
+		})
+	}
+
+	return plan
+}
+
+// rolePlanPhase describes the role creation phase
+func (te *TestExecutor) rolePlanPhase() PlanPhase {
+	phase := PlanPhase{
+		Name:      "roles",
+		Endpoints: []string{"POST /services/RemoteUserStoreManagerService (addRole)"},
+		Threads:   te.config.Execution.NoOfThreads,
+		Requests:  te.config.Execution.NoOfTenants * (1 + len(te.config.extraRoleNames())),
+	}
+	if te.config.Test.SkipRoleCreation {
+		phase.Endpoints, phase.Threads, phase.Requests = nil, 0, 0
+		phase.Note = "skipped, the roles are managed outside the test"
+		return phase
+	}
+	for _, task := range te.roleTasks() {
+		phase.Workers = append(phase.Workers, planWorker(task))
+	}
+	return phase
+}
+
+// loginPlanPhase describes the login phase
+func loginPlanPhase(config *Config) PlanPhase {
+	percent := config.Login.Percent
+	if percent <= 0 || percent > 100 {
+		percent = 100
+	}
+
+	endpoint := "POST " + config.OAuth.TokenPath + " (password grant)"
+	if config.Login.Method == LoginMethodMe {
+		endpoint = "GET " + config.Server.Scim2BasePath + "/Me"
+	}
+
+	return PlanPhase{
+		Name:      "login",
+		Endpoints: []string{endpoint},
+		Threads:   config.Execution.NoOfThreads,
+		Requests:  len(selectPercent(config.Execution.NoOfUsers, percent)) * config.Execution.NoOfTenants,
+		Note:      fmt.Sprintf("%g%% of the users of every tenant, taken from a shared queue", percent),
+	}
+}
+
+// planWorker describes the work of a worker task
+func planWorker(task WorkerTask) PlanWorker {
+	worker := PlanWorker{
+		Thread:      task.ThreadID,
+		TenantStart: task.TenantStart,
+		TenantEnd:   task.TenantEnd - 1,
+		Start:       task.UserStart,
+		End:         task.UserEnd,
+	}
+	if task.StartDelay > 0 {
+		worker.StartDelay = task.StartDelay.String()
+	}
+	return worker
+}
+
+// withoutSecrets returns a copy of the configuration with the secrets cleared: the admin and test
+// user passwords, the patterns of the password policies, the client secret, the alert webhook URL,
+// which often carries a token, and the reporter options, which can hold credentials
+func withoutSecrets(config *Config) *Config {
+	redacted := *config
+	redacted.Server.Password = ""
+	redacted.Test.UserPassword = ""
+	redacted.OAuth.ClientSecret = ""
+	redacted.Alerts.Webhook = ""
+	redacted.Reporters.Options = nil
+
+	redacted.Test.PasswordPolicies = make([]PasswordPolicy, len(config.Test.PasswordPolicies))
+	for i, policy := range config.Test.PasswordPolicies {
+		policy.Pattern = ""
+		redacted.Test.PasswordPolicies[i] = policy
+	}
+	return &redacted
+}
+
+// restoreSecrets fills the secrets withoutSecrets cleared in config from the given configuration.
+// The password policies are matched by name, so every policy of the plan needs a pattern.
+func restoreSecrets(config, secrets *Config) error {
+	config.Server.Password = secrets.Server.Password
+	config.Test.UserPassword = secrets.Test.UserPassword
+	config.OAuth.ClientSecret = secrets.OAuth.ClientSecret
+	config.Alerts.Webhook = secrets.Alerts.Webhook
+	config.Reporters.Options = secrets.Reporters.Options
+
+	patterns := make(map[string]string, len(secrets.Test.PasswordPolicies))
+	for _, policy := range secrets.Test.PasswordPolicies {
+		patterns[policy.Name] = policy.Pattern
+	}
+	for i, policy := range config.Test.PasswordPolicies {
+		pattern, ok := patterns[policy.Name]
+		if !ok {
+			return fmt.Errorf("execution plan uses password policy %q, which the configuration does not define", policy.Name)
+		}
+		config.Test.PasswordPolicies[i].Pattern = pattern
+	}
+	return nil
+}
+
+// SaveExecutionPlan writes the plan as JSON and returns the SHA-256 digest of the file, which
+// identifies the approved plan
+func SaveExecutionPlan(path string, plan *ExecutionPlan) (string, error) {
+	plan.Client = &clientBuild
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal execution plan: %v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write execution plan: %v", err)
+	}
+
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// LoadExecutionPlan reads an approved plan and resolves its mode and configuration, taking the
+// secrets left out of the plan from the given configuration. It fails if this build would divide
+// the work differently from the plan, so the run is exactly the one that was reviewed. It also
+// returns the SHA-256 digest of the plan file.
+func LoadExecutionPlan(path string, secrets *Config) (*Config, ExecutionMode, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("failed to read execution plan: %v", err)
+	}
+	digest := sha256.Sum256(data)
+
+	var plan ExecutionPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, 0, "", fmt.Errorf("failed to parse execution plan: %v", err)
+	}
+	if plan.Version != executionPlanVersion {
+		return nil, 0, "", fmt.Errorf("unsupported execution plan version %d, expected %d", plan.Version, executionPlanVersion)
+	}
+	if plan.Config == nil {
+		return nil, 0, "", fmt.Errorf("execution plan has no configuration")
+	}
+
+	mode, err := parseMode(plan.Mode)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	// The work is compared below, so a plan exported by another build only needs a warning
+	if plan.Client != nil && (plan.Client.Version != clientBuild.Version || plan.Client.Commit != clientBuild.Commit) {
+		printWarning("Execution plan %s was exported by %s, this is %s\n", path, plan.Client, clientBuild)
+	}
+	plan.Client = nil
+
+	// Compare the plan with the one this build makes from the same configuration
+	expected, err := json.Marshal(BuildExecutionPlan(plan.Config, mode))
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("failed to marshal execution plan: %v", err)
+	}
+	actual, err := json.Marshal(&plan)
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("failed to marshal execution plan: %v", err)
+	}
+	if !bytes.Equal(expected, actual) {
+		return nil, 0, "", fmt.Errorf("execution plan %s differs from what this build would run with its configuration; export it again and have it reviewed", path)
+	}
+
+	config := plan.Config
+	if err := restoreSecrets(config, secrets); err != nil {
+		return nil, 0, "", err
+	}
+
+	return config, mode, hex.EncodeToString(digest[:]), nil
+}
+
+// parseMode returns the execution mode with the given name
+func parseMode(name string) (ExecutionMode, error) {
+	for mode, modeName := range modeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown execution mode %q", name)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecutionPlanSecrets(t *testing.T) {
+	config := DefaultConfig()
+	config.Server.Password = "admin-secret"
+	config.Test.UserPassword = "user-secret"
+	config.Test.PasswordPolicies = []PasswordPolicy{{Name: "strong", Pattern: "Aa{11}9{2}#", Weight: 1}}
+	config.OAuth.ClientSecret = "client-secret"
+	config.Alerts.Webhook = "https://hooks.example.com/token-secret"
+	config.Reporters.Options = map[string]map[string]string{"kafka": {"password": "kafka-secret"}}
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if _, err := SaveExecutionPlan(path, BuildExecutionPlan(config, ModeCreate)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"admin-secret", "user-secret", "Aa{11}9{2}#", "client-secret", "token-secret", "kafka-secret"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("plan contains secret %q", secret)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		secrets func(*Config)
+		wantErr string
+	}{
+		{name: "secrets restored", secrets: func(*Config) {}},
+		{name: "policy missing", secrets: func(c *Config) { c.Test.PasswordPolicies = nil }, wantErr: `password policy "strong"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets := *config
+			tt.secrets(&secrets)
+
+			loaded, mode, _, err := LoadExecutionPlan(path, &secrets)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if mode != ModeCreate {
+				t.Errorf("mode = %v, want %v", mode, ModeCreate)
+			}
+			if loaded.Server.Password != "admin-secret" || loaded.Test.UserPassword != "user-secret" ||
+				loaded.OAuth.ClientSecret != "client-secret" || loaded.Alerts.Webhook != config.Alerts.Webhook ||
+				loaded.Reporters.Options["kafka"]["password"] != "kafka-secret" ||
+				loaded.Test.PasswordPolicies[0].Pattern != "Aa{11}9{2}#" {
+				t.Errorf("secrets not restored: %+v", loaded)
+			}
+		})
+	}
+}
+
+func TestExecutionPlanVerification(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func([]byte) []byte
+		wantErr string
+	}{
+		{name: "unchanged plan loads", edit: func(data []byte) []byte { return data }},
+		{
+			name: "edited work rejected",
+			edit: func(data []byte) []byte {
+				return bytes.Replace(data, []byte(`"noOfThreads": 10`), []byte(`"noOfThreads": 11`), 1)
+			},
+			wantErr: "differs from what this build would run",
+		},
+		{
+			name:    "unknown version rejected",
+			edit:    func(data []byte) []byte { return bytes.Replace(data, []byte(`"version": `), []byte(`"version": 9`), 1) },
+			wantErr: "unsupported execution plan version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Execution.NoOfThreads = 10
+
+			path := filepath.Join(t.TempDir(), "plan.json")
+			if _, err := SaveExecutionPlan(path, BuildExecutionPlan(config, ModeCreate)); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, tt.edit(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, _, err = LoadExecutionPlan(path, config)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
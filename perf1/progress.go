@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Progress is the machine-parsable run progress written for external orchestrators
+type Progress struct {
+	Phase          string    `json:"phase"`
+	Completed      int       `json:"completed"`
+	Failed         int       `json:"failed"`
+	RPS            float64   `json:"rps"`
+	AverageRPS     float64   `json:"averageRps"`
+	StartedAt      time.Time `json:"startedAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	ElapsedSeconds float64   `json:"elapsedSeconds"`
+	Done           bool      `json:"done"`
+	Client         BuildInfo `json:"client"`
+}
+
+// progressReporter periodically writes the run progress to a JSON file and prints a progress line
+// to the console. The file is replaced atomically, so a poller never sees a partially written
+// document.
+type progressReporter struct {
+	path          string // empty when no progress file is written
+	interval      time.Duration
+	printInterval time.Duration // 0 when no progress lines are printed
+	stats         *TestStats
+	phase         string
+	startedAt     time.Time
+	last          int
+	lastAt        time.Time
+
+	// phaseTotal is the number of operations the current phase completes, 0 when it is not known up
+	// front, and phaseBase the number of operations completed before the phase started
+	phaseTotal   int
+	phaseBase    int
+	phaseStarted time.Time
+
+	// printLast and printLastAt are the operations at the previous progress line
+	printLast   int
+	printLastAt time.Time
+
+	stop  chan struct{}
+	done  chan struct{}
+	mutex sync.Mutex
+}
+
+// StartProgress starts writing the progress file and printing progress lines, as far as they are
+// configured, with the given initial phase
+func (te *TestExecutor) StartProgress(phase string) {
+	execution := te.config.Execution
+	writeFile := execution.ProgressFile != "" && execution.ProgressInterval >= 1
+	if !writeFile && execution.ProgressPrintInterval < 1 {
+		return
+	}
+
+	now := time.Now()
+	te.progress = &progressReporter{
+		interval:     time.Duration(execution.ProgressInterval) * time.Second,
+		stats:        te.stats,
+		phase:        phase,
+		phaseStarted: now,
+		startedAt:    now,
+		lastAt:       now,
+		printLastAt:  now,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	if writeFile {
+		te.progress.path = execution.ProgressFile
+	}
+	if execution.ProgressPrintInterval > 0 {
+		te.progress.printInterval = time.Duration(execution.ProgressPrintInterval) * time.Second
+	}
+
+	go te.progress.run()
+}
+
+// StopProgress writes the final progress, marked as done, and stops the progress writer
+func (te *TestExecutor) StopProgress() {
+	if te.progress == nil {
+		return
+	}
+
+	close(te.progress.stop)
+	<-te.progress.done
+	te.progress = nil
+}
+
+// setPhase records the phase the run is in, for runs that go through several phases. Request
+// latencies are reported per phase and the phase is published in the progress file.
+func (te *TestExecutor) setPhase(phase string) {
+	te.stats.SetPhase(phase)
+
+	if te.progress == nil {
+		return
+	}
+
+	completed, failed := te.stats.Totals()
+
+	te.progress.mutex.Lock()
+	defer te.progress.mutex.Unlock()
+
+	te.progress.phase = phase
+	te.progress.phaseTotal = te.phaseTotal(phase)
+	te.progress.phaseBase = completed + failed
+	te.progress.phaseStarted = time.Now()
+}
+
+// phaseTotal returns the number of role, user, group and IdP operations the phase completes, 0 when it
+// is not known up front
+func (te *TestExecutor) phaseTotal(phase string) int {
+	tenants := te.config.Execution.NoOfTenants
+	roles := tenants * (1 + len(te.config.extraRoleNames()))
+	if te.config.Test.SkipRoleCreation {
+		roles = 0
+	}
+	users := te.config.Execution.NoOfUsers * tenants
+
+	switch phase {
+	case "roles":
+		return roles
+	case "users":
+		return users
+	case phaseRolesAndUsers:
+		return roles + users
+	case "groups":
+		return te.config.Groups.PerTenant * tenants
+	case "idps":
+		return te.config.IdPs.PerTenant * tenants
+	default:
+		return 0
+	}
+}
+
+// run writes the progress file and prints progress lines at their intervals until stopped
+func (pr *progressReporter) run() {
+	defer close(pr.done)
+
+	var writeTicks, printTicks <-chan time.Time
+	if pr.path != "" {
+		ticker := time.NewTicker(pr.interval)
+		defer ticker.Stop()
+		writeTicks = ticker.C
+
+		pr.write(false)
+	}
+	if pr.printInterval > 0 {
+		ticker := time.NewTicker(pr.printInterval)
+		defer ticker.Stop()
+		printTicks = ticker.C
+	}
+
+	for {
+		select {
+		case <-writeTicks:
+			pr.write(false)
+		case <-printTicks:
+			pr.print()
+		case <-pr.stop:
+			if pr.path != "" {
+				pr.write(true)
+			}
+			return
+		}
+	}
+}
+
+// print prints a progress line with the completed and failed operations, the throughput over the
+// last interval and the whole run, and the time the current phase needs to finish at that rate,
+// e.g. "[0:01:35] users: 12345 completed, 12 failed | 245.3 ops/s now, 230.1 ops/s avg | 61%, ETA 2m10s"
+func (pr *progressReporter) print() {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+
+	completed, failed := pr.stats.Totals()
+	done := completed + failed
+	now := time.Now()
+	elapsed := now.Sub(pr.startedAt)
+
+	var rate, average float64
+	if window := now.Sub(pr.printLastAt).Seconds(); window > 0 {
+		rate = float64(done-pr.printLast) / window
+	}
+	if elapsed > 0 {
+		average = float64(done) / elapsed.Seconds()
+	}
+	pr.printLast = done
+	pr.printLastAt = now
+
+	line := fmt.Sprintf("[%s] %s: %d completed, %d failed | %.1f ops/s now, %.1f ops/s avg",
+		formatElapsed(elapsed.Seconds()), pr.phase, completed, failed, rate, average)
+
+	// The ETA assumes the current rate holds, or the phase's average rate while nothing completes
+	if phaseDone := done - pr.phaseBase; pr.phaseTotal > 0 && phaseDone <= pr.phaseTotal {
+		line += fmt.Sprintf(" | %.0f%%", float64(phaseDone)/float64(pr.phaseTotal)*100)
+
+		phaseRate := rate
+		if phaseRate <= 0 && now.After(pr.phaseStarted) {
+			phaseRate = float64(phaseDone) / now.Sub(pr.phaseStarted).Seconds()
+		}
+		if phaseRate > 0 {
+			eta := time.Duration(float64(pr.phaseTotal-phaseDone) / phaseRate * float64(time.Second))
+			line += fmt.Sprintf(", ETA %v", eta.Round(time.Second))
+		}
+	}
+
+	fmt.Println(line)
+}
+
+// write writes the current progress to a temporary file and renames it over the progress file
+func (pr *progressReporter) write(done bool) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+
+	completed, failed := pr.stats.Totals()
+	now := time.Now()
+
+	progress := Progress{
+		Phase:          pr.phase,
+		Completed:      completed,
+		Failed:         failed,
+		StartedAt:      pr.startedAt,
+		UpdatedAt:      now,
+		ElapsedSeconds: now.Sub(pr.startedAt).Seconds(),
+		Done:           done,
+		Client:         clientBuild,
+	}
+
+	// The current rate covers the last interval, the average the whole run
+	if window := now.Sub(pr.lastAt).Seconds(); window > 0 {
+		progress.RPS = float64(completed+failed-pr.last) / window
+	}
+	if progress.ElapsedSeconds > 0 {
+		progress.AverageRPS = float64(completed+failed) / progress.ElapsedSeconds
+	}
+	pr.last = completed + failed
+	pr.lastAt = now
+
+	if err := writeFileAtomic(pr.path, progress); err != nil {
+		printWarning("Failed to write progress file: %v\n", err)
+	}
+}
+
+// writeFileAtomic writes a value as JSON to a temporary file in the target directory and renames it
+// over the target, which replaces the file atomically on the same filesystem
+func writeFileAtomic(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write temporary file: %v", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to close temporary file: %v", err)
+	}
+
+	if err := renameFile(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace %s: %v", path, fileError(err))
+	}
+
+	return nil
+}
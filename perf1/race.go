@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// raceAttempt holds the outcome of one contender's create request
+type raceAttempt struct {
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// raceStats holds the aggregated outcome of the duplicate-create race test
+type raceStats struct {
+	rounds             int
+	cleanRounds        int
+	created            int
+	conflicts          int
+	unexpected         int
+	winnerTime         time.Duration
+	conflictTime       time.Duration
+	unexpectedByStatus map[int]int
+}
+
+// ExecuteDuplicateRace sends the same username from several workers at the same instant and
+// verifies that the server creates it exactly once, rejecting every other attempt with 409
+func (te *TestExecutor) ExecuteDuplicateRace() error {
+	contenders := te.config.Race.Contenders
+	if contenders < 2 {
+		return fmt.Errorf("duplicate-create race needs at least 2 contenders, got %d", contenders)
+	}
+
+	fmt.Println("Starting duplicate-create race test...")
+	fmt.Printf("- Contenders: %d\n", contenders)
+	fmt.Printf("- Rounds: %d\n", te.config.Race.Rounds)
+
+	// Each contender gets its own client, so requests really travel on separate connections
+	clients := make([]*HTTPClient, contenders)
+	for i := range clients {
+		clients[i] = te.newHTTPClient()
+	}
+
+	stats := &raceStats{unexpectedByStatus: make(map[int]int)}
+	runID := time.Now().Unix()
+	startTime := time.Now()
+
+	for round := 0; round < te.config.Race.Rounds && !te.interrupted(); round++ {
+		tenantIndex := te.config.Execution.TenantStartNumber + round%te.config.Execution.NoOfTenants
+		username := fmt.Sprintf("%srace_%d_%d", te.config.Test.UsernamePrefix, runID, round)
+
+		attempts := runRaceRound(te.ctx, clients, tenantIndex, username)
+
+		// A round aborted by the interruption would look like a violation, so it is not counted
+		if te.interrupted() {
+			break
+		}
+		created, conflicts := stats.record(attempts)
+
+		if created != 1 || conflicts != contenders-1 {
+			fmt.Printf("Round %d: user %s for tenant %d got %d created and %d conflicts, expected 1 and %d\n",
+				round, username, tenantIndex, created, conflicts, contenders-1)
+		}
+	}
+
+	duration := time.Since(startTime)
+	if te.interrupted() {
+		fmt.Printf("\nDuplicate-create race interrupted after %v\n", duration)
+		stats.print(contenders)
+		return ErrInterrupted
+	}
+	fmt.Printf("\nDuplicate-create race completed in %v\n", duration)
+
+	stats.print(contenders)
+
+	return nil
+}
+
+// runRaceRound releases all contenders at once and collects their results
+func runRaceRound(ctx context.Context, clients []*HTTPClient, tenantIndex int, username string) []raceAttempt {
+	attempts := make([]raceAttempt, len(clients))
+	start := make(chan struct{})
+
+	var ready sync.WaitGroup
+	var done sync.WaitGroup
+
+	for i, client := range clients {
+		ready.Add(1)
+		done.Add(1)
+		go func(i int, client *HTTPClient) {
+			defer done.Done()
+			ready.Done()
+			<-start
+
+			requestStart := time.Now()
+			_, err := client.CreateUserWithName(ctx, tenantIndex, username)
+			attempts[i] = raceAttempt{Duration: time.Since(requestStart), Err: err}
+
+			var statusErr *StatusError
+			switch {
+			case err == nil:
+				attempts[i].StatusCode = http.StatusCreated
+			case errors.As(err, &statusErr):
+				attempts[i].StatusCode = statusErr.StatusCode
+			}
+		}(i, client)
+	}
+
+	// Wait until every contender is parked on the start channel before releasing them together
+	ready.Wait()
+	close(start)
+	done.Wait()
+
+	return attempts
+}
+
+// record adds the attempts of one round to the statistics and returns its created and conflict counts
+func (rs *raceStats) record(attempts []raceAttempt) (int, int) {
+	created, conflicts := 0, 0
+
+	for _, attempt := range attempts {
+		switch attempt.StatusCode {
+		case http.StatusCreated:
+			created++
+			rs.winnerTime += attempt.Duration
+		case http.StatusConflict:
+			conflicts++
+			rs.conflictTime += attempt.Duration
+		default:
+			rs.unexpected++
+			rs.unexpectedByStatus[attempt.StatusCode]++
+		}
+	}
+
+	rs.rounds++
+	rs.created += created
+	rs.conflicts += conflicts
+	if created == 1 && conflicts == len(attempts)-1 {
+		rs.cleanRounds++
+	}
+
+	return created, conflicts
+}
+
+// print prints the race test summary
+func (rs *raceStats) print(contenders int) {
+	fmt.Println("\n=== Duplicate-Create Race Statistics ===")
+	fmt.Printf("Rounds - Total: %d, As Expected: %d, Violations: %d\n",
+		rs.rounds, rs.cleanRounds, rs.rounds-rs.cleanRounds)
+	fmt.Printf("Attempts - Total: %d, Created: %d, Conflicts: %d, Unexpected: %d\n",
+		rs.rounds*contenders, rs.created, rs.conflicts, rs.unexpected)
+
+	if rs.created > 0 {
+		fmt.Printf("Created Avg Time: %v\n", rs.winnerTime/time.Duration(rs.created))
+	}
+	if rs.conflicts > 0 {
+		fmt.Printf("Conflict Avg Time: %v\n", rs.conflictTime/time.Duration(rs.conflicts))
+	}
+
+	// Status 0 means the request failed before a response was received
+	for statusCode, count := range rs.unexpectedByStatus {
+		fmt.Printf("Unexpected Status %d: %d\n", statusCode, count)
+	}
+	fmt.Println("========================================")
+}
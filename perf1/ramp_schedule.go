@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// rampChartWidth is the number of columns of the ramp schedule charts
+	rampChartWidth = 60
+	// rampChartHeight is the number of rows of the request rate chart
+	rampChartHeight = 8
+	// rampMaxRows is the most rows of the worker start timeline; threads are grouped beyond it
+	rampMaxRows = 12
+)
+
+// rampPhase is the estimated course of one phase of a dry run
+type rampPhase struct {
+	PlanPhase
+	starts   []time.Duration // when each working thread starts
+	ends     []time.Duration // when each working thread runs out of work, 0 when unknown
+	maxRate  float64         // requests per second the phase is paced to, 0 for unpaced
+	duration time.Duration   // estimated, 0 when the phase has no fixed number of requests
+	idle     int             // threads that would start after all requests are sent
+	steps    []rampStep      // the ramp profile that runs the phase, if any
+	arrivals bool            // requests arrive at maxRate, sent by as many slots as are needed
+}
+
+// PrintRampSchedule prints, without sending any request, when the workers of each phase of the
+// plan start, the request rate expected while they ramp up at the given latency per request, and
+// the estimated duration, so misconfigured ramp-up values are caught before a run
+func PrintRampSchedule(plan *ExecutionPlan, latency time.Duration) {
+	fmt.Println("=== Ramp Schedule (dry run, no requests sent) ===")
+	fmt.Printf("Ramp-up Period: %d seconds, assumed latency: %v per request\n", plan.RampUpPeriod, latency)
+	if plan.TargetTPS > 0 {
+		fmt.Printf("Target Throughput: %.2f users/s\n", plan.TargetTPS)
+	}
+
+	var total time.Duration
+	estimated := true
+	for _, phase := range plan.Phases {
+		rp := estimateRampPhase(plan, phase, latency)
+		rp.print(latency)
+
+		if rp.duration == 0 {
+			estimated = false
+		}
+		total += rp.duration
+	}
+
+	fmt.Println()
+	switch {
+	case total == 0:
+		fmt.Println("Estimated total duration: depends on the workload")
+	case !estimated:
+		fmt.Printf("Estimated total duration: more than %v; some phases run for a configured duration or until their input is used up\n", total.Round(time.Second))
+	case plan.Config != nil && plan.Config.Execution.PipelineTenants:
+		fmt.Printf("Estimated total duration: up to %v; pipelined roles and users overlap\n", total.Round(time.Second))
+	default:
+		fmt.Printf("Estimated total duration: %v\n", total.Round(time.Second))
+	}
+	fmt.Println("=================================================")
+}
+
+// estimateRampPhase works out when the threads of a phase start and how long they run if every
+// thread sends a request per latency. Threads either work through a fixed share of the phase, as
+// listed in the plan, or take requests from a shared queue until it is empty.
+func estimateRampPhase(plan *ExecutionPlan, phase PlanPhase, latency time.Duration) rampPhase {
+	rp := rampPhase{PlanPhase: phase}
+
+	// Only user creation is paced to the target throughput
+	if phase.Name == "users" && plan.Mode == ModeCreate.String() {
+		rp.maxRate = plan.TargetTPS
+	}
+
+	if phase.Name == "users" && plan.Mode == ModeCreate.String() && plan.Config != nil {
+		if steps, err := parseRampProfile(plan.Config.Execution.RampProfile); err == nil && len(steps) > 0 {
+			rp.estimateSteps(steps, latency)
+			return rp
+		}
+		if rate := plan.Config.Execution.ArrivalRate; rate > 0 {
+			rp.maxRate = rate
+			rp.arrivals = true
+		}
+	}
+
+	if len(phase.Workers) > 0 {
+		for _, worker := range phase.Workers {
+			start, _ := time.ParseDuration(worker.StartDelay)
+			items := worker.TenantEnd - worker.TenantStart + 1
+			if worker.End > 0 {
+				items *= worker.End - worker.Start + 1
+			}
+
+			end := start + time.Duration(items)*latency
+			rp.starts = append(rp.starts, start)
+			rp.ends = append(rp.ends, end)
+			if end > rp.duration {
+				rp.duration = end
+			}
+		}
+		return rp
+	}
+
+	// The same spread as rampUpStartDelay: starts evenly over the ramp-up period. Arrivals have no
+	// ramp-up, their request slots are all available from the start.
+	rampUp := time.Duration(plan.RampUpPeriod) * time.Second
+	if rp.arrivals {
+		rampUp = 0
+	}
+	for i := 0; i < phase.Threads; i++ {
+		rp.starts = append(rp.starts, rampUp/time.Duration(phase.Threads)*time.Duration(i))
+	}
+	rp.ends = make([]time.Duration, len(rp.starts))
+
+	if phase.Requests == 0 || latency <= 0 {
+		return rp
+	}
+
+	// The rate only changes when a thread starts, so walk from one start to the next
+	remaining := float64(phase.Requests)
+	for i, start := range rp.starts {
+		rate := rp.limit(float64(i+1) / latency.Seconds())
+		last := i+1 == len(rp.starts)
+		if last || rate*(rp.starts[i+1]-start).Seconds() >= remaining {
+			rp.duration = start + time.Duration(remaining/rate*float64(time.Second))
+			rp.idle = len(rp.starts) - i - 1
+			break
+		}
+		remaining -= rate * (rp.starts[i+1] - start).Seconds()
+	}
+
+	for i, start := range rp.starts {
+		if start < rp.duration {
+			rp.ends[i] = rp.duration
+		}
+	}
+	return rp
+}
+
+// estimateSteps works out when the threads of a phase run by a ramp profile start and stop. The
+// phase ends with the last step, or earlier when its requests are used up.
+func (rp *rampPhase) estimateSteps(steps []rampStep, latency time.Duration) {
+	rp.steps = steps
+
+	var at time.Duration
+	remaining := float64(rp.Requests)
+	for _, step := range steps {
+		rp.duration = at + step.Duration
+		if rp.Requests > 0 && latency > 0 {
+			rate := rp.limit(float64(step.Threads) / latency.Seconds())
+			if rate*step.Duration.Seconds() >= remaining {
+				rp.duration = at + time.Duration(remaining/rate*float64(time.Second))
+				break
+			}
+			remaining -= rate * step.Duration.Seconds()
+		}
+		at += step.Duration
+	}
+
+	// A thread runs from the first to the last step that includes it
+	rp.starts = make([]time.Duration, rampProfileThreads(steps))
+	rp.ends = make([]time.Duration, len(rp.starts))
+	for i := range rp.starts {
+		rp.starts[i] = -1
+	}
+	at = 0
+	for _, step := range steps {
+		for i := 0; i < step.Threads && at < rp.duration; i++ {
+			if rp.starts[i] < 0 {
+				rp.starts[i] = at
+			}
+			rp.ends[i] = at + step.Duration
+			if rp.ends[i] > rp.duration {
+				rp.ends[i] = rp.duration
+			}
+		}
+		at += step.Duration
+	}
+	for i := range rp.starts {
+		if rp.starts[i] < 0 {
+			rp.starts[i] = rp.duration
+			rp.idle++
+		}
+	}
+}
+
+// limit caps a request rate at the rate the phase is paced to
+func (rp rampPhase) limit(rate float64) float64 {
+	if rp.maxRate > 0 && rate > rp.maxRate {
+		return rp.maxRate
+	}
+	return rate
+}
+
+// rate returns the requests per second expected at the given time into the phase
+func (rp rampPhase) rate(at, latency time.Duration) float64 {
+	if len(rp.steps) > 0 {
+		if at >= rp.duration {
+			return 0
+		}
+		var end time.Duration
+		for _, step := range rp.steps {
+			if end += step.Duration; at < end {
+				return rp.limit(float64(step.Threads) / latency.Seconds())
+			}
+		}
+		return 0
+	}
+
+	running := 0
+	for i, start := range rp.starts {
+		if start <= at && at < rp.ends[i] {
+			running++
+		}
+	}
+	return rp.limit(float64(running) / latency.Seconds())
+}
+
+// print prints the worker timeline and expected request rate of the phase
+func (rp rampPhase) print(latency time.Duration) {
+	fmt.Printf("\nPhase %s: %d threads", rp.Name, rp.Threads)
+	if rp.Requests > 0 {
+		fmt.Printf(", %d requests", rp.Requests)
+	}
+	if len(rp.steps) > 0 {
+		fmt.Printf(", ramp profile of %d steps", len(rp.steps))
+	} else if rp.arrivals {
+		fmt.Printf(", Poisson arrivals at %.2f/s", rp.maxRate)
+	} else if len(rp.Workers) == 0 && len(rp.starts) > 1 {
+		fmt.Printf(", a thread starts every %v", (rp.starts[1] - rp.starts[0]).Round(time.Millisecond))
+	}
+	fmt.Println()
+	if rp.Note != "" {
+		fmt.Printf("  (%s)\n", rp.Note)
+	}
+	if len(rp.starts) == 0 {
+		return
+	}
+
+	// The charts span the estimated duration, or the ramp-up with some room when it is unknown
+	span := rp.duration
+	if span == 0 {
+		span = rp.starts[len(rp.starts)-1] * 5 / 4
+	}
+	if span <= 0 {
+		span = time.Second
+	}
+	column := func(at time.Duration) int {
+		c := int(float64(at) / float64(span) * rampChartWidth)
+		if c > rampChartWidth {
+			return rampChartWidth
+		}
+		return c
+	}
+
+	// A row per thread, or per group of threads when there are many, running from the first start
+	// to the last end in the group
+	end := span.Round(time.Millisecond).String()
+	fmt.Printf("  %-25s|0s%s%s|\n", "Workers", strings.Repeat(" ", rampChartWidth-2-len(end)), end)
+	groupSize := (len(rp.starts) + rampMaxRows - 1) / rampMaxRows
+	for first := 0; first < len(rp.starts); first += groupSize {
+		last := first + groupSize - 1
+		if last >= len(rp.starts) {
+			last = len(rp.starts) - 1
+		}
+
+		label := fmt.Sprintf("thread %d", rp.threadID(first))
+		if last > first {
+			label = fmt.Sprintf("threads %d-%d", rp.threadID(first), rp.threadID(last))
+		}
+
+		from, to := column(rp.starts[first]), rampChartWidth
+		if rp.duration > 0 {
+			to = 0
+			for i := first; i <= last; i++ {
+				if c := column(rp.ends[i]); rp.ends[i] > rp.starts[i] && c > to {
+					to = c
+				}
+			}
+		}
+		bar := strings.Repeat(" ", rampChartWidth)
+		if to > from {
+			bar = strings.Repeat(" ", from) + strings.Repeat("=", to-from) + strings.Repeat(" ", rampChartWidth-to)
+		}
+		fmt.Printf("  %-25s|%s| +%v\n", label, bar, rp.starts[first].Round(time.Millisecond))
+	}
+
+	if rp.duration == 0 {
+		fmt.Println("  Request rate and duration depend on the workload; worker starts shown over the ramp-up")
+		return
+	}
+
+	// Expected request rate, one bar per column at the rate in the middle of its time slice
+	rates := make([]float64, rampChartWidth)
+	peak := 0.0
+	for c := range rates {
+		rates[c] = rp.rate(time.Duration((float64(c)+0.5)/rampChartWidth*float64(span)), latency)
+		peak = math.Max(peak, rates[c])
+	}
+
+	fmt.Printf("  Expected request rate\n")
+	for row := rampChartHeight; row >= 1; row-- {
+		axis := ""
+		if row == rampChartHeight {
+			axis = fmt.Sprintf("%.0f/s", peak)
+		}
+
+		var line strings.Builder
+		for _, rate := range rates {
+			if peak > 0 && rate/peak*rampChartHeight >= float64(row)-0.5 {
+				line.WriteByte('#')
+			} else {
+				line.WriteByte(' ')
+			}
+		}
+		fmt.Printf("  %24s |%s|\n", axis, line.String())
+	}
+	fmt.Printf("  %24s +%s+\n", "0/s", strings.Repeat("-", rampChartWidth))
+
+	fmt.Printf("  Estimated duration: %v\n", rp.duration.Round(time.Millisecond))
+	if rp.idle > 0 {
+		if len(rp.steps) > 0 {
+			printWarning("  All requests would be sent before the ramp profile ends; raise noOfUsers to run every step in full\n")
+		} else {
+			printWarning("  %d of %d threads would start after all requests are sent; shorten the ramp-up period or use fewer threads\n", rp.idle, rp.Threads)
+		}
+	}
+	if len(rp.Workers) > 0 && len(rp.Workers) < rp.Threads {
+		fmt.Printf("  %d of %d threads have no work in this phase\n", rp.Threads-len(rp.Workers), rp.Threads)
+	}
+	if concurrent := rp.peakRunning(); len(rp.Workers) > 0 && concurrent < len(rp.starts) {
+		printWarning("  At most %d of %d threads run at the same time, as the first threads finish before the last ones start; shorten the ramp-up period\n", concurrent, len(rp.starts))
+	}
+	if rp.maxRate > 0 {
+		needed := int(math.Ceil(rp.maxRate * latency.Seconds()))
+		if needed < rp.Threads {
+			fmt.Printf("  The target throughput is reached with %d threads; the others only add headroom for slower responses\n", needed)
+		} else if needed > rp.Threads {
+			printWarning("  %d threads reach only %.2f of the %.2f requests/s target at this latency\n", rp.Threads, float64(rp.Threads)/latency.Seconds(), rp.maxRate)
+		}
+	}
+}
+
+// peakRunning returns the most threads running at the same time, which is reached as a thread starts
+func (rp rampPhase) peakRunning() int {
+	peak := 0
+	for _, at := range rp.starts {
+		running := 0
+		for i, start := range rp.starts {
+			if start <= at && at < rp.ends[i] {
+				running++
+			}
+		}
+		if running > peak {
+			peak = running
+		}
+	}
+	return peak
+}
+
+// threadID returns the thread of the given row of the phase
+func (rp rampPhase) threadID(i int) int {
+	if len(rp.Workers) > 0 {
+		return rp.Workers[i].Thread
+	}
+	return i
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket shared by all workers that paces requests to a target rate. Each
+// call reserves a token, so waiting callers are spread evenly instead of waking together.
+type rateLimiter struct {
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+
+	// The schedule the target rate sets, request n starting n/rate after the first one. A bucket
+	// refilled while the workers were busy lets them catch up only by the burst, so requests
+	// falling behind this schedule show the load offered fell short of the target.
+	start    time.Time
+	reserved int
+}
+
+// scheduleSlot is the time a request was due on the schedule of the target rate
+type scheduleSlot struct {
+	rate  float64
+	start time.Time // start of the schedule
+	due   time.Time
+}
+
+// newRateLimiter creates a limiter for the given requests per second that allows bursts of up to
+// burst requests, or returns nil when the rate is unlimited
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until the caller may send its next request, or the context is cancelled, and returns
+// how long it waited and when the request was due on the schedule of the target rate. A nil
+// limiter never blocks.
+func (l *rateLimiter) Wait(ctx context.Context) (time.Duration, scheduleSlot) {
+	if l == nil {
+		return 0, scheduleSlot{}
+	}
+
+	l.mutex.Lock()
+	now := time.Now()
+	if l.reserved == 0 {
+		l.start = now
+	}
+	slot := scheduleSlot{
+		rate:  l.rate,
+		start: l.start,
+		due:   l.start.Add(time.Duration(float64(l.reserved) / l.rate * float64(time.Second))),
+	}
+	l.reserved++
+
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	// Reserve a token, going into debt if none is left; the debt is the caller's wait
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mutex.Unlock()
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	}
+	return wait, slot
+}
+
+// restart starts the schedule of the target rate again with the next request, for requests that
+// follow a pause without work, such as the backoff between retry rounds
+func (l *rateLimiter) restart() {
+	if l == nil {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.reserved = 0
+}
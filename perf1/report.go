@@ -0,0 +1,621 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// reportMaxPoints is the most time buckets drawn in the charts over time
+	reportMaxPoints = 300
+	// reportMaxOperations is the most operations drawn in the latency percentile chart, the ones
+	// with the most requests
+	reportMaxOperations = 8
+)
+
+// reportIntervals are the time bucket widths the charts over time choose from, the smallest one
+// that keeps the number of buckets below reportMaxPoints
+var reportIntervals = []time.Duration{
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute, time.Hour,
+}
+
+// reportColors are the colors of the chart series, in order
+var reportColors = []string{"#1f77b4", "#d62728", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b", "#e377c2", "#17becf"}
+
+// requestTimeline keeps when each request completed and how long it took, for the charts over
+// time of the HTML report. It is guarded by the mutex of the TestStats it belongs to.
+type requestTimeline struct {
+	start    time.Time
+	samples  []requestSample
+	failures []time.Duration // completion times of the failed requests, since start
+	phases   []phaseMarker
+}
+
+// requestSample is a completed request
+type requestSample struct {
+	at      time.Duration // since the start of the timeline
+	latency time.Duration
+}
+
+// phaseMarker is the start of a phase of the run
+type phaseMarker struct {
+	at   time.Duration
+	name string
+}
+
+// newRequestTimeline creates a timeline starting now
+func newRequestTimeline() *requestTimeline {
+	return &requestTimeline{start: time.Now()}
+}
+
+// record adds a request that completed at now
+func (tl *requestTimeline) record(now time.Time, latency time.Duration, failed bool) {
+	at := now.Sub(tl.start)
+	tl.samples = append(tl.samples, requestSample{at: at, latency: latency})
+	if failed {
+		tl.failures = append(tl.failures, at)
+	}
+}
+
+// mark records that a phase started at now
+func (tl *requestTimeline) mark(now time.Time, phase string) {
+	tl.phases = append(tl.phases, phaseMarker{at: now.Sub(tl.start), name: phase})
+}
+
+// reportBucket holds the requests that completed in one time bucket of the report
+type reportBucket struct {
+	requests  int
+	failures  int
+	latencies []time.Duration
+}
+
+// buckets divides the requests completed between the start and end times into buckets of the
+// given interval
+func (tl *requestTimeline) buckets(startTime, endTime time.Time, interval time.Duration) []reportBucket {
+	shift := tl.start.Sub(startTime)
+	count := int(endTime.Sub(startTime)/interval) + 1
+	buckets := make([]reportBucket, count)
+
+	index := func(at time.Duration) int {
+		i := int((at + shift) / interval)
+		if i < 0 {
+			return 0
+		}
+		if i >= count {
+			return count - 1
+		}
+		return i
+	}
+
+	for _, sample := range tl.samples {
+		bucket := &buckets[index(sample.at)]
+		bucket.requests++
+		bucket.latencies = append(bucket.latencies, sample.latency)
+	}
+	for _, at := range tl.failures {
+		buckets[index(at)].failures++
+	}
+
+	return buckets
+}
+
+// reportInterval returns the time bucket width for a run of the given duration
+func reportInterval(duration time.Duration) time.Duration {
+	for _, interval := range reportIntervals {
+		if duration/interval < reportMaxPoints {
+			return interval
+		}
+	}
+	return reportIntervals[len(reportIntervals)-1]
+}
+
+// reportData is what the report template renders
+type reportData struct {
+	Summary    *RunSummary
+	Interval   time.Duration
+	Throughput template.HTML
+	Latency    template.HTML
+	Percentile template.HTML
+	Operations []reportOperation
+	Errors     []reportError
+	Failures   int
+	Tenants    []SummaryTenant // the tenants whose requests spanned the longest
+}
+
+// reportOperation is a row of the operation table
+type reportOperation struct {
+	SummaryOperation
+	Requests    int
+	ErrorRate   float64
+	Throughput  float64
+	StatusCodes string
+}
+
+// reportError is a row of the error table
+type reportError struct {
+	SummaryError
+	Share float64
+}
+
+// WriteReport writes the HTML report of the run that started at the given time to the configured
+// file, if one is configured
+func (te *TestExecutor) WriteReport(mode ExecutionMode, startTime time.Time) error {
+	if te.stats.timeline == nil {
+		return nil
+	}
+
+	endTime := time.Now()
+	summary := te.stats.Summary(startTime, endTime)
+	summary.Mode = mode.String()
+	summary.Config = withoutSecrets(te.config)
+
+	te.stats.mutex.Lock()
+	duration := endTime.Sub(startTime)
+	interval := reportInterval(duration)
+	buckets := te.stats.timeline.buckets(startTime, endTime, interval)
+	phases := te.stats.timeline.phases
+	shift := te.stats.timeline.start.Sub(startTime)
+	percentiles := te.stats.operationPercentiles()
+	te.stats.mutex.Unlock()
+
+	data := reportData{Summary: summary, Interval: interval, Tenants: summary.Tenants}
+	if len(data.Tenants) > slowestTenants {
+		data.Tenants = data.Tenants[:slowestTenants]
+	}
+
+	// Charts over time, with a point per bucket at the bucket's end
+	times := make([]float64, len(buckets))
+	requests := make([]float64, len(buckets))
+	failures := make([]float64, len(buckets))
+	p50 := make([]float64, len(buckets))
+	p90 := make([]float64, len(buckets))
+	p99 := make([]float64, len(buckets))
+	for i, bucket := range buckets {
+		width := interval
+		if i == len(buckets)-1 {
+			width = duration - time.Duration(i)*interval
+		}
+		end := time.Duration(i)*interval + width
+		times[i] = end.Seconds()
+		if width <= 0 {
+			width = interval
+		}
+
+		requests[i] = float64(bucket.requests) / width.Seconds()
+		failures[i] = float64(bucket.failures) / width.Seconds()
+		p50[i], p90[i], p99[i] = math.NaN(), math.NaN(), math.NaN()
+		if len(bucket.latencies) > 0 {
+			sortDurations(bucket.latencies)
+			p50[i] = milliseconds(percentile(bucket.latencies, 50))
+			p90[i] = milliseconds(percentile(bucket.latencies, 90))
+			p99[i] = milliseconds(percentile(bucket.latencies, 99))
+		}
+	}
+
+	var markers []chartMarker
+	for _, phase := range phases {
+		if at := (phase.at + shift).Seconds(); at > 0 {
+			markers = append(markers, chartMarker{X: at, Label: phase.name})
+		}
+	}
+
+	data.Throughput = lineChart{
+		Title:   "Throughput over time",
+		XLabel:  "elapsed",
+		YLabel:  "requests/s",
+		X:       times,
+		XFormat: formatElapsed,
+		Series: []chartSeries{
+			{Name: "requests", Points: requests},
+			{Name: "failed requests", Points: failures},
+		},
+		Markers: markers,
+	}.render()
+
+	data.Latency = lineChart{
+		Title:   "Latency percentiles over time",
+		XLabel:  "elapsed",
+		YLabel:  "ms",
+		X:       times,
+		XFormat: formatElapsed,
+		Series: []chartSeries{
+			{Name: "p50", Points: p50},
+			{Name: "p90", Points: p90},
+			{Name: "p99", Points: p99},
+		},
+		Markers: markers,
+	}.render()
+
+	data.Percentile = percentileChart(percentiles)
+
+	for _, op := range summary.Operations {
+		row := reportOperation{SummaryOperation: op, Requests: op.Latency.Count}
+		if row.Requests > 0 {
+			row.ErrorRate = float64(op.Failed) / float64(row.Requests) * 100
+		}
+		if duration > 0 {
+			row.Throughput = float64(row.Requests) / duration.Seconds()
+		}
+		row.StatusCodes = formatStatusNames(op.StatusCodes)
+		data.Operations = append(data.Operations, row)
+	}
+
+	for _, e := range summary.Errors {
+		data.Failures += e.Count
+	}
+	for _, e := range summary.Errors {
+		data.Errors = append(data.Errors, reportError{SummaryError: e, Share: float64(e.Count) / float64(data.Failures) * 100})
+	}
+
+	file, err := os.Create(te.config.Execution.ReportFile)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %v", err)
+	}
+	defer file.Close()
+
+	if err := reportTemplate.Execute(file, data); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+
+	fmt.Printf("HTML report written to: %s\n", te.config.Execution.ReportFile)
+	return nil
+}
+
+// operationPercentiles returns the latency percentile curve of each operation, for the operations
+// with the most requests
+func (ts *TestStats) operationPercentiles() []chartSeries {
+	labels := make([]string, 0, len(ts.Operations))
+	for label, op := range ts.Operations {
+		if len(op.Latencies) > 0 {
+			labels = append(labels, label)
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		ci, cj := len(ts.Operations[labels[i]].Latencies), len(ts.Operations[labels[j]].Latencies)
+		if ci != cj {
+			return ci > cj
+		}
+		return labels[i] < labels[j]
+	})
+	if len(labels) > reportMaxOperations {
+		labels = labels[:reportMaxOperations]
+	}
+
+	series := make([]chartSeries, 0, len(labels))
+	for _, label := range labels {
+		latencies := ts.Operations[label].Latencies
+		sortDurations(latencies)
+
+		points := make([]float64, len(percentileSteps))
+		for i, p := range percentileSteps {
+			points[i] = milliseconds(percentile(latencies, p))
+		}
+		series = append(series, chartSeries{Name: label, Points: points})
+	}
+	return series
+}
+
+// percentileSteps are the percentiles drawn in the latency percentile chart, denser at the tail
+var percentileSteps = func() []float64 {
+	var steps []float64
+	for p := 0.0; p < 90; p += 5 {
+		steps = append(steps, p)
+	}
+	for p := 90.0; p < 99; p++ {
+		steps = append(steps, p)
+	}
+	return append(steps, 99, 99.5, 99.9, 100)
+}()
+
+// percentileChart draws the latency percentile curves of the operations
+func percentileChart(series []chartSeries) template.HTML {
+	return lineChart{
+		Title:   "Latency percentiles by operation",
+		XLabel:  "percentile",
+		YLabel:  "ms",
+		X:       percentileSteps,
+		XFormat: func(p float64) string { return fmt.Sprintf("%g", p) },
+		Series:  series,
+	}.render()
+}
+
+// formatStatusNames lists response counts by status code, as formatStatusCodes does for the names
+// used in the summary
+func formatStatusNames(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%d", name, counts[name])
+	}
+	return strings.Join(parts, " ")
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// formatElapsed formats elapsed seconds as m:ss, or h:mm:ss for long runs
+func formatElapsed(seconds float64) string {
+	s := int(math.Round(seconds))
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// lineChart is a line chart drawn as inline SVG, so the report needs no scripts or network access
+type lineChart struct {
+	Title   string
+	XLabel  string
+	YLabel  string
+	X       []float64
+	XFormat func(float64) string
+	Series  []chartSeries
+	Markers []chartMarker
+}
+
+// chartSeries is a line of a chart, with a point for each X value. NaN points leave a gap.
+type chartSeries struct {
+	Name   string
+	Points []float64
+}
+
+// chartMarker is a labeled vertical line at an X value, e.g. the start of a phase
+type chartMarker struct {
+	X     float64
+	Label string
+}
+
+// Dimensions of the chart and its plot area in SVG units
+const (
+	chartWidth  = 960
+	chartHeight = 320
+	plotLeft    = 70
+	plotRight   = 20
+	plotTop     = 36
+	plotBottom  = 44
+)
+
+// render draws the chart
+func (c lineChart) render() template.HTML {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" class="chart" role="img" aria-label="%s">`, chartWidth, chartHeight, html.EscapeString(c.Title))
+	fmt.Fprintf(&b, `<text x="%d" y="20" class="title">%s</text>`, plotLeft, html.EscapeString(c.Title))
+
+	if len(c.X) == 0 || len(c.Series) == 0 {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="empty">No requests recorded</text></svg>`, chartWidth/2, chartHeight/2)
+		return template.HTML(b.String())
+	}
+
+	// The X axis starts at zero, the start of the run or the lowest percentile
+	minX, maxX := math.Min(0, c.X[0]), c.X[len(c.X)-1]
+	if maxX <= minX {
+		maxX = minX + 1
+	}
+	maxY := 0.0
+	for _, s := range c.Series {
+		for _, y := range s.Points {
+			if !math.IsNaN(y) && y > maxY {
+				maxY = y
+			}
+		}
+	}
+	step := niceStep(maxY / 5)
+	maxY = math.Ceil(maxY/step) * step
+	if maxY <= 0 {
+		maxY = step
+	}
+
+	width := float64(chartWidth - plotLeft - plotRight)
+	height := float64(chartHeight - plotTop - plotBottom)
+	px := func(x float64) float64 { return plotLeft + (x-minX)/(maxX-minX)*width }
+	py := func(y float64) float64 { return plotTop + height - y/maxY*height }
+
+	// Grid and Y axis labels
+	for y := 0.0; y <= maxY+step/2; y += step {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" class="grid"/>`, plotLeft, py(y), chartWidth-plotRight, py(y))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" class="ylabel">%s</text>`, plotLeft-6, py(y)+4, formatTick(y))
+	}
+	fmt.Fprintf(&b, `<text x="14" y="%d" class="axis" transform="rotate(-90 14 %d)">%s</text>`, plotTop+int(height)/2, plotTop+int(height)/2, html.EscapeString(c.YLabel))
+
+	// X axis labels, about eight of them
+	xStep := niceStep((maxX - minX) / 8)
+	for x := minX; x <= maxX+xStep/100; x += xStep {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="xlabel">%s</text>`, px(x), chartHeight-plotBottom+16, html.EscapeString(c.XFormat(x)))
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="axis">%s</text>`, plotLeft+width/2, chartHeight-6, html.EscapeString(c.XLabel))
+
+	for _, m := range c.Markers {
+		if m.X < minX || m.X > maxX {
+			continue
+		}
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" class="marker"/>`, px(m.X), plotTop, px(m.X), plotTop+height)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="mlabel">%s</text>`, px(m.X)+3, plotTop+10, html.EscapeString(m.Label))
+	}
+
+	for i, s := range c.Series {
+		color := reportColors[i%len(reportColors)]
+
+		var path strings.Builder
+		pen := "M"
+		for j, y := range s.Points {
+			if j >= len(c.X) {
+				break
+			}
+			if math.IsNaN(y) {
+				pen = "M"
+				continue
+			}
+			fmt.Fprintf(&path, "%s%.1f,%.1f ", pen, px(c.X[j]), py(y))
+			pen = "L"
+		}
+		fmt.Fprintf(&b, `<path d="%s" stroke="%s" class="line"/>`, strings.TrimSpace(path.String()), color)
+
+		// Legend
+		lx := chartWidth - plotRight - 180
+		ly := plotTop + 4 + i*16
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, lx, ly, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="legend">%s</text>`, lx+14, ly+9, html.EscapeString(s.Name))
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// niceStep rounds a grid step up to 1, 2 or 5 times a power of ten
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// formatTick formats a Y axis value without needless decimals
+func formatTick(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%g", math.Round(v*1000)/1000)
+}
+
+// reportTemplate renders the report page, styled inline so it is a single self-contained file
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms":    func(v float64) string { return fmt.Sprintf("%.1f", v) },
+	"pct":   func(v float64) string { return fmt.Sprintf("%.2f%%", v) },
+	"rate":  func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"share": func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) },
+	"when":  func(t time.Time) string { return t.Format("2006-01-02 15:04:05 MST") },
+	"ratio": func(part, whole float64) string {
+		if whole <= 0 {
+			return "0.00"
+		}
+		return fmt.Sprintf("%.2f", part/whole)
+	},
+	"portion": func(part, whole float64) string {
+		if whole <= 0 {
+			return "0.0%"
+		}
+		return fmt.Sprintf("%.1f%%", part/whole*100)
+	},
+	"dur": func(seconds float64) string {
+		return (time.Duration(seconds * float64(time.Second))).Round(time.Millisecond).String()
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>go-perf report - {{.Summary.Mode}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 24px; color: #222; }
+h1 { font-size: 22px; } h2 { font-size: 17px; margin-top: 32px; border-bottom: 1px solid #ddd; padding-bottom: 4px; }
+table { border-collapse: collapse; font-size: 13px; margin: 8px 0; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: right; }
+th { background: #f4f4f4; } td.text, th.text { text-align: left; }
+td.failed { color: #c0392b; font-weight: bold; }
+.chart { width: 100%; max-width: 960px; display: block; margin: 12px 0; }
+.chart .title { font-size: 14px; font-weight: bold; }
+.chart .grid { stroke: #e5e5e5; } .chart .marker { stroke: #999; stroke-dasharray: 4 3; }
+.chart .line { fill: none; stroke-width: 1.5; }
+.chart text { font-size: 11px; fill: #444; }
+.chart .ylabel { text-anchor: end; } .chart .xlabel, .chart .axis, .chart .empty { text-anchor: middle; }
+.chart .mlabel { fill: #777; }
+</style>
+</head>
+<body>
+<h1>go-perf report - {{.Summary.Mode}}</h1>
+
+<h2>Summary</h2>
+<table>
+<tr><th class="text">Started</th><td class="text">{{when .Summary.StartedAt}}</td></tr>
+<tr><th class="text">Finished</th><td class="text">{{when .Summary.FinishedAt}}</td></tr>
+<tr><th class="text">Duration</th><td class="text">{{dur .Summary.DurationSeconds}}</td></tr>
+<tr><th class="text">Server</th><td class="text">{{.Summary.Config.GetServerURL}}</td></tr>
+<tr><th class="text">Client</th><td class="text">{{.Summary.Client}}</td></tr>
+<tr><th class="text">Threads</th><td class="text">{{.Summary.Config.Execution.NoOfThreads}}</td></tr>
+<tr><th class="text">Effective TPS</th><td class="text">{{rate .Summary.EffectiveTPS}}</td></tr>
+<tr><th class="text">Requests/s</th><td class="text">{{rate .Summary.RequestsPerSecond}}</td></tr>
+<tr><th class="text">Retried requests</th><td class="text">{{.Summary.Retries}}</td></tr>
+</table>
+<table>
+<tr><th class="text">Resource</th><th>Total</th><th>Success</th><th>Failed</th></tr>
+<tr><td class="text">Roles</td><td>{{.Summary.Roles.Total}}</td><td>{{.Summary.Roles.Success}}</td><td{{if .Summary.Roles.Failed}} class="failed"{{end}}>{{.Summary.Roles.Failed}}</td></tr>
+<tr><td class="text">Users</td><td>{{.Summary.Users.Total}}</td><td>{{.Summary.Users.Success}}</td><td{{if .Summary.Users.Failed}} class="failed"{{end}}>{{.Summary.Users.Failed}}</td></tr>
+<tr><td class="text">Groups</td><td>{{.Summary.Groups.Total}}</td><td>{{.Summary.Groups.Success}}</td><td{{if .Summary.Groups.Failed}} class="failed"{{end}}>{{.Summary.Groups.Failed}}</td></tr>
+{{if .Summary.IdPs.Total}}<tr><td class="text">IdPs</td><td>{{.Summary.IdPs.Total}}</td><td>{{.Summary.IdPs.Success}}</td><td{{if .Summary.IdPs.Failed}} class="failed"{{end}}>{{.Summary.IdPs.Failed}}</td></tr>
+{{end}}</table>
+
+<h2>Throughput</h2>
+<p>Requests completed per second in buckets of {{.Interval}}; dashed lines mark the start of each phase.</p>
+{{.Throughput}}
+
+<h2>Latency</h2>
+{{.Latency}}
+{{.Percentile}}
+{{if .Summary.Phases}}
+<table>
+<tr><th class="text">Phase</th><th>Requests</th><th>Wall time</th><th>Peak in flight</th><th>Utilization</th><th>Avg ms</th><th>Min ms</th><th>P50 ms</th><th>P90 ms</th><th>P95 ms</th><th>P99 ms</th><th>Max ms</th></tr>
+{{range .Summary.Phases}}<tr><td class="text">{{.Name}}</td><td>{{.Latency.Count}}</td><td>{{dur .WallSeconds}}</td><td>{{.PeakInFlight}}</td><td>{{share .Utilization}}</td><td>{{ms .Latency.AvgMs}}</td><td>{{ms .Latency.MinMs}}</td><td>{{ms .Latency.P50Ms}}</td><td>{{ms .Latency.P90Ms}}</td><td>{{ms .Latency.P95Ms}}</td><td>{{ms .Latency.P99Ms}}</td><td>{{ms .Latency.MaxMs}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Summary.Workers}}
+<h2>Workers</h2>
+<p>How the workers of each phase spent their time, from their creation to the end of the phase's last request. Low utilization with a large share between requests means the configured concurrency was not reached because the workers waited on the client rather than the server.</p>
+<table>
+<tr><th class="text">Phase</th><th>Workers</th><th>Avg busy</th><th>Utilization</th><th>Least busy</th><th>Start</th><th>Pacing</th><th>Between requests</th><th>Finished early</th></tr>
+{{range .Summary.Workers}}<tr><td class="text">{{.Phase}}</td><td>{{.Workers}}</td><td>{{ratio .BusySeconds .SpanSeconds}}</td><td>{{share .Utilization}}</td><td>{{share .LeastBusy}}</td><td>{{portion .StartSeconds .TotalSeconds}}</td><td>{{portion .PacingSeconds .TotalSeconds}}</td><td>{{portion .WaitingSeconds .TotalSeconds}}</td><td>{{portion .FinishedSeconds .TotalSeconds}}</td></tr>
+{{end}}</table>
+{{end}}
+{{with .Summary.ScheduleSlip}}
+<h2>Schedule slip</h2>
+<p>How far the {{.Slip.Count}} requests paced to {{rate .TargetRate}}/s started behind the schedule of that rate. They started at {{rate .AchievedRate}}/s; a growing slip means the client could not offer the target load.</p>
+<table>
+<tr><th>Target rate</th><th>Achieved rate</th><th>Avg ms</th><th>Min ms</th><th>P50 ms</th><th>P90 ms</th><th>P95 ms</th><th>P99 ms</th><th>Max ms</th><th>Last ms</th></tr>
+<tr><td>{{rate .TargetRate}}</td><td>{{rate .AchievedRate}}</td><td>{{ms .Slip.AvgMs}}</td><td>{{ms .Slip.MinMs}}</td><td>{{ms .Slip.P50Ms}}</td><td>{{ms .Slip.P90Ms}}</td><td>{{ms .Slip.P95Ms}}</td><td>{{ms .Slip.P99Ms}}</td><td>{{ms .Slip.MaxMs}}</td><td>{{ms .FinalSlipMs}}</td></tr>
+</table>
+{{end}}
+{{if .Tenants}}
+<h2>Tenants</h2>
+<p>The {{len .Tenants}} of {{len .Summary.Tenants}} tenants whose requests spanned the longest wall-clock time.</p>
+<table>
+<tr><th class="text">Tenant</th><th>Requests</th><th>First request</th><th>Wall time</th><th>Busy time</th></tr>
+{{range .Tenants}}<tr><td class="text">{{.Tenant}}</td><td>{{.Requests}}</td><td>+{{dur .StartSeconds}}</td><td>{{dur .WallSeconds}}</td><td>{{dur .BusySeconds}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Operations</h2>
+<table>
+<tr><th class="text">Operation</th><th>Requests</th><th>Failed</th><th>Error %</th><th>Requests/s</th><th>Avg ms</th><th>Min ms</th><th>P50 ms</th><th>P90 ms</th><th>P95 ms</th><th>P99 ms</th><th>Max ms</th><th class="text">Status codes</th></tr>
+{{range .Operations}}<tr><td class="text">{{.Operation}}</td><td>{{.Requests}}</td><td{{if .Failed}} class="failed"{{end}}>{{.Failed}}</td><td>{{pct .ErrorRate}}</td><td>{{rate .Throughput}}</td><td>{{ms .Latency.AvgMs}}</td><td>{{ms .Latency.MinMs}}</td><td>{{ms .Latency.P50Ms}}</td><td>{{ms .Latency.P90Ms}}</td><td>{{ms .Latency.P95Ms}}</td><td>{{ms .Latency.P99Ms}}</td><td>{{ms .Latency.MaxMs}}</td><td class="text">{{.StatusCodes}}</td></tr>
+{{end}}</table>
+
+<h2>Errors</h2>
+{{if .Errors}}<table>
+<tr><th class="text">Error pattern</th><th>Count</th><th>% of failures</th></tr>
+{{range .Errors}}<tr><td class="text">{{.Pattern}}</td><td>{{.Count}}</td><td>{{pct .Share}}</td></tr>
+{{end}}</table>
+{{else}}<p>No failures.</p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Reporter is a metric backend that receives the result of every request of a run, such as a
+// Kafka producer or a StatsD client. A reporter registers itself with RegisterReporter, usually
+// from the init function of its own file, and is enabled by name in the reporters config section.
+// RecordResult is called concurrently from all workers and concurrently with Flush, so
+// implementations must be safe for concurrent use. A reporter that also implements io.Closer is
+// closed after the final flush.
+type Reporter interface {
+	// Start is called once before the first request of the run
+	Start(run RunInfo) error
+	// RecordResult receives the result of a request as it completes
+	RecordResult(result RequestResult)
+	// Flush sends buffered results; it is called every flush interval and once at the end of the run
+	Flush() error
+}
+
+// RunInfo describes the run a reporter is started for
+type RunInfo struct {
+	Mode      string
+	Server    string
+	StartTime time.Time
+}
+
+// RequestResult is the outcome of one request
+type RequestResult struct {
+	Time       time.Time     // when the request completed
+	Phase      string        // phase of the run the request belongs to, e.g. users
+	Operation  string        // operation label, e.g. POST /scim2/Users
+	Duration   time.Duration // including reading the response
+	StatusCode int           // 0 when the request got no response
+	Failed     bool          // no response or a 4xx/5xx status
+}
+
+// ReporterFactory creates a reporter from its options in the reporters config section
+type ReporterFactory func(options map[string]string) (Reporter, error)
+
+// registeredReporter is a reporter registered by name
+type registeredReporter struct {
+	factory ReporterFactory
+	remote  bool
+}
+
+// reporterRegistry holds the registered reporters by name
+var reporterRegistry = make(map[string]registeredReporter)
+
+// RegisterReporter makes a reporter available under a name. Remote reporters send results to
+// another host and are refused in offline mode. Registering a name twice panics, as two reporters
+// built into the client cannot share a name.
+func RegisterReporter(name string, remote bool, factory ReporterFactory) {
+	if _, ok := reporterRegistry[name]; ok {
+		panic(fmt.Sprintf("reporter %q registered twice", name))
+	}
+	reporterRegistry[name] = registeredReporter{factory: factory, remote: remote}
+}
+
+// registeredReporterNames returns the names of the registered reporters in order
+func registeredReporterNames() []string {
+	names := make([]string, 0, len(reporterRegistry))
+	for name := range reporterRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// namedReporter is an enabled reporter with the name it is registered under
+type namedReporter struct {
+	name string
+	Reporter
+}
+
+// reporterSet passes request results to the enabled reporters and flushes them periodically
+type reporterSet struct {
+	reporters []namedReporter
+	interval  time.Duration
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// StartReporters creates and starts the enabled reporters and attaches them to the statistics
+func (te *TestExecutor) StartReporters(mode string) error {
+	cfg := te.config.Reporters
+	if len(cfg.Enabled) == 0 {
+		return nil
+	}
+	if err := validateReporters(cfg); err != nil {
+		return err
+	}
+
+	set := &reporterSet{
+		interval: time.Duration(cfg.FlushInterval) * time.Second,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	// A reporter that fails to start stops the ones started before it, so none is left running
+	run := RunInfo{Mode: mode, Server: te.config.GetServerURL(), StartTime: time.Now()}
+	for _, name := range cfg.Enabled {
+		registered := reporterRegistry[name]
+		reporter, err := registered.factory(cfg.Options[name])
+		if err != nil {
+			set.close()
+			return fmt.Errorf("failed to create reporter %s: %v", name, err)
+		}
+		if err := reporter.Start(run); err != nil {
+			set.reporters = append(set.reporters, namedReporter{name, reporter})
+			set.close()
+			return fmt.Errorf("failed to start reporter %s: %v", name, err)
+		}
+		set.reporters = append(set.reporters, namedReporter{name, reporter})
+	}
+
+	te.reporters = set
+	te.stats.reporters = set
+	go set.run()
+	return nil
+}
+
+// validateReporters checks the flush interval and that every enabled reporter is registered
+func validateReporters(cfg ReportersConfig) error {
+	if len(cfg.Enabled) == 0 {
+		return nil
+	}
+	if cfg.FlushInterval < 1 {
+		return fmt.Errorf("reporter flush interval must be positive, got %d", cfg.FlushInterval)
+	}
+	for _, name := range cfg.Enabled {
+		if _, ok := reporterRegistry[name]; !ok {
+			return fmt.Errorf("unknown reporter %q, registered reporters: %s", name, strings.Join(registeredReporterNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// StopReporters flushes and closes the reporters
+func (te *TestExecutor) StopReporters() {
+	set := te.reporters
+	if set == nil {
+		return
+	}
+
+	close(set.stop)
+	<-set.done
+	te.reporters = nil
+
+	for _, reporter := range set.reporters {
+		if err := reporter.Flush(); err != nil {
+			printWarning("Reporter %s failed to flush: %v\n", reporter.name, err)
+		}
+	}
+	set.close()
+}
+
+// close closes the reporters that implement io.Closer
+func (rs *reporterSet) close() {
+	for _, reporter := range rs.reporters {
+		if closer, ok := reporter.Reporter.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				printWarning("Reporter %s failed to close: %v\n", reporter.name, err)
+			}
+		}
+	}
+}
+
+// run flushes the reporters every interval until stopped
+func (rs *reporterSet) run() {
+	defer close(rs.done)
+
+	ticker := time.NewTicker(rs.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, reporter := range rs.reporters {
+				if err := reporter.Flush(); err != nil {
+					printWarning("Reporter %s failed to flush: %v\n", reporter.name, err)
+				}
+			}
+		case <-rs.stop:
+			return
+		}
+	}
+}
+
+// record passes the result of a request to every reporter
+func (rs *reporterSet) record(phase, label string, duration time.Duration, statusCode int) {
+	result := RequestResult{
+		Time:       time.Now(),
+		Phase:      phase,
+		Operation:  label,
+		Duration:   duration,
+		StatusCode: statusCode,
+		Failed:     statusCode == 0 || statusCode >= http.StatusBadRequest,
+	}
+	for _, reporter := range rs.reporters {
+		reporter.RecordResult(result)
+	}
+}
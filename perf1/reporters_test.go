@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeReporter is a reporter that fails to start on demand and remembers whether it was closed
+type fakeReporter struct {
+	startErr error
+	closed   bool
+}
+
+func (r *fakeReporter) Start(RunInfo) error        { return r.startErr }
+func (r *fakeReporter) RecordResult(RequestResult) {}
+func (r *fakeReporter) Flush() error               { return nil }
+func (r *fakeReporter) Close() error               { r.closed = true; return nil }
+
+func TestStartReportersClosesStartedOnFailure(t *testing.T) {
+	fakes := map[string]*fakeReporter{}
+	for _, name := range []string{"fake-first", "fake-broken", "fake-last"} {
+		name := name
+		RegisterReporter(name, false, func(map[string]string) (Reporter, error) {
+			fakes[name] = &fakeReporter{}
+			if name == "fake-broken" {
+				fakes[name].startErr = errors.New("unreachable")
+			}
+			return fakes[name], nil
+		})
+	}
+
+	tests := []struct {
+		name       string
+		enabled    []string
+		wantErr    bool
+		wantClosed []string
+		wantOpen   []string
+	}{
+		{name: "all start", enabled: []string{"fake-first", "fake-last"}, wantOpen: []string{"fake-first", "fake-last"}},
+		{name: "failed start closes those before it", enabled: []string{"fake-first", "fake-broken", "fake-last"}, wantErr: true, wantClosed: []string{"fake-first", "fake-broken"}},
+		{name: "unknown reporter creates none", enabled: []string{"fake-last", "missing"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name := range fakes {
+				delete(fakes, name)
+			}
+
+			te := &TestExecutor{config: DefaultConfig(), stats: NewTestStats()}
+			te.config.Reporters.Enabled = tt.enabled
+			err := te.StartReporters("create")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			defer te.StopReporters()
+
+			for _, name := range tt.wantClosed {
+				if !fakes[name].closed {
+					t.Errorf("reporter %s left open", name)
+				}
+			}
+			for _, name := range tt.wantOpen {
+				if fakes[name].closed {
+					t.Errorf("reporter %s closed while running", name)
+				}
+			}
+			if _, created := fakes["fake-last"]; tt.wantErr && created {
+				t.Errorf("reporter after the failure was created")
+			}
+		})
+	}
+}
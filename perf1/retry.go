@@ -1,39 +1,38 @@
 package main
 
 import (
-	"encoding/csv"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
-// readFailedUsersFromCSV reads failed users from the CSV file
-func (te *TestExecutor) readFailedUsersFromCSV() ([]FailedUser, error) {
-	file, err := os.Open(te.config.Execution.FailedUsersCsvPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open failed users CSV file: %v", err)
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV file: %v", err)
-	}
-
-	var failedUsers []FailedUser
+// scanFailedUsers reads failed users CSV rows one at a time and passes each user to fn until the
+// rows run out or fn returns false, so files of any size are read in constant memory
+func scanFailedUsers(r io.Reader, fn func(FailedUser) bool) error {
+	reader := newCSVReader(r)
+	reader.FieldsPerRecord = -1 // Malformed records are skipped below instead of failing the read
+	reader.ReuseRecord = true
 	
-	// Skip header row if exists
-	startRow := 0
-	if len(records) > 0 && (records[0][0] == "TenantID" || records[0][0] == "Tenant ID") {
-		startRow = 1
-	}
-	
-	for i := startRow; i < len(records); i++ {
-		record := records[i]
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV file: %v", err)
+		}
+		
+		// Skip header row if exists
+		if row == 0 && len(record) > 0 && (record[0] == "TenantID" || record[0] == "Tenant ID") {
+			continue
+		}
+		
 		if len(record) < 4 {
 			continue // Skip malformed records
 		}
@@ -44,119 +43,310 @@ func (te *TestExecutor) readFailedUsersFromCSV() ([]FailedUser, error) {
 			continue
 		}
 
-		failedUsers = append(failedUsers, FailedUser{
+		user := FailedUser{
 			TenantID:  tenantID,
 			Username:  record[1],
 			Error:     record[2],
 			Timestamp: record[3],
-		})
+			Attempts:  1, // rows written before the attempts column record one attempt each
+		}
+		if len(record) >= 6 {
+			user.StatusCode, _ = strconv.Atoi(record[4])
+			user.ResponseSnippet = record[5]
+		}
+		if len(record) >= 7 {
+			if attempts, err := strconv.Atoi(record[6]); err == nil && attempts > 0 {
+				user.Attempts = attempts
+			}
+		}
+		if !fn(user) {
+			return nil
+		}
 	}
+}
 
-	return failedUsers, nil
+// retryingSuffix is appended to the failed users file to name the file that collects the users a
+// retry leaves failed, until it replaces the file retried at the end of the run
+const retryingSuffix = ".retrying"
+
+// retryRound is the outcome of one pass over the failed users file
+type retryRound struct {
+	queued    int  // users retried
+	final     int  // users not retried as their failure would repeat
+	remaining int  // users that still fail, final ones included
+	replaced  bool // whether the remaining users replaced the file retried
 }
 
-// ExecuteRetryFailed retries only the failed users from the CSV file
+// transientFailure reports whether a failed user may be created when sent again: the request got
+// no response, as with timeouts, or the server answered with a 5xx error or throttled it with 429.
+// Other statuses, such as 400 for a rejected payload or 409 for a user that exists, would repeat.
+func transientFailure(user FailedUser) bool {
+	return user.StatusCode == 0 || user.StatusCode == http.StatusTooManyRequests || user.StatusCode >= http.StatusInternalServerError
+}
+
+// ExecuteRetryFailed retries the failed users from the CSV file, in as many rounds as configured.
+// Every round retries the users the one before left failed, after a backoff that doubles from
+// round to round, until none are left or the rounds run out. With several rounds each is a phase
+// of its own, so the statistics show how the failures dwindled.
 func (te *TestExecutor) ExecuteRetryFailed() error {
-	fmt.Println("Starting retry of failed users...")
+	rounds := te.config.Execution.RetryRounds
+	backoff := te.config.Execution.RetryRoundBackoff
+	if rounds < 0 || backoff < 0 {
+		return fmt.Errorf("retry-rounds and retry-round-backoff must not be negative")
+	}
+	rounds = max(rounds, 1)
 	
-	// Create failed users writer in append mode for logging new failures during retry
-	failedUsersWriter, err := NewFailedUsersCSVWriterAppend(te.config.Execution.FailedUsersCsvPath)
-	if err != nil {
-		return fmt.Errorf("failed to create failed users CSV writer: %v", err)
+	startTime := time.Now()
+	retried := 0
+	for round := 1; round <= rounds; round++ {
+		if rounds > 1 {
+			fmt.Printf("\n=== Retry round %d of %d ===\n", round, rounds)
+			te.setPhase(fmt.Sprintf("retry round %d", round))
+		}
+		
+		result, err := te.retryFailedUsers()
+		if err != nil {
+			return err
+		}
+		retried += result.queued
+		
+		if result.queued == 0 && round == 1 {
+			if result.final == 0 {
+				fmt.Println("No failed users found to retry.")
+			}
+			return nil
+		}
+		if result.remaining == result.final {
+			if rounds > 1 {
+				fmt.Printf("\nNo retryable failed users remain after %d of %d rounds\n", round, rounds)
+			}
+			break
+		}
+		if !result.replaced {
+			// Another round would send the same users again
+			printWarning("Stopping after round %d as the remaining failed users could not replace the retried file\n", round)
+			break
+		}
+		if round == rounds {
+			if rounds > 1 {
+				printWarning("%d users still fail after %d rounds\n", result.remaining-result.final, rounds)
+			}
+			break
+		}
+		
+		fmt.Printf("Waiting %v before retrying the %d users that still fail\n", backoff, result.remaining-result.final)
+		te.setPhase("retry backoff")
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-te.ctx.Done():
+			timer.Stop()
+			printWarning("Retry interrupted before round %d; the users that still fail remain in the failed users CSV\n", round+1)
+			return ErrInterrupted
+		}
+		backoff *= 2
 	}
-	defer failedUsersWriter.Close()
 	
-	// Temporarily assign the writer to the executor for use in retry workers
-	te.failedUsersWriter = failedUsersWriter
+	fmt.Printf("\nRetry of %d failed users completed in %v\n", retried, time.Since(startTime))
+	
+	// Print statistics
+	te.stats.PrintStats()
+	
+	return nil
+}
+
+// retryFailedUsers retries the failed users from the CSV file, each (tenant, username) pair once.
+// Only transient failures are retried; the rest are final and carried over as they were. The rows
+// are streamed from the file to a queue the threads take users from, so millions of failures are
+// retried without loading the rows; only the tenant and username of each user seen are kept, to
+// skip repeated users. The users that fail again, and with an interruption those not retried yet,
+// go to a fresh file that replaces the one retried at the end of the round, with the previous file
+// archived under a timestamped name.
+func (te *TestExecutor) retryFailedUsers() (retryRound, error) {
+	fmt.Println("Starting retry of failed users...")
 	
-	// Read failed users from CSV
-	failedUsers, err := te.readFailedUsersFromCSV()
+	result := retryRound{}
+	path := te.config.Execution.FailedUsersCsvPath
+	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to read failed users: %v", err)
+		return result, fmt.Errorf("failed to open failed users CSV file: %v", err)
 	}
+	defer file.Close()
 	
-	if len(failedUsers) == 0 {
-		fmt.Println("No failed users found to retry.")
-		return nil
+	// The remaining failures are collected next to the file, which stays untouched until the end
+	// of the run, so a crashed retry leaves the failures to retry where they were
+	fresh := path + retryingSuffix
+	failedUsersWriter, err := NewFailedUsersCSVWriter(fresh)
+	if err != nil {
+		return result, fmt.Errorf("failed to create failed users CSV writer: %v", err)
 	}
+	failedUsersWriter.fallbackDir = te.config.Execution.FallbackDir
 	
-	fmt.Printf("Found %d failed users to retry\n", len(failedUsers))
-	
-	startTime := time.Now()
-	
-	// Calculate users per thread using configured number of threads
-	usersPerThread := len(failedUsers) / te.config.Execution.NoOfThreads
-	remainingUsers := len(failedUsers) % te.config.Execution.NoOfThreads
-	
-	// Create retry worker tasks
-	var retryTasks []RetryWorkerTask
-	userStart := 0
+	// Assign the writer to the executor for use in retry workers
+	te.failedUsersWriter = failedUsersWriter
 	
-	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
-		threadUsers := usersPerThread
-		if threadID < remainingUsers {
-			threadUsers++ // Distribute remaining users to first few threads
-		}
+	// Stream the failed users to the workers, skipping repeated users. Once the run is
+	// interrupted the rest of the file is carried over to the fresh file instead.
+	jobs := make(chan FailedUser)
+	queued, final, duplicates := 0, 0, 0
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(jobs)
 		
-		if threadUsers > 0 {
-			userEnd := userStart + threadUsers - 1
+		seen := make(map[failedUserKey]struct{})
+		readErr <- scanFailedUsers(file, func(user FailedUser) bool {
+			key := failedUserKey{user.TenantID, user.Username}
+			if _, ok := seen[key]; ok {
+				duplicates++
+				return true
+			}
+			seen[key] = struct{}{}
 			
-			// Create a separate HTTP client for this retry task
-			taskClient := NewHTTPClient(te.config)
+			if !transientFailure(user) {
+				final++
+				te.keepFailedUser(user)
+				return true
+			}
 			
-			retryTasks = append(retryTasks, RetryWorkerTask{
-				ThreadID:    threadID,
-				UserStart:   userStart,
-				UserEnd:     userEnd,
-				FailedUsers: failedUsers,
-				Client:      taskClient,
-			})
-			userStart = userEnd + 1
-		}
-	}
+			select {
+			case jobs <- user:
+				queued++
+			case <-te.ctx.Done():
+				te.keepFailedUser(user)
+			}
+			return true
+		})
+	}()
+	
+	// The schedule of the target rate starts again after the pause between rounds
+	te.limiter.restart()
+	startTime := time.Now()
 	
 	// Create wait group and result channel
 	var wg sync.WaitGroup
-	resultChan := make(chan TestResult, len(failedUsers))
+	resultChan := make(chan TestResult, te.config.Execution.NoOfThreads)
 	
 	// Start result processor
-	go te.processResults(resultChan)
+	resultsDone := make(chan struct{})
+	go te.processResults(resultChan, resultsDone)
 	
-	// Apply ramp-up delay between thread starts
-	rampUpDelay := time.Duration(te.config.Execution.RampUpPeriod) * time.Second / time.Duration(te.config.Execution.NoOfThreads)
-	
-	// Start retry worker goroutines
-	for _, task := range retryTasks {
-		wg.Add(1)
-		go te.retryUsersWorkerScalable(task, resultChan, &wg)
-		
-		// Ramp-up delay
-		if rampUpDelay > 0 {
-			time.Sleep(rampUpDelay)
+	// Start retry worker goroutines; each worker delays its own start to apply the ramp-up
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		task := RetryWorkerTask{
+			ThreadID:   threadID,
+			Client:     te.newHTTPClient(),
+			StartDelay: te.rampUpStartDelay(threadID, te.config.Execution.NoOfThreads),
 		}
+		
+		wg.Add(1)
+		go te.retryUsersWorkerScalable(task, jobs, resultChan, &wg)
 	}
 	
 	// Wait for all workers to complete
 	wg.Wait()
 	close(resultChan)
 	
-	duration := time.Since(startTime)
-	fmt.Printf("\nRetry execution completed in %v\n", duration)
+	// Wait for the result processor to drain the channel before reporting
+	<-resultsDone
 	
-	// Print statistics
-	te.stats.PrintStats()
+	te.failedUsersWriter = nil
+	closeErr := failedUsersWriter.Close()
+	if err := <-readErr; err != nil {
+		os.Remove(fresh)
+		return result, fmt.Errorf("failed to read failed users: %v", err)
+	}
+	if closeErr != nil {
+		return result, fmt.Errorf("failed to write remaining failed users to %s, %s is left as it was: %v", fresh, path, closeErr)
+	}
 	
-	return nil
+	if duplicates > 0 {
+		fmt.Printf("Skipped %d repeated failed users in %s\n", duplicates, path)
+	}
+	if final > 0 {
+		fmt.Printf("Kept %d failed users whose failure is final, such as a 409 conflict, without retrying them\n", final)
+	}
+	result.queued = queued
+	result.final = final
+	result.remaining = failedUsersWriter.written
+	if result.replaced, err = te.replaceFailedUsers(path, failedUsersWriter); err != nil {
+		return result, err
+	}
+	
+	if te.interrupted() {
+		printWarning("Retry interrupted after %v; users not retried remain in the failed users CSV\n", time.Since(startTime))
+		return result, ErrInterrupted
+	}
+	
+	if queued > 0 {
+		fmt.Printf("Retried %d failed users in %v\n", queued, time.Since(startTime))
+	}
+	return result, nil
+}
+
+// replaceFailedUsers archives the failed users file that was retried under a timestamped name and
+// moves the fresh file with the remaining failures in its place. If the fresh file is incomplete
+// because its disk filled up, the retried file is left in place. Returns whether it was replaced.
+func (te *TestExecutor) replaceFailedUsers(path string, remaining *FailedUsersCSVWriter) (bool, error) {
+	if remaining.paused || remaining.dropped > 0 || remaining.filename != path+retryingSuffix {
+		printWarning("The remaining failed users are incomplete or were moved to %s, so %s is left in place\n", remaining.filename, path)
+		return false, nil
+	}
+	
+	archive := archivedFailedUsersPath(path, time.Now())
+	if err := renameFile(path, archive); err != nil {
+		return false, fmt.Errorf("failed to archive failed users CSV: %v", fileError(err))
+	}
+	if err := renameFile(remaining.filename, path); err != nil {
+		return false, fmt.Errorf("failed to replace failed users CSV, the remaining failures are in %s: %v", remaining.filename, fileError(err))
+	}
+	
+	// The manifest of the retried file no longer matches it, so both files get new ones
+	os.Remove(path + manifestSuffix)
+	te.outputOptions().writeManifest(archive)
+	te.outputOptions().writeManifest(path)
+	
+	fmt.Printf("%d failed users remain in %s; the previous file was archived to %s\n", remaining.written, path, archive)
+	return true, nil
 }
 
-// retryUsersWorkerScalable retries a chunk of failed users assigned to a specific thread
-func (te *TestExecutor) retryUsersWorkerScalable(task RetryWorkerTask, resultChan chan<- TestResult, wg *sync.WaitGroup) {
+// archivedFailedUsersPath names the archive of a retried failed users file after the time of the
+// retry, e.g. failedUsers.20240501-100000.csv, numbering the archives of retry rounds within the
+// same second, e.g. failedUsers.20240501-100000-2.csv
+func archivedFailedUsersPath(path string, at time.Time) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext) + "." + at.Format("20060102-150405")
+	archive := base + ext
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(archive); os.IsNotExist(err) {
+			return archive
+		}
+		archive = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+}
+
+// keepFailedUser carries a failed user that was not retried over to the remaining failures as it was
+func (te *TestExecutor) keepFailedUser(user FailedUser) {
+	if err := te.failedUsersWriter.WriteFailedUser(user); err != nil {
+		printFailure("Failed to keep failed user %s of tenant %d in CSV: %v\n", user.Username, user.TenantID, err)
+	}
+}
+
+// retryUsersWorkerScalable retries the failed users it takes from the queue until the queue is closed
+func (te *TestExecutor) retryUsersWorkerScalable(task RetryWorkerTask, jobs <-chan FailedUser, resultChan chan<- TestResult, wg *sync.WaitGroup) {
 	defer wg.Done()
 	
-	usersToRetry := task.FailedUsers[task.UserStart:task.UserEnd+1]
-	fmt.Printf("Thread %d: Retrying %d users (indices %d-%d)\n", task.ThreadID, len(usersToRetry), task.UserStart, task.UserEnd)
+	waitForRampUp(te.ctx, task.StartDelay)
+	
+	fmt.Printf("Thread %d: Retrying users from the queue\n", task.ThreadID)
 	
-	for _, user := range usersToRetry {
+	retried := 0
+	for user := range jobs {
+		// Leave the users taken after the interruption in the failed users file for the next retry
+		if te.interrupted() {
+			te.keepFailedUser(user)
+			continue
+		}
+		
 		result := TestResult{
 			TenantIndex: user.TenantID,
 			UserIndex:   -1, // We don't have the original user index
@@ -172,18 +362,27 @@ func (te *TestExecutor) retryUsersWorkerScalable(task RetryWorkerTask, resultCha
 			}
 		}
 		
-		userResp, err := task.Client.CreateUserWithName(user.TenantID, user.Username)
+		task.Client.pace(te.ctx, te.limiter)
+		userResp, err := task.Client.CreateUserWithName(te.ctx, user.TenantID, user.Username)
+		
+		// A request aborted by the interruption leaves the user in the failed users file uncounted
+		if err != nil && te.interrupted() {
+			te.keepFailedUser(user)
+			continue
+		}
+		
 		if err != nil {
 			result.Success = false
 			result.Error = err
 			
-			// Write failed user to CSV file again
-			timestamp := time.Now().Format("2006-01-02 15:04:05")
-			if csvErr := te.failedUsersWriter.WriteFailedUser(user.TenantID, user.Username, err.Error(), timestamp); csvErr != nil {
-				fmt.Printf("Thread %d: Failed to write failed user to CSV: %v\n", task.ThreadID, csvErr)
+			// Write failed user to CSV file again, counting this attempt on top of the earlier ones
+			failed := newFailedUser(user.TenantID, user.Username, err)
+			failed.Attempts = user.Attempts + 1
+			if csvErr := te.failedUsersWriter.WriteFailedUser(failed); csvErr != nil {
+				printFailure("Thread %d: Failed to write failed user to CSV: %v\n", task.ThreadID, csvErr)
 			}
 			
-			fmt.Printf("Thread %d: Failed to retry user %s for tenant %d: %v\n", 
+			printFailure("Thread %d: Failed to retry user %s for tenant %d: %v\n", 
 				task.ThreadID, user.Username, user.TenantID, err)
 		} else {
 			result.Success = true
@@ -192,8 +391,9 @@ func (te *TestExecutor) retryUsersWorkerScalable(task RetryWorkerTask, resultCha
 				task.ThreadID, user.Username, user.TenantID, userResp.ID)
 		}
 		
+		retried++
 		resultChan <- result
 	}
 	
-	fmt.Printf("Thread %d: Completed retry for %d users\n", task.ThreadID, len(usersToRetry))
+	fmt.Printf("Thread %d: Completed retry for %d users\n", task.ThreadID, retried)
 }
\ No newline at end of file
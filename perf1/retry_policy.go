@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryable reports whether a request that failed with err may succeed when sent again, i.e. the
+// server answered with one of the configured transient status codes
+func (c RetryConfig) retryable(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+
+	for _, code := range c.StatusCodes {
+		if statusErr.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns how long to wait before the given retry, doubling from the initial backoff up to
+// the maximum. Half of the wait is randomized so threads failing together do not retry together.
+func (c RetryConfig) backoff(retry int) time.Duration {
+	wait := time.Duration(c.InitialBackoff) * time.Millisecond
+	maxWait := time.Duration(c.MaxBackoff) * time.Millisecond
+	for i := 1; i < retry && wait < maxWait; i++ {
+		wait *= 2
+	}
+	if wait > maxWait {
+		wait = maxWait
+	}
+	if wait <= 0 {
+		return 0
+	}
+
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// wait returns how long to wait before the given retry of a request that failed with err: the
+// backoff, or the wait the server asked for with Retry-After if that is longer
+func (c RetryConfig) wait(retry int, err error) time.Duration {
+	wait := c.backoff(retry)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > wait {
+		wait = statusErr.RetryAfter
+	}
+	return wait
+}
+
+// retryAfter returns the wait a response asks for in its Retry-After header, given in seconds or
+// as an HTTP date, or 0 if it has none
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// isStatus reports whether err is a StatusError with the given status code
+func isStatus(err error, statusCode int) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == statusCode
+}
+
+// withRetry calls send until it succeeds, fails with an error that is not retryable or the
+// configured attempts are used up, backing off exponentially between attempts, or as long as the
+// server asks with Retry-After. It returns the error of the last attempt. Waiting stops early when
+// the context is cancelled.
+func (h *HTTPClient) withRetry(ctx context.Context, send func() error) error {
+	policy := h.config.Retry
+
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return err
+		}
+
+		if h.stats != nil {
+			h.stats.RecordRetry()
+		}
+		if !h.sleep(ctx, policy.wait(attempt, err)) {
+			return err
+		}
+	}
+}
+
+// backOff waits the backoff of the retry policy before the given retry and returns false if the
+// context is cancelled first
+func (h *HTTPClient) backOff(ctx context.Context, retry int) bool {
+	return h.sleep(ctx, h.config.Retry.backoff(retry))
+}
+
+// sleep waits for the given duration and returns false if the context is cancelled first
+func (h *HTTPClient) sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
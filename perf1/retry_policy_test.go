@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		min    time.Duration
+		max    time.Duration
+	}{
+		{name: "absent", header: "", min: 0, max: 0},
+		{name: "seconds", header: "3", min: 3 * time.Second, max: 3 * time.Second},
+		{name: "negative seconds", header: "-1", min: 0, max: 0},
+		{name: "date", header: time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), min: 8 * time.Second, max: 10 * time.Second},
+		{name: "past date", header: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), min: 0, max: 0},
+		{name: "garbage", header: "soon", min: 0, max: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+
+			got := retryAfter(resp)
+			if got < tt.min || got > tt.max {
+				t.Errorf("retryAfter(%q) = %v, want between %v and %v", tt.header, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	policy := RetryConfig{InitialBackoff: 100, MaxBackoff: 1000}
+
+	tests := []struct {
+		name string
+		err  error
+		min  time.Duration
+		max  time.Duration
+	}{
+		{name: "backoff without Retry-After", err: &StatusError{StatusCode: 503}, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{name: "longer Retry-After wins", err: &StatusError{StatusCode: 429, RetryAfter: 5 * time.Second}, min: 5 * time.Second, max: 5 * time.Second},
+		{name: "shorter Retry-After ignored", err: &StatusError{StatusCode: 429, RetryAfter: time.Millisecond}, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.wait(1, tt.err)
+			if got < tt.min || got > tt.max {
+				t.Errorf("wait = %v, want between %v and %v", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestCreateUserConflictAfterRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int // answers to the creation attempts in turn
+		wantID   string
+		wantErr  bool
+	}{
+		{name: "created at once", statuses: []int{http.StatusCreated}, wantID: "new-id"},
+		{name: "conflict after a retry is the earlier attempt", statuses: []int{http.StatusServiceUnavailable, http.StatusConflict}, wantID: "existing-id"},
+		{name: "conflict on the first attempt fails", statuses: []int{http.StatusConflict}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if r.Method == http.MethodGet {
+					json.NewEncoder(w).Encode(SCIMListResponse{TotalResults: 1, Resources: []SCIMUserResponse{{ID: "existing-id", UserName: "isTestUser_1"}}})
+					return
+				}
+
+				status := tt.statuses[atomic.AddInt32(&attempts, 1)-1]
+				w.WriteHeader(status)
+				if status == http.StatusCreated {
+					json.NewEncoder(w).Encode(SCIMUserResponse{ID: "new-id", UserName: "isTestUser_1"})
+				}
+			}))
+			defer server.Close()
+
+			client := newTestClient(t, server.URL)
+			user, err := client.CreateUserWithName(context.Background(), 0, "isTestUser_1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && user.ID != tt.wantID {
+				t.Errorf("ID = %q, want %q", user.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+// newTestClient returns an HTTP client for the server at serverURL, retrying 503s without a backoff
+func newTestClient(t *testing.T, serverURL string) *HTTPClient {
+	t.Helper()
+
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.Server.Host = u.Hostname()
+	config.Server.Port = port
+	config.Retry = RetryConfig{MaxAttempts: 3, StatusCodes: []int{http.StatusServiceUnavailable}}
+
+	pool, err := newConnectionPool(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewHTTPClient(config, pool)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExecuteRetryFailedRoles creates the roles in the failed roles CSV again, like the retry of the
+// failed users. The roles that fail again, and with an interruption those not retried yet, go to a
+// fresh file that replaces the one retried, with the previous file archived under a timestamped
+// name. Users of a tenant whose test role was missing failed without being sent, so once their
+// role exists they are created by retrying the failed users.
+func (te *TestExecutor) ExecuteRetryFailedRoles() error {
+	path := te.config.Execution.FailedRolesCsvPath
+	if path == "" {
+		return fmt.Errorf("failedRolesCsvPath must be set to retry the failed roles")
+	}
+
+	roles, err := readFailedRoles(path)
+	if err != nil {
+		return err
+	}
+	if len(roles) == 0 {
+		fmt.Println("No failed roles found to retry.")
+		return nil
+	}
+	fmt.Printf("Starting retry of %d failed roles...\n", len(roles))
+
+	// The remaining failures are collected next to the file, which stays untouched until the end
+	// of the run, so a crashed retry leaves the roles to retry where they were
+	fresh := path + retryingSuffix
+	// The manifest is written once the file has taken the place of the one retried
+	remaining, err := NewFailedRolesCSVWriter(fresh, csvOutputOptions{fallbackDir: te.config.Execution.FallbackDir})
+	if err != nil {
+		return err
+	}
+	te.failedRolesWriter = remaining
+
+	jobs := make(chan FailedRole)
+	go func() {
+		defer close(jobs)
+		for _, role := range roles {
+			select {
+			case jobs <- role:
+			case <-te.ctx.Done():
+				if err := remaining.WriteFailedRole(role); err != nil {
+					printFailure("Failed to keep failed role %s of tenant %d in CSV: %v\n", role.RoleName, role.TenantID, err)
+				}
+			}
+		}
+	}()
+
+	startTime := time.Now()
+	var testRoles int64 // tenants whose test role now exists
+	var wg sync.WaitGroup
+	threads := min(te.config.Execution.NoOfThreads, len(roles))
+	for threadID := 0; threadID < threads; threadID++ {
+		client := te.newHTTPClient()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for role := range jobs {
+				_, err := client.EnsureNamedRole(te.ctx, role.TenantID, role.RoleName)
+				te.recordRoleResult(role.TenantID, role.RoleName, err)
+				if err != nil {
+					printFailure("Failed to create role '%s' for tenant %d: %v\n", role.RoleName, role.TenantID, err)
+					continue
+				}
+				printSuccess("Role '%s' exists for tenant %d\n", role.RoleName, role.TenantID)
+				if role.RoleName == te.config.GetTenantRoleName(role.TenantID) {
+					atomic.AddInt64(&testRoles, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	te.failedRolesWriter = nil
+	if err := remaining.Close(); err != nil {
+		return fmt.Errorf("failed to write remaining failed roles to %s, %s is left as it was: %v", fresh, path, err)
+	}
+
+	if remaining.complete(fresh) {
+		archive := archivedFailedUsersPath(path, time.Now())
+		if err := renameFile(path, archive); err != nil {
+			return fmt.Errorf("failed to archive failed roles CSV: %v", fileError(err))
+		}
+		if err := renameFile(fresh, path); err != nil {
+			return fmt.Errorf("failed to replace failed roles CSV, the remaining failures are in %s: %v", fresh, fileError(err))
+		}
+		os.Remove(path + manifestSuffix)
+		te.outputOptions().writeManifest(archive)
+		te.outputOptions().writeManifest(path)
+		fmt.Printf("%d failed roles remain in %s; the previous file was archived to %s\n", remaining.written, path, archive)
+	} else {
+		printWarning("The remaining failed roles are incomplete or were moved to %s, so %s is left in place\n", remaining.filename, path)
+	}
+
+	if testRoles > 0 {
+		fmt.Printf("The test role now exists in %d tenants whose users may have failed without being sent; create them with the retry command\n", testRoles)
+	}
+
+	if te.interrupted() {
+		printWarning("Retry interrupted after %v; roles not retried remain in the failed roles CSV\n", time.Since(startTime))
+		return ErrInterrupted
+	}
+
+	fmt.Printf("\nRetry of %d failed roles completed in %v\n", len(roles), time.Since(startTime))
+	te.stats.PrintStats()
+	return nil
+}
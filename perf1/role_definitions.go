@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// validateRoleDefinitions checks the roles to create in every tenant
+func validateRoleDefinitions(test TestConfig) error {
+	if len(test.RolePermissions) == 0 {
+		return fmt.Errorf("rolePermissions must list at least one permission")
+	}
+
+	seen := map[string]bool{test.RoleName: true}
+	for i, role := range test.Roles {
+		if strings.TrimSpace(role.Name) == "" {
+			return fmt.Errorf("role %d in roles has no name", i+1)
+		}
+		if seen[role.Name] {
+			return fmt.Errorf("role '%s' is defined more than once or is the test role", role.Name)
+		}
+		seen[role.Name] = true
+	}
+	return nil
+}
+
+// extraRoleNames returns the roles created in every tenant besides its test role: the defined
+// roles, then the variant roles not defined already
+func (c *Config) extraRoleNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, role := range c.Test.Roles {
+		names = append(names, role.Name)
+		seen[role.Name] = true
+	}
+	for _, name := range c.Variants.roleNames() {
+		if !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+	return names
+}
+
+// assignedRoleNames returns the defined roles every test user gets along with the test role
+func (c TestConfig) assignedRoleNames() []string {
+	var names []string
+	for _, role := range c.Roles {
+		if role.Assign {
+			names = append(names, role.Name)
+		}
+	}
+	return names
+}
+
+// rolePermissions returns the permission resource IDs of a role: those of its definition, or the
+// configured role permissions
+func (c *Config) rolePermissions(roleName string) []string {
+	for _, role := range c.Test.Roles {
+		if role.Name == roleName && len(role.Permissions) > 0 {
+			return role.Permissions
+		}
+	}
+	return c.Test.RolePermissions
+}
+
+// addRoleEnvelope builds the RemoteUserStoreManagerService addRole SOAP envelope of a role with the
+// given permissions, each granted for ui.execute
+func addRoleEnvelope(roleName string, permissions []string) string {
+	var body strings.Builder
+	for _, permission := range permissions {
+		fmt.Fprintf(&body, `
+         <ser:permissions>
+            <xsd:action>ui.execute</xsd:action>
+            <xsd:resourceId>%s</xsd:resourceId>
+         </ser:permissions>`, html.EscapeString(permission))
+	}
+
+	return fmt.Sprintf(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:ser="http://service.ws.um.carbon.wso2.org" xmlns:xsd="http://dao.service.ws.um.carbon.wso2.org/xsd">
+   <soapenv:Header/>
+   <soapenv:Body>
+      <ser:addRole>
+         <ser:roleName>%s</ser:roleName>%s
+      </ser:addRole>
+   </soapenv:Body>
+</soapenv:Envelope>`, html.EscapeString(roleName), body.String())
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ExecuteRoleScale assigns every user a configurable number of roles and reports how the
+// per-user role count affects user creation and login latency
+func (te *TestExecutor) ExecuteRoleScale() error {
+	cfg := te.config.RoleScale
+	runID := time.Now().Unix()
+
+	fmt.Println("Starting role count scaling test...")
+	fmt.Printf("- Roles Per User: %d\n", cfg.RolesPerUser)
+	fmt.Printf("- Users Per Tenant: %d\n", cfg.Users)
+	fmt.Printf("- Tenants: %d\n", te.config.Execution.NoOfTenants)
+
+	roleNames := make([]string, cfg.RolesPerUser)
+	for i := range roleNames {
+		roleNames[i] = fmt.Sprintf("%s_scale_%d", te.config.Test.RoleName, i+1)
+	}
+
+	tenantStart := te.config.Execution.TenantStartNumber
+	tenantEnd := tenantStart + te.config.Execution.NoOfTenants
+
+	fmt.Println("Creating roles...")
+	if err := te.openFailedRoles(); err != nil {
+		return err
+	}
+	client := te.newHTTPClient()
+	for tenantIndex := tenantStart; tenantIndex < tenantEnd && !te.interrupted(); tenantIndex++ {
+		for _, roleName := range roleNames {
+			_, err := client.EnsureNamedRole(te.ctx, tenantIndex, roleName)
+			if err != nil && te.interrupted() {
+				break
+			}
+			te.recordRoleResult(tenantIndex, roleName, err)
+
+			if err != nil {
+				printFailure("Failed to create role %s for tenant %d: %v\n", roleName, tenantIndex, err)
+			}
+		}
+	}
+	te.closeFailedRoles()
+	if te.interrupted() {
+		return ErrInterrupted
+	}
+
+	var createLatencies []time.Duration
+	var loginLatencies []time.Duration
+	var mutex sync.Mutex
+
+	type roleScaleUser struct {
+		tenantIndex int
+		number      int
+	}
+
+	users := make(chan roleScaleUser, te.config.Execution.NoOfThreads)
+	go func() {
+		defer close(users)
+
+		for tenantIndex := tenantStart; tenantIndex < tenantEnd; tenantIndex++ {
+			for i := 0; i < cfg.Users; i++ {
+				select {
+				case users <- roleScaleUser{tenantIndex, i}:
+				case <-te.ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	fmt.Println("Creating users and logging them in...")
+	startTime := time.Now()
+
+	var wg sync.WaitGroup
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		wg.Add(1)
+		go func(threadID int) {
+			defer wg.Done()
+
+			client := te.newHTTPClient()
+			for user := range users {
+				if te.interrupted() {
+					continue
+				}
+
+				tenantIndex := user.tenantIndex
+				username := fmt.Sprintf("%sroles%d_%d_%d", te.config.Test.UsernamePrefix, cfg.RolesPerUser, runID, user.number)
+
+				requestStart := time.Now()
+				_, err := client.CreateUserWithRoles(te.ctx, tenantIndex, username, roleNames)
+				createTime := time.Since(requestStart)
+				if err != nil && te.interrupted() {
+					continue
+				}
+				te.stats.IncrementUser(err == nil)
+				te.stats.RecordError(err)
+
+				if err != nil {
+					printFailure("Thread %d: Failed to create user %s for tenant %d: %v\n", threadID, username, tenantIndex, err)
+					continue
+				}
+
+				requestStart = time.Now()
+				err = client.AuthenticateUser(te.ctx, tenantIndex, username, te.userPassword(tenantIndex, username))
+				loginTime := time.Since(requestStart)
+				if err != nil && te.interrupted() {
+					continue
+				}
+
+				mutex.Lock()
+				createLatencies = append(createLatencies, createTime)
+				if err == nil {
+					loginLatencies = append(loginLatencies, loginTime)
+				}
+				mutex.Unlock()
+
+				if err != nil {
+					printFailure("Thread %d: Failed to log in user %s for tenant %d: %v\n", threadID, username, tenantIndex, err)
+				}
+			}
+		}(threadID)
+	}
+
+	wg.Wait()
+
+	duration := time.Since(startTime)
+	if te.interrupted() {
+		fmt.Printf("\nRole count scaling test interrupted after %v\n", duration)
+	} else {
+		fmt.Printf("\nRole count scaling test completed in %v\n", duration)
+	}
+
+	fmt.Printf("\n=== Latency With %d Roles Per User ===\n", cfg.RolesPerUser)
+	fmt.Printf("Create (%d) - %s\n", len(createLatencies), SummarizeLatencies(createLatencies))
+	fmt.Printf("Login (%d) - %s\n", len(loginLatencies), SummarizeLatencies(loginLatencies))
+	fmt.Println("======================================")
+
+	if te.interrupted() {
+		return ErrInterrupted
+	}
+
+	te.stats.PrintStats()
+
+	return nil
+}
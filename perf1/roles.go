@@ -1,14 +1,63 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 )
 
+// ErrRoleMissing is returned when a role could not be created and was found not to exist
+var ErrRoleMissing = errors.New("role does not exist")
+
+// ErrRoleExists is returned when a role already existed and existing roles do not count as created
+var ErrRoleExists = errors.New("role already exists")
+
 // ExecuteRoleCreation creates roles for all tenants concurrently
 func (te *TestExecutor) ExecuteRoleCreation() error {
+	if te.config.Test.SkipRoleCreation {
+		te.skipRoleCreation()
+		return nil
+	}
+	
 	fmt.Println("Starting role creation phase...")
 	
+	if err := te.openFailedRoles(); err != nil {
+		return err
+	}
+	defer te.closeFailedRoles()
+	
+	// Create wait group for synchronization
+	var wg sync.WaitGroup
+	
+	// Start worker goroutines for role creation
+	for _, task := range te.roleTasks() {
+		// Create a separate HTTP client for this thread
+		threadClient := te.newHTTPClient()
+		
+		wg.Add(1)
+		go te.roleCreationWorker(task.ThreadID, task.TenantStart, task.TenantEnd-1, threadClient, &wg)
+	}
+	
+	// Wait for all workers to complete
+	wg.Wait()
+	
+	fmt.Println("Role creation phase completed.")
+	return nil
+}
+
+// skipRoleCreation leaves the roles to whoever manages them outside the test and lets pipelined
+// user creation start in every tenant right away
+func (te *TestExecutor) skipRoleCreation() {
+	fmt.Println("Skipping role creation phase, the roles of the users must already exist")
+	
+	tenantStart := te.config.Execution.TenantStartNumber
+	for tenantIndex := tenantStart; tenantIndex < tenantStart+te.config.Execution.NoOfTenants; tenantIndex++ {
+		te.roleGate.Open(tenantIndex)
+	}
+}
+
+// roleTasks divides the tenants over the threads for role creation, leaving out threads without tenants
+func (te *TestExecutor) roleTasks() []WorkerTask {
 	totalTenants := te.config.Execution.NoOfTenants
 	threads := te.config.Execution.NoOfThreads
 	
@@ -16,35 +65,22 @@ func (te *TestExecutor) ExecuteRoleCreation() error {
 	tenantsPerThread := totalTenants / threads
 	remainingTenants := totalTenants % threads
 	
-	// Create wait group for synchronization
-	var wg sync.WaitGroup
+	var tasks []WorkerTask
 	tenantStart := te.config.Execution.TenantStartNumber
-	
-	// Start worker goroutines for role creation
 	for threadID := 0; threadID < threads; threadID++ {
 		threadTenants := tenantsPerThread
 		if threadID < remainingTenants {
 			threadTenants++ // Distribute remaining tenants to first few threads
 		}
 		
-		tenantEnd := tenantStart + threadTenants - 1
-		
 		if threadTenants > 0 {
-			// Create a separate HTTP client for this thread
-			threadClient := NewHTTPClient(te.config)
-			
-			wg.Add(1)
-			go te.roleCreationWorker(threadID, tenantStart, tenantEnd, threadClient, &wg)
+			tasks = append(tasks, WorkerTask{ThreadID: threadID, TenantStart: tenantStart, TenantEnd: tenantStart + threadTenants})
 		}
 		
-		tenantStart = tenantEnd + 1
+		tenantStart += threadTenants
 	}
 	
-	// Wait for all workers to complete
-	wg.Wait()
-	
-	fmt.Println("Role creation phase completed.")
-	return nil
+	return tasks
 }
 
 // roleCreationWorker creates roles for a specific range of tenants
@@ -56,16 +92,55 @@ func (te *TestExecutor) roleCreationWorker(threadID, tenantStart, tenantEnd int,
 	for tenantIndex := tenantStart; tenantIndex <= tenantEnd; tenantIndex++ {
 		fmt.Printf("Thread %d: Creating role for tenant %d...\n", threadID, tenantIndex)
 		
-		err := client.CreateRole(tenantIndex)
-		te.stats.IncrementRole(err == nil)
+		err := client.CreateRole(te.ctx, tenantIndex)
+		te.recordRoleResult(tenantIndex, te.config.GetTenantRoleName(tenantIndex), err)
 		
 		if err != nil {
-			fmt.Printf("Thread %d: Failed to create role for tenant %d: %v\n", threadID, tenantIndex, err)
-			// Continue with other tenants even if one fails
+			printFailure("Thread %d: Failed to create role for tenant %d: %v\n", threadID, tenantIndex, err)
+			// Continue with other tenants even if one fails, without sending their users
+			if errors.Is(err, ErrRoleMissing) {
+				te.setRoleMissing(tenantIndex, err)
+			}
 		} else {
 			// fmt.Printf("Thread %d: Role created successfully for tenant %d\n", threadID, tenantIndex)
 		}
+		
+		// The defined roles and those of the variant role sets must exist before users get them
+		for _, roleName := range te.config.extraRoleNames() {
+			_, err := client.EnsureNamedRole(te.ctx, tenantIndex, roleName)
+			te.recordRoleResult(tenantIndex, roleName, err)
+			if err != nil {
+				printFailure("Thread %d: Failed to create role '%s' for tenant %d: %v\n", threadID, roleName, tenantIndex, err)
+			}
+		}
+		
+		// Let pipelined user creation start in this tenant, also when the roles could not be created
+		te.roleGate.Open(tenantIndex)
 	}
 	
 	fmt.Printf("Thread %d: Completed role creation for tenants %d-%d\n", threadID, tenantStart, tenantEnd)
+}
+
+// setRoleMissing records that the test role of a tenant could not be created
+func (te *TestExecutor) setRoleMissing(tenantIndex int, err error) {
+	te.missingRolesMutex.Lock()
+	defer te.missingRolesMutex.Unlock()
+	
+	if te.missingRoles == nil {
+		te.missingRoles = make(map[int]error)
+	}
+	te.missingRoles[tenantIndex] = err
+}
+
+// roleMissing returns why the test role of a tenant is missing, or nil if it exists or its state
+// is unknown. The users of a tenant without the role would all be rejected, so they fail without
+// being sent and are left in the failed users CSV for a retry once the role exists.
+func (te *TestExecutor) roleMissing(tenantIndex int) error {
+	te.missingRolesMutex.Lock()
+	defer te.missingRolesMutex.Unlock()
+	
+	if err := te.missingRoles[tenantIndex]; err != nil {
+		return fmt.Errorf("role '%s' of tenant %d could not be created: %v", te.config.GetTenantRoleName(tenantIndex), tenantIndex, err)
+	}
+	return nil
 }
\ No newline at end of file
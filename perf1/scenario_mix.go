@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// scenarioStep is one scenario a virtual user can run in an iteration. vars holds the variables
+// bound from the step's data set row, if the step has a data set, and the expanded attribute templates.
+type scenarioStep func(ctx context.Context, client *HTTPClient, user *VirtualUser, vars map[string]string) error
+
+// Variables that select the identity a data-driven step runs as rather than being passed to the step
+const (
+	varTenant   = "tenant"
+	varUsername = "username"
+	varPassword = "password"
+	// varNewPassword is the password the password-change step sets
+	varNewPassword = "newPassword"
+)
+
+// mixScenarios holds the scenarios available to the weighted mix, by name
+var mixScenarios = map[string]scenarioStep{
+	"login": func(ctx context.Context, client *HTTPClient, user *VirtualUser, vars map[string]string) error {
+		return user.Login(ctx, client)
+	},
+	"profile-update": func(ctx context.Context, client *HTTPClient, user *VirtualUser, vars map[string]string) error {
+		scimID, err := user.ResolveScimID(ctx, client)
+		if err != nil {
+			return err
+		}
+
+		// Bound variables other than the identity are the attributes to update
+		attributes := make(map[string]interface{})
+		for variable, value := range vars {
+			switch variable {
+			case varTenant, varUsername, varPassword, varNewPassword:
+			default:
+				attributes[variable] = value
+			}
+		}
+		if len(attributes) == 0 {
+			attributes = updateAttributes()
+		}
+
+		_, err = client.UpdateUser(ctx, user.TenantIndex, scimID, "", attributes)
+		return err
+	},
+	"password-change": func(ctx context.Context, client *HTTPClient, user *VirtualUser, vars map[string]string) error {
+		scimID, err := user.ResolveScimID(ctx, client)
+		if err != nil {
+			return err
+		}
+
+		// Without a bound new password the password is set to its current value
+		password := user.Password
+		if vars[varNewPassword] != "" {
+			password = vars[varNewPassword]
+		}
+
+		if _, err := client.UpdateUser(ctx, user.TenantIndex, scimID, "", map[string]interface{}{"password": password}); err != nil {
+			return err
+		}
+
+		// Later logins of the same user use the new password
+		user.Password = password
+		return nil
+	},
+}
+
+// weightedScenario is a scenario with the upper bound of its share of the cumulative weight
+type weightedScenario struct {
+	name  string
+	bound int
+}
+
+// scenarioPicker picks scenarios at random in proportion to their weights
+type scenarioPicker struct {
+	scenarios []weightedScenario
+	total     int
+}
+
+// newScenarioPicker validates the weights and builds a picker for them
+func newScenarioPicker(weights map[string]int) (*scenarioPicker, error) {
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	picker := &scenarioPicker{}
+	for _, name := range names {
+		if _, ok := mixScenarios[name]; !ok {
+			return nil, fmt.Errorf("unknown scenario %q", name)
+		}
+		if weights[name] < 0 {
+			return nil, fmt.Errorf("weight of scenario %q must not be negative", name)
+		}
+		if weights[name] == 0 {
+			continue
+		}
+
+		picker.total += weights[name]
+		picker.scenarios = append(picker.scenarios, weightedScenario{name, picker.total})
+	}
+
+	if picker.total == 0 {
+		return nil, fmt.Errorf("no scenario has a positive weight")
+	}
+
+	return picker, nil
+}
+
+// pick returns the name of a randomly chosen scenario
+func (p *scenarioPicker) pick(random *rand.Rand) string {
+	n := random.Intn(p.total)
+	for _, scenario := range p.scenarios {
+		if n < scenario.bound {
+			return scenario.name
+		}
+	}
+	return p.scenarios[len(p.scenarios)-1].name
+}
+
+// mixStats holds the outcome of each scenario in the mix
+type mixStats struct {
+	latencies map[string][]time.Duration
+	failed    map[string]int
+	mutex     sync.Mutex
+}
+
+// ExecuteScenarioMix runs a steady-state soak in which every iteration of a virtual user picks
+// a scenario at random by weight, approximating a production traffic mix
+func (te *TestExecutor) ExecuteScenarioMix() error {
+	cfg := te.config.Mix
+	picker, err := newScenarioPicker(cfg.Weights)
+	if err != nil {
+		return fmt.Errorf("invalid scenario weights: %v", err)
+	}
+
+	dataSets := make(map[string]*csvDataSet)
+	for name, dataSetConfig := range cfg.DataSets {
+		if _, ok := mixScenarios[name]; !ok {
+			return fmt.Errorf("data set bound to unknown scenario %q", name)
+		}
+
+		dataSet, err := loadCSVDataSet(dataSetConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load data set of scenario %s: %v", name, err)
+		}
+		dataSets[name] = dataSet
+	}
+
+	duration := time.Duration(cfg.Duration) * time.Second
+
+	fmt.Println("Starting weighted scenario mix...")
+	fmt.Printf("- Threads: %d\n", te.config.Execution.NoOfThreads)
+	fmt.Printf("- Duration: %v\n", duration)
+	for _, scenario := range picker.scenarios {
+		fmt.Printf("- %s: %.1f%%\n", scenario.name, float64(cfg.Weights[scenario.name])/float64(picker.total)*100)
+		if dataSet := dataSets[scenario.name]; dataSet != nil {
+			fmt.Printf("  Data: %s (%d rows, share %s, recycle %v)\n", dataSet.file, len(dataSet.rows), dataSet.share, dataSet.recycle)
+		}
+	}
+
+	stats := &mixStats{
+		latencies: make(map[string][]time.Duration),
+		failed:    make(map[string]int),
+	}
+	deadline := time.Now().Add(duration)
+
+	// Calculate users per thread
+	usersPerThread := te.config.Execution.NoOfUsers / te.config.Execution.NoOfThreads
+	remainingUsers := te.config.Execution.NoOfUsers % te.config.Execution.NoOfThreads
+
+	var wg sync.WaitGroup
+	userStart := te.config.Execution.UserStartNumber
+
+	startTime := time.Now()
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		threadUsers := usersPerThread
+		if threadID < remainingUsers {
+			threadUsers++ // Distribute remaining users to first few threads
+		}
+
+		if threadUsers == 0 {
+			continue
+		}
+
+		userEnd := userStart + threadUsers - 1
+
+		task := WorkerTask{
+			UserStart:  userStart,
+			UserEnd:    userEnd,
+			ThreadID:   threadID,
+			Client:     te.newHTTPClient(),
+			StartDelay: te.rampUpStartDelay(threadID, te.config.Execution.NoOfThreads),
+		}
+
+		wg.Add(1)
+		go te.mixWorker(task, picker, dataSets, deadline, stats, &wg)
+
+		userStart = userEnd + 1
+	}
+
+	wg.Wait()
+
+	elapsed := time.Since(startTime)
+	if te.interrupted() {
+		fmt.Printf("\nScenario mix interrupted after %v\n", elapsed)
+		stats.print(elapsed)
+		return ErrInterrupted
+	}
+	fmt.Printf("\nScenario mix completed in %v\n", elapsed)
+
+	stats.print(elapsed)
+
+	return nil
+}
+
+// mixWorker cycles through the virtual users of its user range in all tenants until the deadline,
+// running a randomly picked scenario as each one. Virtual users persist across iterations, so a
+// user keeps its session and tokens from one scenario to the next. A scenario with a data set takes
+// the next row of it on every run; the worker stops once a data set that does not recycle runs out.
+func (te *TestExecutor) mixWorker(task WorkerTask, picker *scenarioPicker, dataSets map[string]*csvDataSet, deadline time.Time, stats *mixStats, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	waitForRampUp(te.ctx, task.StartDelay)
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano() + int64(task.ThreadID)))
+
+	var users []*VirtualUser
+	tenantStart := te.config.Execution.TenantStartNumber
+	for userIndex := task.UserStart; userIndex <= task.UserEnd; userIndex++ {
+		for tenantIndex := tenantStart; tenantIndex < tenantStart+te.config.Execution.NoOfTenants; tenantIndex++ {
+			users = append(users, te.newVirtualUser(tenantIndex, userIndex))
+		}
+	}
+
+	if len(users) == 0 {
+		return
+	}
+
+	cursors := make(map[string]*dataCursor)
+	for name, dataSet := range dataSets {
+		cursors[name] = dataSet.cursor()
+	}
+
+	// Users named by data set rows, kept so they too persist across iterations
+	boundUsers := make(map[string]*VirtualUser)
+
+	for iteration := 0; time.Now().Before(deadline) && !te.interrupted(); iteration++ {
+		user := users[iteration%len(users)]
+		name := picker.pick(random)
+
+		var row map[string]string
+		if cursor := cursors[name]; cursor != nil {
+			var ok bool
+			if row, ok = cursor.nextRow(); !ok {
+				fmt.Printf("Thread %d: Data set of scenario %s is used up, stopping\n", task.ThreadID, name)
+				return
+			}
+		}
+
+		vars, err := te.stepVariables(row)
+		if err == nil && row != nil {
+			user, err = te.boundVirtualUser(boundUsers, user, vars)
+		}
+		if err != nil {
+			stats.record(name, 0, err)
+			fmt.Printf("Thread %d: Invalid variables for scenario %s: %v\n", task.ThreadID, name, err)
+			continue
+		}
+
+		requestStart := time.Now()
+		err = mixScenarios[name](te.ctx, task.Client, user, vars)
+		stats.record(name, time.Since(requestStart), err)
+
+		if err != nil {
+			printFailure("Thread %d: Scenario %s failed for user %s in tenant %d: %v\n",
+				task.ThreadID, name, user.Username, user.TenantIndex, err)
+		}
+	}
+}
+
+// stepVariables expands the attribute templates and the values of a data set row against the global
+// variable store. Row values take precedence over attribute templates of the same name.
+func (te *TestExecutor) stepVariables(row map[string]string) (map[string]string, error) {
+	vars := make(map[string]string, len(row)+len(te.config.Mix.Attributes))
+	for name, text := range te.config.Mix.Attributes {
+		value, err := te.vars.Expand(text)
+		if err != nil {
+			return nil, err
+		}
+		vars[name] = value
+	}
+	for name, text := range row {
+		value, err := te.vars.Expand(text)
+		if err != nil {
+			return nil, err
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// boundVirtualUser returns the virtual user a data set row names with its tenant, username and
+// password variables. Variables the row does not bind are taken from the worker's current user.
+func (te *TestExecutor) boundVirtualUser(boundUsers map[string]*VirtualUser, current *VirtualUser, row map[string]string) (*VirtualUser, error) {
+	tenantIndex := current.TenantIndex
+	if value, ok := row[varTenant]; ok {
+		index, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenant %q: %v", value, err)
+		}
+		tenantIndex = index
+	}
+
+	username := current.Username
+	if value, ok := row[varUsername]; ok {
+		username = value
+	}
+
+	user := current
+	if tenantIndex != current.TenantIndex || username != current.Username {
+		key := fmt.Sprintf("%d/%s", tenantIndex, username)
+		user = boundUsers[key]
+		if user == nil {
+			user = &VirtualUser{
+				TenantIndex: tenantIndex,
+				Username:    username,
+				Password:    te.userPassword(tenantIndex, username),
+				State:       make(map[string]string),
+			}
+			boundUsers[key] = user
+		}
+	}
+
+	if value, ok := row[varPassword]; ok {
+		user.Password = value
+	}
+
+	return user, nil
+}
+
+// record records the outcome of one scenario run
+func (ms *mixStats) record(name string, duration time.Duration, err error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if err != nil {
+		ms.failed[name]++
+		return
+	}
+	ms.latencies[name] = append(ms.latencies[name], duration)
+}
+
+// print prints the outcome of every scenario in the mix
+func (ms *mixStats) print(elapsed time.Duration) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	names := make([]string, 0, len(ms.latencies)+len(ms.failed))
+	seen := make(map[string]bool)
+	for name := range ms.latencies {
+		names, seen[name] = append(names, name), true
+	}
+	for name := range ms.failed {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	fmt.Println("\n=== Scenario Mix Statistics ===")
+	total := 0
+	for _, name := range names {
+		succeeded := len(ms.latencies[name])
+		total += succeeded + ms.failed[name]
+		fmt.Printf("%s - Success: %d, Failed: %d, Rate: %.2f/s, %s\n",
+			name, succeeded, ms.failed[name], float64(succeeded)/elapsed.Seconds(), SummarizeLatencies(ms.latencies[name]))
+	}
+	fmt.Printf("Total Iterations: %d, Rate: %.2f/s\n", total, float64(total)/elapsed.Seconds())
+	fmt.Println("===============================")
+}
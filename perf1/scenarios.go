@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// loadScenario is a named preset of a load test: the mode it runs and the configuration it sets on
+// top of the configuration file, so a new test can start from a known workload
+type loadScenario struct {
+	Name        string
+	Description string
+	Mode        ExecutionMode
+	// Settings is a partial configuration in the config file's JSON format
+	Settings string
+}
+
+// loadScenarios holds the built-in scenario presets by name
+var loadScenarios = map[string]loadScenario{
+	"user-onboarding": {
+		Name:        "user-onboarding",
+		Description: "Provision users into the tenants at a steady ramp and log a fifth of them in right after",
+		Mode:        ModeCreate,
+		Settings: `{
+			"execution": {"noOfThreads": 20, "noOfUsers": 5000, "rampUpPeriod": 60, "probeTenants": true},
+			"login": {"percent": 20, "method": "password"}
+		}`,
+	},
+	"login-storm": {
+		Name:        "login-storm",
+		Description: "Log every user created by a previous run in at once from many threads, as after an outage",
+		Mode:        ModeLogin,
+		Settings: `{
+			"execution": {"noOfThreads": 100, "rampUpPeriod": 5},
+			"login": {"percent": 100, "method": "password"}
+		}`,
+	},
+	"read-heavy": {
+		Name:        "read-heavy",
+		Description: "Read the users created by a previous run repeatedly, revalidating with ETags after the first pass",
+		Mode:        ModeRead,
+		Settings: `{
+			"execution": {"noOfThreads": 50, "rampUpPeriod": 10},
+			"read": {"passes": 5, "conditionalRequests": true, "cacheBusting": false}
+		}`,
+	},
+	"churn": {
+		Name:        "churn",
+		Description: "Create and immediately delete users for ten minutes to exercise ID allocation and deletes",
+		Mode:        ModeChurn,
+		Settings: `{
+			"execution": {"noOfThreads": 20, "rampUpPeriod": 10},
+			"churn": {"duration": 600, "cyclesPerSecond": 5}
+		}`,
+	},
+}
+
+// findScenario returns the scenario preset with the given name
+func findScenario(name string) (*loadScenario, error) {
+	scenario, ok := loadScenarios[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scenario %q, available scenarios: %s", name, strings.Join(scenarioNames(), ", "))
+	}
+	return &scenario, nil
+}
+
+// scenarioNames returns the names of the scenario presets in alphabetical order
+func scenarioNames() []string {
+	names := make([]string, 0, len(loadScenarios))
+	for name := range loadScenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply sets the scenario's settings on the configuration, leaving the other values as they are
+func (s *loadScenario) Apply(config *Config) error {
+	decoder := json.NewDecoder(strings.NewReader(s.Settings))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return fmt.Errorf("failed to apply scenario %s: %v", s.Name, err)
+	}
+	return nil
+}
+
+// RunScenariosCommand runs the scenarios command: "list" prints the scenario presets and
+// "describe <name>" prints the mode and settings of one of them
+func RunScenariosCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: scenarios list | scenarios describe <name>")
+	}
+
+	switch args[0] {
+	case "list":
+		fmt.Println("Available scenarios:")
+		for _, name := range scenarioNames() {
+			fmt.Printf("  %-16s %s\n", name, loadScenarios[name].Description)
+		}
+		fmt.Println("\nRun one with -scenario <name>, or see its settings with: scenarios describe <name>")
+		return nil
+	case "describe":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: scenarios describe <name>")
+		}
+		scenario, err := findScenario(args[1])
+		if err != nil {
+			return err
+		}
+		return scenario.describe()
+	default:
+		return fmt.Errorf("unknown scenarios command %q, expected list or describe", args[0])
+	}
+}
+
+// describe prints the scenario's description, mode and settings
+func (s *loadScenario) describe() error {
+	var settings bytes.Buffer
+	if err := json.Indent(&settings, []byte(s.Settings), "", "  "); err != nil {
+		return fmt.Errorf("failed to format scenario settings: %v", err)
+	}
+
+	fmt.Printf("Scenario: %s\n", s.Name)
+	fmt.Printf("Description: %s\n", s.Description)
+	fmt.Printf("Mode: %s\n", s.Mode)
+	fmt.Println("Settings (applied over the configuration file):")
+	fmt.Println(settings.String())
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// slipWarning is how far behind the schedule of the target rate paced requests may start
+	// before the client is reported as unable to offer the target load
+	slipWarning = 100 * time.Millisecond
+	// achievedWarning is the share of the target rate paced requests must start at
+	achievedWarning = 0.95
+)
+
+// scheduleSlip is how far the requests paced to the target rate started behind its schedule. The
+// achieved rate alone hides a slow client: requests that fall behind are sent in a burst once the
+// workers are free again, so the gap shows in when they started, not only in how many were sent.
+type scheduleSlip struct {
+	rate  float64
+	slips []time.Duration
+
+	// The achieved rate is measured over each schedule from its first to its last request, so
+	// pauses between schedules, such as retry rounds, do not lower it
+	schedule  time.Time // start of the current schedule
+	first     time.Time // start of the first request of the current schedule
+	last      time.Time // start of the last request of the current schedule
+	requests  int       // requests of the current schedule
+	intervals int       // intervals between the requests of the earlier schedules
+	duration  time.Duration
+}
+
+// RecordScheduleSlip records the start of a request paced to the target rate against the time it
+// was due on the schedule of that rate
+func (ts *TestStats) RecordScheduleSlip(slot scheduleSlot, start time.Time) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if ts.scheduleSlip == nil {
+		ts.scheduleSlip = &scheduleSlip{rate: slot.rate}
+	}
+	ss := ts.scheduleSlip
+
+	if !slot.start.Equal(ss.schedule) {
+		if ss.requests > 0 {
+			ss.intervals += ss.requests - 1
+			ss.duration += ss.last.Sub(ss.first)
+		}
+		ss.schedule, ss.first, ss.last, ss.requests = slot.start, start, start, 0
+	}
+	ss.requests++
+	if start.Before(ss.first) {
+		ss.first = start
+	}
+	if start.After(ss.last) {
+		ss.last = start
+	}
+
+	// The burst lets requests start ahead of the schedule, which is no slip
+	slip := start.Sub(slot.due)
+	if slip < 0 {
+		slip = 0
+	}
+	ss.slips = append(ss.slips, slip)
+}
+
+// achievedRate returns the rate the paced requests started at over the time their schedules ran
+func (ss *scheduleSlip) achievedRate() float64 {
+	intervals := ss.intervals + ss.requests - 1
+	duration := ss.duration + ss.last.Sub(ss.first)
+	if intervals < 1 || duration <= 0 {
+		return ss.rate
+	}
+	return float64(intervals) / duration.Seconds()
+}
+
+// print prints the offered against the achieved load and warns when the paced requests started
+// behind their schedule or below the target rate, as the client then did not offer the load the
+// results are meant to show. Called with the lock held.
+func (ss *scheduleSlip) print() {
+	if ss == nil {
+		return
+	}
+
+	summary := ss.summary()
+	summary.print()
+
+	p99 := summary.Slip.summary().P99
+	if p99 > slipWarning || summary.AchievedRate < summary.TargetRate*achievedWarning {
+		printWarning("Paced requests started %v behind the schedule of the target rate at P99, at %.2f/s of %.2f/s; "+
+			"the client could not offer the target load, so raise the concurrency or add client machines\n",
+			p99.Round(time.Millisecond), summary.AchievedRate, summary.TargetRate)
+	}
+}
+
+// SummaryScheduleSlip holds how far the requests paced to the target rate started behind its
+// schedule
+type SummaryScheduleSlip struct {
+	TargetRate   float64        `json:"targetRate"`
+	AchievedRate float64        `json:"achievedRate"` // rate the paced requests started at
+	Slip         SummaryLatency `json:"slip"`
+	FinalSlipMs  float64        `json:"finalSlipMs"` // how far the last paced request started behind
+}
+
+// summary returns the schedule slip for the run summary. Called with the lock held.
+func (ss *scheduleSlip) summary() *SummaryScheduleSlip {
+	if ss == nil {
+		return nil
+	}
+	return &SummaryScheduleSlip{
+		TargetRate:   ss.rate,
+		AchievedRate: ss.achievedRate(),
+		Slip:         newSummaryLatency(SummarizeLatencies(ss.slips)),
+		FinalSlipMs:  float64(ss.slips[len(ss.slips)-1]) / float64(time.Millisecond),
+	}
+}
+
+// print prints the schedule slip of a run summary
+func (s *SummaryScheduleSlip) print() {
+	final := time.Duration(s.FinalSlipMs * float64(time.Millisecond))
+	fmt.Printf("Schedule Slip (%d requests paced to %.2f/s, started at %.2f/s, last %v behind) - %s\n",
+		s.Slip.Count, s.TargetRate, s.AchievedRate, final.Round(time.Millisecond), s.Slip.summary())
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// configSchemaDialect is the JSON Schema version of the generated schema, the one editors support best
+	configSchemaDialect = "http://json-schema.org/draft-07/schema#"
+	// defaultSchemaPath is where generate-schema writes the schema without a path argument
+	defaultSchemaPath = "config.schema.json"
+)
+
+// jsonSchema is the subset of JSON Schema needed to describe the configuration file
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"` // false, or the schema of map values
+}
+
+// configSchema describes the configuration file: every field with its type and default, and the
+// usage of its flag as the description. Unknown fields are not allowed, so editors flag typos
+// that would otherwise leave a setting at its default.
+func configSchema() *jsonSchema {
+	defaults := DefaultConfig()
+	schema := fieldSchema(reflect.ValueOf(defaults).Elem(), configFlagUsages(defaults))
+	schema.Schema = configSchemaDialect
+	schema.Title = "go-perf configuration"
+
+	// Lets a configuration file name its schema, e.g. "$schema": "./config.schema.json"
+	schema.Properties["$schema"] = &jsonSchema{Type: "string", Description: "Path or URL of this schema"}
+	return schema
+}
+
+// fieldSchema describes a configuration value, taking the descriptions of the fields of sections
+// from the flag usages by field address
+func fieldSchema(value reflect.Value, usages map[uintptr]string) *jsonSchema {
+	switch value.Kind() {
+	case reflect.Struct:
+		schema := &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema), AdditionalProperties: false}
+		for i := 0; i < value.NumField(); i++ {
+			name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+
+			field := value.Field(i)
+			property := fieldSchema(field, usages)
+			// A section shares its address with its first field, so only values are described
+			if field.Kind() != reflect.Struct {
+				property.Description = usages[field.Addr().Pointer()]
+				if (field.Kind() != reflect.Map && field.Kind() != reflect.Slice) || !field.IsNil() {
+					property.Default = field.Interface()
+				}
+			}
+			schema.Properties[name] = property
+		}
+		return schema
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: fieldSchema(reflect.New(value.Type().Elem()).Elem(), usages)}
+	case reflect.Slice:
+		return &jsonSchema{Type: "array", Items: fieldSchema(reflect.New(value.Type().Elem()).Elem(), usages)}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	}
+	return &jsonSchema{}
+}
+
+// configFlagUsages maps the address of every configuration field that has a flag to the usage of
+// the flag
+func configFlagUsages(config *Config) map[uintptr]string {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	bindConfigFlags(fs, config)
+
+	usages := make(map[uintptr]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		// Flag values point at their field, directly or through the list and weights flags
+		value := reflect.ValueOf(f.Value)
+		if value.Kind() == reflect.Struct && value.NumField() > 0 {
+			value = value.Field(0)
+		}
+		if value.Kind() == reflect.Ptr {
+			usages[value.Pointer()] = f.Usage
+		}
+	})
+	return usages
+}
+
+// unknownConfigFields returns the JSON paths of the fields of a configuration file that the schema
+// does not know, which are ignored when the file is loaded
+func unknownConfigFields(data []byte) []string {
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil
+	}
+
+	var unknown []string
+	collectUnknownFields(configSchema(), document, "", &unknown)
+	sort.Strings(unknown)
+	return unknown
+}
+
+// collectUnknownFields adds the paths of the fields of a document the schema does not allow
+func collectUnknownFields(schema *jsonSchema, document interface{}, path string, unknown *[]string) {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		for name, field := range value {
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+
+			if property, ok := schema.Properties[name]; ok {
+				collectUnknownFields(property, field, fieldPath, unknown)
+			} else if additional, ok := schema.AdditionalProperties.(*jsonSchema); ok {
+				collectUnknownFields(additional, field, fieldPath, unknown)
+			} else if schema.Type == "object" {
+				*unknown = append(*unknown, fieldPath)
+			}
+		}
+	case []interface{}:
+		if schema.Items != nil {
+			for i, item := range value {
+				collectUnknownFields(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), unknown)
+			}
+		}
+	}
+}
+
+// runGenerateSchemaCommand writes the JSON Schema of the configuration file
+func runGenerateSchemaCommand(_ *cliCommand, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: go-perf generate-schema [file]")
+	}
+
+	path := defaultSchemaPath
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	data, err := json.MarshalIndent(configSchema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write schema: %v", err)
+	}
+
+	fmt.Printf("Configuration schema saved to: %s\n", path)
+	fmt.Println("Point the \"$schema\" field of a configuration file at it for validation and completion in editors")
+	return nil
+}
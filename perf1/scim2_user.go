@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// SCIM2 schema URNs of a user payload
+const (
+	scim2UserSchema = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scim2WSO2Schema = "urn:scim:wso2:schema"
+)
+
+// SCIM2User represents a SCIM2 user payload, the counterpart of the SCIM 1.1 SCIMUser payload for
+// the /scim2 endpoints. The WSO2 attributes travel in the extension of the WSO2 schema URN instead
+// of the wso2Extension object.
+type SCIM2User struct {
+	Schemas   []string    `json:"schemas"`
+	UserName  string      `json:"userName"`
+	Password  string      `json:"password"`
+	Name      SCIMName    `json:"name"`
+	Emails    []SCIMEmail `json:"emails"`
+	Roles     []SCIMRole  `json:"roles,omitempty"`
+	Extension SCIMWso2Ext `json:"urn:scim:wso2:schema"`
+
+	// Locale, Timezone and PreferredLanguage are only sent when configured
+	Locale            string `json:"locale,omitempty"`
+	Timezone          string `json:"timezone,omitempty"`
+	PreferredLanguage string `json:"preferredLanguage,omitempty"`
+}
+
+// scim2 returns the SCIM2 payload of the user, with the same attributes
+func (u SCIMUser) scim2() SCIM2User {
+	return SCIM2User{
+		Schemas:           []string{scim2UserSchema, scim2WSO2Schema},
+		UserName:          u.UserName,
+		Password:          u.Password,
+		Name:              u.Name,
+		Emails:            u.Emails,
+		Roles:             u.Roles,
+		Extension:         u.Wso2Extension,
+		Locale:            u.Locale,
+		Timezone:          u.Timezone,
+		PreferredLanguage: u.PreferredLanguage,
+	}
+}
+
+// userPayload returns the payload of the user for the user endpoints: the SCIM2 payload when the
+// SCIM base path is a SCIM2 one, such as /scim2 or /t/{tenant}/scim2, and the SCIM 1.1 payload
+// for the legacy /wso2/scim endpoint
+func (c *Config) userPayload(user SCIMUser) interface{} {
+	if isSCIM2Path(c.Server.ScimBasePath) {
+		return user.scim2()
+	}
+	return user
+}
+
+// isSCIM2Path reports whether a SCIM base path serves SCIM2, i.e. its last segment is scim2
+func isSCIM2Path(basePath string) bool {
+	return path.Base(strings.TrimRight(basePath, "/")) == "scim2"
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+)
+
+// scimIDPattern matches a well-formed UUID, the format Identity Server generates SCIM IDs in
+var scimIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+const (
+	// scimIDFalsePositiveRate is the duplicate false positive rate the bloom filter is sized for
+	scimIDFalsePositiveRate = 0.001
+	// scimIDSamples is the number of anomalous IDs kept as examples for the summary
+	scimIDSamples = 5
+)
+
+// scimIDCheck validates the SCIM IDs returned for created users: that each is a well-formed UUID,
+// and that no ID is handed out twice during the run. Duplicates are detected with a bloom filter,
+// so memory stays small for millions of users at the cost of rare false positives. When more IDs
+// arrive than expected, e.g. while retrying a failed users file of unknown length, a filter twice
+// the size of the last one with half its false positive rate is added, which keeps the overall
+// rate below the target however many filters are added.
+type scimIDCheck struct {
+	filters []*bloomFilter
+
+	Checked    int `json:"checked"`
+	Malformed  int `json:"malformed"`
+	Duplicates int `json:"duplicates"`
+
+	malformedSamples []string
+	duplicateSamples []string
+}
+
+// bloomFilter is a bloom filter holding up to its capacity of IDs at its false positive rate
+type bloomFilter struct {
+	bits     []uint64
+	hashes   int
+	capacity int
+	rate     float64
+	count    int
+}
+
+// newSCIMIDCheck creates a check with a bloom filter sized for the expected number of IDs
+func newSCIMIDCheck(expected int) *scimIDCheck {
+	if expected < 1000 {
+		expected = 1000
+	}
+	// The rates of the filters halve, so their sum stays below twice the first one
+	return &scimIDCheck{filters: []*bloomFilter{newBloomFilter(expected, scimIDFalsePositiveRate/2)}}
+}
+
+// newBloomFilter creates a bloom filter with the optimal size and hash count for the capacity and
+// false positive rate
+func newBloomFilter(capacity int, rate float64) *bloomFilter {
+	bits := int(math.Ceil(-float64(capacity) * math.Log(rate) / (math.Ln2 * math.Ln2)))
+	hashes := int(math.Round(float64(bits) / float64(capacity) * math.Ln2))
+
+	return &bloomFilter{
+		bits:     make([]uint64, (bits+63)/64),
+		hashes:   hashes,
+		capacity: capacity,
+		rate:     rate,
+	}
+}
+
+// Record validates a returned SCIM ID and adds it to the IDs seen
+func (c *scimIDCheck) Record(id string) {
+	c.Checked++
+
+	if !scimIDPattern.MatchString(id) {
+		c.Malformed++
+		if len(c.malformedSamples) < scimIDSamples {
+			c.malformedSamples = append(c.malformedSamples, id)
+		}
+	}
+
+	if c.add(id) {
+		c.Duplicates++
+		if len(c.duplicateSamples) < scimIDSamples {
+			c.duplicateSamples = append(c.duplicateSamples, id)
+		}
+	}
+}
+
+// add reports whether the ID was probably seen before and otherwise adds it to the newest filter,
+// adding a larger filter once the newest one is full
+func (c *scimIDCheck) add(id string) bool {
+	hash := fnv.New64a()
+	hash.Write([]byte(id))
+	sum := hash.Sum64()
+
+	for _, filter := range c.filters {
+		if filter.contains(sum) {
+			return true
+		}
+	}
+
+	newest := c.filters[len(c.filters)-1]
+	if newest.count >= newest.capacity {
+		newest = newBloomFilter(newest.capacity*2, newest.rate/2)
+		c.filters = append(c.filters, newest)
+	}
+	newest.add(sum)
+	return false
+}
+
+// positions returns the bit positions of a hash, derived from two halves of the mixed hash
+// (Kirsch-Mitzenmacher). Mixing spreads the similar hashes FNV gives similar IDs over all bits.
+func (f *bloomFilter) positions(sum uint64) []uint64 {
+	sum = mix64(sum)
+	h1, h2 := sum&0xffffffff, sum>>32|1
+	size := uint64(len(f.bits) * 64)
+
+	positions := make([]uint64, f.hashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % size
+	}
+	return positions
+}
+
+// mix64 is the splitmix64 finalizer
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// contains reports whether all bits of the hash are set
+func (f *bloomFilter) contains(sum uint64) bool {
+	for _, bit := range f.positions(sum) {
+		if f.bits[bit/64]&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// add sets the bits of the hash
+func (f *bloomFilter) add(sum uint64) {
+	for _, bit := range f.positions(sum) {
+		f.bits[bit/64] |= uint64(1) << (bit % 64)
+	}
+	f.count++
+}
+
+// print prints the validation summary, with examples of anomalous IDs
+func (c *scimIDCheck) print() {
+	if c == nil || c.Checked == 0 {
+		return
+	}
+
+	line := fmt.Sprintf("SCIM IDs - Checked: %d, Malformed: %d, Duplicates: %d\n", c.Checked, c.Malformed, c.Duplicates)
+	if c.Malformed == 0 && c.Duplicates == 0 {
+		fmt.Print(line)
+		return
+	}
+
+	printWarning("%s", line)
+	if len(c.malformedSamples) > 0 {
+		printWarning("  Malformed (not UUIDs): %v\n", c.malformedSamples)
+	}
+	if len(c.duplicateSamples) > 0 {
+		printWarning("  Returned more than once (%.1f%% chance of a false positive per ID): %v\n", scimIDFalsePositiveRate*100, c.duplicateSamples)
+	}
+}
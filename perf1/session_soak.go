@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ExecuteSessionSoak logs users in through the authorization code flow without ever logging them
+// out, reporting login latency per checkpoint of the active session count. Once all sessions are
+// built up it holds them idle for the configured period and measures login latency again.
+func (te *TestExecutor) ExecuteSessionSoak() error {
+	cfg := te.config.SessionSoak
+	if cfg.CheckpointInterval < 1 {
+		return fmt.Errorf("session soak checkpoint interval must be positive, got %d", cfg.CheckpointInterval)
+	}
+	// Logins cycle over the created users of every tenant
+	if te.config.Execution.NoOfTenants < 1 || te.config.Execution.NoOfUsers < 1 {
+		return fmt.Errorf("session soak needs at least one tenant and user to log in, got %d tenants and %d users",
+			te.config.Execution.NoOfTenants, te.config.Execution.NoOfUsers)
+	}
+
+	fmt.Println("Starting session buildup soak...")
+	fmt.Printf("- Threads: %d\n", te.config.Execution.NoOfThreads)
+	fmt.Printf("- Sessions: %d\n", cfg.Sessions)
+	fmt.Printf("- Checkpoint Interval: %d sessions\n", cfg.CheckpointInterval)
+	fmt.Printf("- Idle Hold: %ds\n", cfg.HoldDuration)
+	fmt.Printf("- Probe Logins: %d\n", cfg.ProbeLogins)
+
+	recorder := newGrowthRecorder("Login Latency vs Active Sessions", "Sessions", cfg.Sessions, cfg.CheckpointInterval)
+
+	startTime := time.Now()
+	te.runSessionLogins(cfg.Sessions, 0, func(n int, duration time.Duration, success bool) {
+		recorder.record(n, duration, success)
+	})
+	if te.interrupted() {
+		fmt.Printf("\nSession buildup interrupted after %v\n", time.Since(startTime))
+		recorder.print()
+		return ErrInterrupted
+	}
+	fmt.Printf("\nSession buildup completed in %v\n", time.Since(startTime))
+
+	recorder.print()
+
+	if cfg.HoldDuration > 0 {
+		fmt.Printf("\nHolding %d sessions idle for %ds...\n", cfg.Sessions, cfg.HoldDuration)
+
+		timer := time.NewTimer(time.Duration(cfg.HoldDuration) * time.Second)
+		select {
+		case <-timer.C:
+		case <-te.ctx.Done():
+			timer.Stop()
+			printWarning("Idle hold interrupted, skipping the probe logins\n")
+			return ErrInterrupted
+		}
+	}
+
+	if cfg.ProbeLogins > 0 {
+		var probeLatencies []time.Duration
+		probeFailed := 0
+		var mutex sync.Mutex
+
+		// Probe logins continue the user sequence so they add sessions rather than reuse them
+		te.runSessionLogins(cfg.ProbeLogins, cfg.Sessions, func(n int, duration time.Duration, success bool) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if success {
+				probeLatencies = append(probeLatencies, duration)
+			} else {
+				probeFailed++
+			}
+		})
+
+		fmt.Println("\n=== Login Latency After Idle Hold ===")
+		fmt.Printf("Probe Logins - Success: %d, Failed: %d, %s\n",
+			len(probeLatencies), probeFailed, SummarizeLatencies(probeLatencies))
+		fmt.Println("=====================================")
+	}
+
+	if te.interrupted() {
+		return ErrInterrupted
+	}
+	return nil
+}
+
+// runSessionLogins performs count logins numbered from offset, spreading them over the created users
+// and tenants, and reports each result. Every login uses a fresh browser session, so each one leaves
+// a new session behind on the server.
+func (te *TestExecutor) runSessionLogins(count, offset int, record func(n int, duration time.Duration, success bool)) {
+	sequence := make(chan int, te.config.Execution.NoOfThreads)
+	go func() {
+		defer close(sequence)
+
+		for n := 0; n < count; n++ {
+			select {
+			case sequence <- n:
+			case <-te.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	noOfTenants := te.config.Execution.NoOfTenants
+	noOfUsers := te.config.Execution.NoOfUsers
+
+	var wg sync.WaitGroup
+
+	// Each worker delays its own start to apply the ramp-up
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		wg.Add(1)
+		go func(threadID int, startDelay time.Duration) {
+			defer wg.Done()
+
+			waitForRampUp(te.ctx, startDelay)
+
+			client := te.newHTTPClient()
+			for n := range sequence {
+				if te.interrupted() {
+					continue
+				}
+
+				tenantIndex := te.config.Execution.TenantStartNumber + (offset+n)%noOfTenants
+				userIndex := te.config.Execution.UserStartNumber + (offset+n)/noOfTenants%noOfUsers
+				username := te.config.GetTestUsername(userIndex)
+
+				requestStart := time.Now()
+				_, err := client.AuthorizationCodeLogin(te.ctx, client.newBrowserSession(), tenantIndex, username, te.userPassword(tenantIndex, username))
+				duration := time.Since(requestStart)
+
+				// A login aborted by the interruption is not counted
+				if err != nil && te.interrupted() {
+					continue
+				}
+				record(n, duration, err == nil)
+
+				if err != nil {
+					printFailure("Thread %d: Failed to log in user %s in tenant %d: %v\n", threadID, username, tenantIndex, err)
+				}
+			}
+		}(threadID, te.rampUpStartDelay(threadID, te.config.Execution.NoOfThreads))
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// SOAPFaultError is returned when an admin service answers with a SOAP fault. The services send
+// faults with status 500 and at times with 200, so the body decides whether a call failed, not
+// only the status.
+type SOAPFaultError struct {
+	Operation  string
+	StatusCode int
+	Code       string // faultcode, or the Code/Value of a SOAP 1.2 fault
+	String     string // faultstring, or the Reason/Text of a SOAP 1.2 fault
+	Body       string
+}
+
+func (e *SOAPFaultError) Error() string {
+	return fmt.Sprintf("%s failed with status %d: SOAP fault %s: %s", e.Operation, e.StatusCode, e.Code, e.String)
+}
+
+// Unwrap exposes the status of the response, so the retry policy and the failure CSVs treat a
+// fault like any other error response
+func (e *SOAPFaultError) Unwrap() error {
+	return &StatusError{Operation: e.Operation, StatusCode: e.StatusCode, Body: e.Body}
+}
+
+// alreadyExists reports whether the fault rejected creating something that already exists, like
+// the RoleAlreadyExisting fault of addRole
+func (e *SOAPFaultError) alreadyExists() bool {
+	text := strings.ToLower(e.Code + " " + e.String)
+	return strings.Contains(text, "alreadyexist") || strings.Contains(text, "already exist")
+}
+
+// soapFault is the Fault element of a SOAP 1.1 or 1.2 envelope
+type soapFault struct {
+	Code      string `xml:"faultcode"`
+	String    string `xml:"faultstring"`
+	Code12    string `xml:"Code>Value"`
+	Subcode12 string `xml:"Code>Subcode>Value"`
+	Reason12  string `xml:"Reason>Text"`
+}
+
+// parseSOAPFault returns the fault of a SOAP response body, or nil if the body is not a SOAP
+// envelope with a fault
+func parseSOAPFault(operation string, statusCode int, body string) *SOAPFaultError {
+	var envelope struct {
+		Body struct {
+			Fault *soapFault `xml:"Fault"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal([]byte(body), &envelope); err != nil || envelope.Body.Fault == nil {
+		return nil
+	}
+
+	fault := envelope.Body.Fault
+	code, reason := fault.Code, fault.String
+	if code == "" {
+		code = fault.Code12
+		if fault.Subcode12 != "" {
+			code += "/" + fault.Subcode12
+		}
+	}
+	if reason == "" {
+		reason = fault.Reason12
+	}
+	return &SOAPFaultError{
+		Operation:  operation,
+		StatusCode: statusCode,
+		Code:       strings.TrimSpace(code),
+		String:     strings.Join(strings.Fields(reason), " "),
+		Body:       body,
+	}
+}
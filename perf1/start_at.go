@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// startAtRecheck bounds how long waitForStart sleeps before checking the clock again, so a wall
+// clock corrected by NTP during a long wait still starts the run on time
+const startAtRecheck = time.Minute
+
+// waitForStart blocks until the wall clock reaches the start time, so several client machines with
+// synchronized clocks start generating load at the same instant. A start time in the past starts
+// right away. It returns ErrInterrupted if the context is cancelled while waiting.
+func waitForStart(ctx context.Context, startAt time.Time) error {
+	delay := time.Until(startAt)
+	if delay <= 0 {
+		printWarning("Start time %s passed %v ago, starting now\n", startAt.Format(time.RFC3339Nano), (-delay).Round(time.Millisecond))
+		return nil
+	}
+
+	fmt.Printf("Waiting until %s to start (in %v)\n", startAt.Format(time.RFC3339Nano), delay.Round(time.Second))
+
+	for delay > 0 {
+		if delay > startAtRecheck {
+			delay = startAtRecheck
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ErrInterrupted
+		}
+
+		delay = time.Until(startAt)
+	}
+
+	fmt.Printf("Starting at %s\n", time.Now().Format(time.RFC3339Nano))
+	return nil
+}
@@ -2,7 +2,12 @@ package main
 
 import (
 	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // TestResult holds the result of a test operation
@@ -17,18 +22,82 @@ type TestResult struct {
 
 // TestStats holds statistics about test execution
 type TestStats struct {
-	TotalUsers    int
-	SuccessUsers  int
-	FailedUsers   int
-	TotalRoles    int
-	SuccessRoles  int
-	FailedRoles   int
-	mutex         sync.Mutex
+	TotalUsers          int
+	SuccessUsers        int
+	FailedUsers         int
+	TotalRoles          int
+	SuccessRoles        int
+	FailedRoles         int
+	TotalGroups         int
+	SuccessGroups       int
+	FailedGroups        int
+	TotalIdPs           int
+	SuccessIdPs         int
+	FailedIdPs          int
+	AuthChallenges      int
+	AuthChallengeTime   time.Duration
+	Retries             int
+	ReadOK              int
+	ReadNotModified     int
+	ReadFailed          int
+	ReadOKTime          time.Duration
+	ReadNotModifiedTime time.Duration
+	Operations          map[string]*OperationStats
+	Phase               string
+	PhaseOrder          []string
+	PhaseLatencies      map[string][]time.Duration
+	Errors              map[string]int
+	phaseTimes          map[string]*phaseTime  // wall-clock time and peak requests in flight per phase
+	phaseEntered        time.Time              // when the current phase was entered
+	tenantTimes         map[string]*tenantTime // span and time of the requests per tenant domain
+	inFlight            int
+	workers             []*workerTime // where the worker behind every HTTP client spent its time
+	scheduleSlip        *scheduleSlip // how far requests paced to the target rate started behind schedule
+	passwordPolicies    map[string]*policyLatencies // by password policy and operation
+	passwordModes       map[string]*policyLatencies // user creations by password mode, when compared
+	heatmap             *latencyHeatmap
+	alertWindow         []time.Duration // latencies since the alerts last took them, nil while alerts are off
+	alerting            bool
+	timeline            *requestTimeline
+	reporters           *reporterSet
+	scimIDs             *scimIDCheck
+	topErrors           int
+	mutex               sync.Mutex
+}
+
+// OperationStats holds the durations of one type of operation, as labeled by the HTTP client
+type OperationStats struct {
+	Latencies   []time.Duration
+	Failed      int
+	StatusCodes map[int]int // responses per HTTP status code, 0 for requests without a response
 }
 
 // NewTestStats creates a new TestStats instance
 func NewTestStats() *TestStats {
-	return &TestStats{}
+	return &TestStats{
+		Operations:     make(map[string]*OperationStats),
+		PhaseLatencies: make(map[string][]time.Duration),
+		Errors:         make(map[string]int),
+		phaseTimes:     make(map[string]*phaseTime),
+		tenantTimes:    make(map[string]*tenantTime),
+	}
+}
+
+// SetPhase sets the phase that subsequent operation latencies are attributed to
+func (ts *TestStats) SetPhase(phase string) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	ts.enterPhase(phase, time.Now())
+	ts.Phase = phase
+	if _, ok := ts.PhaseLatencies[phase]; !ok {
+		ts.PhaseLatencies[phase] = nil
+		ts.PhaseOrder = append(ts.PhaseOrder, phase)
+	}
+	
+	if ts.timeline != nil {
+		ts.timeline.mark(time.Now(), phase)
+	}
 }
 
 // IncrementRole increments role creation statistics
@@ -57,6 +126,167 @@ func (ts *TestStats) IncrementUser(success bool) {
 	}
 }
 
+// RecordScimID validates the SCIM ID returned for a created user, if ID checking is enabled
+func (ts *TestStats) RecordScimID(id string) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	if ts.scimIDs != nil {
+		ts.scimIDs.Record(id)
+	}
+}
+
+// IncrementGroup increments group creation statistics
+func (ts *TestStats) IncrementGroup(success bool) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	ts.TotalGroups++
+	if success {
+		ts.SuccessGroups++
+	} else {
+		ts.FailedGroups++
+	}
+}
+
+// IncrementIdP increments identity provider creation statistics
+func (ts *TestStats) IncrementIdP(success bool) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	ts.TotalIdPs++
+	if success {
+		ts.SuccessIdPs++
+	} else {
+		ts.FailedIdPs++
+	}
+}
+
+// RecordError counts a failure under its normalized error message. A nil error is ignored.
+func (ts *TestStats) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	
+	pattern := normalizeError(err.Error())
+	
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	ts.Errors[pattern]++
+}
+
+// RecordRead records the outcome of a user read, keeping 200 and 304 paths apart
+func (ts *TestStats) RecordRead(statusCode int, duration time.Duration, success bool) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	switch {
+	case !success:
+		ts.ReadFailed++
+	case statusCode == http.StatusNotModified:
+		ts.ReadNotModified++
+		ts.ReadNotModifiedTime += duration
+	default:
+		ts.ReadOK++
+		ts.ReadOKTime += duration
+	}
+}
+
+// RecordAuthChallenge records the round-trip spent on a 401 authentication challenge
+func (ts *TestStats) RecordAuthChallenge(duration time.Duration) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	ts.AuthChallenges++
+	ts.AuthChallengeTime += duration
+}
+
+// RecordRetry records a request sent again after a transient server error
+func (ts *TestStats) RecordRetry() {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	ts.Retries++
+}
+
+// RecordOperation records the duration and response status code of a labeled operation. A status
+// code of 0 stands for a request that got no response; it and 4xx/5xx responses count as failed.
+func (ts *TestStats) RecordOperation(label string, duration time.Duration, statusCode int) {
+	// Reporters are called outside the lock, so a slow backend does not hold up the statistics
+	if ts.reporters != nil {
+		ts.reporters.record(ts.CurrentPhase(), label, duration, statusCode)
+	}
+	
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	op, ok := ts.Operations[label]
+	if !ok {
+		op = &OperationStats{StatusCodes: make(map[int]int)}
+		ts.Operations[label] = op
+	}
+	
+	op.Latencies = append(op.Latencies, duration)
+	op.StatusCodes[statusCode]++
+	if statusCode == 0 || statusCode >= http.StatusBadRequest {
+		op.Failed++
+	}
+	
+	if ts.Phase != "" {
+		ts.PhaseLatencies[ts.Phase] = append(ts.PhaseLatencies[ts.Phase], duration)
+	}
+	
+	if ts.heatmap != nil {
+		ts.heatmap.record(time.Now(), duration)
+	}
+	
+	if ts.alerting {
+		ts.alertWindow = append(ts.alertWindow, duration)
+	}
+	
+	if ts.timeline != nil {
+		ts.timeline.record(time.Now(), duration, statusCode == 0 || statusCode >= http.StatusBadRequest)
+	}
+}
+
+// StartAlertWindow starts collecting the latencies of all operations for TakeAlertWindow
+func (ts *TestStats) StartAlertWindow() {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	ts.alerting = true
+}
+
+// TakeAlertWindow returns the latencies recorded since the previous call, which the caller owns,
+// and the number of requests in flight now
+func (ts *TestStats) TakeAlertWindow() ([]time.Duration, int) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	latencies := ts.alertWindow
+	ts.alertWindow = nil
+	return latencies, ts.inFlight
+}
+
+// CurrentPhase returns the phase operation latencies are currently attributed to
+func (ts *TestStats) CurrentPhase() string {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	return ts.Phase
+}
+
+// Totals returns the number of completed and failed operations recorded so far
+func (ts *TestStats) Totals() (int, int) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	
+	completed := ts.SuccessRoles + ts.SuccessUsers + ts.SuccessGroups + ts.SuccessIdPs + ts.ReadOK + ts.ReadNotModified
+	failed := ts.FailedRoles + ts.FailedUsers + ts.FailedGroups + ts.FailedIdPs + ts.ReadFailed
+	return completed, failed
+}
+
 // PrintStats prints the current statistics
 func (ts *TestStats) PrintStats() {
 	ts.mutex.Lock()
@@ -67,28 +297,136 @@ func (ts *TestStats) PrintStats() {
 		ts.TotalRoles, ts.SuccessRoles, ts.FailedRoles)
 	fmt.Printf("Users - Total: %d, Success: %d, Failed: %d\n", 
 		ts.TotalUsers, ts.SuccessUsers, ts.FailedUsers)
+	if ts.TotalGroups > 0 {
+		fmt.Printf("Groups - Total: %d, Success: %d, Failed: %d\n",
+			ts.TotalGroups, ts.SuccessGroups, ts.FailedGroups)
+	}
+	if ts.TotalIdPs > 0 {
+		fmt.Printf("IdPs - Total: %d, Success: %d, Failed: %d\n",
+			ts.TotalIdPs, ts.SuccessIdPs, ts.FailedIdPs)
+	}
 	
 	if ts.TotalRoles > 0 {
 		roleSuccessRate := float64(ts.SuccessRoles) / float64(ts.TotalRoles) * 100
-		fmt.Printf("Role Success Rate: %.2f%%\n", roleSuccessRate)
+		fmt.Print(colorize(rateColor(ts.FailedRoles), fmt.Sprintf("Role Success Rate: %.2f%%\n", roleSuccessRate)))
 	}
 	
 	if ts.TotalUsers > 0 {
 		userSuccessRate := float64(ts.SuccessUsers) / float64(ts.TotalUsers) * 100
-		fmt.Printf("User Success Rate: %.2f%%\n", userSuccessRate)
+		fmt.Print(colorize(rateColor(ts.FailedUsers), fmt.Sprintf("User Success Rate: %.2f%%\n", userSuccessRate)))
+	}
+	
+	ts.scimIDs.print()
+	
+	if ts.TotalGroups > 0 {
+		groupSuccessRate := float64(ts.SuccessGroups) / float64(ts.TotalGroups) * 100
+		fmt.Print(colorize(rateColor(ts.FailedGroups), fmt.Sprintf("Group Success Rate: %.2f%%\n", groupSuccessRate)))
+	}
+	
+	totalReads := ts.ReadOK + ts.ReadNotModified + ts.ReadFailed
+	if totalReads > 0 {
+		fmt.Printf("Reads - Total: %d, 200: %d, 304: %d, Failed: %d\n",
+			totalReads, ts.ReadOK, ts.ReadNotModified, ts.ReadFailed)
+		if ts.ReadOK > 0 {
+			fmt.Printf("Read 200 Avg Time: %v\n", ts.ReadOKTime/time.Duration(ts.ReadOK))
+		}
+		if ts.ReadNotModified > 0 {
+			fmt.Printf("Read 304 Avg Time: %v\n", ts.ReadNotModifiedTime/time.Duration(ts.ReadNotModified))
+		}
+	}
+	
+	if ts.AuthChallenges > 0 {
+		avgChallengeTime := ts.AuthChallengeTime / time.Duration(ts.AuthChallenges)
+		fmt.Printf("Auth Challenges: %d, Total Overhead: %v, Avg Overhead: %v\n",
+			ts.AuthChallenges, ts.AuthChallengeTime, avgChallengeTime)
+	}
+	
+	if ts.Retries > 0 {
+		fmt.Printf("Retried Requests: %d\n", ts.Retries)
+	}
+	
+	if len(ts.PhaseOrder) > 0 {
+		fmt.Println("Request Latency By Phase:")
+		for _, phase := range ts.PhaseOrder {
+			latencies := ts.PhaseLatencies[phase]
+			if len(latencies) == 0 {
+				continue
+			}
+			fmt.Printf("  %s (%d) - %s\n", phase, len(latencies), SummarizeLatencies(latencies))
+		}
+	}
+	
+	ts.printTimeBreakdown()
+	ts.printWorkerUtilization()
+	ts.scheduleSlip.print()
+	printPasswordPolicies(ts.passwordPolicySummaries())
+	printPasswordModes(ts.passwordModeSummaries())
+	
+	if len(ts.Operations) > 0 {
+		labels := make([]string, 0, len(ts.Operations))
+		for label := range ts.Operations {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		
+		fmt.Println("Operation Latency:")
+		for _, label := range labels {
+			op := ts.Operations[label]
+			fmt.Printf("  %s (%d, %d failed) - %s\n", label, len(op.Latencies), op.Failed, SummarizeLatencies(op.Latencies))
+			fmt.Printf("    status: %s\n", formatStatusCodes(op.StatusCodes))
+		}
+	}
+	
+	if len(ts.Errors) > 0 {
+		patterns := topErrors(ts.Errors, ts.topErrors)
+		fmt.Printf("Top Errors (%d of %d patterns):\n", len(patterns), len(ts.Errors))
+		for _, p := range patterns {
+			fmt.Printf("  %8d  %s\n", p.Count, p.Pattern)
+		}
 	}
 	fmt.Println("================================")
 }
 
-// processResults processes test results and updates statistics
-func (te *TestExecutor) processResults(resultChan <-chan TestResult) {
+// formatStatusCodes lists response counts by status code in ascending order, e.g. "201=950 409=50",
+// with requests that got no response counted as "error"
+func formatStatusCodes(counts map[int]int) string {
+	codes := make([]int, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	
+	parts := make([]string, 0, len(codes))
+	for _, code := range codes {
+		parts = append(parts, fmt.Sprintf("%s=%d", statusCodeName(code), counts[code]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// statusCodeName names a recorded status code, "error" for requests that got no response
+func statusCodeName(code int) string {
+	if code == 0 {
+		return "error"
+	}
+	return strconv.Itoa(code)
+}
+
+// processResults processes test results and updates statistics, closing done once the
+// result channel is closed and every result has been counted
+func (te *TestExecutor) processResults(resultChan <-chan TestResult, done chan<- struct{}) {
+	defer close(done)
+	
 	for result := range resultChan {
 		te.stats.IncrementUser(result.Success)
+		te.stats.RecordError(result.Error)
+		if result.Success && result.ScimID != "" {
+			te.stats.RecordScimID(result.ScimID)
+		}
 		
-		// if result.Success && result.ScimID != "" {
-		// 	if err := te.csvWriter.WriteScimID(result.ScimID); err != nil {
-		// 		fmt.Printf("Failed to write SCIM ID to CSV: %v\n", err)
-		// 	}
-		// }
+		if result.Success && result.ScimID != "" && te.csvWriter != nil && te.config.Execution.WriteScimIds {
+			if err := te.csvWriter.WriteScimID(result.TenantIndex, result.ScimID); err != nil {
+				printFailure("Failed to write SCIM ID to CSV: %v\n", err)
+			}
+		}
 	}
 }
\ No newline at end of file
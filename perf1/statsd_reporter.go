@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterReporter("statsd", true, newStatsDReporter)
+}
+
+// statsdMaxPacket keeps every datagram under the payload of a single Ethernet frame
+const statsdMaxPacket = 1432
+
+var (
+	// statsdNameUnsafe matches the characters not allowed in a plain StatsD metric name segment
+	statsdNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	// statsdTagUnsafe matches the characters not allowed in a DogStatsD tag value
+	statsdTagUnsafe = regexp.MustCompile(`[\s,|#:@]+`)
+)
+
+// statsdReporter emits live run metrics over UDP to a StatsD server or a Datadog agent: a requests
+// and a failures counter, and a latency timer sampled at the configured rate. Plain StatsD gets the
+// phase and operation in the metric name; DogStatsD gets them as tags.
+type statsdReporter struct {
+	address    string
+	prefix     string
+	sampleRate float64
+	datadog    bool
+	tags       []string
+
+	conn   net.Conn
+	packet strings.Builder
+	random *rand.Rand
+	errors int
+	err    error
+	mutex  sync.Mutex
+}
+
+// newStatsDReporter creates the reporter from its options: address (host:port, localhost:8125 by
+// default), prefix, sampleRate of the latency timer (0 to 1), datadog (true for DogStatsD tags) and
+// tags (comma-separated name:value tags added to every metric with datadog)
+func newStatsDReporter(options map[string]string) (Reporter, error) {
+	r := &statsdReporter{
+		address:    "localhost:8125",
+		prefix:     "goperf.",
+		sampleRate: 1,
+		random:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	if address := options["address"]; address != "" {
+		r.address = address
+	}
+	if prefix, ok := options["prefix"]; ok {
+		r.prefix = prefix
+		if prefix != "" && !strings.HasSuffix(prefix, ".") {
+			r.prefix += "."
+		}
+	}
+	if value := options["sampleRate"]; value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid sampleRate %q, expected a number above 0 and up to 1", value)
+		}
+		r.sampleRate = rate
+	}
+	if value := options["datadog"]; value != "" {
+		datadog, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid datadog %q, expected true or false", value)
+		}
+		r.datadog = datadog
+	}
+	for _, tag := range strings.Split(options["tags"], ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			r.tags = append(r.tags, tag)
+		}
+	}
+
+	return r, nil
+}
+
+// Start resolves the server address, so a wrong one fails the run at start
+func (r *statsdReporter) Start(run RunInfo) error {
+	conn, err := net.Dial("udp", r.address)
+	if err != nil {
+		return fmt.Errorf("failed to resolve StatsD address: %v", err)
+	}
+
+	r.conn = conn
+	if r.datadog {
+		r.tags = append(r.tags, "mode:"+run.Mode)
+	}
+	return nil
+}
+
+// RecordResult counts the request, and its failure, and samples its latency
+func (r *statsdReporter) RecordResult(result RequestResult) {
+	status := strconv.Itoa(result.StatusCode)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.emit(result, "requests", "1|c", status)
+	if result.Failed {
+		r.emit(result, "failures", "1|c", status)
+	}
+	if r.sampleRate >= 1 {
+		r.emit(result, "latency", fmt.Sprintf("%.3f|ms", milliseconds(result.Duration)), status)
+	} else if r.random.Float64() < r.sampleRate {
+		r.emit(result, "latency", fmt.Sprintf("%.3f|ms|@%g", milliseconds(result.Duration), r.sampleRate), status)
+	}
+}
+
+// emit adds a metric line to the packet, sending the packet first when the line does not fit
+func (r *statsdReporter) emit(result RequestResult, metric, value, status string) {
+	var line string
+	if r.datadog {
+		tags := append([]string{
+			"phase:" + statsdTagUnsafe.ReplaceAllString(result.Phase, "_"),
+			"operation:" + statsdTagUnsafe.ReplaceAllString(result.Operation, "_"),
+			"status:" + status,
+		}, r.tags...)
+		line = fmt.Sprintf("%s%s:%s|#%s", r.prefix, metric, value, strings.Join(tags, ","))
+	} else {
+		line = fmt.Sprintf("%s%s.%s.%s:%s", r.prefix, statsdSegment(result.Phase), statsdSegment(result.Operation), metric, value)
+	}
+
+	if r.packet.Len() > 0 && r.packet.Len()+1+len(line) > statsdMaxPacket {
+		r.send()
+	}
+	if r.packet.Len() > 0 {
+		r.packet.WriteByte('\n')
+	}
+	r.packet.WriteString(line)
+}
+
+// send writes the packet as a datagram, remembering the errors for the next flush
+func (r *statsdReporter) send() {
+	if r.packet.Len() == 0 || r.conn == nil {
+		return
+	}
+
+	if _, err := r.conn.Write([]byte(r.packet.String())); err != nil {
+		r.errors++
+		r.err = err
+	}
+	r.packet.Reset()
+}
+
+// Flush sends the metrics not yet sent and reports the packets lost since the last flush
+func (r *statsdReporter) Flush() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.send()
+
+	if r.errors > 0 {
+		err := fmt.Errorf("failed to send %d packets to %s: %v", r.errors, r.address, r.err)
+		r.errors, r.err = 0, nil
+		return err
+	}
+	return nil
+}
+
+// Close closes the socket
+func (r *statsdReporter) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
+
+// statsdSegment turns a phase or operation into a metric name segment, e.g. POST /scim2/Users/{id}
+// into POST_scim2_Users_id
+func statsdSegment(s string) string {
+	s = strings.Trim(statsdNameUnsafe.ReplaceAllString(s, "_"), "_")
+	if s == "" {
+		return "none"
+	}
+	return s
+}
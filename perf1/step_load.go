@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rampStep is a load level of a ramp profile: a number of threads for a duration
+type rampStep struct {
+	Threads  int
+	Duration time.Duration
+}
+
+// parseRampProfile parses a ramp profile of comma-separated threads:duration steps, e.g.
+// 10:2m,20:2m,50:5m for 10 threads for two minutes, then 20 for two minutes, then 50 for five
+func parseRampProfile(spec string) ([]rampStep, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	var steps []rampStep
+	for _, part := range strings.Split(spec, ",") {
+		threads, duration, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid ramp step %q, expected threads:duration, e.g. 10:2m", part)
+		}
+
+		step := rampStep{}
+		var err error
+		if step.Threads, err = strconv.Atoi(threads); err != nil || step.Threads < 1 {
+			return nil, fmt.Errorf("invalid thread count in ramp step %q", part)
+		}
+		if step.Duration, err = time.ParseDuration(duration); err != nil || step.Duration <= 0 {
+			return nil, fmt.Errorf("invalid duration in ramp step %q", part)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// describeRampProfile describes the steps of a ramp profile, e.g. "10 threads for 2m0s, then 20
+// threads for 5m0s"
+func describeRampProfile(steps []rampStep) string {
+	parts := make([]string, len(steps))
+	for i, step := range steps {
+		parts[i] = fmt.Sprintf("%d threads for %v", step.Threads, step.Duration)
+	}
+	return strings.Join(parts, ", then ")
+}
+
+// rampProfileThreads returns the most threads any step of a ramp profile runs
+func rampProfileThreads(steps []rampStep) int {
+	threads := 0
+	for _, step := range steps {
+		if step.Threads > threads {
+			threads = step.Threads
+		}
+	}
+	return threads
+}
+
+// stepLoad walks the user creation threads through the steps of a ramp profile. Every step is a
+// phase of its own, so the statistics, summary and report show the latency at each load level.
+// Threads beyond the count of the current step wait, and the phase ends with the last step.
+type stepLoad struct {
+	te    *TestExecutor
+	steps []rampStep
+
+	mutex   sync.Mutex
+	current int           // index of the current step, len(steps) once the profile is over
+	changed chan struct{} // closed when the step changes
+	stop    chan struct{} // closed when the profile is over
+	done    chan struct{}
+	results []stepResult
+}
+
+// stepResult is the outcome of one step of a ramp profile
+type stepResult struct {
+	phase     string
+	started   time.Time
+	ended     time.Time
+	completed int
+	failed    int
+}
+
+// startStepLoad starts walking through the steps of the ramp profile
+func (te *TestExecutor) startStepLoad(steps []rampStep) *stepLoad {
+	sl := &stepLoad{
+		te:      te,
+		steps:   steps,
+		current: -1,
+		changed: make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	fmt.Printf("Ramp profile: %s\n", describeRampProfile(steps))
+
+	sl.advance(false)
+	go sl.run()
+	return sl
+}
+
+// run moves to the next step whenever the current one has run for its duration, until the profile
+// is over or the run is interrupted
+func (sl *stepLoad) run() {
+	defer close(sl.done)
+
+	for i := 0; i < len(sl.steps); i++ {
+		timer := time.NewTimer(sl.steps[i].Duration)
+		select {
+		case <-timer.C:
+		case <-sl.te.ctx.Done():
+			timer.Stop()
+			sl.advance(true)
+			return
+		case <-sl.stop:
+			timer.Stop()
+			return
+		}
+		sl.advance(false)
+	}
+}
+
+// advance closes the current step and starts the next one, or ends the profile after the last
+// step or when end is set
+func (sl *stepLoad) advance(end bool) {
+	now := time.Now()
+	completed, failed := sl.te.stats.Totals()
+
+	sl.mutex.Lock()
+	if sl.current >= len(sl.steps) {
+		sl.mutex.Unlock()
+		return
+	}
+	if sl.current >= 0 {
+		result := &sl.results[sl.current]
+		result.ended = now
+		result.completed = completed - result.completed
+		result.failed = failed - result.failed
+	}
+	sl.current++
+	if end {
+		sl.current = len(sl.steps)
+	}
+	close(sl.changed)
+	sl.changed = make(chan struct{})
+	current := sl.current
+	if current < len(sl.steps) {
+		sl.results = append(sl.results, stepResult{
+			phase:     fmt.Sprintf("users step %d (%d threads)", current+1, sl.steps[current].Threads),
+			started:   now,
+			completed: completed,
+			failed:    failed,
+		})
+	}
+	sl.mutex.Unlock()
+
+	if current >= len(sl.steps) {
+		close(sl.stop)
+		return
+	}
+
+	step := sl.steps[current]
+	fmt.Printf("Step %d of %d: %d threads for %v\n", current+1, len(sl.steps), step.Threads, step.Duration)
+	sl.te.setPhase(sl.results[current].phase)
+}
+
+// Stop ends the profile if it is not over yet, as when the users run out, and waits for it,
+// returning whether it ran to its end
+func (sl *stepLoad) Stop() bool {
+	sl.mutex.Lock()
+	over := sl.current >= len(sl.steps)
+	sl.mutex.Unlock()
+
+	sl.advance(true)
+	<-sl.done
+	return over
+}
+
+// active returns whether the thread at the given index runs in the current step, whether the
+// profile is over, and a channel closed when the step changes
+func (sl *stepLoad) active(index int) (bool, bool, <-chan struct{}) {
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+
+	if sl.current >= len(sl.steps) {
+		return false, true, sl.changed
+	}
+	return index < sl.steps[sl.current].Threads, false, sl.changed
+}
+
+// gate passes users from the queue to the thread at the given index while the current step runs
+// that thread, and closes the thread's queue when the profile is over or the run is interrupted
+func (sl *stepLoad) gate(ctx context.Context, index int, jobs <-chan userJob) <-chan userJob {
+	gated := make(chan userJob)
+
+	go func() {
+		defer close(gated)
+
+		for {
+			active, over, changed := sl.active(index)
+			if over {
+				return
+			}
+			if !active {
+				select {
+				case <-changed:
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case job, ok := <-jobs:
+				if !ok {
+					return
+				}
+				gated <- job
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return gated
+}
+
+// print prints the throughput and latency of every step, the load curve of the run
+func (sl *stepLoad) print() {
+	sl.mutex.Lock()
+	results := append([]stepResult(nil), sl.results...)
+	sl.mutex.Unlock()
+
+	sl.te.stats.mutex.Lock()
+	latencies := make([]LatencySummary, len(results))
+	for i, result := range results {
+		latencies[i] = SummarizeLatencies(sl.te.stats.PhaseLatencies[result.phase])
+	}
+	sl.te.stats.mutex.Unlock()
+
+	fmt.Println("\n=== Ramp Profile Steps ===")
+	for i, result := range results {
+		duration := result.ended.Sub(result.started)
+		throughput := 0.0
+		if duration > 0 {
+			throughput = float64(result.completed+result.failed) / duration.Seconds()
+		}
+		fmt.Printf("Step %d - %d threads, %v: Success: %d, Failed: %d, Throughput: %.2f users/s, %s\n",
+			i+1, sl.steps[i].Threads, duration.Round(time.Second), result.completed, result.failed, throughput, latencies[i])
+	}
+	fmt.Println("==========================")
+}
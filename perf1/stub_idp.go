@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// stubCodeLifetime is how long an authorization code of the stub IdP can be redeemed
+const stubCodeLifetime = time.Minute
+
+// stubIdP is a minimal OpenID Connect provider for load testing federated logins. It approves every
+// authorization request without a login page and issues RS256 ID tokens for one of its users, so the
+// server under test does all of its federation work while the external IdP costs next to nothing.
+type stubIdP struct {
+	config StubIdPConfig
+	issuer string
+	key    *rsa.PrivateKey
+	kid    string
+
+	mutex sync.Mutex
+	codes map[string]stubGrant
+
+	// requests counts the requests by endpoint, rejected those answered with an error
+	requests map[string]int
+	rejected int
+}
+
+// stubGrant is what an authorization code of the stub IdP was issued for
+type stubGrant struct {
+	clientID    string
+	redirectURI string
+	subject     string
+	nonce       string
+	expires     time.Time
+}
+
+// newStubIdP validates the stub configuration and generates the key its tokens are signed with
+func newStubIdP(config StubIdPConfig) (*stubIdP, error) {
+	if config.Users < 1 {
+		return nil, fmt.Errorf("stub IdP users must be positive, got %d", config.Users)
+	}
+	if config.TokenLifetime < 1 {
+		return nil, fmt.Errorf("stub IdP token lifetime must be positive, got %d", config.TokenLifetime)
+	}
+	if (config.CertPath == "") != (config.KeyPath == "") {
+		return nil, fmt.Errorf("stub IdP needs both a certificate and a key to serve HTTPS")
+	}
+
+	issuer := strings.TrimSuffix(config.URL, "/")
+	if issuer == "" {
+		scheme := "http"
+		if config.CertPath != "" {
+			scheme = "https"
+		}
+		host := config.Address
+		if strings.HasPrefix(host, ":") {
+			host = "localhost" + host
+		}
+		issuer = scheme + "://" + host
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate stub IdP signing key: %v", err)
+	}
+	digest := sha256.Sum256(key.N.Bytes())
+
+	return &stubIdP{
+		config:   config,
+		issuer:   issuer,
+		key:      key,
+		kid:      base64.RawURLEncoding.EncodeToString(digest[:8]),
+		codes:    make(map[string]stubGrant),
+		requests: make(map[string]int),
+	}, nil
+}
+
+// Serve answers requests until the context is done, then prints how many it handled
+func (s *stubIdP) Serve(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.handleDiscovery)
+	mux.HandleFunc("/authorize", s.handleAuthorize)
+	mux.HandleFunc("/token", s.handleToken)
+	mux.HandleFunc("/userinfo", s.handleUserInfo)
+	mux.HandleFunc("/jwks", s.handleJWKS)
+
+	server := &http.Server{
+		Addr:              s.config.Address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		if s.config.CertPath != "" {
+			errs <- server.ListenAndServeTLS(s.config.CertPath, s.config.KeyPath)
+		} else {
+			errs <- server.ListenAndServe()
+		}
+	}()
+
+	fmt.Printf("Stub IdP listening on %s with issuer %s, logging in as %d users\n", s.config.Address, s.issuer, s.config.Users)
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("stub IdP stopped: %v", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		printWarning("Failed to shut down the stub IdP: %v\n", err)
+	}
+
+	s.printRequests()
+	return nil
+}
+
+// count records a request to the endpoint, and whether it was rejected
+func (s *stubIdP) count(endpoint string, rejected bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests[endpoint]++
+	if rejected {
+		s.rejected++
+	}
+}
+
+// printRequests prints the requests handled by endpoint
+func (s *stubIdP) printRequests() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	fmt.Printf("Stub IdP handled %d authorize, %d token, %d userinfo, %d JWKS and %d discovery requests, %d rejected\n",
+		s.requests["authorize"], s.requests["token"], s.requests["userinfo"], s.requests["jwks"], s.requests["discovery"], s.rejected)
+}
+
+// reject answers an OAuth2 error and counts the request as rejected
+func (s *stubIdP) reject(w http.ResponseWriter, endpoint string, status int, errCode, description string) {
+	s.count(endpoint, true)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": errCode, "error_description": description})
+}
+
+// writeJSON answers the value as JSON and counts the request
+func (s *stubIdP) writeJSON(w http.ResponseWriter, endpoint string, v interface{}) {
+	s.count(endpoint, false)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(v)
+}
+
+// handleDiscovery publishes the provider metadata
+func (s *stubIdP) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, "discovery", map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"userinfo_endpoint":                     s.issuer + "/userinfo",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+// handleAuthorize approves the authorization request at once and redirects back with a code
+func (s *stubIdP) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.reject(w, "authorize", http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	redirectURI := r.Form.Get("redirect_uri")
+	callback, err := url.Parse(redirectURI)
+	if redirectURI == "" || err != nil || !callback.IsAbs() {
+		s.reject(w, "authorize", http.StatusBadRequest, "invalid_request", "redirect_uri must be an absolute URL")
+		return
+	}
+	if r.Form.Get("client_id") == "" || r.Form.Get("response_type") != "code" {
+		s.reject(w, "authorize", http.StatusBadRequest, "invalid_request", "client_id and response_type=code are required")
+		return
+	}
+
+	state := r.Form.Get("state")
+	code := randomState()
+	s.issueCode(code, stubGrant{
+		clientID:    r.Form.Get("client_id"),
+		redirectURI: redirectURI,
+		subject:     s.subject(r.Form.Get("login_hint"), state),
+		nonce:       r.Form.Get("nonce"),
+		expires:     time.Now().Add(stubCodeLifetime),
+	})
+	s.count("authorize", false)
+
+	query := callback.Query()
+	query.Set("code", code)
+	if state != "" {
+		query.Set("state", state)
+	}
+	callback.RawQuery = query.Encode()
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+// subject returns the user a login is approved for: the login hint, if the server sent one, or one
+// of the stub's users picked by the state, so repeated logins spread over a fixed set of users the
+// server provisions just in time
+func (s *stubIdP) subject(loginHint, state string) string {
+	if loginHint != "" {
+		return loginHint
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(state))
+	return fmt.Sprintf("%s%d", s.config.UserPrefix, mix64(hash.Sum64())%uint64(s.config.Users)+1)
+}
+
+// issueCode stores the grant of an authorization code, dropping the expired codes that were never
+// redeemed once there are many
+func (s *stubIdP) issueCode(code string, grant stubGrant) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.codes) >= 10000 {
+		now := time.Now()
+		for c, g := range s.codes {
+			if now.After(g.expires) {
+				delete(s.codes, c)
+			}
+		}
+	}
+	s.codes[code] = grant
+}
+
+// redeemCode returns the grant of an authorization code and forgets it, so a code works once
+func (s *stubIdP) redeemCode(code string) (stubGrant, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	grant, ok := s.codes[code]
+	delete(s.codes, code)
+	if !ok || time.Now().After(grant.expires) {
+		return stubGrant{}, false
+	}
+	return grant, true
+}
+
+// handleToken exchanges an authorization code for an access token and an ID token. Any client
+// secret is accepted; the client and redirect URI must match those the code was issued for.
+func (s *stubIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.reject(w, "token", http.StatusMethodNotAllowed, "invalid_request", "the token endpoint takes POST")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		s.reject(w, "token", http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if grantType := r.PostForm.Get("grant_type"); grantType != "authorization_code" {
+		s.reject(w, "token", http.StatusBadRequest, "unsupported_grant_type", fmt.Sprintf("grant type %q is not supported", grantType))
+		return
+	}
+
+	clientID, _, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+	}
+
+	grant, ok := s.redeemCode(r.PostForm.Get("code"))
+	if !ok || grant.clientID != clientID || grant.redirectURI != r.PostForm.Get("redirect_uri") {
+		s.reject(w, "token", http.StatusBadRequest, "invalid_grant", "the code is unknown, expired or was issued to another client or redirect URI")
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":   s.issuer,
+		"sub":   grant.subject,
+		"aud":   grant.clientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Duration(s.config.TokenLifetime) * time.Second).Unix(),
+		"email": grant.subject + "@federated.example.com",
+	}
+	accessToken, err := SignJWT(claims, s.key, "RS256", s.kid)
+	if err != nil {
+		s.reject(w, "token", http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	if grant.nonce != "" {
+		claims["nonce"] = grant.nonce
+	}
+	idToken, err := SignJWT(claims, s.key, "RS256", s.kid)
+	if err != nil {
+		s.reject(w, "token", http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	s.writeJSON(w, "token", map[string]interface{}{
+		"access_token": accessToken,
+		"id_token":     idToken,
+		"token_type":   "Bearer",
+		"expires_in":   s.config.TokenLifetime,
+		"scope":        "openid",
+	})
+}
+
+// handleUserInfo returns the claims of the user of a bearer access token issued by the stub
+func (s *stubIdP) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		s.reject(w, "userinfo", http.StatusUnauthorized, "invalid_token", "a bearer access token is required")
+		return
+	}
+
+	claims, err := ValidateJWT(token, s.jwks(), []string{"sub"})
+	if err != nil {
+		s.reject(w, "userinfo", http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+
+	s.writeJSON(w, "userinfo", map[string]interface{}{
+		"sub":   claims["sub"],
+		"email": claims["email"],
+	})
+}
+
+// handleJWKS publishes the key the tokens are signed with
+func (s *stubIdP) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, "jwks", s.jwks())
+}
+
+// jwks returns the key set of the stub's signing key
+func (s *stubIdP) jwks() *JWKS {
+	return &JWKS{Keys: []JWK{{
+		Kty: "RSA",
+		Kid: s.kid,
+		Alg: "RS256",
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+	}}}
+}
+
+// runStubIdPCommand serves the stub IdP until interrupted
+func runStubIdPCommand(cmd *cliCommand, args []string) error {
+	config, positional, err := cmd.loadCommandConfig(args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("usage: go-perf %s [flags]", cmd.name)
+	}
+
+	stub, err := newStubIdP(config.StubIdP)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	return stub.Serve(ctx)
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// RunSummary is the machine-readable result of a run, written as JSON for CI pipelines and other
+// tooling
+type RunSummary struct {
+	Mode   string    `json:"mode"`
+	Status string    `json:"status"` // completed, interrupted or failed
+	Error  string    `json:"error,omitempty"`
+	Client BuildInfo `json:"client"`
+
+	StartedAt       time.Time `json:"startedAt"`
+	FinishedAt      time.Time `json:"finishedAt"`
+	DurationSeconds float64   `json:"durationSeconds"`
+
+	// EffectiveTPS is the rate of successful role, user, group and IdP creations over the whole run,
+	// RequestsPerSecond the rate of all requests sent
+	EffectiveTPS      float64 `json:"effectiveTps"`
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+
+	Roles   SummaryCounts `json:"roles"`
+	Users   SummaryCounts `json:"users"`
+	Groups  SummaryCounts `json:"groups"`
+	IdPs    SummaryCounts `json:"idps"`
+	Retries int           `json:"retries"`
+
+	Phases       []SummaryPhase       `json:"phases"`
+	Tenants      []SummaryTenant      `json:"tenants"`                // longest first
+	Workers      []SummaryWorkers     `json:"workers"`                // per phase of the workers' first request
+	ScheduleSlip *SummaryScheduleSlip `json:"scheduleSlip,omitempty"` // with a target rate
+
+	PasswordPolicies []SummaryPasswordPolicy `json:"passwordPolicies,omitempty"` // by policy and operation
+	PasswordModes    []SummaryPasswordMode   `json:"passwordModes,omitempty"`    // when compared
+
+	Operations []SummaryOperation `json:"operations"`
+	Errors     []SummaryError     `json:"errors"`
+	ScimIDs    *scimIDCheck       `json:"scimIds,omitempty"`
+
+	// Config is the configuration of the run with its secrets left out
+	Config *Config `json:"config"`
+}
+
+// SummaryCounts holds the outcome counts of one resource type
+type SummaryCounts struct {
+	Total   int `json:"total"`
+	Success int `json:"success"`
+	Failed  int `json:"failed"`
+}
+
+// SummaryPhase holds the request latencies of one phase of the run and where it spent its
+// wall-clock time
+type SummaryPhase struct {
+	Name         string         `json:"name"`
+	Latency      SummaryLatency `json:"latency"`
+	WallSeconds  float64        `json:"wallSeconds"`
+	BusySeconds  float64        `json:"busySeconds"` // time of all requests of the phase together
+	PeakInFlight int            `json:"peakInFlight"`
+	Utilization  float64        `json:"utilization"` // share of the peak requests in flight kept busy, 0 to 1
+}
+
+// breakdown converts the wall-clock fields of the phase back to a breakdown
+func (p SummaryPhase) breakdown() phaseBreakdown {
+	d := func(s float64) time.Duration { return time.Duration(s * float64(time.Second)) }
+	return phaseBreakdown{wall: d(p.WallSeconds), busy: d(p.BusySeconds), peak: p.PeakInFlight}
+}
+
+// SummaryTenant holds the wall-clock time the requests sent to a tenant spanned
+type SummaryTenant struct {
+	Tenant       string  `json:"tenant"`
+	Requests     int     `json:"requests"`
+	StartSeconds float64 `json:"startSeconds"` // first request, from the start of the run
+	WallSeconds  float64 `json:"wallSeconds"`  // from the first request to the end of the last
+	BusySeconds  float64 `json:"busySeconds"`  // time of all its requests together
+}
+
+// SummaryWorkers holds how the workers whose first request was in a phase spent their time, each
+// from its creation to the end of the phase's last request, in seconds of all workers together
+type SummaryWorkers struct {
+	Phase           string  `json:"phase"`
+	Workers         int     `json:"workers"`
+	Utilization     float64 `json:"utilization"` // share of their time the workers had a request in flight, 0 to 1
+	LeastBusy       float64 `json:"leastBusy"`   // utilization of the least busy worker
+	SpanSeconds     float64 `json:"spanSeconds"` // from the first worker's creation to the end of the last request
+	TotalSeconds    float64 `json:"totalSeconds"`
+	BusySeconds     float64 `json:"busySeconds"`
+	StartSeconds    float64 `json:"startSeconds"`    // before the first request, mostly the ramp-up
+	PacingSeconds   float64 `json:"pacingSeconds"`   // waiting for the target rate
+	WaitingSeconds  float64 `json:"waitingSeconds"`  // between requests, for the queue, gates and client-side work
+	FinishedSeconds float64 `json:"finishedSeconds"` // after the worker's last request, while the others finished
+}
+
+// breakdown converts the fields of the workers back to a breakdown
+func (w SummaryWorkers) breakdown() workerBreakdown {
+	d := func(s float64) time.Duration { return time.Duration(s * float64(time.Second)) }
+	return workerBreakdown{
+		phase: w.Phase, workers: w.Workers, leastBusy: w.LeastBusy, span: d(w.SpanSeconds), total: d(w.TotalSeconds),
+		busy: d(w.BusySeconds), starting: d(w.StartSeconds), pacing: d(w.PacingSeconds),
+		waiting: d(w.WaitingSeconds), finished: d(w.FinishedSeconds),
+	}
+}
+
+// SummaryOperation holds the requests of one operation, as labeled by the HTTP client
+type SummaryOperation struct {
+	Operation   string         `json:"operation"`
+	Failed      int            `json:"failed"`
+	StatusCodes map[string]int `json:"statusCodes"` // "error" counts requests without a response
+	Latency     SummaryLatency `json:"latency"`
+}
+
+// SummaryError is an error pattern and the number of failures that matched it
+type SummaryError struct {
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+}
+
+// SummaryLatency is a latency distribution in milliseconds
+type SummaryLatency struct {
+	Count int     `json:"count"`
+	MinMs float64 `json:"minMs"`
+	AvgMs float64 `json:"avgMs"`
+	MaxMs float64 `json:"maxMs"`
+	P50Ms float64 `json:"p50Ms"`
+	P90Ms float64 `json:"p90Ms"`
+	P95Ms float64 `json:"p95Ms"`
+	P99Ms float64 `json:"p99Ms"`
+}
+
+// newSummaryLatency converts a latency summary to milliseconds
+func newSummaryLatency(ls LatencySummary) SummaryLatency {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return SummaryLatency{
+		Count: ls.Count,
+		MinMs: ms(ls.Min),
+		AvgMs: ms(ls.Avg),
+		MaxMs: ms(ls.Max),
+		P50Ms: ms(ls.P50),
+		P90Ms: ms(ls.P90),
+		P95Ms: ms(ls.P95),
+		P99Ms: ms(ls.P99),
+	}
+}
+
+// Summary builds the run summary from the statistics gathered between the start and end times
+func (ts *TestStats) Summary(startTime, endTime time.Time) *RunSummary {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	duration := endTime.Sub(startTime)
+	summary := &RunSummary{
+		Client:          clientBuild,
+		StartedAt:       startTime,
+		FinishedAt:      endTime,
+		DurationSeconds: duration.Seconds(),
+		Roles:           SummaryCounts{Total: ts.TotalRoles, Success: ts.SuccessRoles, Failed: ts.FailedRoles},
+		Users:           SummaryCounts{Total: ts.TotalUsers, Success: ts.SuccessUsers, Failed: ts.FailedUsers},
+		Groups:          SummaryCounts{Total: ts.TotalGroups, Success: ts.SuccessGroups, Failed: ts.FailedGroups},
+		IdPs:            SummaryCounts{Total: ts.TotalIdPs, Success: ts.SuccessIdPs, Failed: ts.FailedIdPs},
+		Retries:         ts.Retries,
+		Phases:          []SummaryPhase{},
+		Tenants:         []SummaryTenant{},
+		Workers:         []SummaryWorkers{},
+		Operations:      []SummaryOperation{},
+		Errors:          []SummaryError{},
+		ScimIDs:         ts.scimIDs,
+		ScheduleSlip:    ts.scheduleSlip.summary(),
+	}
+	if len(ts.passwordPolicies) > 0 {
+		summary.PasswordPolicies = ts.passwordPolicySummaries()
+	}
+	if len(ts.passwordModes) > 0 {
+		summary.PasswordModes = ts.passwordModeSummaries()
+	}
+
+	for _, phase := range ts.PhaseOrder {
+		if latencies := ts.PhaseLatencies[phase]; len(latencies) > 0 {
+			b := ts.phaseBreakdown(phase, endTime)
+			summary.Phases = append(summary.Phases, SummaryPhase{
+				Name:         phase,
+				Latency:      newSummaryLatency(SummarizeLatencies(latencies)),
+				WallSeconds:  b.wall.Seconds(),
+				BusySeconds:  b.busy.Seconds(),
+				PeakInFlight: b.peak,
+				Utilization:  b.utilization(),
+			})
+		}
+	}
+
+	for _, tenant := range ts.tenantBreakdowns() {
+		summary.Tenants = append(summary.Tenants, SummaryTenant{
+			Tenant:       tenant.tenant,
+			Requests:     tenant.requests,
+			StartSeconds: tenant.first.Sub(startTime).Seconds(),
+			WallSeconds:  tenant.wall.Seconds(),
+			BusySeconds:  tenant.busy.Seconds(),
+		})
+	}
+
+	for _, b := range ts.workerBreakdowns(endTime) {
+		summary.Workers = append(summary.Workers, SummaryWorkers{
+			Phase:           b.phase,
+			Workers:         b.workers,
+			Utilization:     b.utilization(),
+			LeastBusy:       b.leastBusy,
+			SpanSeconds:     b.span.Seconds(),
+			TotalSeconds:    b.total.Seconds(),
+			BusySeconds:     b.busy.Seconds(),
+			StartSeconds:    b.starting.Seconds(),
+			PacingSeconds:   b.pacing.Seconds(),
+			WaitingSeconds:  b.waiting.Seconds(),
+			FinishedSeconds: b.finished.Seconds(),
+		})
+	}
+
+	requests := 0
+	for label, op := range ts.Operations {
+		statusCodes := make(map[string]int, len(op.StatusCodes))
+		for code, count := range op.StatusCodes {
+			statusCodes[statusCodeName(code)] = count
+		}
+
+		requests += len(op.Latencies)
+		summary.Operations = append(summary.Operations, SummaryOperation{
+			Operation:   label,
+			Failed:      op.Failed,
+			StatusCodes: statusCodes,
+			Latency:     newSummaryLatency(SummarizeLatencies(op.Latencies)),
+		})
+	}
+	sort.Slice(summary.Operations, func(i, j int) bool {
+		return summary.Operations[i].Operation < summary.Operations[j].Operation
+	})
+
+	for _, p := range topErrors(ts.Errors, 0) {
+		summary.Errors = append(summary.Errors, SummaryError{Pattern: p.Pattern, Count: p.Count})
+	}
+
+	if duration > 0 {
+		created := ts.SuccessRoles + ts.SuccessUsers + ts.SuccessGroups + ts.SuccessIdPs
+		summary.EffectiveTPS = float64(created) / duration.Seconds()
+		summary.RequestsPerSecond = float64(requests) / duration.Seconds()
+	}
+
+	return summary
+}
+
+// writeSummary writes the summary of a run that started at the given time and ended with err to
+// the summary file, if one is configured
+func (te *TestExecutor) writeSummary(mode ExecutionMode, startTime time.Time, err error) {
+	if te.config.Execution.SummaryFile == "" {
+		return
+	}
+
+	summary := te.stats.Summary(startTime, time.Now())
+	summary.Mode = mode.String()
+	summary.Config = withoutSecrets(te.config)
+
+	switch {
+	case err == nil:
+		summary.Status = "completed"
+	case errors.Is(err, ErrInterrupted):
+		summary.Status = "interrupted"
+	default:
+		summary.Status = "failed"
+		summary.Error = err.Error()
+	}
+
+	if writeErr := writeFileAtomic(te.config.Execution.SummaryFile, summary); writeErr != nil {
+		printWarning("Failed to write run summary: %v\n", writeErr)
+		return
+	}
+
+	fmt.Printf("Run summary written to %s\n", te.config.Execution.SummaryFile)
+}
+
+// ReadRunSummary reads a run summary file written by a previous run
+func ReadRunSummary(path string) (*RunSummary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run summary: %v", err)
+	}
+
+	var summary RunSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("failed to parse run summary %s: %v", path, err)
+	}
+	return &summary, nil
+}
+
+// Print prints the summary in the layout of the statistics printed at the end of a run
+func (s *RunSummary) Print(path string) {
+	fmt.Printf("=== Run Summary (%s) ===\n", path)
+	fmt.Printf("Mode: %s, Status: %s\n", s.Mode, s.Status)
+	if s.Error != "" {
+		printFailure("Error: %s\n", s.Error)
+	}
+	if s.Client.Version != "" {
+		fmt.Printf("Client: %s\n", s.Client)
+	}
+	fmt.Printf("Started: %s, Duration: %v\n", s.StartedAt.Format(time.RFC3339),
+		(time.Duration(s.DurationSeconds * float64(time.Second))).Round(time.Millisecond))
+	fmt.Printf("Roles - Total: %d, Success: %d, Failed: %d\n", s.Roles.Total, s.Roles.Success, s.Roles.Failed)
+	fmt.Printf("Users - Total: %d, Success: %d, Failed: %d\n", s.Users.Total, s.Users.Success, s.Users.Failed)
+	if s.Groups.Total > 0 {
+		fmt.Printf("Groups - Total: %d, Success: %d, Failed: %d\n", s.Groups.Total, s.Groups.Success, s.Groups.Failed)
+	}
+	if s.IdPs.Total > 0 {
+		fmt.Printf("IdPs - Total: %d, Success: %d, Failed: %d\n", s.IdPs.Total, s.IdPs.Success, s.IdPs.Failed)
+	}
+	if s.Retries > 0 {
+		fmt.Printf("Retried Requests: %d\n", s.Retries)
+	}
+	fmt.Printf("Effective TPS: %.2f, Requests/s: %.2f\n", s.EffectiveTPS, s.RequestsPerSecond)
+
+	if len(s.Phases) > 0 {
+		fmt.Println("Request Latency By Phase:")
+		for _, phase := range s.Phases {
+			fmt.Printf("  %s (%d) - %s\n", phase.Name, phase.Latency.Count, phase.Latency.summary())
+		}
+
+		// Summaries of earlier versions have no wall-clock times
+		if s.Phases[0].WallSeconds > 0 {
+			fmt.Println("Wall-Clock Time By Phase:")
+			for _, phase := range s.Phases {
+				fmt.Printf("  %s - %s\n", phase.Name, phase.breakdown())
+			}
+		}
+	}
+
+	if len(s.Tenants) > 0 {
+		shown := s.Tenants
+		if len(shown) > slowestTenants {
+			shown = shown[:slowestTenants]
+		}
+		fmt.Printf("Wall-Clock Time By Tenant (%d longest of %d):\n", len(shown), len(s.Tenants))
+		for _, tenant := range shown {
+			d := func(s float64) time.Duration { return time.Duration(s * float64(time.Second)).Round(time.Millisecond) }
+			fmt.Printf("  %s - %v from +%v, busy %v, %d requests\n", tenant.Tenant, d(tenant.WallSeconds),
+				d(tenant.StartSeconds), d(tenant.BusySeconds), tenant.Requests)
+		}
+	}
+
+	if len(s.Workers) > 0 {
+		fmt.Println("Worker Utilization By Phase:")
+		for _, workers := range s.Workers {
+			fmt.Printf("  %s - %s\n", workers.Phase, workers.breakdown())
+		}
+	}
+
+	if s.ScheduleSlip != nil {
+		s.ScheduleSlip.print()
+	}
+	printPasswordPolicies(s.PasswordPolicies)
+	printPasswordModes(s.PasswordModes)
+
+	if len(s.Operations) > 0 {
+		fmt.Println("Operation Latency:")
+		for _, op := range s.Operations {
+			fmt.Printf("  %s (%d, %d failed) - %s\n", op.Operation, op.Latency.Count, op.Failed, op.Latency.summary())
+			fmt.Printf("    status: %s\n", formatStatusNames(op.StatusCodes))
+		}
+	}
+
+	if len(s.Errors) > 0 {
+		fmt.Printf("Top Errors (%d patterns):\n", len(s.Errors))
+		for _, e := range s.Errors {
+			fmt.Printf("  %8d  %s\n", e.Count, e.Pattern)
+		}
+	}
+	fmt.Println("================================")
+}
+
+// summary converts the latency distribution back to durations
+func (sl SummaryLatency) summary() LatencySummary {
+	d := func(ms float64) time.Duration { return time.Duration(ms * float64(time.Millisecond)) }
+	return LatencySummary{
+		Count: sl.Count,
+		Min:   d(sl.MinMs),
+		Avg:   d(sl.AvgMs),
+		Max:   d(sl.MaxMs),
+		P50:   d(sl.P50Ms),
+		P90:   d(sl.P90Ms),
+		P95:   d(sl.P95Ms),
+		P99:   d(sl.P99Ms),
+	}
+}
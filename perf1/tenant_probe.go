@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// tenantReadiness probes every tenant once before users are created in it. Workers that need a
+// tenant wait for its probe, so the users of a tenant that is still being activated are parked
+// instead of failing one by one.
+type tenantReadiness struct {
+	attempts int
+	interval time.Duration
+
+	mutex  sync.Mutex
+	probes map[int]*tenantProbe
+}
+
+// tenantProbe is the outcome of probing one tenant, available once done is closed
+type tenantProbe struct {
+	done chan struct{}
+	err  error
+}
+
+// newTenantReadiness creates a readiness check that probes a tenant up to the given number of
+// attempts, the given number of seconds apart. It returns nil when probing is disabled.
+func newTenantReadiness(enabled bool, attempts, intervalSeconds int) *tenantReadiness {
+	if !enabled {
+		return nil
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	return &tenantReadiness{
+		attempts: attempts,
+		interval: time.Duration(intervalSeconds) * time.Second,
+		probes:   make(map[int]*tenantProbe),
+	}
+}
+
+// Wait blocks until the tenant has answered the readiness probe and returns an error if it never
+// did. The first caller for a tenant probes it with its client; later callers share the outcome.
+// A nil readiness check never blocks.
+func (tr *tenantReadiness) Wait(ctx context.Context, client *HTTPClient, tenantIndex int) error {
+	if tr == nil {
+		return nil
+	}
+
+	tr.mutex.Lock()
+	probe, ok := tr.probes[tenantIndex]
+	if !ok {
+		probe = &tenantProbe{done: make(chan struct{})}
+		tr.probes[tenantIndex] = probe
+	}
+	tr.mutex.Unlock()
+
+	if !ok {
+		probe.err = tr.probe(ctx, client, tenantIndex)
+		close(probe.done)
+	}
+
+	select {
+	case <-probe.done:
+		return probe.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// probe retries the tenant's readiness probe until it succeeds or the attempts run out
+func (tr *tenantReadiness) probe(ctx context.Context, client *HTTPClient, tenantIndex int) error {
+	var err error
+	for attempt := 1; attempt <= tr.attempts; attempt++ {
+		if err = client.ProbeTenant(ctx, tenantIndex); err == nil {
+			if attempt > 1 {
+				printSuccess("Tenant %d ready after %d attempts\n", tenantIndex, attempt)
+			}
+			return nil
+		}
+
+		printWarning("Tenant %d not ready (attempt %d/%d): %v\n", tenantIndex, attempt, tr.attempts, err)
+		if attempt == tr.attempts {
+			break
+		}
+
+		timer := time.NewTimer(tr.interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	return fmt.Errorf("tenant %d not ready after %d attempts: %v", tenantIndex, tr.attempts, err)
+}
+
+// ProbeTenant checks that a tenant is active with a cheap authenticated SCIM search for one user
+func (h *HTTPClient) ProbeTenant(ctx context.Context, tenantIndex int) error {
+	reqURL := h.config.GetSCIMURL("/Users?startIndex=1&count=1", tenantIndex)
+
+	req, err := newTenantRequest(ctx, tenantIndex, "GET", reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create tenant probe request: %v", err)
+	}
+
+	resp, err := h.do(h.client, req)
+	if err != nil {
+		return fmt.Errorf("failed to execute tenant probe request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("tenant probe failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// slowestTenants is the number of tenants the statistics list, those whose requests spanned the
+// longest; the JSON summary has all of them
+const slowestTenants = 10
+
+// phaseTime is the wall-clock time spent in a phase and the most requests it had in flight
+type phaseTime struct {
+	wall time.Duration // up to when the phase was last left
+	peak int
+}
+
+// tenantTime is the span of the requests sent to a tenant and the time they took together
+type tenantTime struct {
+	first    time.Time
+	last     time.Time
+	busy     time.Duration
+	requests int
+}
+
+// phaseBreakdown is where a phase spent its wall-clock time. Busy is the time of its requests
+// together, so busy/wall is the average number of requests in flight, and the share of the peak
+// that average reaches shows how much of the time the workers waited on the server rather than
+// being idle, in ramp-up, pacing, think time or client-side work.
+type phaseBreakdown struct {
+	wall time.Duration
+	busy time.Duration
+	peak int
+}
+
+// utilization returns the share of the phase's peak request slots that were busy, from 0 to 1
+func (b phaseBreakdown) utilization() float64 {
+	if b.wall <= 0 || b.peak == 0 {
+		return 0
+	}
+	return b.busy.Seconds() / (b.wall.Seconds() * float64(b.peak))
+}
+
+// String formats the breakdown on a single line
+func (b phaseBreakdown) String() string {
+	inFlight := 0.0
+	if b.wall > 0 {
+		inFlight = b.busy.Seconds() / b.wall.Seconds()
+	}
+	return fmt.Sprintf("%v, busy %v, %.2f of peak %d requests in flight (%.1f%% utilized)",
+		b.wall.Round(time.Millisecond), b.busy.Round(time.Millisecond), inFlight, b.peak, b.utilization()*100)
+}
+
+// tenantBreakdown is the wall-clock time a tenant's requests spanned
+type tenantBreakdown struct {
+	tenant   string
+	first    time.Time
+	wall     time.Duration
+	busy     time.Duration
+	requests int
+}
+
+// StartRequest counts a request as in flight in the current phase and, if it is known, for the
+// worker sending it
+func (ts *TestStats) StartRequest(worker *workerTime) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if worker != nil {
+		ts.startWorkerRequest(worker, time.Now())
+	}
+	ts.inFlight++
+	if pt := ts.phaseTimes[ts.Phase]; pt != nil && ts.inFlight > pt.peak {
+		pt.peak = ts.inFlight
+	}
+}
+
+// FinishRequest counts a request as no longer in flight and adds its time to the worker that sent
+// it and the tenant it was sent to, if they are known
+func (ts *TestStats) FinishRequest(worker *workerTime, tenant string, start time.Time, duration time.Duration) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if worker != nil {
+		ts.finishWorkerRequest(worker, start.Add(duration))
+	}
+	ts.inFlight--
+	if tenant == "" {
+		return
+	}
+
+	tt, ok := ts.tenantTimes[tenant]
+	if !ok {
+		tt = &tenantTime{first: start}
+		ts.tenantTimes[tenant] = tt
+	}
+	if start.Before(tt.first) {
+		tt.first = start
+	}
+	if end := start.Add(duration); end.After(tt.last) {
+		tt.last = end
+	}
+	tt.busy += duration
+	tt.requests++
+}
+
+// enterPhase adds the time since the current phase was entered to it and enters the given phase.
+// Called with the lock held.
+func (ts *TestStats) enterPhase(phase string, now time.Time) {
+	if pt := ts.phaseTimes[ts.Phase]; pt != nil {
+		pt.wall += now.Sub(ts.phaseEntered)
+	}
+	ts.phaseEntered = now
+
+	pt, ok := ts.phaseTimes[phase]
+	if !ok {
+		pt = &phaseTime{}
+		ts.phaseTimes[phase] = pt
+	}
+	if ts.inFlight > pt.peak {
+		pt.peak = ts.inFlight
+	}
+}
+
+// phaseBreakdown returns where a phase spent its wall-clock time up to now, counting the current
+// phase as running until now. Called with the lock held.
+func (ts *TestStats) phaseBreakdown(phase string, now time.Time) phaseBreakdown {
+	b := phaseBreakdown{}
+	if pt := ts.phaseTimes[phase]; pt != nil {
+		b.wall, b.peak = pt.wall, pt.peak
+	}
+	if phase == ts.Phase {
+		b.wall += now.Sub(ts.phaseEntered)
+	}
+	for _, latency := range ts.PhaseLatencies[phase] {
+		b.busy += latency
+	}
+	return b
+}
+
+// tenantBreakdowns returns the wall-clock time of every tenant, longest first. Called with the lock
+// held.
+func (ts *TestStats) tenantBreakdowns() []tenantBreakdown {
+	tenants := make([]tenantBreakdown, 0, len(ts.tenantTimes))
+	for tenant, tt := range ts.tenantTimes {
+		tenants = append(tenants, tenantBreakdown{
+			tenant:   tenant,
+			first:    tt.first,
+			wall:     tt.last.Sub(tt.first),
+			busy:     tt.busy,
+			requests: tt.requests,
+		})
+	}
+	sort.Slice(tenants, func(i, j int) bool {
+		if tenants[i].wall != tenants[j].wall {
+			return tenants[i].wall > tenants[j].wall
+		}
+		return tenants[i].tenant < tenants[j].tenant
+	})
+	return tenants
+}
+
+// printTimeBreakdown prints the wall-clock time of every phase and of the slowest tenants. Called
+// with the lock held.
+func (ts *TestStats) printTimeBreakdown() {
+	now := time.Now()
+
+	var total time.Duration
+	phases := make([]phaseBreakdown, len(ts.PhaseOrder))
+	for i, phase := range ts.PhaseOrder {
+		phases[i] = ts.phaseBreakdown(phase, now)
+		total += phases[i].wall
+	}
+	if total > 0 {
+		fmt.Println("Wall-Clock Time By Phase:")
+		for i, phase := range ts.PhaseOrder {
+			// Phases that only mark the start of a mode, without requests, take no measurable time
+			if phases[i].busy == 0 && phases[i].wall < time.Millisecond {
+				continue
+			}
+			share := phases[i].wall.Seconds() / total.Seconds() * 100
+			fmt.Printf("  %s - %.1f%% of %v: %s\n", phase, share, total.Round(time.Millisecond), phases[i])
+		}
+	}
+
+	tenants := ts.tenantBreakdowns()
+	if len(tenants) == 0 {
+		return
+	}
+	start := tenants[0].first
+	for _, tenant := range tenants {
+		if tenant.first.Before(start) {
+			start = tenant.first
+		}
+	}
+
+	shown := tenants
+	if len(shown) > slowestTenants {
+		shown = shown[:slowestTenants]
+	}
+	fmt.Printf("Wall-Clock Time By Tenant (%d longest of %d):\n", len(shown), len(tenants))
+	for _, tenant := range shown {
+		fmt.Printf("  %s - %v from +%v, busy %v, %d requests\n", tenant.tenant, tenant.wall.Round(time.Millisecond),
+			tenant.first.Sub(start).Round(time.Millisecond), tenant.busy.Round(time.Millisecond), tenant.requests)
+	}
+}
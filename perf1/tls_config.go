@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tlsVersions maps the accepted tlsMinVersion values to TLS versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newTLSConfig creates the TLS configuration of the connections to the server. Certificates are
+// only verified with tlsVerify, against the system roots and the CA bundle, if one is given, so
+// the client keeps working against servers with self-signed certificates by default. A client
+// certificate, if one is configured, is presented for mutual TLS.
+func newTLSConfig(server ServerConfig) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: !server.TLSVerify}
+
+	if server.TLSCACertFile != "" {
+		if !server.TLSVerify {
+			printWarning("tlsCaCertFile %s is not used because tlsVerify is off\n", server.TLSCACertFile)
+		}
+
+		pem, err := os.ReadFile(server.TLSCACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA bundle %s", server.TLSCACertFile)
+		}
+		config.RootCAs = roots
+	}
+
+	if server.TLSMinVersion != "" {
+		version, ok := tlsVersions[server.TLSMinVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid tlsMinVersion %q, expected 1.0, 1.1, 1.2 or 1.3", server.TLSMinVersion)
+		}
+		config.MinVersion = version
+	}
+
+	if server.TLSClientCertFile != "" {
+		certificate, err := loadClientCertificate(server.TLSClientCertFile, server.TLSClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		// Presented whatever CAs the server asks for, since gateways often list only some of the
+		// issuers they accept, and the standard selection then sends no certificate at all
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certificate, nil
+		}
+	} else if server.TLSClientKeyFile != "" {
+		return nil, fmt.Errorf("tlsClientKeyFile is set without tlsClientCertFile")
+	}
+
+	if len(server.TLSCipherSuites) > 0 {
+		suites, err := tlsCipherSuiteIDs(server.TLSCipherSuites)
+		if err != nil {
+			return nil, err
+		}
+		config.CipherSuites = suites
+	}
+
+	return config, nil
+}
+
+// loadClientCertificate loads the client certificate for mutual TLS, taking the key from the
+// certificate file when no key file is given. Expired certificates are refused up front, as the
+// server would refuse every handshake.
+func loadClientCertificate(certFile, keyFile string) (*tls.Certificate, error) {
+	if keyFile == "" {
+		keyFile = certFile
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client certificate: %v", err)
+	}
+	if now := time.Now(); now.After(leaf.NotAfter) {
+		return nil, fmt.Errorf("client certificate %s expired on %s", certFile, leaf.NotAfter.Format(time.RFC3339))
+	} else if now.Before(leaf.NotBefore) {
+		return nil, fmt.Errorf("client certificate %s is not valid before %s", certFile, leaf.NotBefore.Format(time.RFC3339))
+	}
+	certificate.Leaf = leaf
+	return &certificate, nil
+}
+
+// tlsCipherSuiteIDs looks up cipher suites by their IANA names, e.g.
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. Suites Go considers insecure are accepted too, so their
+// cost can be measured. Go does not allow choosing the TLS 1.3 suites, so they are always enabled.
+func tlsCipherSuiteIDs(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			available := make([]string, 0, len(known))
+			for suite := range known {
+				available = append(available, suite)
+			}
+			sort.Strings(available)
+			return nil, fmt.Errorf("unknown cipher suite %q, available suites: %s", name, strings.Join(available, ", "))
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// tokenStats holds the outcome of token requests and the validation of the issued JWTs
+type tokenStats struct {
+	issued           int
+	requestFailed    int
+	validated        int
+	validationFailed int
+	failuresByReason map[string]int
+	accessTokenSizes []int
+	idTokenSizes     []int
+	requestLatencies []time.Duration
+	mutex            sync.Mutex
+}
+
+// jwksCache holds the key set of each tenant, fetched once per run
+type jwksCache struct {
+	keys  map[int]*JWKS
+	mutex sync.Mutex
+}
+
+// ExecuteTokenPhase obtains tokens for the created users with the configured grant and validates
+// the issued JWTs, reporting validation failures separately from request failures
+func (te *TestExecutor) ExecuteTokenPhase() error {
+	fmt.Println("Starting token phase...")
+	fmt.Printf("- Users: %d\n", te.config.Execution.NoOfUsers)
+	fmt.Printf("- Tenants: %d\n", te.config.Execution.NoOfTenants)
+	fmt.Printf("- Grant: %s\n", te.config.OAuth.Grant)
+	if te.config.OAuth.Grant == "authorization_code" {
+		fmt.Printf("- PKCE: %t\n", te.config.OAuth.PKCE)
+	}
+	fmt.Printf("- Validate Tokens: %t\n", te.config.OAuth.ValidateTokens)
+
+	stats := &tokenStats{failuresByReason: make(map[string]int)}
+	cache := &jwksCache{keys: make(map[int]*JWKS)}
+
+	// Calculate users per thread
+	usersPerThread := te.config.Execution.NoOfUsers / te.config.Execution.NoOfThreads
+	remainingUsers := te.config.Execution.NoOfUsers % te.config.Execution.NoOfThreads
+
+	var wg sync.WaitGroup
+	userStart := te.config.Execution.UserStartNumber
+
+	startTime := time.Now()
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		threadUsers := usersPerThread
+		if threadID < remainingUsers {
+			threadUsers++ // Distribute remaining users to first few threads
+		}
+
+		if threadUsers == 0 {
+			continue
+		}
+
+		userEnd := userStart + threadUsers - 1
+
+		task := WorkerTask{
+			UserStart: userStart,
+			UserEnd:   userEnd,
+			ThreadID:  threadID,
+			Client:    te.newHTTPClient(),
+		}
+
+		wg.Add(1)
+		go te.tokenWorker(task, stats, cache, &wg)
+
+		userStart = userEnd + 1
+	}
+
+	wg.Wait()
+
+	elapsed := time.Since(startTime)
+	if te.interrupted() {
+		fmt.Printf("\nToken phase interrupted after %v\n", elapsed)
+		stats.print(elapsed)
+		return ErrInterrupted
+	}
+	fmt.Printf("\nToken phase completed in %v\n", elapsed)
+
+	stats.print(elapsed)
+
+	return nil
+}
+
+// tokenWorker requests and validates tokens for the assigned user range in all tenants
+func (te *TestExecutor) tokenWorker(task WorkerTask, stats *tokenStats, cache *jwksCache, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	tenantStart := te.config.Execution.TenantStartNumber
+	tenantEnd := tenantStart + te.config.Execution.NoOfTenants
+
+	for userIndex := task.UserStart; userIndex <= task.UserEnd && !te.interrupted(); userIndex++ {
+		for tenantIndex := tenantStart; tenantIndex < tenantEnd && !te.interrupted(); tenantIndex++ {
+			username := te.config.GetTestUsername(userIndex)
+
+			requestStart := time.Now()
+			tokenResp, err := te.obtainToken(task.Client, tenantIndex, username)
+			duration := time.Since(requestStart)
+
+			// A request aborted by the interruption is not counted
+			if err != nil && te.interrupted() {
+				return
+			}
+			if err != nil {
+				stats.recordRequestFailure()
+				printFailure("Thread %d: Failed to obtain token for user %s in tenant %d: %v\n",
+					task.ThreadID, username, tenantIndex, err)
+				continue
+			}
+
+			stats.recordIssued(tokenResp, duration)
+
+			if te.config.OAuth.ValidateTokens {
+				if err := te.validateTokenResponse(task.Client, cache, tenantIndex, tokenResp); err != nil {
+					stats.recordValidation(err)
+					fmt.Printf("Thread %d: Invalid token for user %s in tenant %d: %v\n",
+						task.ThreadID, username, tenantIndex, err)
+				} else {
+					stats.recordValidation(nil)
+				}
+			}
+		}
+	}
+}
+
+// obtainToken obtains a token for a user with the configured grant
+func (te *TestExecutor) obtainToken(client *HTTPClient, tenantIndex int, username string) (*TokenResponse, error) {
+	password := te.userPassword(tenantIndex, username)
+	switch te.config.OAuth.Grant {
+	case "authorization_code":
+		// Every login starts from a fresh browser with no existing session
+		return client.AuthorizationCodeLogin(te.ctx, client.newBrowserSession(), tenantIndex, username, password)
+	case "device_code":
+		return client.DeviceCodeLogin(te.ctx, tenantIndex, username, password)
+	default:
+		return client.RequestPasswordGrant(te.ctx, tenantIndex, username, password)
+	}
+}
+
+// validateTokenResponse validates the access token, when it is a JWT, and the ID token of a token response
+func (te *TestExecutor) validateTokenResponse(client *HTTPClient, cache *jwksCache, tenantIndex int, tokenResp *TokenResponse) error {
+	keys, err := cache.get(te.ctx, client, tenantIndex)
+	if err != nil {
+		return &JWTValidationError{"jwks_unavailable", err.Error()}
+	}
+
+	if IsJWT(tokenResp.AccessToken) {
+		if _, err := ValidateJWT(tokenResp.AccessToken, keys, te.config.OAuth.RequiredClaims); err != nil {
+			return err
+		}
+	}
+
+	if tokenResp.IDToken != "" {
+		if _, err := ValidateJWT(tokenResp.IDToken, keys, te.config.OAuth.RequiredClaims); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// get returns the key set of a tenant, fetching it on first use
+func (c *jwksCache) get(ctx context.Context, client *HTTPClient, tenantIndex int) (*JWKS, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if keys, ok := c.keys[tenantIndex]; ok {
+		return keys, nil
+	}
+
+	keys, err := client.FetchJWKS(ctx, tenantIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	c.keys[tenantIndex] = keys
+	return keys, nil
+}
+
+// recordRequestFailure records a token request that did not produce a token
+func (ts *tokenStats) recordRequestFailure() {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	ts.requestFailed++
+}
+
+// recordIssued records an issued token with its sizes and request latency
+func (ts *tokenStats) recordIssued(tokenResp *TokenResponse, duration time.Duration) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	ts.issued++
+	ts.accessTokenSizes = append(ts.accessTokenSizes, len(tokenResp.AccessToken))
+	if tokenResp.IDToken != "" {
+		ts.idTokenSizes = append(ts.idTokenSizes, len(tokenResp.IDToken))
+	}
+	ts.requestLatencies = append(ts.requestLatencies, duration)
+}
+
+// recordValidation records the outcome of validating an issued token
+func (ts *tokenStats) recordValidation(err error) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if err == nil {
+		ts.validated++
+		return
+	}
+
+	ts.validationFailed++
+
+	var validationErr *JWTValidationError
+	if errors.As(err, &validationErr) {
+		ts.failuresByReason[validationErr.Reason]++
+	} else {
+		ts.failuresByReason["other"]++
+	}
+}
+
+// print prints the token phase summary
+func (ts *tokenStats) print(elapsed time.Duration) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	fmt.Println("\n=== Token Statistics ===")
+	fmt.Printf("Token Requests - Total: %d, Issued: %d, Failed: %d, Rate: %.2f/s\n",
+		ts.issued+ts.requestFailed, ts.issued, ts.requestFailed, float64(ts.issued)/elapsed.Seconds())
+	if len(ts.requestLatencies) > 0 {
+		fmt.Printf("Token Request Latency - %s\n", SummarizeLatencies(ts.requestLatencies))
+	}
+
+	if ts.validated+ts.validationFailed > 0 {
+		fmt.Printf("Validation - Valid: %d, Invalid: %d\n", ts.validated, ts.validationFailed)
+		for reason, count := range ts.failuresByReason {
+			fmt.Printf("Validation Failure %s: %d\n", reason, count)
+		}
+	}
+
+	printTokenSizes("Access Token", ts.accessTokenSizes)
+	printTokenSizes("ID Token", ts.idTokenSizes)
+	fmt.Println("========================")
+}
+
+// printTokenSizes prints the min/avg/max size in bytes of a set of tokens
+func printTokenSizes(label string, sizes []int) {
+	if len(sizes) == 0 {
+		return
+	}
+
+	minSize, maxSize, total := sizes[0], sizes[0], 0
+	for _, size := range sizes {
+		total += size
+		if size < minSize {
+			minSize = size
+		}
+		if size > maxSize {
+			maxSize = size
+		}
+	}
+
+	fmt.Printf("%s Size (bytes) - Min: %d, Avg: %d, Max: %d\n", label, minSize, total/len(sizes), maxSize)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// updateStats holds the outcome of conditional updates and of the stale-ETag updates that
+// should be rejected by optimistic locking
+type updateStats struct {
+	updated         int
+	updateFailed    int
+	staleRejected   int
+	staleAccepted   int
+	staleFailed     int
+	updateLatencies []time.Duration
+	staleLatencies  []time.Duration
+	mutex           sync.Mutex
+}
+
+// ExecuteUpdatePhase updates the created users with SCIM2 PATCH. Every update carries the user's
+// current ETag in If-Match (or none when conditional updates are disabled, as a baseline for the
+// locking overhead); for the configured fraction of users the update is then repeated with the
+// now stale ETag, which the server must reject with 412 Precondition Failed.
+func (te *TestExecutor) ExecuteUpdatePhase() error {
+	cfg := te.config.Update
+	if cfg.StaleFraction < 0 || cfg.StaleFraction > 1 {
+		return fmt.Errorf("stale fraction must be between 0 and 1, got %v", cfg.StaleFraction)
+	}
+
+	fmt.Println("Starting user update phase...")
+	fmt.Printf("- Users: %d\n", te.config.Execution.NoOfUsers)
+	fmt.Printf("- Tenants: %d\n", te.config.Execution.NoOfTenants)
+	fmt.Printf("- Conditional Updates: %t\n", cfg.Conditional)
+	fmt.Printf("- Stale ETag Fraction: %.2f\n", cfg.StaleFraction)
+
+	stats := &updateStats{}
+
+	// Calculate users per thread
+	usersPerThread := te.config.Execution.NoOfUsers / te.config.Execution.NoOfThreads
+	remainingUsers := te.config.Execution.NoOfUsers % te.config.Execution.NoOfThreads
+
+	var wg sync.WaitGroup
+	userStart := te.config.Execution.UserStartNumber
+
+	startTime := time.Now()
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		threadUsers := usersPerThread
+		if threadID < remainingUsers {
+			threadUsers++ // Distribute remaining users to first few threads
+		}
+
+		if threadUsers == 0 {
+			continue
+		}
+
+		userEnd := userStart + threadUsers - 1
+
+		task := WorkerTask{
+			UserStart: userStart,
+			UserEnd:   userEnd,
+			ThreadID:  threadID,
+			Client:    te.newHTTPClient(),
+		}
+
+		wg.Add(1)
+		go te.updateWorker(task, stats, &wg)
+
+		userStart = userEnd + 1
+	}
+
+	wg.Wait()
+
+	elapsed := time.Since(startTime)
+	if te.interrupted() {
+		fmt.Printf("\nUser update phase interrupted after %v\n", elapsed)
+		stats.print(elapsed)
+		return ErrInterrupted
+	}
+	fmt.Printf("\nUser update phase completed in %v\n", elapsed)
+
+	stats.print(elapsed)
+
+	return nil
+}
+
+// updateWorker updates the assigned user range in all tenants
+func (te *TestExecutor) updateWorker(task WorkerTask, stats *updateStats, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano() + int64(task.ThreadID)))
+
+	tenantStart := te.config.Execution.TenantStartNumber
+	tenantEnd := tenantStart + te.config.Execution.NoOfTenants
+
+	for userIndex := task.UserStart; userIndex <= task.UserEnd && !te.interrupted(); userIndex++ {
+		for tenantIndex := tenantStart; tenantIndex < tenantEnd && !te.interrupted(); tenantIndex++ {
+			username := te.config.GetTestUsername(userIndex)
+
+			scimID, err := task.Client.FindUserID(te.ctx, tenantIndex, username)
+			if err == nil && scimID == "" {
+				err = fmt.Errorf("user not found")
+			}
+
+			var current *UserReadResult
+			if err == nil {
+				current, err = task.Client.GetSCIM2User(te.ctx, tenantIndex, scimID, "")
+			}
+
+			// A request aborted by the interruption is not counted
+			if err != nil && te.interrupted() {
+				return
+			}
+			if err != nil {
+				stats.record(&stats.updateFailed, nil, 0)
+				printFailure("Thread %d: Failed to read user %s for tenant %d: %v\n",
+					task.ThreadID, username, tenantIndex, err)
+				continue
+			}
+
+			etag := ""
+			if te.config.Update.Conditional {
+				etag = current.ETag
+			}
+
+			requestStart := time.Now()
+			result, err := task.Client.UpdateUser(te.ctx, tenantIndex, scimID, etag, updateAttributes())
+			duration := time.Since(requestStart)
+
+			if err != nil && te.interrupted() {
+				return
+			}
+			if err == nil && result.StatusCode == http.StatusPreconditionFailed {
+				err = fmt.Errorf("update with current ETag %s was rejected", etag)
+			}
+			if err != nil {
+				stats.record(&stats.updateFailed, nil, 0)
+				printFailure("Thread %d: Failed to update user %s for tenant %d: %v\n",
+					task.ThreadID, username, tenantIndex, err)
+				continue
+			}
+
+			stats.record(&stats.updated, &stats.updateLatencies, duration)
+
+			if current.ETag == "" || random.Float64() >= te.config.Update.StaleFraction {
+				continue
+			}
+
+			// The update above has moved the user to a new version, so its previous ETag is stale
+			requestStart = time.Now()
+			result, err = task.Client.UpdateUser(te.ctx, tenantIndex, scimID, current.ETag, updateAttributes())
+			duration = time.Since(requestStart)
+
+			if err != nil && te.interrupted() {
+				return
+			}
+			switch {
+			case err != nil:
+				stats.record(&stats.staleFailed, nil, 0)
+				printFailure("Thread %d: Stale update of user %s for tenant %d failed: %v\n",
+					task.ThreadID, username, tenantIndex, err)
+			case result.StatusCode == http.StatusPreconditionFailed:
+				stats.record(&stats.staleRejected, &stats.staleLatencies, duration)
+			default:
+				stats.record(&stats.staleAccepted, nil, 0)
+				fmt.Printf("Thread %d: Stale update of user %s for tenant %d was accepted with status %d\n",
+					task.ThreadID, username, tenantIndex, result.StatusCode)
+			}
+		}
+	}
+}
+
+// updateAttributes returns attributes that differ on every update, so each one creates a new version
+func updateAttributes() map[string]interface{} {
+	return map[string]interface{}{
+		"nickName": fmt.Sprintf("perf-%d", time.Now().UnixNano()),
+	}
+}
+
+// record increments a counter and, when given, appends the latency to a sample list
+func (us *updateStats) record(counter *int, latencies *[]time.Duration, duration time.Duration) {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	*counter++
+	if latencies != nil {
+		*latencies = append(*latencies, duration)
+	}
+}
+
+// print prints the update phase summary
+func (us *updateStats) print(elapsed time.Duration) {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	fmt.Println("\n=== Update Statistics ===")
+	fmt.Printf("Updates - Total: %d, Successful: %d, Failed: %d, Rate: %.2f/s\n",
+		us.updated+us.updateFailed, us.updated, us.updateFailed, float64(us.updated)/elapsed.Seconds())
+	if len(us.updateLatencies) > 0 {
+		fmt.Printf("Update Latency - %s\n", SummarizeLatencies(us.updateLatencies))
+	}
+
+	if stale := us.staleRejected + us.staleAccepted + us.staleFailed; stale > 0 {
+		fmt.Printf("Stale ETag Updates - Total: %d, Rejected (412): %d, Accepted: %d, Failed: %d\n",
+			stale, us.staleRejected, us.staleAccepted, us.staleFailed)
+		if len(us.staleLatencies) > 0 {
+			fmt.Printf("412 Latency - %s\n", SummarizeLatencies(us.staleLatencies))
+		}
+		if us.staleAccepted > 0 {
+			fmt.Println("WARNING: stale updates were accepted; the server is not enforcing If-Match")
+		}
+	}
+	fmt.Println("=========================")
+}
@@ -6,38 +6,67 @@ import (
 	"time"
 )
 
+// UserRange is a range of user creations: the users from NextUser to UserEnd in the tenants from
+// TenantStart to TenantEnd, except that NextUser itself starts at NextTenant
+type UserRange struct {
+	Pool        int `json:"pool"` // the worker pool that creates the range
+	NextUser    int `json:"nextUser"`
+	UserEnd     int `json:"userEnd"`
+	NextTenant  int `json:"nextTenant"`
+	TenantStart int `json:"tenantStart"`
+	TenantEnd   int `json:"tenantEnd"` // exclusive
+}
+
+// userJob is the creation of one user in one tenant
+type userJob struct {
+	TenantIndex int
+	UserIndex   int
+}
+
+// userPool is a set of threads taking the users of its ranges from a shared queue, so threads held
+// up by slow requests do not leave users waiting that idle threads could create
+type userPool struct {
+	ID      int
+	Threads int
+	Ranges  []UserRange
+}
+
 // ExecuteUserCreation creates users using multiple threads
 func (te *TestExecutor) ExecuteUserCreation() error {
 	fmt.Println("Starting user creation phase...")
 	
-	// Calculate users per thread
-	usersPerThread := te.config.Execution.NoOfUsers / te.config.Execution.NoOfThreads
-	remainingUsers := te.config.Execution.NoOfUsers % te.config.Execution.NoOfThreads
-	
-	// Create worker tasks
-	var tasks []WorkerTask
-	userStart := te.config.Execution.UserStartNumber
+	// A pipelined run is checkpointed as a whole, as the roles may not be complete yet either
+	phase := "users"
+	if te.roleGate != nil {
+		phase = phaseRolesAndUsers
+	}
 	
-	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
-		threadUsers := usersPerThread
-		if remainingUsers > 0 {
-			threadUsers++ // Distribute remaining users to first few threads
-			remainingUsers--
+	pools := te.userPools()
+	if resumePhase := te.resumePhase(); resumePhase == "users" || resumePhase == phaseRolesAndUsers {
+		// Continue every pool with the users the interrupted run left uncreated
+		for i := range pools {
+			pools[i].Ranges = nil
+		}
+		for _, remaining := range te.resume.Ranges {
+			if remaining.Pool < 0 || remaining.Pool >= len(pools) {
+				return fmt.Errorf("checkpoint range belongs to pool %d, but the configuration has %d pools", remaining.Pool, len(pools))
+			}
+			pools[remaining.Pool].Ranges = append(pools[remaining.Pool].Ranges, remaining)
 		}
 		
-		userEnd := userStart + threadUsers - 1
-		
-		// Create a separate HTTP client for this task
-		taskClient := NewHTTPClient(te.config)
-		
-		tasks = append(tasks, WorkerTask{
-			UserStart:   userStart,
-			UserEnd:     userEnd,
-			ThreadID:    threadID,
-			Client:      taskClient,
-		})
-		
-		userStart = userEnd + 1
+		fmt.Printf("Resuming %d unfinished user ranges\n", len(te.resume.Ranges))
+	}
+	
+	totalThreads := 0
+	for _, pool := range pools {
+		if len(pool.Ranges) > 0 {
+			totalThreads += pool.Threads
+		}
+	}
+	
+	if totalThreads == 0 {
+		fmt.Println("No tenants to create users in")
+		return nil
 	}
 	
 	// Create wait group and result channel
@@ -46,20 +75,61 @@ func (te *TestExecutor) ExecuteUserCreation() error {
 	resultChan := make(chan TestResult, totalResults)
 	
 	// Start result processor
-	go te.processResults(resultChan)
+	resultsDone := make(chan struct{})
+	go te.processResults(resultChan, resultsDone)
 	
-	// Apply ramp-up delay between thread starts
-	rampUpDelay := time.Duration(te.config.Execution.RampUpPeriod) * time.Second / time.Duration(te.config.Execution.NoOfThreads)
-
-	// Start worker goroutines
+	// With a ramp profile its steps start and stop the threads of the single pool instead of the
+	// ramp-up, and the phase ends with the last step
+	var steps *stepLoad
+	if len(te.rampSteps) > 0 {
+		pools[0].Threads = rampProfileThreads(te.rampSteps)
+		steps = te.startStepLoad(te.rampSteps)
+	}
+	
+	// With an arrival rate the users arrive as an open workload instead of being taken by threads
+	var arrivals *arrivalGenerator
+	if te.config.Execution.ArrivalRate > 0 {
+		arrivals = te.newArrivalGenerator()
+	}
+	
+	// Start a queue per pool and its worker goroutines; each worker delays its own start to apply
+	// the ramp-up
 	startTime := time.Now()
-	for _, task := range tasks {
-		wg.Add(1)
-		go te.userCreationWorker(task, resultChan, &wg)
+	threadID := 0
+	for _, pool := range pools {
+		if len(pool.Ranges) == 0 {
+			continue
+		}
+		
+		jobs := make(chan userJob)
+		var stop <-chan struct{}
+		if steps != nil {
+			stop = steps.stop
+		}
+		go te.queueUserJobs(pool, jobs, stop)
 		
-		// Ramp-up delay
-		if rampUpDelay > 0 {
-			time.Sleep(rampUpDelay)
+		if arrivals != nil {
+			wg.Add(1)
+			go arrivals.run(pool.ID, jobs, resultChan, &wg)
+			continue
+		}
+		
+		for i := 0; i < pool.Threads; i++ {
+			task := WorkerTask{
+				ThreadID:   threadID,
+				Client:     te.newHTTPClient(),
+				StartDelay: te.rampUpStartDelay(threadID, totalThreads),
+			}
+			
+			threadJobs := (<-chan userJob)(jobs)
+			if steps != nil {
+				task.StartDelay = 0
+				threadJobs = steps.gate(te.ctx, threadID, jobs)
+			}
+			threadID++
+			
+			wg.Add(1)
+			go te.userCreationWorker(task, pool.ID, threadJobs, resultChan, &wg)
 		}
 	}
 	
@@ -67,55 +137,218 @@ func (te *TestExecutor) ExecuteUserCreation() error {
 	wg.Wait()
 	close(resultChan)
 	
+	// Wait for the result processor to drain the channel before reporting
+	<-resultsDone
+	
+	if steps != nil {
+		if !steps.Stop() && !te.interrupted() {
+			printWarning("All users were created before the ramp profile ended; raise noOfUsers to run every step in full\n")
+		}
+		steps.print()
+	}
+	if arrivals != nil {
+		arrivals.print()
+	}
+	
+	if te.interrupted() {
+		printWarning("User creation interrupted after %v\n", time.Since(startTime))
+		return te.saveCheckpoint(phase, te.unfinished)
+	}
+	
 	duration := time.Since(startTime)
 	fmt.Printf("User creation completed in %v\n", duration)
+	
+	if te.limiter != nil {
+		fmt.Printf("Achieved throughput: %.2f users/s (target %.2f)\n",
+			float64(totalResults)/duration.Seconds(), te.config.Execution.TargetTPS)
+	}
 	return nil
 }
 
-// userCreationWorker creates users for all tenants within the assigned user range
-func (te *TestExecutor) userCreationWorker(task WorkerTask, resultChan chan<- TestResult, wg *sync.WaitGroup) {
-	defer wg.Done()
+// userPools divides the threads into worker pools: a single pool creating the configured users in
+// all tenants, or with parallel tenants one pool per tenant so all tenants are loaded concurrently
+func (te *TestExecutor) userPools() []userPool {
+	tenantStart := te.config.Execution.TenantStartNumber
+	tenantEnd := tenantStart + te.config.Execution.NoOfTenants
+	if tenantEnd <= tenantStart {
+		return nil
+	}
 	
-	startTime := time.Now()
-	fmt.Printf("Thread %d: Creating users %d-%d for all tenants\n", 
-		task.ThreadID, task.UserStart, task.UserEnd)
-	
-	for userIndex := task.UserStart; userIndex <= task.UserEnd; userIndex++ {
-		// Create this user for all tenants
-		for tenantIndex := te.config.Execution.TenantStartNumber; tenantIndex < te.config.Execution.TenantStartNumber+te.config.Execution.NoOfTenants; tenantIndex++ {
-			result := TestResult{
-				TenantIndex: tenantIndex,
-				UserIndex:   userIndex,
-				ThreadID:    task.ThreadID,
+	users := func(pool, tenantStart, tenantEnd int) []UserRange {
+		userStart := te.config.Execution.UserStartNumber
+		return []UserRange{{
+			Pool:        pool,
+			NextUser:    userStart,
+			UserEnd:     userStart + te.config.Execution.NoOfUsers - 1,
+			NextTenant:  tenantStart,
+			TenantStart: tenantStart,
+			TenantEnd:   tenantEnd,
+		}}
+	}
+	
+	if !te.config.Execution.ParallelTenants {
+		return []userPool{{ID: 0, Threads: te.config.Execution.NoOfThreads, Ranges: users(0, tenantStart, tenantEnd)}}
+	}
+	
+	// Each tenant gets its own pool of threads covering the full user range, so every thread sends
+	// requests to a single tenant and all tenants are loaded concurrently
+	noOfTenants := te.config.Execution.NoOfTenants
+	threadsPerTenant := te.config.Execution.NoOfThreads / noOfTenants
+	remainingThreads := te.config.Execution.NoOfThreads % noOfTenants
+	
+	var pools []userPool
+	threads := 0
+	for tenantIndex := tenantStart; tenantIndex < tenantEnd; tenantIndex++ {
+		poolThreads := threadsPerTenant
+		if tenantIndex-tenantStart < remainingThreads {
+			poolThreads++ // Distribute remaining threads to first few tenants
+		}
+		if poolThreads == 0 {
+			poolThreads = 1 // Every tenant needs at least one thread
+		}
+		
+		id := len(pools)
+		pools = append(pools, userPool{ID: id, Threads: poolThreads, Ranges: users(id, tenantIndex, tenantIndex+1)})
+		threads += poolThreads
+	}
+	
+	fmt.Printf("Parallel tenants: %d threads across %d tenant pools\n", threads, noOfTenants)
+	return pools
+}
+
+// queueUserJobs feeds the users of the pool's ranges to its threads in order, closing the queue once
+// all are taken or stop is closed. When the run is interrupted it stops and records the users not
+// yet taken.
+func (te *TestExecutor) queueUserJobs(pool userPool, jobs chan<- userJob, stop <-chan struct{}) {
+	defer close(jobs)
+	
+	for i, users := range pool.Ranges {
+		for userIndex := users.NextUser; userIndex <= users.UserEnd; userIndex++ {
+			// Create this user for all of the range's tenants
+			tenantStart := users.TenantStart
+			if userIndex == users.NextUser {
+				tenantStart = users.NextTenant
 			}
 			
-			userResp, err := task.Client.CreateUser(tenantIndex, userIndex)
-			if err != nil {
-				result.Success = false
-				result.Error = err
-				
-				// Generate the username that was attempted
-				username := te.config.GetTestUsername(userIndex)
-				
-				// Write failed user to CSV file (only if not in retry mode)
-				if te.failedUsersWriter != nil {
-					timestamp := time.Now().Format("2006-01-02 15:04:05")
-					if csvErr := te.failedUsersWriter.WriteFailedUser(tenantIndex, username, err.Error(), timestamp); csvErr != nil {
-						fmt.Printf("Thread %d: Failed to write failed user (Tenant:%d, Username:%s) to CSV: %v\n", task.ThreadID, tenantIndex, username, csvErr)
+			for tenantIndex := tenantStart; tenantIndex < users.TenantEnd; tenantIndex++ {
+				select {
+				case jobs <- userJob{TenantIndex: tenantIndex, UserIndex: userIndex}:
+				case <-stop:
+					return
+				case <-te.ctx.Done():
+					remaining := users
+					remaining.NextUser = userIndex
+					remaining.NextTenant = tenantIndex
+					te.recordUnfinished(remaining)
+					
+					for _, rest := range pool.Ranges[i+1:] {
+						te.recordUnfinished(rest)
 					}
+					return
 				}
-				
-				fmt.Printf("Thread %d: Failed to create user %d for tenant %d: %v\n", 
-					task.ThreadID, userIndex, tenantIndex, err)
-			} else {
-				result.Success = true
-				result.ScimID = userResp.ID
 			}
-			
-			resultChan <- result
+		}
+	}
+}
+
+// userCreationWorker creates the users it takes from its pool's queue until the queue is closed
+func (te *TestExecutor) userCreationWorker(task WorkerTask, pool int, jobs <-chan userJob, resultChan chan<- TestResult, wg *sync.WaitGroup) {
+	defer wg.Done()
+	
+	waitForRampUp(te.ctx, task.StartDelay)
+	
+	startTime := time.Now()
+	fmt.Printf("Thread %d: Creating users from the queue of pool %d\n", task.ThreadID, pool)
+	
+	completed := 0
+	for job := range jobs {
+		if te.createQueuedUser(task.Client, task.ThreadID, pool, job, resultChan) {
+			completed++
 		}
 	}
 	
 	duration := time.Since(startTime)
-	fmt.Printf("Thread %d: Completed users %d-%d for all tenants in %v\n", task.ThreadID, task.UserStart, task.UserEnd, duration)
-}
\ No newline at end of file
+	fmt.Printf("Thread %d: Completed %d users in %v\n", task.ThreadID, completed, duration)
+}
+
+// createQueuedUser creates a user taken from a pool's queue and passes on the result. It returns
+// false when the user is left for the resumed run because the run was interrupted.
+func (te *TestExecutor) createQueuedUser(client *HTTPClient, threadID, pool int, job userJob, resultChan chan<- TestResult) bool {
+	tenantIndex, userIndex := job.TenantIndex, job.UserIndex
+	
+	// Leave the users taken after the interruption to the resumed run
+	if te.interrupted() {
+		te.recordUnfinishedJob(pool, job)
+		return false
+	}
+	
+	result := TestResult{
+		TenantIndex: tenantIndex,
+		UserIndex:   userIndex,
+		ThreadID:    threadID,
+	}
+	
+	// Park the tenant's users until its role exists, if the phases are pipelined, fail them if the
+	// role could not be created, and park them until the tenant answers the readiness probe, if
+	// probing is enabled
+	err := te.roleGate.Wait(te.ctx, tenantIndex)
+	if err == nil {
+		err = te.roleMissing(tenantIndex)
+	}
+	if err == nil {
+		err = te.readiness.Wait(te.ctx, client, tenantIndex)
+	}
+	
+	var userResp *SCIMUserResponse
+	if err == nil {
+		// Pace requests to the target throughput, if one is set
+		client.pace(te.ctx, te.limiter)
+		requestStart := time.Now()
+		userResp, err = client.CreateUser(te.ctx, tenantIndex, userIndex)
+		duration := time.Since(requestStart)
+		te.recordPasswordPolicy("user creation", tenantIndex, te.config.GetTestUsername(userIndex), duration, err)
+		te.recordPasswordMode(tenantIndex, te.config.GetTestUsername(userIndex), duration, err)
+	}
+	
+	// A request aborted by the interruption is left for the resumed run rather than counted
+	if err != nil && te.interrupted() {
+		te.recordUnfinishedJob(pool, job)
+		return false
+	}
+	
+	if err != nil {
+		result.Success = false
+		result.Error = err
+		
+		// Generate the username that was attempted
+		username := te.config.GetTestUsername(userIndex)
+		
+		// Write failed user to CSV file (only if not in retry mode)
+		if te.failedUsersWriter != nil {
+			if csvErr := te.failedUsersWriter.WriteFailedUser(newFailedUser(tenantIndex, username, err)); csvErr != nil {
+				printFailure("Thread %d: Failed to write failed user (Tenant:%d, Username:%s) to CSV: %v\n", threadID, tenantIndex, username, csvErr)
+			}
+		}
+		
+		printFailure("Thread %d: Failed to create user %d for tenant %d: %v\n",
+			threadID, userIndex, tenantIndex, err)
+	} else {
+		result.Success = true
+		result.ScimID = userResp.ID
+	}
+	
+	resultChan <- result
+	return true
+}
+
+// recordUnfinishedJob records a single user left uncreated by an interruption
+func (te *TestExecutor) recordUnfinishedJob(pool int, job userJob) {
+	te.recordUnfinished(UserRange{
+		Pool:        pool,
+		NextUser:    job.UserIndex,
+		UserEnd:     job.UserIndex,
+		NextTenant:  job.TenantIndex,
+		TenantStart: job.TenantIndex,
+		TenantEnd:   job.TenantIndex + 1,
+	})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ExecuteUserRead reads the users created by a previous run using multiple threads
+func (te *TestExecutor) ExecuteUserRead() error {
+	fmt.Println("Starting user read phase...")
+	fmt.Printf("- Passes: %d\n", te.config.Read.Passes)
+	fmt.Printf("- Conditional Requests: %t\n", te.config.Read.ConditionalRequests)
+	fmt.Printf("- Cache Busting: %t\n", te.config.Read.CacheBusting)
+
+	// Calculate users per thread
+	usersPerThread := te.config.Execution.NoOfUsers / te.config.Execution.NoOfThreads
+	remainingUsers := te.config.Execution.NoOfUsers % te.config.Execution.NoOfThreads
+
+	var wg sync.WaitGroup
+	userStart := te.config.Execution.UserStartNumber
+
+	startTime := time.Now()
+	for threadID := 0; threadID < te.config.Execution.NoOfThreads; threadID++ {
+		threadUsers := usersPerThread
+		if threadID < remainingUsers {
+			threadUsers++ // Distribute remaining users to first few threads
+		}
+
+		if threadUsers == 0 {
+			continue
+		}
+
+		userEnd := userStart + threadUsers - 1
+
+		task := WorkerTask{
+			UserStart: userStart,
+			UserEnd:   userEnd,
+			ThreadID:  threadID,
+			Client:    te.newHTTPClient(),
+		}
+
+		wg.Add(1)
+		go te.userReadWorker(task, &wg)
+
+		userStart = userEnd + 1
+	}
+
+	wg.Wait()
+
+	if te.interrupted() {
+		return ErrInterrupted
+	}
+
+	duration := time.Since(startTime)
+	fmt.Printf("User read completed in %v\n", duration)
+
+	te.stats.PrintStats()
+
+	return nil
+}
+
+// userReadWorker reads the assigned user range for all tenants, once per configured pass.
+// The first pass resolves SCIM IDs and collects ETags; later passes send them back as
+// If-None-Match when conditional requests are enabled.
+func (te *TestExecutor) userReadWorker(task WorkerTask, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	fmt.Printf("Thread %d: Reading users %d-%d for all tenants\n", task.ThreadID, task.UserStart, task.UserEnd)
+
+	type userKey struct {
+		tenantIndex int
+		userIndex   int
+	}
+	scimIDs := make(map[userKey]string)
+	etags := make(map[userKey]string)
+
+	tenantStart := te.config.Execution.TenantStartNumber
+	tenantEnd := tenantStart + te.config.Execution.NoOfTenants
+
+	for pass := 0; pass < te.config.Read.Passes; pass++ {
+		for userIndex := task.UserStart; userIndex <= task.UserEnd && !te.interrupted(); userIndex++ {
+			for tenantIndex := tenantStart; tenantIndex < tenantEnd && !te.interrupted(); tenantIndex++ {
+				key := userKey{tenantIndex, userIndex}
+
+				scimID, ok := scimIDs[key]
+				if !ok {
+					id, err := task.Client.FindUserID(te.ctx, tenantIndex, te.config.GetTestUsername(userIndex))
+					if err == nil && id == "" {
+						err = fmt.Errorf("user not found")
+					}
+					if err != nil && te.interrupted() {
+						break
+					}
+					if err != nil {
+						te.stats.RecordRead(0, 0, false)
+						printFailure("Thread %d: Failed to resolve user %d for tenant %d: %v\n",
+							task.ThreadID, userIndex, tenantIndex, err)
+						continue
+					}
+					scimID = id
+					scimIDs[key] = scimID
+				}
+
+				etag := ""
+				if te.config.Read.ConditionalRequests {
+					etag = etags[key]
+				}
+
+				requestStart := time.Now()
+				result, err := task.Client.GetUser(te.ctx, tenantIndex, scimID, etag)
+				duration := time.Since(requestStart)
+
+				// A request aborted by the interruption is not counted
+				if err != nil && te.interrupted() {
+					break
+				}
+				if err != nil {
+					te.stats.RecordRead(0, duration, false)
+					printFailure("Thread %d: Failed to read user %d for tenant %d: %v\n",
+						task.ThreadID, userIndex, tenantIndex, err)
+					continue
+				}
+
+				te.stats.RecordRead(result.StatusCode, duration, true)
+				etags[key] = result.ETag
+			}
+		}
+	}
+
+	fmt.Printf("Thread %d: Completed reading users %d-%d\n", task.ThreadID, task.UserStart, task.UserEnd)
+}
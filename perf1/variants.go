@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// Payload variants a user can be selected for
+const (
+	variantAttributes = "attributes"
+	variantRoles      = "roles"
+	variantLocked     = "locked"
+)
+
+// selected reports whether the user is among the given percentage of users that get a variant. The
+// choice is a hash of the user, so a retried or resumed user gets the same payload as before.
+func (c VariantConfig) selected(variant string, tenantIndex int, username string, percent float64) bool {
+	if percent <= 0 {
+		return false
+	}
+	if percent >= 100 {
+		return true
+	}
+
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%d/%s/%d/%s", c.Seed, variant, tenantIndex, username)
+	return float64(mix64(hash.Sum64())%10000) < percent*100
+}
+
+// roleNames returns the variant roles created in every tenant, none when no user gets them
+func (c VariantConfig) roleNames() []string {
+	if c.RolesPercent <= 0 {
+		return nil
+	}
+	return c.Roles
+}
+
+// enabled reports whether any user gets a payload variant
+func (c VariantConfig) enabled() bool {
+	return (c.AttributesPercent > 0 && c.Attributes > 0) || len(c.roleNames()) > 0 || c.LockedPercent > 0
+}
+
+// String describes the configured variants, e.g. "20% with 5 extra attributes, 5% locked"
+func (c VariantConfig) String() string {
+	var parts []string
+	if c.AttributesPercent > 0 && c.Attributes > 0 {
+		parts = append(parts, fmt.Sprintf("%g%% with %d extra attributes", c.AttributesPercent, c.Attributes))
+	}
+	if roles := c.roleNames(); len(roles) > 0 {
+		parts = append(parts, fmt.Sprintf("%g%% with roles %s", c.RolesPercent, strings.Join(roles, ",")))
+	}
+	if c.LockedPercent > 0 {
+		parts = append(parts, fmt.Sprintf("%g%% locked", c.LockedPercent))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// newVariantUser builds the payload of a test user with the test role, varied as configured for
+// the user: extra custom attributes with values of its own, the variant roles, a locked account,
+// and the configured locale attributes
+func (h *HTTPClient) newVariantUser(tenantIndex int, username string) SCIMUser {
+	variants := h.config.Variants
+
+	roleNames := append([]string{h.config.GetTenantRoleName(tenantIndex)}, h.config.Test.assignedRoleNames()...)
+	if roles := variants.roleNames(); len(roles) > 0 && variants.selected(variantRoles, tenantIndex, username, variants.RolesPercent) {
+		roleNames = append(roleNames, roles...)
+	}
+
+	var attributes map[string]string
+	if variants.Attributes > 0 && variants.selected(variantAttributes, tenantIndex, username, variants.AttributesPercent) {
+		attributes = make(map[string]string, variants.Attributes)
+		for i := 1; i <= variants.Attributes; i++ {
+			attributes[fmt.Sprintf("%s%d", variants.AttributePrefix, i)] = fmt.Sprintf("%s_%d", username, i)
+		}
+	}
+
+	user := h.newSCIMUser(tenantIndex, username, roleNames, attributes)
+	h.locales.apply(&user, tenantIndex)
+	if variants.selected(variantLocked, tenantIndex, username, variants.LockedPercent) {
+		user.Wso2Extension.AccountLocked = "true"
+	}
+	return user
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+)
+
+// VariableStore holds global variables shared by all workers: counters and sequences that are
+// updated atomically, and string values. Payload templates reach it through template functions,
+// so scenarios can generate globally unique values without coordinating through files.
+type VariableStore struct {
+	counters map[string]*int64
+	values   map[string]string
+	mutex    sync.RWMutex
+
+	// templates caches parsed templates by their text
+	templates sync.Map
+}
+
+// NewVariableStore creates a variable store with the given initial values
+func NewVariableStore(initial map[string]string) *VariableStore {
+	store := &VariableStore{
+		counters: make(map[string]*int64),
+		values:   make(map[string]string),
+	}
+	for key, value := range initial {
+		store.values[key] = value
+	}
+	return store
+}
+
+// counter returns the counter with the given name, creating it at zero on first use
+func (vs *VariableStore) counter(name string) *int64 {
+	vs.mutex.RLock()
+	c, ok := vs.counters[name]
+	vs.mutex.RUnlock()
+	if ok {
+		return c
+	}
+
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	if c, ok = vs.counters[name]; !ok {
+		c = new(int64)
+		vs.counters[name] = c
+	}
+	return c
+}
+
+// Add adds delta to a counter and returns the new value
+func (vs *VariableStore) Add(name string, delta int64) int64 {
+	return atomic.AddInt64(vs.counter(name), delta)
+}
+
+// Counter returns the current value of a counter
+func (vs *VariableStore) Counter(name string) int64 {
+	return atomic.LoadInt64(vs.counter(name))
+}
+
+// Next returns the next value of a sequence, starting at 1. No two calls for the same sequence
+// return the same value, whichever workers make them.
+func (vs *VariableStore) Next(name string) int64 {
+	return vs.Add(name, 1)
+}
+
+// Get returns a value, or an empty string if it is not set
+func (vs *VariableStore) Get(key string) string {
+	vs.mutex.RLock()
+	defer vs.mutex.RUnlock()
+
+	return vs.values[key]
+}
+
+// Set sets a value
+func (vs *VariableStore) Set(key, value string) {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	vs.values[key] = value
+}
+
+// Expand evaluates a payload template against the store. Text without template actions is returned
+// as is. Available functions:
+//
+//	{{seq "name"}}       next value of a sequence
+//	{{add "name" 5}}     add to a counter and return its new value
+//	{{counter "name"}}   current value of a counter
+//	{{get "key"}}        a value
+//	{{set "key" "v"}}    set a value, expanding to nothing
+func (vs *VariableStore) Expand(text string) (string, error) {
+	return vs.ExpandData(text, nil)
+}
+
+// ExpandData evaluates a payload template like Expand, with data available to the template as dot,
+// e.g. {{.ScimID}} for a struct with a ScimID field
+func (vs *VariableStore) ExpandData(text string, data interface{}) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := vs.template(text)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to expand template %q: %v", text, err)
+	}
+	return out.String(), nil
+}
+
+// template returns the parsed template for a text, parsing it on first use
+func (vs *VariableStore) template(text string) (*template.Template, error) {
+	if cached, ok := vs.templates.Load(text); ok {
+		return cached.(*template.Template), nil
+	}
+
+	tmpl, err := template.New("payload").Funcs(template.FuncMap{
+		"seq":     vs.Next,
+		"add":     vs.Add,
+		"counter": vs.Counter,
+		"get":     vs.Get,
+		"set": func(key, value string) string {
+			vs.Set(key, value)
+			return ""
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %q: %v", text, err)
+	}
+
+	vs.templates.Store(text, tmpl)
+	return tmpl, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Build metadata set when the binary is built, e.g.
+//
+//	go build -ldflags "-X main.version=v1.4.0 -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" -o go-perf .
+//
+// The commit is taken from the version control information Go embeds when building from a git
+// checkout, unless it is set too.
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+// BuildInfo identifies the client build that produced a result
+type BuildInfo struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commitTime,omitempty"`
+	Modified   bool   `json:"modified,omitempty"` // built from a checkout with uncommitted changes
+	BuildDate  string `json:"buildDate,omitempty"`
+	GoVersion  string `json:"goVersion"`
+}
+
+// clientBuild is the build of this binary
+var clientBuild = readBuildInfo()
+
+// readBuildInfo combines the metadata set at build time with the version control information
+// embedded by Go
+func readBuildInfo() BuildInfo {
+	build := BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if build.Version == "" && info.Main.Version != "(devel)" {
+			build.Version = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if build.Commit == "" {
+					build.Commit = setting.Value
+				}
+			case "vcs.time":
+				build.CommitTime = setting.Value
+			case "vcs.modified":
+				build.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if build.Version == "" {
+		build.Version = "dev"
+	}
+	return build
+}
+
+// String describes the build on one line, e.g. go-perf v1.4.0 (commit 3d5ff02, built
+// 2026-10-16T09:12:00Z, go1.22.4)
+func (b BuildInfo) String() string {
+	var details []string
+	if b.Commit != "" {
+		commit := b.Commit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		if b.Modified {
+			commit += "-modified"
+		}
+		details = append(details, "commit "+commit)
+	}
+	if b.BuildDate != "" {
+		details = append(details, "built "+b.BuildDate)
+	} else if b.CommitTime != "" {
+		details = append(details, "committed "+b.CommitTime)
+	}
+	details = append(details, b.GoVersion)
+
+	return fmt.Sprintf("go-perf %s (%s)", b.Version, strings.Join(details, ", "))
+}
+
+// runVersionCommand prints the build of the client, as JSON with the json argument
+func runVersionCommand(_ *cliCommand, args []string) error {
+	if len(args) == 0 {
+		fmt.Println(clientBuild)
+		return nil
+	}
+	if len(args) > 1 || args[0] != "json" {
+		return fmt.Errorf("usage: go-perf version [json]")
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(clientBuild)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// VirtualUser is the identity a worker acts as across the steps of a multi-step scenario. It keeps
+// the user's credentials together with everything the server hands back along the way, so that
+// every step after the first runs as the same user with the same session.
+type VirtualUser struct {
+	TenantIndex int
+	Username    string
+	Password    string
+
+	// ScimID is resolved on first use
+	ScimID string
+
+	// Session holds the user's browser cookies, including the server-side login session
+	Session *browserSession
+
+	// Tokens holds the tokens issued by the most recent login
+	Tokens *TokenResponse
+
+	// State holds scenario-specific values carried between steps
+	State map[string]string
+}
+
+// NewVirtualUser creates the virtual user for a test user of a tenant
+func (c *Config) NewVirtualUser(tenantIndex, userIndex int) *VirtualUser {
+	return &VirtualUser{
+		TenantIndex: tenantIndex,
+		Username:    c.GetTestUsername(userIndex),
+		Password:    c.Test.UserPassword,
+		State:       make(map[string]string),
+	}
+}
+
+// newVirtualUser creates the virtual user for a test user of a tenant, with the password of the
+// user's password policy
+func (te *TestExecutor) newVirtualUser(tenantIndex, userIndex int) *VirtualUser {
+	user := te.config.NewVirtualUser(tenantIndex, userIndex)
+	user.Password = te.userPassword(tenantIndex, user.Username)
+	return user
+}
+
+// session returns the user's browser session, starting one on first use
+func (vu *VirtualUser) session(client *HTTPClient) *browserSession {
+	if vu.Session == nil {
+		vu.Session = client.newBrowserSession()
+	}
+	return vu.Session
+}
+
+// Login logs the user in through the authorization code flow in the user's browser session and
+// keeps the issued tokens
+func (vu *VirtualUser) Login(ctx context.Context, client *HTTPClient) error {
+	tokens, err := client.AuthorizationCodeLogin(ctx, vu.session(client), vu.TenantIndex, vu.Username, vu.Password)
+	if err != nil {
+		return err
+	}
+
+	vu.Tokens = tokens
+	return nil
+}
+
+// Logout ends the user's session with OIDC RP-initiated logout and forgets the issued tokens
+func (vu *VirtualUser) Logout(ctx context.Context, client *HTTPClient) error {
+	if vu.Tokens == nil || vu.Tokens.IDToken == "" {
+		return fmt.Errorf("user %s has no ID token to log out with", vu.Username)
+	}
+
+	if err := client.Logout(ctx, vu.session(client), vu.TenantIndex, vu.Tokens.IDToken); err != nil {
+		return err
+	}
+
+	vu.Tokens = nil
+	return nil
+}
+
+// ResolveScimID looks up the user's SCIM ID on first use
+func (vu *VirtualUser) ResolveScimID(ctx context.Context, client *HTTPClient) (string, error) {
+	if vu.ScimID != "" {
+		return vu.ScimID, nil
+	}
+
+	scimID, err := client.FindUserID(ctx, vu.TenantIndex, vu.Username)
+	if err != nil {
+		return "", err
+	}
+	if scimID == "" {
+		return "", fmt.Errorf("user %s not found", vu.Username)
+	}
+
+	vu.ScimID = scimID
+	return scimID, nil
+}
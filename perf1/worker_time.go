@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// workerTime is where a worker, the goroutine behind an HTTP client, spent its time: in requests,
+// waiting for pacing, before its first request, which is mostly its ramp-up delay, and after its
+// last one while the rest of its phase finished. The remainder went to waiting between requests,
+// for work from its queue, for tenant gates and on client-side work.
+type workerTime struct {
+	phase       string // the phase of its first request
+	created     time.Time
+	first       time.Time
+	last        time.Time
+	busy        time.Duration // time with at least one request in flight
+	pacing      time.Duration // time waiting for the target rate
+	startPacing time.Duration // pacing before the first request, part of the start
+	inFlight    int
+	busySince   time.Time
+	requests    int
+}
+
+// workerBreakdown is how the workers whose first request was in a phase spent their time, each
+// from its creation to the end of the last request of those workers
+type workerBreakdown struct {
+	phase    string
+	workers  int
+	total    time.Duration // the time of all workers together
+	busy     time.Duration
+	starting time.Duration
+	pacing   time.Duration
+	waiting  time.Duration
+	finished time.Duration
+	span     time.Duration // from the first worker's creation to the end of the last request
+
+	// leastBusy is the utilization of the least busy worker, from 0 to 1
+	leastBusy float64
+}
+
+// share returns the part of the workers' time a duration is, from 0 to 1
+func (b workerBreakdown) share(d time.Duration) float64 {
+	if b.total <= 0 {
+		return 0
+	}
+	return d.Seconds() / b.total.Seconds()
+}
+
+// utilization returns the share of the workers' time they had a request in flight, from 0 to 1
+func (b workerBreakdown) utilization() float64 {
+	return b.share(b.busy)
+}
+
+// concurrency returns the average number of workers with a request in flight over the phase
+func (b workerBreakdown) concurrency() float64 {
+	if b.span <= 0 {
+		return 0
+	}
+	return b.busy.Seconds() / b.span.Seconds()
+}
+
+// String formats the breakdown on a single line
+func (b workerBreakdown) String() string {
+	return fmt.Sprintf("%d workers, %.2f busy on average (%.1f%% utilized, least busy worker %.1f%%); "+
+		"start %.1f%%, pacing %.1f%%, between requests %.1f%%, finished early %.1f%%",
+		b.workers, b.concurrency(), b.utilization()*100, b.leastBusy*100,
+		b.share(b.starting)*100, b.share(b.pacing)*100, b.share(b.waiting)*100, b.share(b.finished)*100)
+}
+
+// newWorker starts tracking the time of a worker created now
+func (ts *TestStats) newWorker() *workerTime {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	worker := &workerTime{created: time.Now()}
+	ts.workers = append(ts.workers, worker)
+	return worker
+}
+
+// paced counts a wait of the worker for the target rate. Called by the worker without the lock.
+func (ts *TestStats) paced(worker *workerTime, wait time.Duration) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	worker.pacing += wait
+	if worker.first.IsZero() {
+		worker.startPacing += wait
+	}
+}
+
+// startWorkerRequest counts a request of the worker as in flight. Called with the lock held.
+func (ts *TestStats) startWorkerRequest(worker *workerTime, now time.Time) {
+	if worker.first.IsZero() {
+		worker.first = now
+		worker.phase = ts.Phase
+	}
+	if worker.inFlight == 0 {
+		worker.busySince = now
+	}
+	worker.inFlight++
+}
+
+// finishWorkerRequest counts a request of the worker as no longer in flight, so overlapping
+// requests of a worker count as busy once. Called with the lock held.
+func (ts *TestStats) finishWorkerRequest(worker *workerTime, end time.Time) {
+	worker.inFlight--
+	worker.requests++
+	if worker.inFlight == 0 {
+		worker.busy += end.Sub(worker.busySince)
+	}
+	if end.After(worker.last) {
+		worker.last = end
+	}
+}
+
+// workerBreakdowns returns how the workers of every phase spent their time, in phase order, leaving
+// out workers without requests. Called with the lock held.
+func (ts *TestStats) workerBreakdowns(now time.Time) []workerBreakdown {
+	byPhase := make(map[string][]*workerTime)
+	for _, worker := range ts.workers {
+		if worker.requests > 0 || worker.inFlight > 0 {
+			byPhase[worker.phase] = append(byPhase[worker.phase], worker)
+		}
+	}
+
+	var breakdowns []workerBreakdown
+	for _, phase := range ts.PhaseOrder {
+		workers := byPhase[phase]
+		if len(workers) == 0 {
+			continue
+		}
+
+		// The phase is over for its workers when the last of their requests ended
+		end, start := time.Time{}, workers[0].created
+		for _, worker := range workers {
+			last := worker.last
+			if worker.inFlight > 0 {
+				last = now
+			}
+			if last.After(end) {
+				end = last
+			}
+			if worker.created.Before(start) {
+				start = worker.created
+			}
+		}
+
+		b := workerBreakdown{phase: phase, workers: len(workers), span: end.Sub(start), leastBusy: 1}
+		for _, worker := range workers {
+			busy, last := worker.busy, worker.last
+			if worker.inFlight > 0 {
+				busy += now.Sub(worker.busySince)
+				last = now
+			}
+			total := end.Sub(worker.created)
+			starting := worker.first.Sub(worker.created) - worker.startPacing
+			finished := end.Sub(last)
+
+			b.total += total
+			b.busy += busy
+			b.starting += starting
+			b.pacing += worker.pacing
+			b.finished += finished
+			b.waiting += total - busy - worker.pacing - starting - finished
+			if total > 0 && busy.Seconds()/total.Seconds() < b.leastBusy {
+				b.leastBusy = busy.Seconds() / total.Seconds()
+			}
+		}
+		breakdowns = append(breakdowns, b)
+	}
+	return breakdowns
+}
+
+// printWorkerUtilization prints how the workers of every phase spent their time. Called with the
+// lock held.
+func (ts *TestStats) printWorkerUtilization() {
+	breakdowns := ts.workerBreakdowns(time.Now())
+	if len(breakdowns) == 0 {
+		return
+	}
+
+	fmt.Println("Worker Utilization By Phase:")
+	for _, b := range breakdowns {
+		fmt.Printf("  %s - %s\n", b.phase, b)
+	}
+}
+
+// pace waits for the target rate before a request of the client's worker and counts the wait. The
+// request's start is compared with its time on the schedule of the target rate.
+func (h *HTTPClient) pace(ctx context.Context, limiter *rateLimiter) {
+	wait, slot := limiter.Wait(ctx)
+	h.paced(wait)
+	h.scheduled = slot
+}
+
+// paced counts a wait of the client's worker for the target rate
+func (h *HTTPClient) paced(wait time.Duration) {
+	if h.stats != nil && h.worker != nil && wait > 0 {
+		h.stats.paced(h.worker, wait)
+	}
+}
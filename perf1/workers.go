@@ -1,26 +1,59 @@
 package main
 
+import (
+	"context"
+	"time"
+)
+
 // WorkerTask represents a task for a worker thread
 type WorkerTask struct {
 	UserStart   int
 	UserEnd     int
 	ThreadID    int
 	Client      *HTTPClient
+	TenantStart int
+	TenantEnd   int // exclusive
+	StartDelay  time.Duration
 }
 
 // RetryWorkerTask represents a task for retry worker thread
 type RetryWorkerTask struct {
-	ThreadID    int
-	UserStart   int
-	UserEnd     int
-	FailedUsers []FailedUser
-	Client      *HTTPClient
+	ThreadID   int
+	Client     *HTTPClient
+	StartDelay time.Duration
 }
 
 // FailedUser represents a failed user from CSV
 type FailedUser struct {
-	TenantID  int
-	Username  string
-	Error     string
-	Timestamp string
-}
\ No newline at end of file
+	TenantID        int
+	Username        string
+	Error           string
+	Timestamp       string
+	StatusCode      int    // 0 when the request got no response
+	ResponseSnippet string // scimType and detail of the SCIM error, or the start of the response
+	Attempts        int    // failed creations of the user, counting every retry run
+}
+
+// rampUpStartDelay returns how long the thread at position index waits before starting,
+// spreading the starts of all threads evenly over the ramp-up period
+func (te *TestExecutor) rampUpStartDelay(index, threads int) time.Duration {
+	if threads < 1 {
+		return 0
+	}
+	return time.Duration(te.config.Execution.RampUpPeriod) * time.Second / time.Duration(threads) * time.Duration(index)
+}
+
+// waitForRampUp blocks a worker until its ramp-up start delay has passed or the run is interrupted
+func waitForRampUp(ctx context.Context, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}